@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// maxAudienceListNameLength is the longest name an audience list can have, in characters
+const maxAudienceListNameLength = 64
+
+func writeAudienceListError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrAudienceListNotFound):
+		http.Error(w, "Audience list not found", http.StatusNotFound)
+	case errors.Is(err, db.ErrAudienceListExists):
+		http.Error(w, "An audience list with this name already exists", http.StatusConflict)
+	default:
+		http.Error(w, "Error updating audience lists", http.StatusInternalServerError)
+	}
+}
+
+// cleanAudienceListName trims a list name, answering the request when it's invalid
+func cleanAudienceListName(w http.ResponseWriter, name string) (string, bool) {
+	name = strings.TrimSpace(utils.SanitizeString(name))
+	if name == "" || utf8.RuneCountInString(name) > maxAudienceListNameLength {
+		http.Error(w, "Audience list name must be between 1 and 64 characters", http.StatusBadRequest)
+		return "", false
+	}
+	return name, true
+}
+
+// requireFollowerMembers drops duplicates from the members of a list and checks they all follow the user,
+// answering the request when one does not
+func requireFollowerMembers(w http.ResponseWriter, userID int64, members []int64) ([]int64, bool) {
+	seen := make(map[int64]bool, len(members))
+	unique := []int64{}
+	for _, memberID := range members {
+		if !seen[memberID] {
+			seen[memberID] = true
+			unique = append(unique, memberID)
+		}
+	}
+	followers, err := db.DBService.FilterFollowers(userID, unique)
+	if err != nil {
+		http.Error(w, "Failed to check followers", http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(followers) != len(unique) {
+		http.Error(w, "Audience lists can only hold your followers", http.StatusBadRequest)
+		return nil, false
+	}
+	return unique, true
+}
+
+// withAudienceList adds the members of the list listID to the selected followers of a private post,
+// answering the request when the list isn't one of the user's. Members who stopped following the user
+// since they were added are left out.
+func withAudienceList(w http.ResponseWriter, userID int64, listID *int64, selected []int64) ([]int64, bool) {
+	if listID == nil {
+		return selected, true
+	}
+	list, err := db.DBService.GetAudienceList(userID, *listID)
+	if err != nil {
+		writeAudienceListError(w, err)
+		return nil, false
+	}
+	members, err := db.DBService.FilterFollowers(userID, list.Members)
+	if err != nil {
+		http.Error(w, "Error reading the audience list", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	audience := append([]int64{}, selected...)
+	for _, memberID := range members {
+		picked := false
+		for _, id := range selected {
+			if id == memberID {
+				picked = true
+				break
+			}
+		}
+		if !picked {
+			audience = append(audience, memberID)
+		}
+	}
+	return audience, true
+}
+
+// GetAudienceListsHandler lists the audience lists of the user, or returns the one given by id
+func GetAudienceListsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var response any
+	var err error
+	if idParam := r.URL.Query().Get("id"); idParam != "" {
+		listID, parseErr := strconv.ParseInt(idParam, 10, 64)
+		if parseErr != nil || listID <= 0 {
+			http.Error(w, "Invalid audience list ID", http.StatusBadRequest)
+			return
+		}
+		response, err = db.DBService.GetAudienceList(int64(userID), listID)
+	} else {
+		response, err = db.DBService.GetAudienceLists(int64(userID))
+	}
+	if err != nil {
+		writeAudienceListError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateAudienceListHandler creates a named list of followers
+func CreateAudienceListHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateAudienceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name, ok := cleanAudienceListName(w, req.Name)
+	if !ok {
+		return
+	}
+	members, ok := requireFollowerMembers(w, int64(userID), req.Members)
+	if !ok {
+		return
+	}
+
+	listID, err := db.DBService.CreateAudienceList(int64(userID), name, members)
+	if err != nil {
+		writeAudienceListError(w, err)
+		return
+	}
+	list, err := db.DBService.GetAudienceList(int64(userID), listID)
+	if err != nil {
+		writeAudienceListError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(list)
+}
+
+// UpdateAudienceListHandler renames a list or replaces its members, apply_to_posts carries the member changes
+// over to the posts shared with the list
+func UpdateAudienceListHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateAudienceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Name != nil {
+		name, ok := cleanAudienceListName(w, *req.Name)
+		if !ok {
+			return
+		}
+		req.Name = &name
+	}
+	if req.Members != nil {
+		members, ok := requireFollowerMembers(w, int64(userID), *req.Members)
+		if !ok {
+			return
+		}
+		req.Members = &members
+		// Emptying the audience of a private post would open it to every follower
+		if req.ApplyToPosts && len(members) == 0 {
+			http.Error(w, "An empty list can't be applied to posts", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := db.DBService.UpdateAudienceList(int64(userID), req); err != nil {
+		writeAudienceListError(w, err)
+		return
+	}
+	list, err := db.DBService.GetAudienceList(int64(userID), req.ID)
+	if err != nil {
+		writeAudienceListError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// DeleteAudienceListHandler deletes the list id, the posts shared with it keep their audience
+func DeleteAudienceListHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || listID <= 0 {
+		http.Error(w, "Invalid audience list ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.DeleteAudienceList(int64(userID), listID); err != nil {
+		writeAudienceListError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Audience list deleted"})
+}
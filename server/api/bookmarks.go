@@ -0,0 +1,278 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// maxCollectionNameLength is the longest name a bookmark collection can have, in characters
+const maxCollectionNameLength = 64
+
+// bookmarkTarget returns the content a bookmark request is about, exactly one of post_id and group_post_id
+func bookmarkTarget(postID, groupPostID int64) (string, int64, bool) {
+	switch {
+	case postID > 0 && groupPostID == 0:
+		return models.BookmarkTargetPost, postID, true
+	case groupPostID > 0 && postID == 0:
+		return models.BookmarkTargetGroupPost, groupPostID, true
+	}
+	return "", 0, false
+}
+
+func writeBookmarkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrBookmarkExists):
+		http.Error(w, "Already bookmarked", http.StatusConflict)
+	case errors.Is(err, db.ErrBookmarkNotFound):
+		http.Error(w, "Bookmark not found", http.StatusNotFound)
+	case errors.Is(err, db.ErrCollectionNotFound):
+		http.Error(w, "Collection not found", http.StatusNotFound)
+	case errors.Is(err, db.ErrCollectionExists):
+		http.Error(w, "A collection with this name already exists", http.StatusConflict)
+	default:
+		http.Error(w, "Error updating bookmarks", http.StatusInternalServerError)
+	}
+}
+
+// GetBookmarksHandler lists the bookmarks of the user, in one collection when collection_id is given
+func GetBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	var collectionID *int64
+	if param := q.Get("collection_id"); param != "" {
+		id, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+			return
+		}
+		collectionID = &id
+	}
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = config.GetConfig().FeedPostLimit
+	}
+	offset, err := strconv.Atoi(q.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	bookmarks, err := db.DBService.GetBookmarks(int64(userID), collectionID, limit, offset)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookmarks)
+}
+
+// CreateBookmarkHandler saves a post or a group post the user can see
+func CreateBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	target, contentID, ok := bookmarkTarget(req.PostID, req.GroupPostID)
+	if !ok {
+		http.Error(w, "Give either post_id or group_post_id", http.StatusBadRequest)
+		return
+	}
+
+	if target == models.BookmarkTargetPost {
+		if !requirePostAccess(w, contentID, int64(userID)) {
+			return
+		}
+	} else if !requireGroupPostAccess(w, contentID, int64(userID)) {
+		return
+	}
+
+	id, err := db.DBService.CreateBookmark(int64(userID), target, contentID, req.CollectionID)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"message": "Bookmark saved", "id": id})
+}
+
+// MoveBookmarkHandler moves a bookmark to the collection collection_id, or out of any collection when it's null
+func MoveBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.BookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	target, contentID, ok := bookmarkTarget(req.PostID, req.GroupPostID)
+	if !ok {
+		http.Error(w, "Give either post_id or group_post_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.MoveBookmark(int64(userID), target, contentID, req.CollectionID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Bookmark moved"})
+}
+
+// DeleteBookmarkHandler removes the bookmark of the post_id or group_post_id given in the query
+func DeleteBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	postID, _ := strconv.ParseInt(q.Get("post_id"), 10, 64)
+	groupPostID, _ := strconv.ParseInt(q.Get("group_post_id"), 10, 64)
+	target, contentID, ok := bookmarkTarget(postID, groupPostID)
+	if !ok {
+		http.Error(w, "Give either post_id or group_post_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.DeleteBookmark(int64(userID), target, contentID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Bookmark removed"})
+}
+
+// GetBookmarkCollectionsHandler lists the bookmark collections of the user
+func GetBookmarkCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collections, err := db.DBService.GetBookmarkCollections(int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving collections", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collections)
+}
+
+// decodeCollectionRequest reads a collection request and cleans its name, answering the request when it's invalid
+func decodeCollectionRequest(w http.ResponseWriter, r *http.Request) (*models.BookmarkCollectionRequest, bool) {
+	var req models.BookmarkCollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	req.Name = strings.TrimSpace(utils.SanitizeString(req.Name))
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > maxCollectionNameLength {
+		http.Error(w, "Collection name must be between 1 and 64 characters", http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// CreateBookmarkCollectionHandler creates a named bookmark collection
+func CreateBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req, ok := decodeCollectionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := db.DBService.CreateBookmarkCollection(int64(userID), req.Name)
+	if err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.BookmarkCollection{ID: id, Name: req.Name})
+}
+
+// RenameBookmarkCollectionHandler renames a bookmark collection of the user
+func RenameBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req, ok := decodeCollectionRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.ID <= 0 {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.RenameBookmarkCollection(int64(userID), req.ID, req.Name); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Collection renamed"})
+}
+
+// DeleteBookmarkCollectionHandler deletes the collection id, its bookmarks are kept outside of any collection
+func DeleteBookmarkCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	collectionID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || collectionID <= 0 {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.DeleteBookmarkCollection(int64(userID), collectionID); err != nil {
+		writeBookmarkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Collection deleted"})
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Golden76z/social-network/calendar"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// calendarFeedURL is where calendar apps fetch the feed, the token goes in the query
+var calendarFeedURL = "/calendar/feed.ics"
+
+// SetCalendarFeedURL sets the public address of the calendar feed
+func SetCalendarFeedURL(url string) {
+	if url != "" {
+		calendarFeedURL = url
+	}
+}
+
+// writeCalendar renders the events as an .ics response, filename set makes it a download
+func writeCalendar(w http.ResponseWriter, name, filename string, events []calendar.Event) {
+	var buf bytes.Buffer
+	if err := calendar.Write(&buf, name, events); err != nil {
+		http.Error(w, "Error exporting the calendar", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if filename != "" {
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// ExportGroupEventHandler downloads an event as an iCalendar file. Supports ?id=1
+func ExportGroupEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	eventID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil || eventID <= 0 {
+		http.Error(w, "Missing or invalid id", http.StatusBadRequest)
+		return
+	}
+
+	event, err := db.DBService.GetGroupEventByID(eventID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	isMember, err := db.DBService.IsUserInGroup(int64(userID), event.GroupID)
+	if err != nil {
+		http.Error(w, "Error checking group membership", http.StatusInternalServerError)
+		return
+	}
+	if !isMember {
+		http.Error(w, "Forbidden: you must be a group member to view events", http.StatusForbidden)
+		return
+	}
+
+	events, err := db.DBService.GetGroupEventCalendar(eventID)
+	if err != nil {
+		http.Error(w, "Error exporting the event", http.StatusInternalServerError)
+		return
+	}
+	writeCalendar(w, event.Title, fmt.Sprintf("event-%d.ics", eventID), events)
+}
+
+// GetCalendarFeedHandler returns the secret feed URL of the user, creating it on first use
+func GetCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := utils.RandomURLToken(24)
+	if err != nil {
+		http.Error(w, "Error creating the calendar feed", http.StatusInternalServerError)
+		return
+	}
+	token, err = db.DBService.EnsureCalendarFeedToken(int64(userID), token)
+	if err != nil {
+		http.Error(w, "Error creating the calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"url": calendarFeedURL + "?token=" + token,
+	})
+}
+
+// DeleteCalendarFeedHandler revokes the feed URL, apps subscribed to it stop receiving events
+func DeleteCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := db.DBService.DeleteCalendarFeedToken(int64(userID)); err != nil {
+		http.Error(w, "Error revoking the calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Calendar feed revoked"}`))
+}
+
+// CalendarFeedHandler serves the events of every group of the token owner, no login needed
+func CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := db.DBService.GetUserIDByCalendarFeedToken(token)
+	if err != nil {
+		http.Error(w, "Error reading the calendar feed", http.StatusInternalServerError)
+		return
+	}
+	if userID == 0 {
+		http.Error(w, "Invalid or revoked calendar feed", http.StatusNotFound)
+		return
+	}
+
+	events, err := db.DBService.GetUserCalendarEvents(userID)
+	if err != nil {
+		http.Error(w, "Error reading the calendar feed", http.StatusInternalServerError)
+		return
+	}
+	writeCalendar(w, "Group events", "", events)
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
 	"github.com/Golden76z/social-network/websockets"
 )
 
@@ -19,8 +21,9 @@ type SendMessageRequest struct {
 }
 
 type SendGroupMessageRequest struct {
-	GroupID int64  `json:"group_id"`
-	Body    string `json:"body"`
+	GroupID int64                     `json:"group_id"`
+	Body    string                    `json:"body"`
+	Poll    *models.CreatePollRequest `json:"poll,omitempty"`
 }
 
 type GetMessagesRequest struct {
@@ -253,6 +256,12 @@ func SendGroupMessageHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Message body must be between 1 and 1000 characters", http.StatusBadRequest)
 		return
 	}
+	if req.Poll != nil {
+		if problem := validatePollRequest(req.Poll); problem != "" {
+			http.Error(w, problem, http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Check if user is a member of the group
 	isMember, err := db.DBService.CheckGroupMembership(userID, int(req.GroupID))
@@ -267,8 +276,16 @@ func SendGroupMessageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create the group message
-	messageID, err := db.DBService.CreateGroupMessage(int(req.GroupID), userID, req.Body)
+	messageID, err := db.DBService.CreateGroupMessageWithPoll(int(req.GroupID), userID, req.Body, req.Poll)
 	if err != nil {
+		if errors.Is(err, db.ErrGroupArchived) {
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, db.ErrGroupMuted) {
+			http.Error(w, "Forbidden: you are muted in this group", http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Failed to send group message", http.StatusInternalServerError)
 		return
 	}
@@ -285,6 +302,10 @@ func SendGroupMessageHandler(w http.ResponseWriter, r *http.Request) {
 			Timestamp: time.Now(),
 			MessageID: messageID,
 		}
+		// Clients fetch the poll of the message by its group_message_id
+		if req.Poll != nil {
+			message.Data = map[string]any{"has_poll": true}
+		}
 		hub.BroadcastMessage(message)
 
 		update := websockets.Message{
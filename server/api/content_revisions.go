@@ -0,0 +1,122 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+)
+
+// requireGroupPostAccess answers the request when the user can't see the group post
+func requireGroupPostAccess(w http.ResponseWriter, groupPostID, userID int64) bool {
+	if _, err := db.DBService.GetGroupPostWithImagesByID(groupPostID, userID); err != nil {
+		if err.Error() == "user is not a member of the group" {
+			http.Error(w, "Access denied: Not a group member", http.StatusForbidden)
+			return false
+		}
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// requirePostAccess answers the request when the user can't see the post
+func requirePostAccess(w http.ResponseWriter, postID, userID int64) bool {
+	if _, err := db.DBService.GetPostByID(postID, userID); err != nil {
+		if err.Error() == "unauthorized" {
+			http.Error(w, "You are not authorized to view this post", http.StatusForbidden)
+			return false
+		}
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// requireRevisionAccess answers the request when the user can't see the current version of the content,
+// previous versions are visible to the same users
+func requireRevisionAccess(w http.ResponseWriter, target string, contentID, userID int64) bool {
+	switch target {
+	case models.RevisionTargetPost:
+		return requirePostAccess(w, contentID, userID)
+	case models.RevisionTargetComment:
+		comment, err := db.DBService.GetCommentByID(contentID)
+		if err != nil {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return false
+		}
+		return requirePostAccess(w, comment.PostID, userID)
+	case models.RevisionTargetGroupPost:
+		return requireGroupPostAccess(w, contentID, userID)
+	default:
+		comment, err := db.DBService.GetGroupCommentByID(contentID, userID)
+		if err != nil {
+			http.Error(w, "Comment not found", http.StatusNotFound)
+			return false
+		}
+		return requireGroupPostAccess(w, comment.PostID, userID)
+	}
+}
+
+// GetContentRevisionsHandler lists the previous versions of a post, comment, group post or group comment,
+// or returns one of them when a revision is given
+func GetContentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	target := ""
+	for _, param := range []string{models.RevisionTargetPost, models.RevisionTargetComment, models.RevisionTargetGroupPost, models.RevisionTargetGroupComment} {
+		if q.Get(param) != "" {
+			target = param
+			break
+		}
+	}
+	if target == "" {
+		http.Error(w, "Missing post_id, comment_id, group_post_id or group_comment_id", http.StatusBadRequest)
+		return
+	}
+	contentID, err := strconv.ParseInt(q.Get(target), 10, 64)
+	if err != nil || contentID <= 0 {
+		http.Error(w, "Invalid "+target, http.StatusBadRequest)
+		return
+	}
+	revision := 0
+	if revisionParam := q.Get("revision"); revisionParam != "" {
+		revision, err = strconv.Atoi(revisionParam)
+		if err != nil || revision <= 0 {
+			http.Error(w, "Invalid revision", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !requireRevisionAccess(w, target, contentID, int64(userID)) {
+		return
+	}
+
+	var response any
+	if revision > 0 {
+		response, err = db.DBService.GetContentRevision(target, contentID, revision)
+	} else {
+		response, err = db.DBService.GetContentHistory(target, contentID)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error retrieving revisions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -2,18 +2,77 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
 	"github.com/Golden76z/social-network/utils"
 )
 
+// notifyWaitlistPromoted tells the users taken off the waitlist of an event they are now attending
+func notifyWaitlistPromoted(eventID int64, promoted []db.EventRSVP) {
+	if len(promoted) == 0 {
+		return
+	}
+	event, err := db.DBService.GetGroupEventByID(eventID)
+	if err != nil {
+		return
+	}
+	group, err := db.DBService.GetGroupByID(event.GroupID)
+	if err != nil {
+		return
+	}
+	for _, rsvp := range promoted {
+		notifications.Send(rsvp.UserID, &notifications.EventWaitlist{
+			GroupID:    event.GroupID,
+			GroupName:  group.Title,
+			EventID:    eventID,
+			EventTitle: event.Title,
+			Guests:     rsvp.Guests,
+		})
+	}
+}
+
+// validateRSVPGuests checks the plus ones of a request, only attendees bring guests
+func validateRSVPGuests(w http.ResponseWriter, req models.RSVPToEventRequest) bool {
+	if req.Guests == nil || *req.Guests == 0 {
+		return true
+	}
+	if *req.Guests < 0 {
+		http.Error(w, "Invalid number of guests", http.StatusBadRequest)
+		return false
+	}
+	if req.Status != "come" {
+		http.Error(w, "Only attendees can bring guests", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writeRSVPError maps the errors of the RSVP methods to a response
+func writeRSVPError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, db.ErrEventCancelled):
+		http.Error(w, "Event is cancelled", http.StatusConflict)
+	case errors.Is(err, db.ErrRSVPClosed):
+		http.Error(w, "The RSVP deadline has passed, RSVPs are locked", http.StatusConflict)
+	case errors.Is(err, db.ErrEventFull):
+		http.Error(w, "Not enough seats left for your guests", http.StatusConflict)
+	case errors.Is(err, db.ErrTooManyGuests):
+		http.Error(w, "Too many guests for this event", http.StatusBadRequest)
+	default:
+		http.Error(w, "Error "+action+" RSVP", http.StatusInternalServerError)
+	}
+}
+
 // POST /api/group/event/rsvp
 // Only group members (including event creator) can RSVP.
 // Status must be "going", "interested", or "not_going".
+// Coming to a full event puts the RSVP on the waitlist, the response tells so.
 func RSVPToEventHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.RSVPToEventRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -31,6 +90,9 @@ func RSVPToEventHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid status", http.StatusBadRequest)
 		return
 	}
+	if !validateRSVPGuests(w, req) {
+		return
+	}
 
 	event, err := db.DBService.GetGroupEventByID(req.EventID)
 	if err != nil {
@@ -46,22 +108,30 @@ func RSVPToEventHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "You must be a group member to RSVP", http.StatusForbidden)
 		return
 	}
+	if !requireGroupWritable(w, event.GroupID) {
+		return
+	}
 
 	existing, _ := db.DBService.GetEventRSVPByUserAndEvent(req.EventID, userID)
 	if existing != nil {
 		http.Error(w, "RSVP already exists. Use PUT to update.", http.StatusBadRequest)
 		return
 	}
-	if err := db.DBService.CreateEventRSVP(req); err != nil {
-		http.Error(w, "Error creating RSVP", http.StatusInternalServerError)
+	rsvp, err := db.DBService.CreateEventRSVP(req)
+	if err != nil {
+		writeRSVPError(w, err, "creating")
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	w.Write([]byte(`{"message": "RSVP created"}`))
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "RSVP created",
+		"waitlisted": rsvp.Waitlisted,
+	})
 }
 
 // GET /api/group/event/rsvp?event_id=123&status=going&limit=20&offset=0
-// Only group members can view RSVPs for an event.
+// Only group members can view RSVPs for an event. status=waitlisted lists the waitlist in order.
 func GetEventRSVPsHandler(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 	// Support both event_id and eventId
@@ -110,6 +180,8 @@ func GetEventRSVPsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // PUT /api/group/event/rsvp
+// Guests left out of the body are kept while still coming. Sending the same answer confirms it
+// after the event moved.
 func UpdateEventRSVPHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.RSVPToEventRequest
 	_ = json.NewDecoder(r.Body).Decode(&req) // ignore body errors; we'll also support path/query
@@ -124,6 +196,9 @@ func UpdateEventRSVPHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid status", http.StatusBadRequest)
 		return
 	}
+	if !validateRSVPGuests(w, req) {
+		return
+	}
 
 	// Prefer RSVP id from path if provided
 	var existing *db.EventRSVP
@@ -151,16 +226,32 @@ func UpdateEventRSVPHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	if existing.Status == req.Status {
+	// RSVPs of archived groups are frozen
+	if event, err := db.DBService.GetGroupEventByID(existing.EventID); err == nil && !requireGroupWritable(w, event.GroupID) {
+		return
+	}
+	guests := 0
+	if req.Guests != nil {
+		guests = *req.Guests
+	} else if req.Status == "come" {
+		guests = existing.Guests
+	}
+	if existing.Status == req.Status && existing.Guests == guests && !existing.NeedsConfirmation {
 		http.Error(w, "You already set this RSVP status", http.StatusBadRequest)
 		return
 	}
-	if err := db.DBService.UpdateEventRSVPStatus(existing.ID, req.Status); err != nil {
-		http.Error(w, "Error updating RSVP", http.StatusInternalServerError)
+	rsvp, promoted, err := db.DBService.UpdateEventRSVPStatus(existing.ID, req.Status, guests)
+	if err != nil {
+		writeRSVPError(w, err, "updating")
 		return
 	}
+	notifyWaitlistPromoted(rsvp.EventID, promoted)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message": "RSVP updated"}`))
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":    "RSVP updated",
+		"waitlisted": rsvp.Waitlisted,
+	})
 }
 
 // DELETE /api/group/event/rsvp
@@ -195,14 +286,20 @@ func DeleteEventRSVPHandler(w http.ResponseWriter, r *http.Request) {
 	// 	http.Error(w, "RSVP not found", http.StatusNotFound)
 	// 	return
 	// }
+	// RSVPs of archived groups are frozen
+	if event, err := db.DBService.GetGroupEventByID(existing.EventID); err == nil && !requireGroupWritable(w, event.GroupID) {
+		return
+	}
 	if req.Status != "" && req.Status != existing.Status {
 		http.Error(w, "Status does not match current RSVP", http.StatusBadRequest)
 		return
 	}
-	if err := db.DBService.DeleteEventRSVP(existing.ID); err != nil {
-		http.Error(w, "Error deleting RSVP", http.StatusInternalServerError)
+	promoted, err := db.DBService.DeleteEventRSVP(existing.ID)
+	if err != nil {
+		writeRSVPError(w, err, "deleting")
 		return
 	}
+	notifyWaitlistPromoted(existing.EventID, promoted)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message": "RSVP deleted"}`))
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
 )
 
 // GetFollowRequestHandler returns all pending follow requests for the current user (where the user is the target)
@@ -121,20 +122,14 @@ func CreateFollowHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Continue without notification
 	} else {
-		avatar := ""
-		if requester.Avatar.Valid {
-			avatar = requester.Avatar.String
-		}
-		notificationData := fmt.Sprintf(`{"requester_id": %d, "requester_nickname": "%s", "requester_avatar": "%s", "type": "follow_request"}`,
-			userID, requester.Nickname, avatar)
-		notificationReq := models.CreateNotificationRequest{
-			UserID: req.TargetID,
-			Type:   "follow_request",
-			Data:   notificationData,
-		}
+		actor := notifications.ActorFromUser(requester)
 
 		// Don't fail the follow request if notification creation fails
-		db.DBService.CreateNotification(notificationReq)
+		notifications.Send(req.TargetID, &notifications.FollowRequest{
+			RequesterID:       actor.ID,
+			RequesterNickname: actor.Nickname,
+			RequesterAvatar:   actor.Avatar,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -375,7 +370,7 @@ func DeleteFollowHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clean up related notifications
-	db.DBService.DeleteNotificationsByTypeAndData("follow_request", fmt.Sprintf(`{"requester_id":%d}`, followReq.RequesterID))
+	notifications.Withdraw(followReq.TargetID, notifications.TypeFollowRequest, "requester_id", followReq.RequesterID)
 
 	// If the relationship was accepted, decrement counters
 	if followReq.Status == "accepted" {
@@ -428,21 +423,7 @@ func AcceptFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
 	_ = db.DBService.IncrementFollowersCount(int64(currentUserID))
 
 	// Create notification for the requester
-	targetUser, err := db.DBService.GetUserByID(int64(currentUserID))
-	if err == nil {
-		avatar := ""
-		if targetUser.Avatar.Valid {
-			avatar = targetUser.Avatar.String
-		}
-		notificationData := fmt.Sprintf(`{"target_id": %d, "target_nickname": "%s", "target_avatar": "%s", "type": "follow_accepted"}`,
-			currentUserID, targetUser.Nickname, avatar)
-		notificationReq := models.CreateNotificationRequest{
-			UserID: req.RequesterID,
-			Type:   "follow_accepted",
-			Data:   notificationData,
-		}
-		db.DBService.CreateNotification(notificationReq)
-	}
+	sendFollowAccepted(req.RequesterID, int64(currentUserID))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -525,7 +506,7 @@ func CancelFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clean up related notifications
-	db.DBService.DeleteNotificationsByTypeAndData("follow_request", fmt.Sprintf(`{"requester_id":%d}`, followReq.RequesterID))
+	notifications.Withdraw(followReq.TargetID, notifications.TypeFollowRequest, "requester_id", followReq.RequesterID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -580,3 +561,17 @@ func GetMutualFriendsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(displayInfo)
 }
+
+// sendFollowAccepted tells the requester that targetID accepted their follow request
+func sendFollowAccepted(requesterID, targetID int64) {
+	target, err := db.DBService.GetUserByID(targetID)
+	if err != nil {
+		return
+	}
+	actor := notifications.ActorFromUser(target)
+	notifications.Send(requesterID, &notifications.FollowAccepted{
+		TargetID:       actor.ID,
+		TargetNickname: actor.Nickname,
+		TargetAvatar:   actor.Avatar,
+	})
+}
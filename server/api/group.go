@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Golden76z/social-network/middleware"
 
@@ -111,7 +112,7 @@ func GetGroupHandler(w http.ResponseWriter, r *http.Request) {
 		_ = limitStr
 		// For now, reuse GetGroupByID pattern via a simple query function
 		// Implement a basic listing from DB
-		rows, err := db.DBService.DB.Query(`SELECT id, title, avatar, bio, creator_id, created_at, updated_at FROM groups ORDER BY created_at DESC LIMIT 50`)
+		rows, err := db.DBService.DB.Query(`SELECT id, title, avatar, bio, creator_id, created_at, updated_at, status FROM groups WHERE status != 'deleted' ORDER BY created_at DESC LIMIT 50`)
 		if err != nil {
 			http.Error(w, "Error retrieving groups", http.StatusInternalServerError)
 			return
@@ -121,7 +122,7 @@ func GetGroupHandler(w http.ResponseWriter, r *http.Request) {
 		for rows.Next() {
 			var g models.GroupResponse
 			var avatar sql.NullString
-			if err := rows.Scan(&g.ID, &g.Title, &avatar, &g.Bio, &g.CreatorID, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			if err := rows.Scan(&g.ID, &g.Title, &avatar, &g.Bio, &g.CreatorID, &g.CreatedAt, &g.UpdatedAt, &g.Status); err != nil {
 				http.Error(w, "Error scanning groups", http.StatusInternalServerError)
 				return
 			}
@@ -167,10 +168,10 @@ func GetUserGroupsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Query to get groups where user is a member
 	query := `
-		SELECT g.id, g.title, g.avatar, g.bio, g.creator_id, g.created_at, g.updated_at 
+		SELECT g.id, g.title, g.avatar, g.bio, g.creator_id, g.created_at, g.updated_at, g.status
 		FROM groups g
 		INNER JOIN group_members gm ON g.id = gm.group_id
-		WHERE gm.user_id = ?
+		WHERE gm.user_id = ? AND g.status != 'deleted'
 		ORDER BY g.created_at DESC
 	`
 
@@ -184,7 +185,7 @@ func GetUserGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	var groups []models.GroupResponse
 	for rows.Next() {
 		var g models.GroupResponse
-		if err := rows.Scan(&g.ID, &g.Title, &g.Avatar, &g.Bio, &g.CreatorID, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		if err := rows.Scan(&g.ID, &g.Title, &g.Avatar, &g.Bio, &g.CreatorID, &g.CreatedAt, &g.UpdatedAt, &g.Status); err != nil {
 			http.Error(w, "Error scanning user groups", http.StatusInternalServerError)
 			return
 		}
@@ -232,15 +233,8 @@ func UpdateGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is the creator of the group
-	group, errGet := db.DBService.GetGroupByID(req.ID)
-	if errGet != nil {
-		http.Error(w, "Group not found", http.StatusNotFound)
-		return
-	}
-
-	if group.CreatorID != userID {
-		http.Error(w, "Only the group creator can update the group", http.StatusForbidden)
+	// Only the owner edits the group, and archived groups stay as they are
+	if !requireGroupWritable(w, req.ID) || !requireGroupOwner(w, userID, req.ID) {
 		return
 	}
 
@@ -272,9 +266,13 @@ func UpdateGroupHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Update group in database
+	// Update group in database, with the admin 2FA requirement in the same transaction
 	errDB := db.DBService.UpdateGroup(req.ID, req)
 	if errDB != nil {
+		if errDB.Error() == "every group admin must enable two-factor authentication first" {
+			http.Error(w, "Every group admin must enable two-factor authentication first", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Error updating the group", http.StatusInternalServerError)
 		return
 	}
@@ -312,26 +310,29 @@ func DeleteGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is the creator of the group
 	group, errGet := db.DBService.GetGroupByID(int64(groupID))
 	if errGet != nil {
 		http.Error(w, "Group not found", http.StatusNotFound)
 		return
 	}
-
-	if group.CreatorID != userID {
-		http.Error(w, "Only the group creator can delete the group", http.StatusForbidden)
+	if !requireGroupOwner(w, userID, group.ID) {
 		return
 	}
 
-	// Delete group from database
-	errDB := db.DBService.DeleteGroup(int64(groupID))
+	// The group is hidden and purged once the restore window has passed
+	restoreUntil, errDB := db.DBService.DeleteGroup(group.ID, groupRestoreWindow)
 	if errDB != nil {
 		http.Error(w, "Error deleting the group", http.StatusInternalServerError)
 		return
 	}
 
-	// Send back a response to the client-side in case of success
+	logModerationAction(group.ID, userID, 0, models.ModerationDeleteGroup, "restorable until "+restoreUntil.Format(time.RFC3339))
+	notifyGroupStatus(group.ID, userID, group.Title, models.GroupStatusDeleted, restoreUntil.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"response": "Group successfully deleted"}`))
+	json.NewEncoder(w).Encode(map[string]string{
+		"response":      "Group successfully deleted",
+		"restore_until": restoreUntil.Format(time.RFC3339),
+	})
 }
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -35,9 +36,22 @@ func CreateGroupCommentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	groupID, err := db.DBService.GetGroupIDFromPost(req.GroupPostID)
+	if err != nil {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if !requireGroupPermission(w, int64(userID), groupID, models.GroupPermComment) {
+		return
+	}
+
 	// Calling the Database to create the new Group's Comment
 	errDB := db.DBService.CreateGroupComment(req, int64(userID))
 	if errDB != nil {
+		if errors.Is(errDB, db.ErrCommentsClosed) {
+			http.Error(w, "Comments are closed on announcements", http.StatusForbidden)
+			return
+		}
 		http.Error(w, "Error creating the group comment", http.StatusInternalServerError)
 		return
 	}
@@ -154,6 +168,10 @@ func UpdateGroupCommentHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Comment not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(errDB, db.ErrGroupArchived) {
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+			return
+		}
 		if strings.HasPrefix(errDB.Error(), "unauthorized") {
 			http.Error(w, "Forbidden: You can only update your own comments", http.StatusForbidden)
 			return
@@ -199,6 +217,10 @@ func DeleteGroupCommentHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Comment not found", http.StatusNotFound)
 			return
 		}
+		if errors.Is(errDB, db.ErrGroupArchived) {
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+			return
+		}
 		if strings.HasPrefix(errDB.Error(), "unauthorized") {
 			http.Error(w, "Forbidden: You can only delete your own comments", http.StatusForbidden)
 			return
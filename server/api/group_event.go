@@ -2,17 +2,159 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/Golden76z/social-network/calendar"
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
 	"github.com/Golden76z/social-network/utils"
 )
 
+// maxEventLocationLength bounds the free text location of an event
+const maxEventLocationLength = 255
+
+// eventRescheduleThreshold is how far an event moves before the RSVPs are asked to be confirmed again
+var eventRescheduleThreshold = time.Hour
+
+// SetEventRescheduleThreshold sets how far an event moves before its RSVPs need confirming again
+func SetEventRescheduleThreshold(threshold time.Duration) {
+	if threshold > 0 {
+		eventRescheduleThreshold = threshold
+	}
+}
+
+// eventFieldLabels name the changed fields of an event in notifications
+var eventFieldLabels = map[string]string{
+	"event_datetime": "time",
+	"end_datetime":   "end time",
+	"rrule":          "recurrence",
+	"max_guests":     "guest limit",
+	"rsvp_deadline":  "RSVP deadline",
+}
+
+// notifyEventChanged tells the users who answered an event, the actor aside, what an update changed
+func notifyEventChanged(event *db.GroupEvent, actorID int64, changes []db.GroupEventChange, confirmRSVP bool) {
+	recipients, group, actor, ok := eventNotificationTargets(event, actorID)
+	if !ok {
+		return
+	}
+	fields := make([]notifications.EventFieldChange, len(changes))
+	for i, change := range changes {
+		label, ok := eventFieldLabels[change.Field]
+		if !ok {
+			label = change.Field
+		}
+		fields[i] = notifications.EventFieldChange{Field: change.Field, Label: label, Old: change.OldValue, New: change.NewValue}
+	}
+	notifications.SendToMany(recipients, &notifications.EventChanged{
+		GroupID:       event.GroupID,
+		GroupName:     group.Title,
+		EventID:       event.ID,
+		EventTitle:    event.Title,
+		Changes:       fields,
+		ConfirmRSVP:   confirmRSVP,
+		ActorID:       actor.ID,
+		ActorNickname: actor.Nickname,
+	})
+}
+
+// notifyEventCancelled tells the users who answered an event, the actor aside, it was cancelled
+func notifyEventCancelled(event *db.GroupEvent, actorID int64, reason string) {
+	recipients, group, actor, ok := eventNotificationTargets(event, actorID)
+	if !ok {
+		return
+	}
+	notifications.SendToMany(recipients, &notifications.EventCancelled{
+		GroupID:       event.GroupID,
+		GroupName:     group.Title,
+		EventID:       event.ID,
+		EventTitle:    event.Title,
+		Reason:        reason,
+		ActorID:       actor.ID,
+		ActorNickname: actor.Nickname,
+	})
+}
+
+func eventNotificationTargets(event *db.GroupEvent, actorID int64) ([]int64, *models.GroupResponse, notifications.Actor, bool) {
+	userIDs, err := db.DBService.GetEventRSVPUserIDs(event.ID)
+	if err != nil {
+		return nil, nil, notifications.Actor{}, false
+	}
+	recipients := make([]int64, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id != actorID {
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, nil, notifications.Actor{}, false
+	}
+	group, err := db.DBService.GetGroupByID(event.GroupID)
+	if err != nil {
+		return nil, nil, notifications.Actor{}, false
+	}
+	user, err := db.DBService.GetUserByID(actorID)
+	if err != nil {
+		return nil, nil, notifications.Actor{}, false
+	}
+	return recipients, group, notifications.ActorFromUser(user), true
+}
+
+// parseEventTime accepts "YYYY-MM-DD HH:MM:SS" as a wall clock time in loc or RFC3339,
+// and normalizes to the stored wall clock format
+func parseEventTime(value string, loc *time.Location) (string, error) {
+	if t, err := time.ParseInLocation(db.EventTimeLayout, value, loc); err == nil {
+		return t.Format(db.EventTimeLayout), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", err
+	}
+	return t.In(loc).Format(db.EventTimeLayout), nil
+}
+
+// requireEventCreator lets the creator of the event change it while still a member of a writable group
+func requireEventCreator(w http.ResponseWriter, userID int64, event *db.GroupEvent) bool {
+	if event.CreatorID != userID {
+		http.Error(w, "Forbidden: only the creator can update this event", http.StatusForbidden)
+		return false
+	}
+	// Check if creator is still a member of the group
+	isMember, err := db.DBService.IsUserInGroup(userID, event.GroupID)
+	if err != nil {
+		http.Error(w, "Error checking group membership", http.StatusInternalServerError)
+		return false
+	}
+	if !isMember {
+		http.Error(w, "Forbidden: creator is no longer a member of the group", http.StatusForbidden)
+		return false
+	}
+	return requireGroupWritable(w, event.GroupID)
+}
+
+// requireEventManager lets the creator of the event remove or cancel it, and moderators through the
+// delete_content permission
+func requireEventManager(w http.ResponseWriter, userID int64, event *db.GroupEvent) bool {
+	if event.CreatorID != userID {
+		return requireGroupPermission(w, userID, event.GroupID, models.GroupPermDeleteContent)
+	}
+	isMember, err := db.DBService.IsUserInGroup(userID, event.GroupID)
+	if err != nil {
+		http.Error(w, "Error checking group membership", http.StatusInternalServerError)
+		return false
+	}
+	if !isMember {
+		http.Error(w, "Forbidden: creator is no longer a member of the group", http.StatusForbidden)
+		return false
+	}
+	return requireGroupWritable(w, event.GroupID)
+}
+
 // CreateGroupEventHandler handles the creation of a new group event
 func CreateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateGroupEventRequest
@@ -24,12 +166,53 @@ func CreateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing required fields", http.StatusBadRequest)
 		return
 	}
-	// Validate datetime format: accept "YYYY-MM-DD HH:MM:SS" or RFC3339, normalize to SQL format
-	if _, err := time.Parse("2006-01-02 15:04:05", req.EventDateTime); err != nil {
-		if t, errRFC := time.Parse(time.RFC3339, req.EventDateTime); errRFC == nil {
-			req.EventDateTime = t.Format("2006-01-02 15:04:05")
-		} else {
-			http.Error(w, "Invalid event_date_time format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	loc, err := calendar.LoadLocation(req.Timezone)
+	if err != nil {
+		http.Error(w, "Invalid timezone, use an IANA name like Europe/Paris", http.StatusBadRequest)
+		return
+	}
+	req.Timezone = loc.String()
+	// Validate datetime format: accept "YYYY-MM-DD HH:MM:SS" in the timezone or RFC3339, normalize to SQL format
+	if req.EventDateTime, err = parseEventTime(req.EventDateTime, loc); err != nil {
+		http.Error(w, "Invalid event_date_time format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+		return
+	}
+	if req.EndDateTime != "" {
+		if req.EndDateTime, err = parseEventTime(req.EndDateTime, loc); err != nil {
+			http.Error(w, "Invalid end_date_time format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+			return
+		}
+		if req.EndDateTime <= req.EventDateTime {
+			http.Error(w, "The event must end after it starts", http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Location) > maxEventLocationLength {
+		http.Error(w, "Location is too long", http.StatusBadRequest)
+		return
+	}
+	if req.RRule != "" {
+		rule, err := calendar.ParseRule(req.RRule, loc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.RRule = rule.String()
+	}
+	if req.Capacity < 0 || req.MaxGuests < 0 {
+		http.Error(w, "capacity and max_guests must not be negative", http.StatusBadRequest)
+		return
+	}
+	if req.RSVPDeadline != "" {
+		if req.RSVPDeadline, err = parseEventTime(req.RSVPDeadline, loc); err != nil {
+			http.Error(w, "Invalid rsvp_deadline format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+			return
+		}
+		if req.RSVPDeadline > req.EventDateTime {
+			http.Error(w, "The RSVP deadline must not be after the event starts", http.StatusBadRequest)
 			return
 		}
 	}
@@ -39,29 +222,13 @@ func CreateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 		creatorID = int64(ctxID)
 	}
 
-	// 1. Check if group exists
-	exists, err := db.DBService.GroupExists(req.GroupID)
-	if err != nil {
-		http.Error(w, "Error checking group existence", http.StatusInternalServerError)
-		return
-	}
-	if !exists {
-		http.Error(w, "Group does not exist", http.StatusBadRequest)
+	// Check the group exists and the role of the user allows creating events
+	if !requireGroupPermission(w, creatorID, req.GroupID, models.GroupPermCreateEvent) {
 		return
 	}
 
-	// 2. Check if user is a member of the group
-	isMember, err := db.DBService.IsUserInGroup(creatorID, req.GroupID)
+	eventID, err := db.DBService.CreateGroupEvent(req, creatorID)
 	if err != nil {
-		http.Error(w, "Error checking group membership", http.StatusInternalServerError)
-		return
-	}
-	if !isMember {
-		http.Error(w, "You must be a group member to create an event", http.StatusForbidden)
-		return
-	}
-
-	if err := db.DBService.CreateGroupEvent(req, creatorID); err != nil {
 		http.Error(w, "Error creating event", http.StatusInternalServerError)
 		return
 	}
@@ -73,24 +240,22 @@ func CreateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 		if err == nil {
 			creator, err := db.DBService.GetUserByID(creatorID)
 			if err == nil {
+				recipients := make([]int64, 0, len(members))
 				for _, member := range members {
 					if member.UserID != creatorID { // Don't notify the creator
-						avatar := ""
-						if creator.Avatar.Valid {
-							avatar = creator.Avatar.String
-						}
-						notificationData := fmt.Sprintf(`{"group_id": %d, "group_name": "%s", "event_id": %d, "event_title": "%s", "creator_id": %d, "creator_nickname": "%s", "creator_avatar": "%s", "type": "group_event"}`,
-							req.GroupID, group.Title, 0, req.Title, creatorID, creator.Nickname, avatar) // event_id will be 0 for now, could be improved
-						notificationReq := models.CreateNotificationRequest{
-							UserID: member.UserID,
-							Type:   "group_event",
-							Data:   notificationData,
-						}
-						if err := db.DBService.CreateNotification(notificationReq); err != nil {
-							fmt.Printf("[WARNING] Failed to create notification for group event: %v\n", err)
-						}
+						recipients = append(recipients, member.UserID)
 					}
 				}
+				actor := notifications.ActorFromUser(creator)
+				notifications.SendToMany(recipients, &notifications.GroupEvent{
+					GroupID:         req.GroupID,
+					GroupName:       group.Title,
+					EventID:         eventID,
+					EventTitle:      req.Title,
+					CreatorID:       actor.ID,
+					CreatorNickname: actor.Nickname,
+					CreatorAvatar:   actor.Avatar,
+				})
 			}
 		}
 	}
@@ -174,6 +339,10 @@ func GetGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 	toDate := q.Get("to_date")
 
 	events, err := db.DBService.GetGroupEvents(groupID, upcoming, limit, offset, fromDate, toDate)
+	if errors.Is(err, db.ErrInvalidEventDate) {
+		http.Error(w, "Invalid from_date or to_date, use YYYY-MM-DD or RFC3339", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, "Error fetching events: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -201,22 +370,12 @@ func UpdateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing event ID", http.StatusBadRequest)
 		return
 	}
-	if req.Title == nil && req.Description == nil && req.EventDateTime == nil {
+	if req.Title == nil && req.Description == nil && req.EventDateTime == nil && req.EndDateTime == nil &&
+		req.Timezone == nil && req.Location == nil && req.RRule == nil &&
+		req.Capacity == nil && req.MaxGuests == nil && req.RSVPDeadline == nil {
 		http.Error(w, "No fields to update", http.StatusBadRequest)
 		return
 	}
-	// Validate/normalize date format if provided: accept SQL format or RFC3339
-	if req.EventDateTime != nil {
-		if _, err := time.Parse("2006-01-02 15:04:05", *req.EventDateTime); err != nil {
-			if t, errRFC := time.Parse(time.RFC3339, *req.EventDateTime); errRFC == nil {
-				normalized := t.Format("2006-01-02 15:04:05")
-				req.EventDateTime = &normalized
-			} else {
-				http.Error(w, "Invalid event_date_time format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
-				return
-			}
-		}
-	}
 	// Access control: only creator can update and must still be a group member
 	event, err := db.DBService.GetGroupEventByID(req.ID)
 	if err != nil {
@@ -227,26 +386,98 @@ func UpdateGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 	if ctxID, ok := r.Context().Value(middleware.UserIDKey).(int); ok {
 		userID = int64(ctxID)
 	}
-	if event.CreatorID != userID {
-		http.Error(w, "Forbidden: only the creator can update this event", http.StatusForbidden)
+	if !requireEventCreator(w, userID, event) {
 		return
 	}
-	// Check if creator is still a member of the group
-	isMember, err := db.DBService.IsUserInGroup(userID, event.GroupID)
+	// Times are read in the new timezone when it changes
+	timezone := event.Timezone
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	loc, err := calendar.LoadLocation(timezone)
 	if err != nil {
-		http.Error(w, "Error checking group membership", http.StatusInternalServerError)
+		http.Error(w, "Invalid timezone, use an IANA name like Europe/Paris", http.StatusBadRequest)
 		return
 	}
-	if !isMember {
-		http.Error(w, "Forbidden: creator is no longer a member of the group", http.StatusForbidden)
+	if req.Timezone != nil {
+		timezone = loc.String()
+		req.Timezone = &timezone
+	}
+	// Validate/normalize date format if provided: accept SQL format or RFC3339
+	if req.EventDateTime != nil {
+		normalized, err := parseEventTime(*req.EventDateTime, loc)
+		if err != nil {
+			http.Error(w, "Invalid event_date_time format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+			return
+		}
+		req.EventDateTime = &normalized
+	}
+	if req.EndDateTime != nil && *req.EndDateTime != "" {
+		normalized, err := parseEventTime(*req.EndDateTime, loc)
+		if err != nil {
+			http.Error(w, "Invalid end_date_time format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+			return
+		}
+		req.EndDateTime = &normalized
+	}
+	if req.Location != nil && len(*req.Location) > maxEventLocationLength {
+		http.Error(w, "Location is too long", http.StatusBadRequest)
 		return
 	}
-	if err := db.DBService.UpdateGroupEvent(req.ID, req); err != nil {
-		http.Error(w, "Error updating event: "+err.Error(), http.StatusInternalServerError)
+	if req.RRule != nil && *req.RRule != "" {
+		rule, err := calendar.ParseRule(*req.RRule, loc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		normalized := rule.String()
+		req.RRule = &normalized
+	}
+	if (req.Capacity != nil && *req.Capacity < 0) || (req.MaxGuests != nil && *req.MaxGuests < 0) {
+		http.Error(w, "capacity and max_guests must not be negative", http.StatusBadRequest)
 		return
 	}
+	if req.RSVPDeadline != nil && *req.RSVPDeadline != "" {
+		normalized, err := parseEventTime(*req.RSVPDeadline, loc)
+		if err != nil {
+			http.Error(w, "Invalid rsvp_deadline format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+			return
+		}
+		req.RSVPDeadline = &normalized
+	}
+	update, err := db.DBService.UpdateGroupEvent(req.ID, req, userID, eventRescheduleThreshold)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrEventCancelled):
+			http.Error(w, "Event is cancelled", http.StatusConflict)
+		case err.Error() == "event must end after it starts":
+			http.Error(w, "The event must end after it starts", http.StatusBadRequest)
+		case err.Error() == "RSVP deadline must not be after the event starts":
+			http.Error(w, "The RSVP deadline must not be after the event starts", http.StatusBadRequest)
+		default:
+			http.Error(w, "Error updating event: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	notifyWaitlistPromoted(req.ID, update.Promoted)
+
+	if len(update.Changes) > 0 {
+		if updated, err := db.DBService.GetGroupEventByID(req.ID); err == nil {
+			notifyEventChanged(updated, userID, update.Changes, update.RSVPsReset)
+		}
+	}
+
+	changes := update.Changes
+	if changes == nil {
+		changes = []db.GroupEventChange{}
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message": "Event updated"}`))
+	json.NewEncoder(w).Encode(map[string]any{
+		"message":     "Event updated",
+		"changes":     changes,
+		"rsvps_reset": update.RSVPsReset,
+	})
 }
 
 // Handler to delete an event in a group
@@ -275,25 +506,264 @@ func DeleteGroupEventHandler(w http.ResponseWriter, r *http.Request) {
 	if ctxID, ok := r.Context().Value(middleware.UserIDKey).(int); ok {
 		userID = int64(ctxID)
 	}
-	// Only the creator can delete, or deletion is triggered by creator leaving the group (handled elsewhere)
+	if !requireEventManager(w, userID, event) {
+		return
+	}
+	if err := db.DBService.DeleteGroupEvent(req.ID); err != nil {
+		http.Error(w, "Error deleting event: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
 	if event.CreatorID != userID {
-		http.Error(w, "Forbidden: only the creator can delete this event", http.StatusForbidden)
+		logModerationAction(event.GroupID, userID, event.CreatorID, models.ModerationDeleteEvent, "event "+strconv.FormatInt(req.ID, 10)+": "+event.Title)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Event deleted"}`))
+}
+
+// GetGroupEventExceptionsHandler lists the cancelled and moved occurrences of an event. Supports ?event_id=1
+func GetGroupEventExceptionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	// Check if creator is still a member of the group
-	isMember, err := db.DBService.IsUserInGroup(userID, event.GroupID)
+	eventID, err := strconv.ParseInt(r.URL.Query().Get("event_id"), 10, 64)
+	if err != nil || eventID <= 0 {
+		http.Error(w, "Missing or invalid event_id", http.StatusBadRequest)
+		return
+	}
+	event, err := db.DBService.GetGroupEventByID(eventID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	isMember, err := db.DBService.IsUserInGroup(int64(userID), event.GroupID)
 	if err != nil {
 		http.Error(w, "Error checking group membership", http.StatusInternalServerError)
 		return
 	}
 	if !isMember {
-		http.Error(w, "Forbidden: creator is no longer a member of the group", http.StatusForbidden)
+		http.Error(w, "Forbidden: you must be a group member to view events", http.StatusForbidden)
 		return
 	}
-	if err := db.DBService.DeleteGroupEvent(req.ID); err != nil {
-		http.Error(w, "Error deleting event: "+err.Error(), http.StatusInternalServerError)
+
+	exceptions, err := db.DBService.GetGroupEventExceptions(eventID)
+	if err != nil {
+		http.Error(w, "Error fetching exceptions", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exceptions)
+}
+
+// SetGroupEventExceptionHandler cancels or reschedules one occurrence of a recurring event (creator only)
+func SetGroupEventExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.GroupEventExceptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EventID <= 0 || req.Occurrence == "" {
+		http.Error(w, "Missing event_id or occurrence", http.StatusBadRequest)
+		return
+	}
+	if !req.Cancelled && req.EventDateTime == nil && req.EndDateTime == nil && req.Title == nil &&
+		req.Description == nil && req.Location == nil {
+		http.Error(w, "Cancel the occurrence or give the fields to change", http.StatusBadRequest)
+		return
+	}
+
+	event, err := db.DBService.GetGroupEventByID(req.EventID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if !requireEventCreator(w, int64(userID), event) {
+		return
+	}
+	if event.RRule == "" {
+		http.Error(w, "Only the occurrences of a recurring event can be changed", http.StatusBadRequest)
+		return
+	}
+
+	loc, err := calendar.LoadLocation(event.Timezone)
+	if err != nil {
+		http.Error(w, "Error reading the event timezone", http.StatusInternalServerError)
+		return
+	}
+	if req.Occurrence, err = parseEventTime(req.Occurrence, loc); err != nil {
+		http.Error(w, "Invalid occurrence format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+		return
+	}
+	for _, field := range []**string{&req.EventDateTime, &req.EndDateTime} {
+		if *field == nil {
+			continue
+		}
+		normalized, err := parseEventTime(**field, loc)
+		if err != nil {
+			http.Error(w, "Invalid event_date_time or end_date_time format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+			return
+		}
+		*field = &normalized
+	}
+	if req.Location != nil && len(*req.Location) > maxEventLocationLength {
+		http.Error(w, "Location is too long", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.SetGroupEventException(req); err != nil {
+		switch err.Error() {
+		case "occurrence not found":
+			http.Error(w, "The event has no occurrence starting at this time", http.StatusNotFound)
+		case "event must end after it starts":
+			http.Error(w, "The event must end after it starts", http.StatusBadRequest)
+		default:
+			http.Error(w, "Error saving the exception", http.StatusInternalServerError)
+		}
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message": "Event deleted"}`))
+	w.Write([]byte(`{"message": "Occurrence updated"}`))
+}
+
+// DeleteGroupEventExceptionHandler restores an occurrence as the recurrence gives it (creator only)
+func DeleteGroupEventExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.GroupEventExceptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.EventID <= 0 || req.Occurrence == "" {
+		http.Error(w, "Missing event_id or occurrence", http.StatusBadRequest)
+		return
+	}
+
+	event, err := db.DBService.GetGroupEventByID(req.EventID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if !requireEventCreator(w, int64(userID), event) {
+		return
+	}
+	loc, err := calendar.LoadLocation(event.Timezone)
+	if err != nil {
+		http.Error(w, "Error reading the event timezone", http.StatusInternalServerError)
+		return
+	}
+	occurrence, err := parseEventTime(req.Occurrence, loc)
+	if err != nil {
+		http.Error(w, "Invalid occurrence format. Use YYYY-MM-DD HH:MM:SS or RFC3339", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.DeleteGroupEventException(req.EventID, occurrence); err != nil {
+		if err.Error() == "exception not found" {
+			http.Error(w, "This occurrence has no exception", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error restoring the occurrence", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Occurrence restored"}`))
+}
+
+// CancelGroupEventHandler cancels an event, it stays listed with its RSVPs locked. Body: {"id": 1, "reason": "..."}
+func CancelGroupEventHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.CancelGroupEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Reason) > 500 {
+		http.Error(w, "Reason is too long", http.StatusBadRequest)
+		return
+	}
+
+	event, err := db.DBService.GetGroupEventByID(req.ID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	if !requireEventManager(w, int64(userID), event) {
+		return
+	}
+	if err := db.DBService.CancelGroupEvent(req.ID, int64(userID), req.Reason); err != nil {
+		if errors.Is(err, db.ErrEventCancelled) {
+			http.Error(w, "Event is already cancelled", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Error cancelling event", http.StatusInternalServerError)
+		return
+	}
+	if event.CreatorID != int64(userID) {
+		logModerationAction(event.GroupID, int64(userID), event.CreatorID, models.ModerationCancelEvent,
+			"event "+strconv.FormatInt(req.ID, 10)+": "+event.Title)
+	}
+	notifyEventCancelled(event, int64(userID), req.Reason)
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Event cancelled"}`))
+}
+
+// GetGroupEventChangesHandler lists what updates changed on an event, latest first. Supports ?event_id=1&limit=20&offset=0
+func GetGroupEventChangesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+	eventID, err := strconv.ParseInt(q.Get("event_id"), 10, 64)
+	if err != nil || eventID <= 0 {
+		http.Error(w, "Missing or invalid event_id", http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	event, err := db.DBService.GetGroupEventByID(eventID)
+	if err != nil {
+		http.Error(w, "Event not found", http.StatusNotFound)
+		return
+	}
+	isMember, err := db.DBService.IsUserInGroup(int64(userID), event.GroupID)
+	if err != nil {
+		http.Error(w, "Error checking group membership", http.StatusInternalServerError)
+		return
+	}
+	if !isMember {
+		http.Error(w, "Forbidden: you must be a group member to view events", http.StatusForbidden)
+		return
+	}
+
+	changes, err := db.DBService.GetGroupEventChanges(eventID, limit, offset)
+	if err != nil {
+		http.Error(w, "Error fetching the event changes", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
 }
@@ -2,13 +2,13 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
 )
 
 // POST /api/group/invitation
@@ -23,21 +23,15 @@ func CreateGroupInvitationHandler(w http.ResponseWriter, r *http.Request) {
 		creatorID = int64(ctxID)
 	}
 
-	// Check if the user is a member of the group (creator or regular member)
 	group, err := db.DBService.GetGroupByID(req.GroupID)
 	if err != nil {
 		http.Error(w, "Group not found", http.StatusNotFound)
 		return
 	}
 
-	// Check if the user is a member of the group (creator or regular member can invite)
-	inviterIsMember, err := db.DBService.IsUserInGroup(creatorID, req.GroupID)
-	if err != nil {
-		http.Error(w, "Error checking membership", http.StatusInternalServerError)
-		return
-	}
-	if !inviterIsMember {
-		http.Error(w, "Only group members can send invitations", http.StatusForbidden)
+	// The role of the inviter must allow invitations
+	if !requireGroupPermission(w, creatorID, req.GroupID, models.GroupPermInvite) ||
+		!requireNotBanned(w, req.UserID, req.GroupID) {
 		return
 	}
 
@@ -69,20 +63,16 @@ func CreateGroupInvitationHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create notification for the invited user
-	invitedUser, err := db.DBService.GetUserByID(req.UserID)
+	inviter, err := db.DBService.GetUserByID(creatorID)
 	if err == nil {
-		avatar := ""
-		if invitedUser.Avatar.Valid {
-			avatar = invitedUser.Avatar.String
-		}
-		notificationData := fmt.Sprintf(`{"group_id": %d, "group_name": "%s", "inviter_id": %d, "inviter_nickname": "%s", "inviter_avatar": "%s", "type": "group_invite"}`,
-			req.GroupID, group.Title, creatorID, invitedUser.Nickname, avatar)
-		notificationReq := models.CreateNotificationRequest{
-			UserID: req.UserID,
-			Type:   "group_invite",
-			Data:   notificationData,
-		}
-		db.DBService.CreateNotification(notificationReq)
+		actor := notifications.ActorFromUser(inviter)
+		notifications.Send(req.UserID, &notifications.GroupInvite{
+			GroupID:         req.GroupID,
+			GroupName:       group.Title,
+			InviterID:       actor.ID,
+			InviterNickname: actor.Nickname,
+			InviterAvatar:   actor.Avatar,
+		})
 	}
 
 	w.WriteHeader(http.StatusCreated)
@@ -154,6 +144,9 @@ func UpdateGroupInvitationHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invitation already responded", http.StatusBadRequest)
 		return
 	}
+	if req.Status == "accepted" && (!requireGroupWritable(w, inv.GroupID) || !requireNotBanned(w, userID, inv.GroupID)) {
+		return
+	}
 
 	if err := db.DBService.UpdateGroupInvitationStatus(req.ID, req.Status); err != nil {
 		http.Error(w, "Error updating invitation", http.StatusInternalServerError)
@@ -177,7 +170,7 @@ func UpdateGroupInvitationHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // DELETE /api/group/invitation
-// Only the creator can delete the invitation
+// The inviter or members who approve requests can delete the invitation
 func DeleteGroupInvitationHandler(w http.ResponseWriter, r *http.Request) {
 	type DeleteInvitationRequest struct {
 		ID int64 `json:"id"`
@@ -197,14 +190,7 @@ func DeleteGroupInvitationHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invitation not found", http.StatusNotFound)
 		return
 	}
-	// Only creator can delete
-	group, err := db.DBService.GetGroupByID(inv.GroupID)
-	if err != nil {
-		http.Error(w, "Group not found", http.StatusNotFound)
-		return
-	}
-	if group.CreatorID != userID {
-		http.Error(w, "Only the group creator can delete the invitation", http.StatusForbidden)
+	if inv.InvitedBy != userID && !requireGroupPermission(w, userID, inv.GroupID, models.GroupPermApproveRequests) {
 		return
 	}
 
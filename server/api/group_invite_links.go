@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// inviteLinkCodeBytes is the entropy of an invite code, 12 bytes give a 16 character code
+const inviteLinkCodeBytes = 12
+
+// GetGroupInviteLinksHandler lists the invite links of a group. Supports ?group_id=1
+func GetGroupInviteLinksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := groupIDFromQuery(w, r)
+	if !ok || !requireGroupModerator(w, int64(userID), groupID, models.GroupPermInviteLinks) {
+		return
+	}
+
+	links, err := db.DBService.GetGroupInviteLinks(groupID)
+	if err != nil {
+		http.Error(w, "Error retrieving invite links", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// CreateGroupInviteLinkHandler generates a link with a random code
+func CreateGroupInviteLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateGroupInviteLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.GroupInviteLinkRequest
+	}
+	if req.GroupID <= 0 || (req.Mode != models.GroupInviteLinkAuto && req.Mode != models.GroupInviteLinkRequest) {
+		http.Error(w, "Missing group_id or invalid mode", http.StatusBadRequest)
+		return
+	}
+	if req.MaxUses < 0 || req.ExpiresInMinutes < 0 {
+		http.Error(w, "max_uses and expires_in_minutes cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	if !requireGroupPermission(w, int64(userID), req.GroupID, models.GroupPermInviteLinks) {
+		return
+	}
+
+	code, err := utils.RandomURLToken(inviteLinkCodeBytes)
+	if err != nil {
+		http.Error(w, "Error creating the invite link", http.StatusInternalServerError)
+		return
+	}
+	var expiresAt *time.Time
+	if req.ExpiresInMinutes > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.ExpiresInMinutes) * time.Minute)
+		expiresAt = &t
+	}
+
+	link, err := db.DBService.CreateGroupInviteLink(req.GroupID, int64(userID), code, req.Mode, req.MaxUses, expiresAt)
+	if err != nil {
+		http.Error(w, "Error creating the invite link", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
+
+// RevokeGroupInviteLinkHandler revokes one link, or every outstanding link of the group when no id is given
+func RevokeGroupInviteLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RevokeGroupInviteLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.GroupID <= 0 {
+		http.Error(w, "Missing group_id", http.StatusBadRequest)
+		return
+	}
+
+	if !requireGroupPermission(w, int64(userID), req.GroupID, models.GroupPermInviteLinks) {
+		return
+	}
+
+	revoked, err := db.DBService.RevokeGroupInviteLinks(req.GroupID, req.ID, int64(userID))
+	if err != nil {
+		if err.Error() == "invite link not found" {
+			http.Error(w, "No outstanding invite link to revoke", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error revoking invite links", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"response": "Invite links revoked",
+		"revoked":  revoked,
+	})
+}
+
+// RedeemGroupInviteLinkHandler joins the group of an auto link or asks to join through a request link
+func RedeemGroupInviteLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RedeemGroupInviteLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	if req.Code == "" {
+		http.Error(w, "Missing code", http.StatusBadRequest)
+		return
+	}
+
+	link, err := db.DBService.RedeemGroupInviteLink(req.Code, int64(userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrGroupArchived):
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+		case errors.Is(err, db.ErrGroupBanned):
+			http.Error(w, "Forbidden: user is banned from this group", http.StatusForbidden)
+		case err.Error() == "invite link not found" || err.Error() == "group does not exist":
+			http.Error(w, "Invite link not found", http.StatusNotFound)
+		case err.Error() == "invite link is no longer valid":
+			http.Error(w, "Invite link has expired, been revoked or used up", http.StatusGone)
+		case err.Error() == "user is already a member of the group" || err.Error() == "a pending request already exists":
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Error redeeming the invite link", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status := "joined"
+	if link.Mode == models.GroupInviteLinkRequest {
+		status = "pending"
+		notifyGroupRequest(link.GroupID, int64(userID))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"group_id": link.GroupID,
+		"status":   status,
+	})
+}
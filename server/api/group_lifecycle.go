@@ -0,0 +1,385 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// groupRestoreWindow is how long a deleted group can be restored before it is purged
+var groupRestoreWindow = 30 * 24 * time.Hour
+
+// SetGroupRestoreWindow sets how long deleted groups are kept
+func SetGroupRestoreWindow(window time.Duration) {
+	if window > 0 {
+		groupRestoreWindow = window
+	}
+}
+
+// requireGroupWritable refuses changes to archived groups and hides deleted ones.
+// It writes the error response and returns false when the group cannot be changed.
+func requireGroupWritable(w http.ResponseWriter, groupID int64) bool {
+	status, err := db.DBService.GetGroupStatus(groupID)
+	if err != nil {
+		http.Error(w, "Error checking group status", http.StatusInternalServerError)
+		return false
+	}
+	switch status {
+	case models.GroupStatusActive:
+		return true
+	case models.GroupStatusArchived:
+		http.Error(w, "Group is archived and read-only", http.StatusConflict)
+		return false
+	default:
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return false
+	}
+}
+
+// requireGroupOwner writes the error response and returns false unless the user owns the group
+func requireGroupOwner(w http.ResponseWriter, userID, groupID int64) bool {
+	role, err := db.DBService.GetGroupRole(userID, groupID)
+	if err != nil {
+		http.Error(w, "Error checking group permissions", http.StatusInternalServerError)
+		return false
+	}
+	if role != models.GroupRoleOwner {
+		http.Error(w, "Forbidden: only the group owner can do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// notifyGroupStatus tells every member but the actor that the group changed state
+func notifyGroupStatus(groupID, actorID int64, title, status, restoreUntil string) {
+	memberIDs, err := db.DBService.GetGroupMemberIDs(groupID)
+	if err != nil {
+		return
+	}
+	actor, err := db.DBService.GetUserByID(actorID)
+	if err != nil {
+		return
+	}
+
+	recipients := make([]int64, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if id != actorID {
+			recipients = append(recipients, id)
+		}
+	}
+	notifications.SendToMany(recipients, &notifications.GroupStatus{
+		GroupID:       groupID,
+		GroupName:     title,
+		Status:        status,
+		ActorID:       actor.ID,
+		ActorNickname: actor.Nickname,
+		RestoreUntil:  restoreUntil,
+	})
+}
+
+// decodeGroupIDRequest reads {"group_id": ...} from the body
+func decodeGroupIDRequest(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	var req models.GroupIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return 0, false
+	}
+	if req.GroupID <= 0 {
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return req.GroupID, true
+}
+
+// ArchiveGroupHandler makes a group read-only, the owner can unarchive it at any time
+func ArchiveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := decodeGroupIDRequest(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := db.DBService.GetGroupByID(groupID)
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if !requireGroupOwner(w, int64(userID), groupID) {
+		return
+	}
+
+	if err := db.DBService.ArchiveGroup(groupID); err != nil {
+		if err.Error() == "group is not active" {
+			http.Error(w, "Group is already archived", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Error archiving the group", http.StatusInternalServerError)
+		return
+	}
+
+	logModerationAction(groupID, int64(userID), 0, models.ModerationArchiveGroup, "")
+	notifyGroupStatus(groupID, int64(userID), group.Title, models.GroupStatusArchived, "")
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Group archived"}`))
+}
+
+// UnarchiveGroupHandler makes an archived group writable again
+func UnarchiveGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := decodeGroupIDRequest(w, r)
+	if !ok {
+		return
+	}
+
+	group, err := db.DBService.GetGroupByID(groupID)
+	if err != nil {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+	if !requireGroupOwner(w, int64(userID), groupID) {
+		return
+	}
+
+	if err := db.DBService.UnarchiveGroup(groupID); err != nil {
+		if err.Error() == "group is not archived" {
+			http.Error(w, "Group is not archived", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Error unarchiving the group", http.StatusInternalServerError)
+		return
+	}
+
+	logModerationAction(groupID, int64(userID), 0, models.ModerationUnarchiveGroup, "")
+	notifyGroupStatus(groupID, int64(userID), group.Title, models.GroupStatusActive, "")
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Group unarchived"}`))
+}
+
+// GetDeletedGroupsHandler lists the deleted groups the user owns and can still restore
+func GetDeletedGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groups, err := db.DBService.GetDeletedGroups(int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving deleted groups", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+// RestoreGroupHandler brings back a deleted group before the end of its restore window
+func RestoreGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := decodeGroupIDRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// Members of a deleted group keep their role until it is purged
+	if !requireGroupOwner(w, int64(userID), groupID) {
+		return
+	}
+
+	if err := db.DBService.RestoreGroup(groupID); err != nil {
+		if err.Error() == "group cannot be restored" {
+			http.Error(w, "Group is not deleted or its restore window has ended", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Error restoring the group", http.StatusInternalServerError)
+		return
+	}
+
+	logModerationAction(groupID, int64(userID), 0, models.ModerationRestoreGroup, "")
+	if group, err := db.DBService.GetGroupByID(groupID); err == nil {
+		notifyGroupStatus(groupID, int64(userID), group.Title, group.Status, "")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Group restored"}`))
+}
+
+// GetOwnershipTransfersHandler lists the pending transfers the user sent or must answer
+func GetOwnershipTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transfers, err := db.DBService.GetPendingOwnershipTransfers(int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving ownership transfers", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// CreateOwnershipTransferHandler lets the owner offer the group to another member,
+// the ownership only moves once they accept
+func CreateOwnershipTransferHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateOwnershipTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.GroupID <= 0 || req.UserID <= 0 {
+		http.Error(w, "Missing group_id or user_id", http.StatusBadRequest)
+		return
+	}
+
+	transferID, err := db.DBService.CreateOwnershipTransfer(req.GroupID, int64(userID), req.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "group does not exist":
+			http.Error(w, "Group not found", http.StatusNotFound)
+		case "only the owner can transfer ownership":
+			http.Error(w, "Forbidden: only the group owner can transfer ownership", http.StatusForbidden)
+		case "cannot transfer ownership to yourself", "new owner must be a group member":
+			http.Error(w, "The new owner must be another member of the group", http.StatusBadRequest)
+		case "new owner must enable two-factor authentication first":
+			http.Error(w, "This group requires its admins to use two-factor authentication, the new owner must enable it first", http.StatusConflict)
+		default:
+			http.Error(w, "Error creating the ownership transfer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	group, err := db.DBService.GetGroupByID(req.GroupID)
+	owner, errOwner := db.DBService.GetUserByID(int64(userID))
+	if err == nil && errOwner == nil {
+		actor := notifications.ActorFromUser(owner)
+		notifications.Send(req.UserID, &notifications.GroupOwnershipTransfer{
+			TransferID:    transferID,
+			GroupID:       req.GroupID,
+			GroupName:     group.Title,
+			OwnerID:       actor.ID,
+			OwnerNickname: actor.Nickname,
+			OwnerAvatar:   actor.Avatar,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"id": transferID})
+}
+
+// RespondOwnershipTransferHandler lets the recipient accept or decline the group
+func RespondOwnershipTransferHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RespondOwnershipTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ID <= 0 {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := db.DBService.RespondOwnershipTransfer(req.ID, int64(userID), req.Accept)
+	if err != nil {
+		switch err.Error() {
+		case "transfer not found":
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+		case "transfer is no longer pending", "transfer has expired", "transfer is no longer valid":
+			http.Error(w, "This transfer can no longer be answered", http.StatusConflict)
+		case "new owner must enable two-factor authentication first":
+			http.Error(w, "This group requires its admins to use two-factor authentication, enable it before accepting", http.StatusConflict)
+		default:
+			http.Error(w, "Error answering the ownership transfer", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	notifications.Withdraw(int64(userID), notifications.TypeGroupOwnershipTransfer, "transfer_id", transfer.ID)
+	if recipient, err := db.DBService.GetUserByID(int64(userID)); err == nil {
+		actor := notifications.ActorFromUser(recipient)
+		notifications.Send(transfer.FromUserID, &notifications.GroupOwnershipResponse{
+			TransferID:        transfer.ID,
+			GroupID:           transfer.GroupID,
+			GroupName:         transfer.GroupTitle,
+			RecipientID:       actor.ID,
+			RecipientNickname: actor.Nickname,
+			RecipientAvatar:   actor.Avatar,
+			Accepted:          req.Accept,
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if req.Accept {
+		w.Write([]byte(`{"response": "You are now the owner of the group"}`))
+	} else {
+		w.Write([]byte(`{"response": "Ownership transfer declined"}`))
+	}
+}
+
+// CancelOwnershipTransferHandler lets the owner withdraw a pending transfer
+func CancelOwnershipTransferHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := db.DBService.CancelOwnershipTransfer(req.ID, int64(userID))
+	if err != nil {
+		if err.Error() == "transfer not found" {
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error cancelling the ownership transfer", http.StatusInternalServerError)
+		return
+	}
+	if transfer != nil {
+		notifications.Withdraw(transfer.ToUserID, notifications.TypeGroupOwnershipTransfer, "transfer_id", transfer.ID)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Ownership transfer cancelled"}`))
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
 	"github.com/Golden76z/social-network/utils"
 )
 
@@ -49,14 +50,8 @@ func CreateGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Ensure group exists
-	exists, err := db.DBService.GroupExists(groupID)
-	if err != nil {
-		http.Error(w, "Error checking group existence", http.StatusInternalServerError)
-		return
-	}
-	if !exists {
-		http.Error(w, "Group not found", http.StatusNotFound)
+	// Ensure group exists and still accepts members
+	if !requireGroupWritable(w, groupID) || !requireNotBanned(w, int64(currentUserID), groupID) {
 		return
 	}
 
@@ -87,18 +82,14 @@ func CreateGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		requester, err := db.DBService.GetUserByID(int64(currentUserID))
 		if err == nil {
-			avatar := ""
-			if requester.Avatar.Valid {
-				avatar = requester.Avatar.String
-			}
-			notificationData := fmt.Sprintf(`{"group_id": %d, "group_name": "%s", "requester_id": %d, "requester_nickname": "%s", "requester_avatar": "%s", "type": "group_request"}`,
-				groupID, group.Title, currentUserID, requester.Nickname, avatar)
-			notificationReq := models.CreateNotificationRequest{
-				UserID: group.CreatorID,
-				Type:   "group_request",
-				Data:   notificationData,
-			}
-			if err := db.DBService.CreateNotification(notificationReq); err != nil {
+			actor := notifications.ActorFromUser(requester)
+			if _, err := notifications.Send(group.CreatorID, &notifications.GroupRequest{
+				GroupID:           groupID,
+				GroupName:         group.Title,
+				RequesterID:       actor.ID,
+				RequesterNickname: actor.Nickname,
+				RequesterAvatar:   actor.Avatar,
+			}); err != nil {
 				fmt.Printf("[WARNING] Failed to create notification for group request: %v\n", err)
 			}
 		}
@@ -166,7 +157,7 @@ func UpdateGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate request fields
-	if validationErrors := utils.ValidateStringLength(&req, 1, 50); len(validationErrors) > 0 || (req.Role != "admin" && req.Role != "member") {
+	if validationErrors := utils.ValidateStringLength(&req, 1, 50); len(validationErrors) > 0 || !db.IsValidGroupRole(req.Role) {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(validationErrors)
 		return
@@ -176,8 +167,15 @@ func UpdateGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
 	errDB := db.DBService.UpdateGroupMemberRole(req, int64(userID))
 	if errDB != nil {
 		switch errDB.Error() {
-		case "not authorized: requester is not an admin of this group":
-			http.Error(w, "Forbidden: requester is not an admin of this group", http.StatusForbidden)
+		case "not authorized: requester cannot manage roles in this group":
+			http.Error(w, "Forbidden: your role in this group does not allow managing roles", http.StatusForbidden)
+			return
+		case "cannot assign a role equal to or above your own",
+			"cannot change the role of a member at or above your own role":
+			http.Error(w, "Forbidden: "+errDB.Error(), http.StatusForbidden)
+			return
+		case "ownership must be transferred":
+			http.Error(w, "The owner role cannot be assigned, transfer ownership instead", http.StatusBadRequest)
 			return
 		case "member not found in this group":
 			http.Error(w, "Member not found in this group", http.StatusNotFound)
@@ -188,6 +186,9 @@ func UpdateGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
 		case "cannot demote: would remove the last admin from the group":
 			http.Error(w, "Cannot demote the last admin", http.StatusBadRequest)
 			return
+		case "member must enable two-factor authentication to become admin":
+			http.Error(w, "This group requires admins to enable two-factor authentication", http.StatusForbidden)
+			return
 		default:
 			http.Error(w, "Failed to update group member: "+errDB.Error(), http.StatusInternalServerError)
 			return
@@ -225,6 +226,14 @@ func DeleteGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
 	// Calling the Database to remove the user from the group
 	errDB := db.DBService.DeleteGroupMember(req, userID)
 	if errDB != nil {
+		if errDB.Error() == "permission denied or user not found in group" {
+			http.Error(w, "Forbidden: you cannot remove this member", http.StatusForbidden)
+			return
+		}
+		if errDB.Error() == "owner must transfer ownership before leaving" {
+			http.Error(w, "The owner must transfer ownership or delete the group before leaving", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Error leaving the group", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+)
+
+// logModerationAction records an action in the moderation log of the group.
+// The action already happened, a failure to log it is reported but does not fail the request.
+func logModerationAction(groupID, actorID, targetID int64, action, details string) {
+	if err := db.DBService.LogModerationAction(groupID, actorID, targetID, action, details); err != nil {
+		log.Printf("Failed to log %s in group %d: %v", action, groupID, err)
+	}
+}
+
+// requireNotBanned writes the error response and returns false when the user is banned from the group
+func requireNotBanned(w http.ResponseWriter, userID, groupID int64) bool {
+	banned, err := db.DBService.IsBannedFromGroup(userID, groupID)
+	if err != nil {
+		http.Error(w, "Error checking group bans", http.StatusInternalServerError)
+		return false
+	}
+	if banned {
+		http.Error(w, "Forbidden: user is banned from this group", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// decodeSanctionRequest reads a ban or mute request from the body
+func decodeSanctionRequest(w http.ResponseWriter, r *http.Request) (models.GroupSanctionRequest, bool) {
+	var req models.GroupSanctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if req.GroupID <= 0 || req.UserID <= 0 {
+		http.Error(w, "Missing group_id or user_id", http.StatusBadRequest)
+		return req, false
+	}
+	if req.DurationMinutes < 0 {
+		http.Error(w, "Invalid duration", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// writeSanctionError maps the errors of the ban and mute methods to a response
+func writeSanctionError(w http.ResponseWriter, err error, action string) {
+	switch err.Error() {
+	case "not authorized":
+		http.Error(w, "Forbidden: your role in this group does not allow this", http.StatusForbidden)
+	case "cannot moderate a member at or above your own role", "cannot moderate yourself":
+		http.Error(w, "Forbidden: you can only "+action+" members below your own role", http.StatusForbidden)
+	case "user is not a group member":
+		http.Error(w, "User is not a member of the group", http.StatusNotFound)
+	case "user is not banned":
+		http.Error(w, "User is not banned", http.StatusNotFound)
+	case "user is not muted":
+		http.Error(w, "User is not muted", http.StatusNotFound)
+	default:
+		http.Error(w, "Error trying to "+action+" the user", http.StatusInternalServerError)
+	}
+}
+
+// groupIDFromQuery reads the group_id query parameter
+func groupIDFromQuery(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	groupID, err := strconv.ParseInt(r.URL.Query().Get("group_id"), 10, 64)
+	if err != nil || groupID <= 0 {
+		http.Error(w, "Missing or invalid group_id", http.StatusBadRequest)
+		return 0, false
+	}
+	return groupID, true
+}
+
+// requireGroupModerator lets the members holding permission read the sanctions of the group
+func requireGroupModerator(w http.ResponseWriter, userID, groupID int64, permission string) bool {
+	allowed, err := db.DBService.HasGroupPermission(userID, groupID, permission)
+	if err != nil {
+		http.Error(w, "Error checking group permissions", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "Forbidden: your role in this group does not allow "+permission, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// BanGroupMemberHandler removes a user from the group and blocks their join requests and invitations.
+// Without duration_minutes the ban is permanent.
+func BanGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req, ok := decodeSanctionRequest(w, r)
+	if !ok || !requireGroupWritable(w, req.GroupID) {
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.DurationMinutes > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		expiresAt = &t
+	}
+	if err := db.DBService.BanFromGroup(req.GroupID, int64(userID), req.UserID, req.Reason, expiresAt); err != nil {
+		writeSanctionError(w, err, "ban")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "User banned from the group"}`))
+}
+
+// UnbanGroupMemberHandler lifts a ban, the user can ask to join again
+func UnbanGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req, ok := decodeSanctionRequest(w, r)
+	if !ok || !requireGroupWritable(w, req.GroupID) {
+		return
+	}
+
+	if err := db.DBService.UnbanFromGroup(req.GroupID, int64(userID), req.UserID); err != nil {
+		writeSanctionError(w, err, "unban")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "User unbanned"}`))
+}
+
+// GetGroupBansHandler lists the active bans of a group
+func GetGroupBansHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := groupIDFromQuery(w, r)
+	if !ok || !requireGroupModerator(w, int64(userID), groupID, models.GroupPermBanMembers) {
+		return
+	}
+
+	bans, err := db.DBService.GetGroupBans(groupID)
+	if err != nil {
+		http.Error(w, "Error retrieving group bans", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bans)
+}
+
+// MuteGroupMemberHandler stops a member from posting, commenting and chatting for duration_minutes
+func MuteGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req, ok := decodeSanctionRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.DurationMinutes == 0 {
+		http.Error(w, "Mutes need a duration_minutes", http.StatusBadRequest)
+		return
+	}
+	if !requireGroupWritable(w, req.GroupID) {
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(time.Duration(req.DurationMinutes) * time.Minute)
+	if err := db.DBService.MuteInGroup(req.GroupID, int64(userID), req.UserID, req.Reason, expiresAt); err != nil {
+		writeSanctionError(w, err, "mute")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"response":    "User muted",
+		"muted_until": expiresAt.Format(time.RFC3339),
+	})
+}
+
+// UnmuteGroupMemberHandler lifts a mute before it expires
+func UnmuteGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	req, ok := decodeSanctionRequest(w, r)
+	if !ok || !requireGroupWritable(w, req.GroupID) {
+		return
+	}
+
+	if err := db.DBService.UnmuteInGroup(req.GroupID, int64(userID), req.UserID); err != nil {
+		writeSanctionError(w, err, "unmute")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "User unmuted"}`))
+}
+
+// GetGroupMutesHandler lists the active mutes of a group
+func GetGroupMutesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := groupIDFromQuery(w, r)
+	if !ok || !requireGroupModerator(w, int64(userID), groupID, models.GroupPermMuteMembers) {
+		return
+	}
+
+	mutes, err := db.DBService.GetGroupMutes(groupID)
+	if err != nil {
+		http.Error(w, "Error retrieving group mutes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mutes)
+}
+
+// GetModerationLogHandler returns who did what to whom in the group, readable by its owner and admins.
+// Supports ?group_id=1&limit=50&offset=0
+func GetModerationLogHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := groupIDFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	role, err := db.DBService.GetGroupRole(int64(userID), groupID)
+	if err != nil {
+		http.Error(w, "Error checking group permissions", http.StatusInternalServerError)
+		return
+	}
+	if db.GroupRoleRank(role) < db.GroupRoleRank(models.GroupRoleAdmin) {
+		http.Error(w, "Forbidden: only group admins can read the moderation log", http.StatusForbidden)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries, err := db.DBService.GetModerationLog(groupID, limit, offset)
+	if err != nil {
+		http.Error(w, "Error retrieving the moderation log", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
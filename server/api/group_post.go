@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -41,13 +42,45 @@ func CreateGroupPostHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Maximum 4 images allowed per post", http.StatusBadRequest)
 		return
 	}
+	if req.Poll != nil {
+		if problem := validatePollRequest(req.Poll); problem != "" {
+			http.Error(w, problem, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !requireGroupPermission(w, int64(userID), req.GroupID, models.GroupPermPost) {
+		return
+	}
+	if req.IsAnnouncement && !requireGroupPermission(w, int64(userID), req.GroupID, models.GroupPermAnnounce) {
+		return
+	}
 
 	// Create the group post in the database
-	errDB := db.DBService.CreateGroupPost(req, int64(userID))
+	postID, errDB := db.DBService.CreateGroupPost(req, int64(userID))
 	if errDB != nil {
 		http.Error(w, "Error creating the group post", http.StatusInternalServerError)
 		return
 	}
+	// Posts held for review are announced once approved
+	status, errDB := db.DBService.GetGroupPostStatus(postID)
+	if errDB != nil {
+		http.Error(w, "Error creating the group post", http.StatusInternalServerError)
+		return
+	}
+	if status == models.GroupPostPending {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{
+			"response": "Group Post submitted for approval",
+			"id":       postID,
+			"status":   status,
+		})
+		return
+	}
+	if req.IsAnnouncement {
+		notifyGroupAnnouncement(req.GroupID, postID, int64(userID), req.Title)
+	}
 
 	// Respond with success
 	w.WriteHeader(http.StatusCreated)
@@ -196,6 +229,10 @@ func UpdateGroupPostHandler(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, "Forbidden: You can only update your own posts", http.StatusForbidden)
 				return
 			}
+			if errors.Is(err, db.ErrGroupArchived) {
+				http.Error(w, "Group is archived and read-only", http.StatusConflict)
+				return
+			}
 			http.Error(w, "Error updating the group post", http.StatusInternalServerError)
 			return
 		}
@@ -240,6 +277,10 @@ func UpdateGroupPostHandler(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Forbidden: You can only update your own posts", http.StatusForbidden)
 			return
 		}
+		if errors.Is(err, db.ErrGroupArchived) {
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Error updating the group post", http.StatusInternalServerError)
 		return
 	}
@@ -286,21 +327,22 @@ func DeleteGroupPostHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Check if the post exists and belongs to the user
-	existingPost, err := db.DBService.GetGroupPostWithImagesByID(postID, int64(userID))
-	if err != nil {
+	// Check if the post exists and is visible to the user
+	if _, err := db.DBService.GetGroupPostWithImagesByID(postID, int64(userID)); err != nil {
 		http.Error(w, "Post not found", http.StatusNotFound)
 		return
 	}
 
-	// Only the owner can delete their post
-	if existingPost.UserID != int64(userID) {
-		http.Error(w, "Forbidden: You can only delete your own posts", http.StatusForbidden)
-		return
-	}
-
-	// Delete the group post from the database
+	// Delete the group post from the database, authors and members allowed to delete content can
 	if err := db.DBService.DeleteGroupPost(postID, int64(userID)); err != nil {
+		if err.Error() == "not authorized" {
+			http.Error(w, "Forbidden: You can only delete your own posts", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, db.ErrGroupArchived) {
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Error deleting the group post", http.StatusInternalServerError)
 		return
 	}
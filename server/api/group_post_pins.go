@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// notifyGroupAnnouncement tells every member but the author about an announcement
+func notifyGroupAnnouncement(groupID, postID, authorID int64, title string) {
+	group, err := db.DBService.GetGroupByID(groupID)
+	if err != nil {
+		return
+	}
+	memberIDs, err := db.DBService.GetGroupMemberIDs(groupID)
+	if err != nil {
+		return
+	}
+	author, err := db.DBService.GetUserByID(authorID)
+	if err != nil {
+		return
+	}
+
+	recipients := make([]int64, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if id != authorID {
+			recipients = append(recipients, id)
+		}
+	}
+	actor := notifications.ActorFromUser(author)
+	notifications.SendToMany(recipients, &notifications.GroupAnnouncement{
+		GroupID:        groupID,
+		GroupName:      group.Title,
+		PostID:         postID,
+		PostTitle:      title,
+		AuthorID:       actor.ID,
+		AuthorNickname: actor.Nickname,
+		AuthorAvatar:   actor.Avatar,
+	})
+}
+
+// writeGroupPostActionError maps the errors of the pin and announcement methods to a response
+func writeGroupPostActionError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, db.ErrGroupArchived):
+		http.Error(w, "Group is archived and read-only", http.StatusConflict)
+	case errors.Is(err, db.ErrPostNotPublished):
+		http.Error(w, "Post is waiting for review", http.StatusConflict)
+	case err.Error() == "post not found" || err.Error() == "group does not exist":
+		http.Error(w, "Post not found", http.StatusNotFound)
+	case err.Error() == "not authorized":
+		http.Error(w, "Forbidden: your role in this group does not allow this", http.StatusForbidden)
+	case err.Error() == "post is not pinned" || err.Error() == "post is already an announcement":
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Error trying to "+action+" the post", http.StatusInternalServerError)
+	}
+}
+
+// PinGroupPostHandler lists a post ahead of the others, for duration_minutes or until unpinned
+func PinGroupPostHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.PinGroupPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.PostID <= 0 || req.DurationMinutes < 0 {
+		http.Error(w, "Invalid post_id or duration", http.StatusBadRequest)
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.DurationMinutes > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		expiresAt = &t
+	}
+	if err := db.DBService.PinGroupPost(req.PostID, int64(userID), expiresAt); err != nil {
+		writeGroupPostActionError(w, err, "pin")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Post pinned"}`))
+}
+
+// UnpinGroupPostHandler puts a pinned post back in the chronological order
+func UnpinGroupPostHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.GroupPostIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PostID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.UnpinGroupPost(req.PostID, int64(userID)); err != nil {
+		writeGroupPostActionError(w, err, "unpin")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Post unpinned"}`))
+}
+
+// AnnounceGroupPostHandler marks an existing post as an announcement, notifying every member
+func AnnounceGroupPostHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.GroupPostIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PostID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.MarkGroupPostAnnouncement(req.PostID, int64(userID)); err != nil {
+		writeGroupPostActionError(w, err, "announce")
+		return
+	}
+
+	if post, err := db.DBService.GetGroupPostWithImagesByID(req.PostID, int64(userID)); err == nil {
+		if groupID, err := db.DBService.GetGroupIDFromPost(req.PostID); err == nil {
+			notifyGroupAnnouncement(groupID, post.ID, post.UserID, post.Title)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Post marked as an announcement"}`))
+}
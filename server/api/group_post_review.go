@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// GetGroupPostQueueHandler lists the posts waiting for review: all of them for moderators,
+// their own pending and rejected posts for the other members. Supports ?group_id=1
+func GetGroupPostQueueHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	groupID, ok := groupIDFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	posts, err := db.DBService.GetGroupPostQueue(groupID, int64(userID))
+	if err != nil {
+		if err.Error() == "user is not a member of the group" {
+			http.Error(w, "Access denied: Not a group member", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Error retrieving the post queue", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(posts)
+}
+
+// ReviewGroupPostHandler publishes or rejects a pending post and tells its author
+func ReviewGroupPostHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ReviewGroupPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.PostID <= 0 {
+		http.Error(w, "Invalid post_id", http.StatusBadRequest)
+		return
+	}
+	if len(req.Reason) > 500 {
+		http.Error(w, "Reason must be at most 500 characters", http.StatusBadRequest)
+		return
+	}
+
+	post, err := db.DBService.ReviewGroupPost(req.PostID, int64(userID), req.Approve, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrGroupArchived):
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+		case err.Error() == "a rejection needs a reason":
+			http.Error(w, "A rejection needs a reason", http.StatusBadRequest)
+		case err.Error() == "post not found" || err.Error() == "group does not exist":
+			http.Error(w, "Post not found", http.StatusNotFound)
+		case err.Error() == "not authorized":
+			http.Error(w, "Forbidden: your role in this group does not allow approving posts", http.StatusForbidden)
+		case err.Error() == "post is not pending":
+			http.Error(w, "Post is not waiting for review", http.StatusConflict)
+		default:
+			http.Error(w, "Error reviewing the post", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	groupID, err := db.DBService.GetGroupIDFromPost(post.ID)
+	if err == nil {
+		if group, err := db.DBService.GetGroupByID(groupID); err == nil {
+			if reviewer, err := db.DBService.GetUserByID(int64(userID)); err == nil {
+				notifications.Send(post.UserID, &notifications.GroupPostReview{
+					GroupID:          groupID,
+					GroupName:        group.Title,
+					PostID:           post.ID,
+					PostTitle:        post.Title,
+					Approved:         req.Approve,
+					Reason:           req.Reason,
+					ReviewerID:       reviewer.ID,
+					ReviewerNickname: reviewer.Nickname,
+				})
+			}
+		}
+		if req.Approve && post.IsAnnouncement {
+			notifyGroupAnnouncement(groupID, post.ID, post.UserID, post.Title)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if req.Approve {
+		w.Write([]byte(`{"response": "Post approved"}`))
+	} else {
+		w.Write([]byte(`{"response": "Post rejected"}`))
+	}
+}
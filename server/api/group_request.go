@@ -2,15 +2,35 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
 )
 
+// notifyGroupRequest tells the owner of the group someone asked to join
+func notifyGroupRequest(groupID, requesterID int64) {
+	group, err := db.DBService.GetGroupByID(groupID)
+	if err != nil {
+		return
+	}
+	requester, err := db.DBService.GetUserByID(requesterID)
+	if err != nil {
+		return
+	}
+	actor := notifications.ActorFromUser(requester)
+	notifications.Send(group.CreatorID, &notifications.GroupRequest{
+		GroupID:           groupID,
+		GroupName:         group.Title,
+		RequesterID:       actor.ID,
+		RequesterNickname: actor.Nickname,
+		RequesterAvatar:   actor.Avatar,
+	})
+}
+
 // CreateGroupRequestHandler creates a new group join request
 func CreateGroupRequestHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
@@ -33,14 +53,8 @@ func CreateGroupRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if group exists
-	exists, err := db.DBService.GroupExists(req.GroupID)
-	if err != nil {
-		http.Error(w, "Error checking group existence", http.StatusInternalServerError)
-		return
-	}
-	if !exists {
-		http.Error(w, "Group not found", http.StatusNotFound)
+	// Check the group exists and still accepts members
+	if !requireGroupWritable(w, req.GroupID) || !requireNotBanned(w, int64(userID), req.GroupID) {
 		return
 	}
 
@@ -74,24 +88,7 @@ func CreateGroupRequestHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create notification for the group owner
-	group, err := db.DBService.GetGroupByID(req.GroupID)
-	if err == nil {
-		requester, err := db.DBService.GetUserByID(int64(userID))
-		if err == nil {
-			avatar := ""
-			if requester.Avatar.Valid {
-				avatar = requester.Avatar.String
-			}
-			notificationData := fmt.Sprintf(`{"group_id": %d, "group_name": "%s", "requester_id": %d, "requester_nickname": "%s", "requester_avatar": "%s", "type": "group_request"}`,
-				req.GroupID, group.Title, userID, requester.Nickname, avatar)
-			notificationReq := models.CreateNotificationRequest{
-				UserID: group.CreatorID,
-				Type:   "group_request",
-				Data:   notificationData,
-			}
-			db.DBService.CreateNotification(notificationReq)
-		}
-	}
+	notifyGroupRequest(req.GroupID, int64(userID))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -124,7 +121,7 @@ func GetUserPendingRequestsHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// GetGroupRequestsHandler lists join requests for a group (group owner only)
+// GetGroupRequestsHandler lists join requests for a group (roles approving requests only)
 func GetGroupRequestsHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
@@ -145,14 +142,8 @@ func GetGroupRequestsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is group owner
-	isOwner, err := db.DBService.IsUserGroupAdmin(int64(userID), groupID)
-	if err != nil {
-		http.Error(w, "Error checking ownership", http.StatusInternalServerError)
-		return
-	}
-	if !isOwner {
-		http.Error(w, "Forbidden: Only group owner can view requests", http.StatusForbidden)
+	// Only roles approving requests can view them
+	if !requireGroupPermission(w, int64(userID), groupID, models.GroupPermApproveRequests) {
 		return
 	}
 
@@ -219,14 +210,7 @@ func UpdateGroupRequestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is group owner
-	isOwner, err := db.DBService.IsUserGroupAdmin(int64(userID), request.GroupID)
-	if err != nil {
-		http.Error(w, "Error checking ownership", http.StatusInternalServerError)
-		return
-	}
-	if !isOwner {
-		http.Error(w, "Forbidden: Only group owner can approve/decline requests", http.StatusForbidden)
+	if !requireGroupPermission(w, int64(userID), request.GroupID, models.GroupPermApproveRequests) {
 		return
 	}
 
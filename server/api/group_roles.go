@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+)
+
+// requireGroupPermission is the authorization check of the group handlers.
+// It writes the error response and returns false when the user may not perform the action.
+// Every action of the matrix changes the group, so archived groups refuse them all.
+func requireGroupPermission(w http.ResponseWriter, userID, groupID int64, permission string) bool {
+	if !requireGroupWritable(w, groupID) {
+		return false
+	}
+
+	role, err := db.DBService.GetGroupRole(userID, groupID)
+	if err != nil {
+		http.Error(w, "Error checking group permissions", http.StatusInternalServerError)
+		return false
+	}
+	if role == "" {
+		http.Error(w, "Forbidden: you must be a group member", http.StatusForbidden)
+		return false
+	}
+
+	allowed, err := db.DBService.RoleHasGroupPermission(groupID, role, permission)
+	if err != nil {
+		http.Error(w, "Error checking group permissions", http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, "Forbidden: your role in this group does not allow "+permission, http.StatusForbidden)
+		return false
+	}
+
+	// Muted members keep their role but cannot speak until the mute ends
+	if permission == models.GroupPermPost || permission == models.GroupPermComment {
+		mutedUntil, err := db.DBService.GetGroupMuteExpiry(userID, groupID)
+		if err != nil {
+			http.Error(w, "Error checking group permissions", http.StatusInternalServerError)
+			return false
+		}
+		if mutedUntil != nil {
+			http.Error(w, "Forbidden: you are muted in this group until "+mutedUntil.UTC().Format(time.RFC3339), http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// GetGroupPermissionsHandler returns the permission matrix of a group and the role of the requester
+func GetGroupPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	groupID, err := strconv.ParseInt(r.URL.Query().Get("group_id"), 10, 64)
+	if err != nil || groupID <= 0 {
+		http.Error(w, "Missing or invalid group_id", http.StatusBadRequest)
+		return
+	}
+
+	role, err := db.DBService.GetGroupRole(int64(userID), groupID)
+	if err != nil {
+		http.Error(w, "Error retrieving group permissions", http.StatusInternalServerError)
+		return
+	}
+	if role == "" {
+		http.Error(w, "Forbidden: you must be a group member", http.StatusForbidden)
+		return
+	}
+
+	matrix, err := db.DBService.GetGroupPermissionMatrix(groupID)
+	if err != nil {
+		http.Error(w, "Error retrieving group permissions", http.StatusInternalServerError)
+		return
+	}
+	overrides, err := db.DBService.GetGroupPermissionOverrides(groupID)
+	if err != nil {
+		http.Error(w, "Error retrieving group permissions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.GroupPermissionsResponse{
+		GroupID:     groupID,
+		Role:        role,
+		Permissions: matrix,
+		Overrides:   overrides,
+	})
+}
+
+// UpdateGroupPermissionsHandler lets members who manage roles change the matrix of their group
+func UpdateGroupPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateGroupPermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.GroupID <= 0 || len(req.Overrides) == 0 {
+		http.Error(w, "Missing group_id or overrides", http.StatusBadRequest)
+		return
+	}
+
+	if !requireGroupPermission(w, int64(userID), req.GroupID, models.GroupPermManageRoles) {
+		return
+	}
+
+	role, err := db.DBService.GetGroupRole(int64(userID), req.GroupID)
+	if err != nil {
+		http.Error(w, "Error updating group permissions", http.StatusInternalServerError)
+		return
+	}
+	for _, o := range req.Overrides {
+		if !db.IsOverridableGroupPermission(o.Role, o.Permission) {
+			http.Error(w, "Invalid override: "+o.Role+"/"+o.Permission+" cannot be changed", http.StatusBadRequest)
+			return
+		}
+		// Admins cannot change what their own role or the roles above can do
+		if db.GroupRoleRank(o.Role) >= db.GroupRoleRank(role) {
+			http.Error(w, "Forbidden: you can only change the permissions of roles below your own", http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := db.DBService.SetGroupPermissionOverrides(req.GroupID, req.Overrides); err != nil {
+		http.Error(w, "Error updating group permissions", http.StatusInternalServerError)
+		return
+	}
+
+	changes := make([]string, 0, len(req.Overrides))
+	for _, o := range req.Overrides {
+		value := "default"
+		if o.Allowed != nil {
+			value = strconv.FormatBool(*o.Allowed)
+		}
+		changes = append(changes, o.Role+"/"+o.Permission+"="+value)
+	}
+	logModerationAction(req.GroupID, int64(userID), 0, models.ModerationUpdatePermissions, strings.Join(changes, ", "))
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Group permissions updated"}`))
+}
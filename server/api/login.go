@@ -48,37 +48,90 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Accounts in backoff or lockout are refused before the password is even checked, with the answer
+	// of a wrong password so neither tells the account exists. The owner is warned of a lockout.
+	userID, lookupErr := db.DBService.GetUserIDByUsername(req.Username)
+	if lookupErr == nil {
+		wait, err := db.DBService.GetLoginRetryAfter(userID)
+		if err != nil {
+			fmt.Printf("[API] Error checking login guard: %v\n", err)
+			http.Error(w, "Error checking account status", http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			http.Error(w, db.InvalidCredentialsMessage, http.StatusUnauthorized)
+			return
+		}
+	}
+
 	err := db.DBService.LoginDB(req.Username, req.Password, w)
 	if err != nil {
 		// fmt.Println("Error checking credentials with the database", err)
+		if lookupErr == nil && err.Error() == "invalid credentials" {
+			recordFailedLogin(r, userID)
+		}
 		return
 	}
 
-	// Creating a session for the user
+	// Get user_id for session creation
+	if lookupErr != nil {
+		fmt.Printf("[API] Error getting user ID: %v\n", lookupErr)
+		http.Error(w, "Error getting user ID", http.StatusInternalServerError)
+		return
+	}
 
-	// Generate JWT token
-	token, err := utils.JWTGeneration(req.Username, w)
+	// Accounts with two-factor authentication get a short-lived challenge instead of a session
+	twoFactorEnabled, err := db.DBService.IsTwoFactorEnabled(userID)
 	if err != nil {
-		fmt.Printf("[API] Error generating JWT: %v\n", err)
-		http.Error(w, "Error generating JWT token", http.StatusInternalServerError)
+		fmt.Printf("[API] Error checking two-factor status: %v\n", err)
+		http.Error(w, "Error checking two-factor status", http.StatusInternalServerError)
+		return
+	}
+	if twoFactorEnabled {
+		sendLoginChallenge(w, userID)
 		return
 	}
 
-	// Get user_id for session creation
-	userID, err := db.DBService.GetUserIDByUsername(req.Username)
+	_ = db.DBService.ResetFailedLogins(userID)
+	issueSession(w, req.Username, userID)
+}
+
+// issueSession creates the JWT and session for an authenticated user and sends them back
+func issueSession(w http.ResponseWriter, username string, userID int64) {
+	token, err := startSession(w, username, userID)
 	if err != nil {
-		fmt.Printf("[API] Error getting user ID: %v\n", err)
-		http.Error(w, "Error getting user ID", http.StatusInternalServerError)
 		return
 	}
 
+	// Send back a response with the token for localStorage
+	response := map[string]interface{}{
+		"message": "Login successful",
+		"token":   token,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// startSession generates the JWT, stores the session and sets the cookie.
+// On failure the HTTP error has already been written.
+func startSession(w http.ResponseWriter, username string, userID int64) (string, error) {
+	// Generate JWT token
+	token, err := utils.JWTGeneration(username, w)
+	if err != nil {
+		fmt.Printf("[API] Error generating JWT: %v\n", err)
+		http.Error(w, "Error generating JWT token", http.StatusInternalServerError)
+		return "", err
+	}
+
 	// Store session in database
 	// We need to access config through the utils package
 	err = db.DBService.CreateSession(int(userID), token, time.Hour*4) // Use default 4 hours for now
 	if err != nil {
 		fmt.Printf("[API] Error creating session in DB: %v\n", err)
 		http.Error(w, "Error creating session in database", http.StatusInternalServerError)
-		return
+		return "", err
 	}
 
 	// Set cookie manually
@@ -97,16 +150,7 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	http.SetCookie(w, cookie)
-
-	// Send back a response with the token for localStorage
-	response := map[string]interface{}{
-		"message": "Login successful",
-		"token":   token,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	return token, nil
 }
 
 // Helper function to validate username (can be email or nickname)
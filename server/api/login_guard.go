@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+const (
+	loginBackoffBaseDelay = time.Second
+	loginBackoffMaxDelay  = 5 * time.Minute
+	loginFailureResetTime = 24 * time.Hour
+)
+
+func loginGuardPolicy() db.LoginGuardPolicy {
+	cfg := config.GetConfig()
+	return db.LoginGuardPolicy{
+		BackoffThreshold: cfg.LoginBackoffThreshold,
+		BaseDelay:        loginBackoffBaseDelay,
+		MaxDelay:         loginBackoffMaxDelay,
+		MaxAttempts:      cfg.LoginMaxAttempts,
+		LockoutDuration:  cfg.LoginLockoutDuration,
+		ResetAfter:       loginFailureResetTime,
+	}
+}
+
+// rejectLockedLogin answers 429 with Retry-After when the account is in backoff or locked out.
+// Only used once the password was accepted, before that it would tell the account exists.
+func rejectLockedLogin(w http.ResponseWriter, userID int64) bool {
+	wait, err := db.DBService.GetLoginRetryAfter(userID)
+	if err != nil {
+		fmt.Printf("[API] Error checking login guard: %v\n", err)
+		http.Error(w, "Error checking account status", http.StatusInternalServerError)
+		return true
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, fmt.Sprintf("Too many failed login attempts. Please try again in %s", wait.Round(time.Second)), http.StatusTooManyRequests)
+	return true
+}
+
+// recordFailedLogin counts a failed password or code and warns the owner when the account gets locked
+func recordFailedLogin(r *http.Request, userID int64) {
+	ip := middleware.GetClientIP(r)
+	result, err := db.DBService.RecordFailedLogin(userID, ip, loginGuardPolicy())
+	if err != nil {
+		fmt.Printf("[API] Error recording failed login: %v\n", err)
+		return
+	}
+	if !result.Notify {
+		return
+	}
+
+	// Don't fail the login response if the notification cannot be created
+	_, _ = notifications.Send(userID, &notifications.SecurityAlert{
+		Reason:         "account_locked",
+		FailedAttempts: result.FailedCount,
+		LockedUntil:    result.LockedUntil.UTC().Format(time.RFC3339),
+		IP:             ip,
+	})
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// GetNotificationDigestHandler returns how often the user receives the email digest
+func GetNotificationDigestHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	frequency, err := db.DBService.GetDigestFrequency(int64(userID), config.GetConfig().DigestDefaultFrequency)
+	if err != nil {
+		http.Error(w, "Error retrieving digest settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"frequency": frequency})
+}
+
+// UpdateNotificationDigestHandler sets the digest frequency: daily, weekly or off
+func UpdateNotificationDigestHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Frequency string `json:"frequency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !notifications.IsValidDigestFrequency(req.Frequency) {
+		http.Error(w, "Invalid frequency: use daily, weekly or off", http.StatusBadRequest)
+		return
+	}
+
+	token, err := notifications.NewUnsubscribeToken()
+	if err != nil {
+		http.Error(w, "Error saving digest settings", http.StatusInternalServerError)
+		return
+	}
+	if err := db.DBService.SetDigestFrequency(int64(userID), req.Frequency, token); err != nil {
+		http.Error(w, "Error saving digest settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(fmt.Sprintf(`{"response": "Digest set to %s"}`, req.Frequency)))
+}
+
+// unsubscribeDigestPage asks to confirm, link scanners and prefetchers only follow the GET
+var unsubscribeDigestPage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe from notification digests</title></head>
+<body>
+<p>Stop receiving notification digests by email?</p>
+<form method="POST" action="/notifications/unsubscribe?token={{.}}">
+<button type="submit">Unsubscribe</button>
+</form>
+</body>
+</html>
+`))
+
+// UnsubscribeDigestHandler turns the digest off from the link of an email, no login needed.
+// GET serves a page confirming with a POST, which is also the one-click unsubscribe of mail clients (RFC 8058).
+func UnsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing token", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		exists, err := db.DBService.DigestTokenExists(token)
+		if err != nil {
+			http.Error(w, "Error reading digest settings", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "Invalid or expired unsubscribe link", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		unsubscribeDigestPage.Execute(w, token)
+		return
+	}
+
+	found, err := db.DBService.UnsubscribeDigest(token)
+	if err != nil {
+		http.Error(w, "Error updating digest settings", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Invalid or expired unsubscribe link", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("You will no longer receive notification digests. You can turn them back on in your settings."))
+}
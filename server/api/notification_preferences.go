@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// GetNotificationPreferencesHandler returns the channel of every notification type, quiet hours and muted groups
+func GetNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	saved, err := db.DBService.GetNotificationChannels(int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving notification preferences", http.StatusInternalServerError)
+		return
+	}
+
+	// Types the user never changed use the default channel
+	channels := make(map[string]string)
+	for _, notificationType := range notifications.ConfigurableTypes() {
+		channels[notificationType] = models.NotificationChannelPush
+		if channel, ok := saved[notificationType]; ok {
+			channels[notificationType] = channel
+		}
+	}
+
+	quietHours, err := db.DBService.GetQuietHours(int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving quiet hours", http.StatusInternalServerError)
+		return
+	}
+
+	mutedGroups, err := db.DBService.GetMutedGroups(int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving muted groups", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NotificationPreferencesResponse{
+		Channels:    channels,
+		QuietHours:  quietHours,
+		MutedGroups: mutedGroups,
+	})
+}
+
+// UpdateNotificationPreferencesHandler changes channels per type and the quiet hours
+func UpdateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	for notificationType, channel := range req.Channels {
+		if !notifications.IsConfigurable(notificationType) {
+			http.Error(w, fmt.Sprintf("Notification type %q cannot be configured", notificationType), http.StatusBadRequest)
+			return
+		}
+		if !notifications.IsValidChannel(channel) {
+			http.Error(w, fmt.Sprintf("Invalid channel %q: use push, in_app, email or off", channel), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.QuietHours != nil {
+		if req.QuietHours.Timezone == "" {
+			req.QuietHours.Timezone = "UTC"
+		}
+		if err := notifications.ValidateQuietHours(req.QuietHours); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(req.Channels) > 0 {
+		if err := db.DBService.SetNotificationChannels(int64(userID), req.Channels); err != nil {
+			http.Error(w, "Error saving notification preferences", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.QuietHours != nil || req.ClearQuietHours {
+		if err := db.DBService.SetQuietHours(int64(userID), req.QuietHours); err != nil {
+			http.Error(w, "Error saving quiet hours", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Notification preferences updated"}`))
+}
+
+// MuteGroupNotificationsHandler stops pushes about a group, for a while or until unmuted
+func MuteGroupNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.MuteGroupNotificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.GroupID == 0 || req.DurationMinutes < 0 {
+		http.Error(w, "Missing group_id or invalid duration", http.StatusBadRequest)
+		return
+	}
+
+	exists, err := db.DBService.GroupExists(req.GroupID)
+	if err != nil {
+		http.Error(w, "Error checking group existence", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		http.Error(w, "Group not found", http.StatusNotFound)
+		return
+	}
+
+	var until *time.Time
+	if req.DurationMinutes > 0 {
+		t := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+		until = &t
+	}
+
+	if err := db.DBService.MuteGroupNotifications(int64(userID), req.GroupID, until); err != nil {
+		http.Error(w, "Error muting group", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Group muted"}`))
+}
+
+// UnmuteGroupNotificationsHandler removes the mute of a group
+func UnmuteGroupNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.MuteGroupNotificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GroupID == 0 {
+		http.Error(w, "Missing group_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.UnmuteGroupNotifications(int64(userID), req.GroupID); err != nil {
+		http.Error(w, "Error unmuting group", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Group unmuted"}`))
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
 )
 
 // GetUserNotificationsHandler gets notifications for the current user
@@ -61,6 +62,18 @@ func SendUserNotificationsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Data != "" && !json.Valid([]byte(req.Data)) {
+		http.Error(w, "Invalid notification data: must be JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Respect the recipient's preferences like every other notification
+	if !notifications.Enabled(req.UserID, req.Type) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"response": "Notification sent"}`))
+		return
+	}
+
 	if err := db.DBService.CreateNotification(req); err != nil {
 		http.Error(w, "Error creating notification", http.StatusInternalServerError)
 		return
@@ -319,21 +332,7 @@ func handleFollowRequestAccept(data map[string]interface{}, userID int64) error
 	_ = db.DBService.IncrementFollowingCount(int64(requesterID))
 
 	// Create notification for requester
-	targetUser, err := db.DBService.GetUserByID(userID)
-	if err == nil {
-		avatar := ""
-		if targetUser.Avatar.Valid {
-			avatar = targetUser.Avatar.String
-		}
-		notificationData := fmt.Sprintf(`{"target_id": %d, "target_nickname": "%s", "target_avatar": "%s", "type": "follow_accepted"}`,
-			userID, targetUser.Nickname, avatar)
-		notificationReq := models.CreateNotificationRequest{
-			UserID: int64(requesterID),
-			Type:   "follow_accepted",
-			Data:   notificationData,
-		}
-		db.DBService.CreateNotification(notificationReq)
-	}
+	sendFollowAccepted(int64(requesterID), userID)
 
 	return nil
 }
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+	"github.com/Golden76z/social-network/websockets"
+)
+
+const (
+	maxPollOptions       = 10
+	maxPollQuestion      = 200
+	maxPollOptionLabel   = 100
+	maxPollDurationHours = 30 * 24
+)
+
+// validatePollRequest cleans up a poll attached to a new post or message, it returns the problem if any
+func validatePollRequest(poll *models.CreatePollRequest) string {
+	poll.Question = utils.SanitizeString(poll.Question)
+	if poll.Question == "" || utf8.RuneCountInString(poll.Question) > maxPollQuestion {
+		return fmt.Sprintf("Poll question must be between 1 and %d characters", maxPollQuestion)
+	}
+	if len(poll.Options) < 2 || len(poll.Options) > maxPollOptions {
+		return fmt.Sprintf("A poll needs between 2 and %d options", maxPollOptions)
+	}
+	seen := map[string]bool{}
+	for i, label := range poll.Options {
+		label = utils.SanitizeString(label)
+		if label == "" || utf8.RuneCountInString(label) > maxPollOptionLabel {
+			return fmt.Sprintf("Poll options must be between 1 and %d characters", maxPollOptionLabel)
+		}
+		if seen[strings.ToLower(label)] {
+			return "Poll options must be different"
+		}
+		seen[strings.ToLower(label)] = true
+		poll.Options[i] = label
+	}
+	if poll.DurationMinutes < 0 || poll.DurationMinutes > maxPollDurationHours*60 {
+		return fmt.Sprintf("Poll duration must be at most %d days", maxPollDurationHours/24)
+	}
+	return ""
+}
+
+// requirePollAccess lets through the users who can see what the poll is attached to
+func requirePollAccess(w http.ResponseWriter, poll *models.Poll, userID int64) bool {
+	switch {
+	case poll.PostID != nil:
+		if _, err := db.DBService.GetPostByID(*poll.PostID, userID); err != nil {
+			if err.Error() == "unauthorized" {
+				http.Error(w, "You are not authorized to view this post", http.StatusForbidden)
+				return false
+			}
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return false
+		}
+	case poll.GroupPostID != nil:
+		if _, err := db.DBService.GetGroupPostWithImagesByID(*poll.GroupPostID, userID); err != nil {
+			if err.Error() == "user is not a member of the group" {
+				http.Error(w, "Access denied: Not a group member", http.StatusForbidden)
+				return false
+			}
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return false
+		}
+	default:
+		if poll.GroupID == nil {
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return false
+		}
+		isMember, err := db.DBService.CheckGroupMembership(int(userID), int(*poll.GroupID))
+		if err != nil {
+			http.Error(w, "Failed to check group membership", http.StatusInternalServerError)
+			return false
+		}
+		if !isMember {
+			http.Error(w, "You are not a member of this group", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// broadcastPollTally pushes the new counts to the sockets of those who can see the poll.
+// Only counts are sent, voters and personal choices are fetched through the API.
+func broadcastPollTally(poll *models.Poll) {
+	hub := websockets.GetHub()
+	if hub == nil {
+		return
+	}
+
+	options := make([]map[string]any, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		options = append(options, map[string]any{"id": option.ID, "votes": option.Votes})
+	}
+	message := websockets.Message{
+		Type:      websockets.MessageTypePollUpdate,
+		Timestamp: time.Now(),
+		Data: map[string]any{
+			"poll_id": poll.ID,
+			"options": options,
+			"voters":  poll.Voters,
+			"closed":  poll.Closed,
+		},
+	}
+
+	if poll.GroupID != nil {
+		message.GroupID = strconv.FormatInt(*poll.GroupID, 10)
+		hub.BroadcastMessage(message)
+		return
+	}
+	if poll.PostID == nil {
+		return
+	}
+	post, err := db.DBService.GetPostByID(*poll.PostID, poll.CreatorID)
+	if err != nil {
+		return
+	}
+	if post.Visibility == "public" {
+		hub.BroadcastMessage(message)
+		return
+	}
+	// Private posts only reach their author and the followers picked for them
+	viewers, err := db.DBService.GetPostVisibilityUsers(*poll.PostID)
+	if err != nil {
+		return
+	}
+	hub.BroadcastToUser(int(post.AuthorID), message)
+	for _, viewerID := range viewers {
+		hub.BroadcastToUser(int(viewerID), message)
+	}
+}
+
+// GetPollHandler returns a poll with its tally. Supports ?id=, ?post_id=, ?group_post_id= or ?group_message_id=
+func GetPollHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	var poll *models.Poll
+	var err error
+	if idParam := q.Get("id"); idParam != "" {
+		pollID, parseErr := strconv.ParseInt(idParam, 10, 64)
+		if parseErr != nil || pollID <= 0 {
+			http.Error(w, "Invalid poll ID", http.StatusBadRequest)
+			return
+		}
+		poll, err = db.DBService.GetPoll(pollID, int64(userID))
+	} else {
+		target := ""
+		for _, param := range []string{models.PollTargetPost, models.PollTargetGroupPost, models.PollTargetGroupMessage} {
+			if q.Get(param) != "" {
+				target = param
+				break
+			}
+		}
+		if target == "" {
+			http.Error(w, "Missing id, post_id, group_post_id or group_message_id", http.StatusBadRequest)
+			return
+		}
+		targetID, parseErr := strconv.ParseInt(q.Get(target), 10, 64)
+		if parseErr != nil || targetID <= 0 {
+			http.Error(w, "Invalid "+target, http.StatusBadRequest)
+			return
+		}
+		poll, err = db.DBService.GetPollByTarget(target, targetID, int64(userID))
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Poll not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error retrieving poll", http.StatusInternalServerError)
+		return
+	}
+	if !requirePollAccess(w, poll, int64(userID)) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(poll)
+}
+
+// VotePollHandler replaces the votes of the user on a poll, an empty option_ids withdraws them
+func VotePollHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.VotePollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PollID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	poll, err := db.DBService.GetPoll(req.PollID, int64(userID))
+	if err != nil {
+		http.Error(w, "Poll not found", http.StatusNotFound)
+		return
+	}
+	if !requirePollAccess(w, poll, int64(userID)) {
+		return
+	}
+	if err := db.DBService.VotePoll(req.PollID, int64(userID), req.OptionIDs); err != nil {
+		switch {
+		case errors.Is(err, db.ErrPollClosed):
+			http.Error(w, "Poll is closed", http.StatusConflict)
+		case errors.Is(err, db.ErrInvalidPollVote):
+			http.Error(w, "Invalid options for this poll", http.StatusBadRequest)
+		case errors.Is(err, db.ErrGroupArchived):
+			http.Error(w, "Group is archived and read-only", http.StatusConflict)
+		default:
+			http.Error(w, "Error voting", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	poll, err = db.DBService.GetPoll(req.PollID, int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving poll", http.StatusInternalServerError)
+		return
+	}
+	broadcastPollTally(poll)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(poll)
+}
+
+// ClosePollHandler lets the creator of a poll close it early, its results are final from then on
+func ClosePollHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.ClosePollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PollID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.ClosePoll(req.PollID, int64(userID)); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Poll not found", http.StatusNotFound)
+		case errors.Is(err, db.ErrPollClosed):
+			http.Error(w, "Poll is already closed", http.StatusConflict)
+		case err.Error() == "not authorized":
+			http.Error(w, "Forbidden: only the creator of the poll can close it", http.StatusForbidden)
+		default:
+			http.Error(w, "Error closing poll", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	poll, err := db.DBService.GetPoll(req.PollID, int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving poll", http.StatusInternalServerError)
+		return
+	}
+	broadcastPollTally(poll)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(poll)
+}
@@ -0,0 +1,449 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// validateDraftContent cleans up the content of a draft and returns the problem if any.
+// Drafts can be saved half written, complete asks for everything publishing needs.
+func validateDraftContent(draft *models.PostDraft, complete bool) string {
+	draft.Title = utils.SanitizeString(draft.Title)
+	draft.Body = utils.SanitizeString(draft.Body)
+	if draft.Images == nil {
+		draft.Images = []string{}
+	}
+
+	if draft.GroupID != nil {
+		// Group posts have no visibility of their own, the group decides who sees them
+		draft.Visibility = "public"
+		draft.SelectedFollowers = nil
+		if len(draft.Title) > 100 || len(draft.Body) > 100 {
+			return "Title and body must be at most 100 characters"
+		}
+		if complete {
+			if validationErrors := utils.ValidateStringLength(&models.CreateGroupPostRequest{Title: draft.Title, Body: draft.Body}, 3, 100); len(validationErrors) > 0 {
+				return "Title and body must be between 3 and 100 characters"
+			}
+		}
+		if len(draft.Images) > 4 {
+			return "Maximum 4 images allowed per post"
+		}
+	} else {
+		cfg := config.GetConfig()
+		draft.IsAnnouncement = false
+		if draft.Visibility == "" {
+			draft.Visibility = "public"
+		}
+		if draft.Visibility != "public" && draft.Visibility != "private" {
+			return "Invalid visibility setting. Use 'public' or 'private'"
+		}
+		if draft.Visibility == "public" {
+			draft.SelectedFollowers = nil
+		}
+		if len(draft.Title) > cfg.PostTitleMaxLength || (complete && !utils.ValidatePostTitle(draft.Title, cfg.PostTitleMaxLength)) {
+			return fmt.Sprintf("Invalid title format. Max length: %d characters", cfg.PostTitleMaxLength)
+		}
+		if len(draft.Body) > cfg.PostContentMaxLength || (complete && !utils.ValidatePostBody(draft.Body, cfg.PostContentMaxLength)) {
+			return fmt.Sprintf("Invalid body format. Max length: %d characters", cfg.PostContentMaxLength)
+		}
+		if !utils.ValidatePostImageCount(draft.Images, cfg.MaxImagesPerPost) {
+			return fmt.Sprintf("Invalid number of images. Max: %d", cfg.MaxImagesPerPost)
+		}
+	}
+	if draft.SelectedFollowers == nil {
+		draft.SelectedFollowers = []int64{}
+	}
+
+	if draft.Poll != nil {
+		return validatePollRequest(draft.Poll)
+	}
+	return ""
+}
+
+// scheduleDraft validates a draft and sets its status from its publish time, a new publish time must be in the future.
+// Scheduled drafts must be complete, the publisher has no one to ask when something is missing.
+func scheduleDraft(draft *models.PostDraft, newPublishAt bool) string {
+	if draft.PublishAt == "" {
+		draft.Status = models.PostDraftDraft
+		draft.FailureReason = ""
+		return validateDraftContent(draft, false)
+	}
+	publishAt, err := time.Parse(time.RFC3339, draft.PublishAt)
+	if err != nil {
+		return "publish_at must be an RFC3339 time"
+	}
+	if newPublishAt && !publishAt.After(time.Now()) {
+		return "publish_at must be in the future"
+	}
+	draft.PublishAt = publishAt.UTC().Format(time.RFC3339)
+	draft.Status = models.PostDraftScheduled
+	draft.FailureReason = ""
+	return validateDraftContent(draft, true)
+}
+
+// requireDraftAudience checks the author may post where the draft goes, and only picked followers who follow them
+func requireDraftAudience(w http.ResponseWriter, draft *models.PostDraft) bool {
+	if draft.GroupID != nil {
+		if !requireGroupPermission(w, draft.UserID, *draft.GroupID, models.GroupPermPost) {
+			return false
+		}
+		return !draft.IsAnnouncement || requireGroupPermission(w, draft.UserID, *draft.GroupID, models.GroupPermAnnounce)
+	}
+	followers, err := db.DBService.FilterFollowers(draft.UserID, draft.SelectedFollowers)
+	if err != nil {
+		http.Error(w, "Error checking followers", http.StatusInternalServerError)
+		return false
+	}
+	if len(followers) != len(draft.SelectedFollowers) {
+		http.Error(w, "Selected users must be your followers", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// CreatePostDraftHandler saves a post or group post as a draft, it is scheduled when publish_at is given
+func CreatePostDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.CreatePostDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	draft := &models.PostDraft{
+		UserID:            int64(userID),
+		GroupID:           req.GroupID,
+		Title:             req.Title,
+		Body:              req.Body,
+		Images:            req.Images,
+		Visibility:        req.Visibility,
+		SelectedFollowers: req.SelectedFollowers,
+		IsAnnouncement:    req.IsAnnouncement,
+		Poll:              req.Poll,
+		PublishAt:         req.PublishAt,
+	}
+	if problem := scheduleDraft(draft, true); problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return
+	}
+	if !requireDraftAudience(w, draft) {
+		return
+	}
+
+	draftID, err := db.DBService.CreatePostDraft(draft)
+	if err != nil {
+		http.Error(w, "Error saving the draft", http.StatusInternalServerError)
+		return
+	}
+	writePostDraft(w, draftID, int64(userID), http.StatusCreated)
+}
+
+// GetPostDraftHandler returns a draft with ?id=, or every draft of the user
+func GetPostDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if idParam := r.URL.Query().Get("id"); idParam != "" {
+		draftID, err := strconv.ParseInt(idParam, 10, 64)
+		if err != nil || draftID <= 0 {
+			http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+			return
+		}
+		writePostDraft(w, draftID, int64(userID), http.StatusOK)
+		return
+	}
+
+	drafts, err := db.DBService.GetPostDrafts(int64(userID))
+	if err != nil {
+		http.Error(w, "Error retrieving drafts", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(drafts)
+}
+
+// UpdatePostDraftHandler edits a draft, it can be scheduled, rescheduled or unscheduled with publish_at.
+// A failed draft goes back to the drafts until it is scheduled again.
+func UpdatePostDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.UpdatePostDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	draft, err := db.DBService.GetPostDraft(req.ID, int64(userID))
+	if err != nil {
+		writePostDraftError(w, err)
+		return
+	}
+	if req.Title != nil {
+		draft.Title = *req.Title
+	}
+	if req.Body != nil {
+		draft.Body = *req.Body
+	}
+	if req.Images != nil {
+		draft.Images = *req.Images
+	}
+	if req.Visibility != nil {
+		draft.Visibility = *req.Visibility
+	}
+	if req.SelectedFollowers != nil {
+		draft.SelectedFollowers = *req.SelectedFollowers
+	}
+	if req.IsAnnouncement != nil {
+		draft.IsAnnouncement = *req.IsAnnouncement
+	}
+	if req.RemovePoll {
+		draft.Poll = nil
+	} else if req.Poll != nil {
+		draft.Poll = req.Poll
+	}
+	if req.PublishAt != nil {
+		draft.PublishAt = *req.PublishAt
+	} else if draft.Status == models.PostDraftFailed {
+		draft.PublishAt = ""
+	}
+
+	if problem := scheduleDraft(draft, req.PublishAt != nil); problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return
+	}
+	if !requireDraftAudience(w, draft) {
+		return
+	}
+	if err := db.DBService.UpdatePostDraft(draft); err != nil {
+		writePostDraftError(w, err)
+		return
+	}
+	writePostDraft(w, draft.ID, int64(userID), http.StatusOK)
+}
+
+// DeletePostDraftHandler deletes a draft, a scheduled one is not published anymore
+func DeletePostDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.PostDraftIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.DeletePostDraft(req.ID, int64(userID)); err != nil {
+		writePostDraftError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Draft successfully deleted"}`))
+}
+
+// PublishPostDraftHandler publishes a draft right away, whether it is scheduled or not
+func PublishPostDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req models.PostDraftIDRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	draft, err := db.DBService.GetPostDraft(req.ID, int64(userID))
+	if err != nil {
+		writePostDraftError(w, err)
+		return
+	}
+	if problem := validateDraftContent(draft, true); problem != "" {
+		http.Error(w, problem, http.StatusBadRequest)
+		return
+	}
+	if draft.GroupID != nil && !requireDraftAudience(w, draft) {
+		return
+	}
+
+	postID, status, err := publishDraft(draft)
+	if err != nil {
+		writePostDraftError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if status == models.GroupPostPending {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{
+			"response": "Group Post submitted for approval",
+			"id":       postID,
+			"status":   status,
+		})
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{
+		"response": "Post successfully published",
+		"id":       postID,
+		"status":   status,
+	})
+}
+
+func writePostDraft(w http.ResponseWriter, draftID, userID int64, status int) {
+	draft, err := db.DBService.GetPostDraft(draftID, userID)
+	if err != nil {
+		writePostDraftError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(draft)
+}
+
+// writePostDraftError maps the errors of the draft methods to a response
+func writePostDraftError(w http.ResponseWriter, err error) {
+	if reason, ok := draftFailureReason(err); ok {
+		http.Error(w, reason, http.StatusConflict)
+		return
+	}
+	switch {
+	case errors.Is(err, db.ErrDraftNotFound):
+		http.Error(w, "Draft not found", http.StatusNotFound)
+	case errors.Is(err, db.ErrDraftChanged):
+		http.Error(w, "Draft was changed meanwhile, reload it", http.StatusConflict)
+	default:
+		http.Error(w, "Error saving the draft", http.StatusInternalServerError)
+	}
+}
+
+// draftFailureReason explains why a draft cannot be published anymore, false for errors worth retrying
+func draftFailureReason(err error) (string, bool) {
+	switch {
+	case errors.Is(err, db.ErrDraftAudienceGone):
+		return "None of the selected followers follow you anymore", true
+	case errors.Is(err, db.ErrGroupArchived):
+		return "Group is archived and read-only", true
+	case errors.Is(err, db.ErrGroupMuted):
+		return "You are muted in this group", true
+	case err.Error() == "group does not exist":
+		return "Group does not exist anymore", true
+	case err.Error() == "user is not a member of the group":
+		return "You are not a member of the group anymore", true
+	case err.Error() == "not authorized":
+		return "Your role in the group does not allow this post anymore", true
+	}
+	return "", false
+}
+
+// publishDraft turns a draft into a post and sends what publishing a post sends
+func publishDraft(draft *models.PostDraft) (int64, string, error) {
+	postID, status, err := db.DBService.PublishPostDraft(draft)
+	if err != nil {
+		return 0, "", err
+	}
+	// Posts held for review are announced once approved
+	if draft.GroupID != nil && status == models.GroupPostPublished && draft.IsAnnouncement {
+		notifyGroupAnnouncement(*draft.GroupID, postID, draft.UserID, draft.Title)
+	}
+	return postID, status, nil
+}
+
+// StartPostDraftPublisher publishes the scheduled drafts that are due every interval
+func StartPostDraftPublisher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if published, err := PublishDueDrafts(now); err != nil {
+			log.Printf("Scheduled posts failed: %v", err)
+		} else if published > 0 {
+			log.Printf("Published %d scheduled posts", published)
+		}
+	}
+}
+
+// PublishDueDrafts publishes the drafts scheduled up to now and tells their authors how it went.
+// Drafts that can no longer be published are marked failed with the reason, other errors are retried next time.
+func PublishDueDrafts(now time.Time) (int, error) {
+	drafts, err := db.DBService.GetDuePostDrafts(now)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range drafts {
+		draft := &drafts[i]
+		notification := &notifications.ScheduledPost{DraftID: draft.ID, PostTitle: draft.Title}
+		if draft.GroupID != nil {
+			notification.GroupID = *draft.GroupID
+			if group, err := db.DBService.GetGroupByID(*draft.GroupID); err == nil {
+				notification.GroupName = group.Title
+			}
+		}
+
+		if problem := validateDraftContent(draft, true); problem != "" {
+			if err := failDraft(draft, notification, problem); err != nil {
+				return published, err
+			}
+			continue
+		}
+		postID, status, err := publishDraft(draft)
+		if err != nil {
+			if errors.Is(err, db.ErrDraftChanged) {
+				// Edited or published by its author since it was read
+				continue
+			}
+			reason, final := draftFailureReason(err)
+			if !final {
+				return published, err
+			}
+			if err := failDraft(draft, notification, reason); err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		published++
+		notification.PostID = postID
+		notification.Status = status
+		notifications.Send(draft.UserID, notification)
+	}
+	return published, nil
+}
+
+func failDraft(draft *models.PostDraft, notification *notifications.ScheduledPost, reason string) error {
+	failed, err := db.DBService.FailPostDraft(draft.ID, draft.Revision, reason)
+	if err != nil || !failed {
+		return err
+	}
+	notification.Status = models.PostDraftFailed
+	notification.Reason = reason
+	notifications.Send(draft.UserID, notification)
+	return nil
+}
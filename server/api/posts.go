@@ -61,6 +61,30 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if createRequest.AudienceListID != nil {
+		if createRequest.Visibility != "private" {
+			http.Error(w, "Audience lists are only used for private posts", http.StatusBadRequest)
+			return
+		}
+		audience, ok := withAudienceList(w, int64(currentUserID), createRequest.AudienceListID, createRequest.SelectedFollowers)
+		if !ok {
+			return
+		}
+		// An empty audience would open the post to every follower
+		if len(audience) == 0 {
+			http.Error(w, "The audience list is empty", http.StatusBadRequest)
+			return
+		}
+		createRequest.SelectedFollowers = audience
+	}
+
+	if createRequest.Poll != nil {
+		if problem := validatePollRequest(createRequest.Poll); problem != "" {
+			http.Error(w, problem, http.StatusBadRequest)
+			return
+		}
+	}
+
 	postID, err := db.DBService.CreatePost(int64(currentUserID), createRequest)
 	if err != nil {
 		fmt.Printf("[API] Create post failed: %v\n", err)
@@ -232,6 +256,10 @@ func UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "You are not authorized to edit this post", http.StatusForbidden)
 		return
 	}
+	if post.Kind == models.PostKindRepost {
+		http.Error(w, "Reposts can't be edited", http.StatusBadRequest)
+		return
+	}
 
 	if err := db.DBService.UpdatePost(postID, req); err != nil {
 		fmt.Printf("[API] Update post failed: %v\n", err)
@@ -383,11 +411,21 @@ func UpdatePostVisibilityHandler(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		SelectedFollowers []int64 `json:"selected_followers"`
+		AudienceListID    *int64  `json:"audience_list_id,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	audience, ok := withAudienceList(w, int64(currentUserID), req.AudienceListID, req.SelectedFollowers)
+	if !ok {
+		return
+	}
+	// An empty audience would open the post to every follower
+	if req.AudienceListID != nil && len(audience) == 0 {
+		http.Error(w, "The audience list is empty", http.StatusBadRequest)
+		return
+	}
 
 	// Update post visibility
 	err = db.DBService.DeletePostVisibilityForPost(postID)
@@ -396,7 +434,14 @@ func UpdatePostVisibilityHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = db.DBService.CreatePostVisibilityForFollowers(postID, req.SelectedFollowers)
+	err = db.DBService.CreatePostVisibilityForFollowers(postID, audience)
+	if err != nil {
+		http.Error(w, "Error updating post visibility", http.StatusInternalServerError)
+		return
+	}
+
+	// Posts whose audience was picked by hand no longer follow the list
+	err = db.DBService.SetPostAudienceList(postID, req.AudienceListID)
 	if err != nil {
 		http.Error(w, "Error updating post visibility", http.StatusInternalServerError)
 		return
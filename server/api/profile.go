@@ -576,21 +576,7 @@ func autoAcceptPendingFollowRequests(userID int64) error {
 		db.DBService.IncrementFollowersCount(userID)
 
 		// Create notification for the requester
-		targetUser, err := db.DBService.GetUserByID(userID)
-		if err == nil {
-			avatar := ""
-			if targetUser.Avatar.Valid {
-				avatar = targetUser.Avatar.String
-			}
-			notificationData := fmt.Sprintf(`{"target_id": %d, "target_nickname": "%s", "target_avatar": "%s", "type": "follow_accepted"}`,
-				userID, targetUser.Nickname, avatar)
-			notificationReq := models.CreateNotificationRequest{
-				UserID: request.RequesterID,
-				Type:   "follow_accepted",
-				Data:   notificationData,
-			}
-			db.DBService.CreateNotification(notificationReq)
-		}
+		sendFollowAccepted(request.RequesterID, userID)
 	}
 
 	return nil
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/utils"
+)
+
+var (
+	vapidPublicKey         string
+	allowInsecurePushHosts bool
+)
+
+// SetWebPush enables push subscriptions, allowInsecure accepts http and private endpoints for a local push service
+func SetWebPush(publicKey string, allowInsecure bool) {
+	vapidPublicKey = publicKey
+	allowInsecurePushHosts = allowInsecure
+}
+
+type pushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// GetVAPIDPublicKeyHandler returns the applicationServerKey the browser subscribes with
+func GetVAPIDPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if vapidPublicKey == "" {
+		http.Error(w, "Push notifications are not enabled", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"public_key": vapidPublicKey})
+}
+
+// SubscribePushHandler stores the PushSubscription of the browser, the body is its toJSON() output
+func SubscribePushHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if vapidPublicKey == "" {
+		http.Error(w, "Push notifications are not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req pushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub := utils.PushSubscription{Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := utils.ValidatePushSubscription(sub, allowInsecurePushHosts); err != nil {
+		http.Error(w, "Invalid subscription: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	err := db.DBService.SavePushSubscription(db.PushSubscription{
+		UserID:    int64(userID),
+		Endpoint:  sub.Endpoint,
+		P256dh:    sub.P256dh,
+		Auth:      sub.Auth,
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		http.Error(w, "Error saving subscription", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"response": "Push notifications enabled"}`))
+}
+
+// UnsubscribePushHandler removes a push endpoint of the user
+func UnsubscribePushHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Endpoint string `json:"endpoint"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Endpoint == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	found, err := db.DBService.DeletePushSubscription(int64(userID), req.Endpoint)
+	if err != nil {
+		http.Error(w, "Error removing subscription", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Push notifications disabled"}`))
+}
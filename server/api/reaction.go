@@ -4,12 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/middleware"
 	"github.com/Golden76z/social-network/models"
-	"github.com/Golden76z/social-network/websockets"
+	"github.com/Golden76z/social-network/notifications"
 )
 
 // Handler to create a new Reaction (like/dislike)
@@ -215,28 +214,11 @@ func sendPostLikeNotification(likerID int, postID int64, likerUsername string) {
 		return
 	}
 
-	// Get post owner username for notification (not used in current implementation)
-	_, err = db.DBService.GetUsernameByID(postOwnerID)
-	if err != nil {
-		return // Silently fail if we can't get username
-	}
-
-	// Send websocket notification
-	hub := websockets.GetHub()
-	if hub != nil {
-		message := websockets.Message{
-			Type:      websockets.MessageTypeNotify,
-			Content:   likerUsername + " liked your post",
-			UserID:    likerID,
-			Username:  likerUsername,
-			Timestamp: time.Now(),
-			Data: map[string]interface{}{
-				"notification_type": "post_like",
-				"post_id":           postID,
-				"liker_id":          likerID,
-				"liker_nickname":    likerUsername,
-			},
-		}
-		hub.BroadcastToUser(postOwnerID, message)
+	liker := notifications.Actor{ID: int64(likerID), Nickname: likerUsername}
+	if user, err := db.DBService.GetUserByID(int64(likerID)); err == nil {
+		liker = notifications.ActorFromUser(user)
 	}
+
+	// Likes of the same post are grouped while unread
+	notifications.Send(int64(postOwnerID), notifications.NewPostLike(postID, liker))
 }
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// RepostHandler shares a post the user can see, as a repost or as a quote when a body is given.
+// Sharing a repost shares the post it reposts.
+func RepostHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.RepostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PostID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Visibility != "public" && req.Visibility != "private" {
+		http.Error(w, "Invalid visibility setting. Use 'public' or 'private'", http.StatusBadRequest)
+		return
+	}
+	if req.Body != "" {
+		cfg := config.GetConfig()
+		req.Body = utils.SanitizeString(req.Body)
+		if !utils.ValidatePostBody(req.Body, cfg.PostContentMaxLength) {
+			http.Error(w, fmt.Sprintf("Invalid body format. Max length: %d characters", cfg.PostContentMaxLength), http.StatusBadRequest)
+			return
+		}
+	}
+
+	post, err := db.DBService.GetPostByID(req.PostID, int64(currentUserID))
+	if err != nil {
+		if err.Error() == "unauthorized" {
+			http.Error(w, "You are not authorized to view this post", http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	originalID := post.ID
+	if post.Kind == models.PostKindRepost {
+		originalID = *post.OriginalPostID
+	}
+
+	postID, err := db.DBService.CreateRepost(int64(currentUserID), originalID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrAlreadyReposted):
+			http.Error(w, "You already reposted this post", http.StatusConflict)
+		case errors.Is(err, db.ErrRepostAudience):
+			http.Error(w, "A private post can only be reshared privately to those who can see it", http.StatusForbidden)
+		default:
+			fmt.Printf("[API] Repost failed: %v\n", err)
+			http.Error(w, "Failed to repost", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Post shared successfully",
+		"postID":  postID,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteRepostHandler undoes the repost of the post given by post_id
+func DeleteRepostHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.ParseInt(r.URL.Query().Get("post_id"), 10, 64)
+	if err != nil || postID <= 0 {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.DeleteRepost(int64(currentUserID), postID); err != nil {
+		if errors.Is(err, db.ErrRepostNotFound) {
+			http.Error(w, "Repost not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete repost", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Repost deleted successfully"})
+}
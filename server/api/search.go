@@ -187,7 +187,7 @@ func searchGroupsInDB(query string, currentUserID int64, limit int) ([]models.Gr
 		         SELECT 1 FROM group_requests WHERE group_id = g.id AND user_id = ? AND status = 'accepted'
 		       ) AS is_member
 		FROM groups g
-		WHERE (g.title LIKE ? OR g.bio LIKE ?)
+		WHERE g.status != 'deleted' AND (g.title LIKE ? OR g.bio LIKE ?)
 		ORDER BY 
 			CASE 
 				WHEN g.title LIKE ? THEN 1
@@ -1,10 +1,214 @@
 package api
 
 import (
+	"crypto/subtle"
+	"database/sql"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/utils"
+)
+
+const (
+	oidcAuthRequestTTL  = 10 * time.Minute
+	oidcStateCookieName = "oidc_state"
+)
+
+var (
+	oidcProvider          *utils.OIDCProvider
+	oidcPostLoginRedirect string
 )
 
-func GoogleSignInHandler(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("test")
+// SetOIDCProvider enables third-party sign-in, the browser is sent back to postLoginRedirect afterwards
+func SetOIDCProvider(provider *utils.OIDCProvider, postLoginRedirect string) {
+	oidcProvider = provider
+	oidcPostLoginRedirect = postLoginRedirect
+}
+
+// Handler starting the authorization code flow: stores state, nonce and PKCE verifier then redirects to the provider
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if oidcProvider == nil {
+		http.Error(w, "Third-party sign-in is not enabled", http.StatusNotFound)
+		return
+	}
+
+	state, err := utils.RandomURLToken(32)
+	if err != nil {
+		http.Error(w, "Error starting sign-in", http.StatusInternalServerError)
+		return
+	}
+	nonce, err := utils.RandomURLToken(32)
+	if err != nil {
+		http.Error(w, "Error starting sign-in", http.StatusInternalServerError)
+		return
+	}
+	codeVerifier, err := utils.RandomURLToken(48)
+	if err != nil {
+		http.Error(w, "Error starting sign-in", http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := oidcProvider.AuthCodeURL(r.Context(), state, nonce, utils.PKCEChallenge(codeVerifier))
+	if err != nil {
+		fmt.Printf("[API] OIDC discovery failed: %v\n", err)
+		http.Error(w, "Identity provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	if err := db.DBService.SaveOIDCAuthRequest(state, nonce, codeVerifier, oidcAuthRequestTTL); err != nil {
+		http.Error(w, "Error starting sign-in", http.StatusInternalServerError)
+		return
+	}
+
+	// Binds the state to this browser, so a callback started elsewhere is refused
+	setOIDCStateCookie(w, state, int(oidcAuthRequestTTL.Seconds()))
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Handler receiving the provider redirect: validates state, exchanges the code, verifies the ID token and logs the linked user in
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if oidcProvider == nil {
+		http.Error(w, "Third-party sign-in is not enabled", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"access_denied"}}, false)
+		return
+	}
+
+	state, code := query.Get("state"), query.Get("code")
+	if state == "" || code == "" {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"invalid_request"}}, false)
+		return
+	}
+
+	// The state must match the cookie set when this browser started the sign-in, otherwise an
+	// attacker could log the victim into the attacker's account with their own code
+	cookie, err := r.Cookie(oidcStateCookieName)
+	setOIDCStateCookie(w, "", -1)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"invalid_state"}}, false)
+		return
+	}
+
+	// State is single-use, so a callback cannot be replayed
+	nonce, codeVerifier, err := db.DBService.ConsumeOIDCAuthRequest(state)
+	if err != nil {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"invalid_state"}}, false)
+		return
+	}
+
+	token, err := oidcProvider.Exchange(r.Context(), code, codeVerifier)
+	if err != nil {
+		fmt.Printf("[API] OIDC code exchange failed: %v\n", err)
+		oidcRedirect(w, r, url.Values{"oidc_error": {"exchange_failed"}}, false)
+		return
+	}
+
+	claims, err := oidcProvider.VerifyIDToken(r.Context(), token.IDToken, nonce)
+	if err != nil {
+		fmt.Printf("[API] OIDC id_token rejected: %v\n", err)
+		oidcRedirect(w, r, url.Values{"oidc_error": {"invalid_token"}}, false)
+		return
+	}
+
+	userID, err := db.DBService.FindOrLinkOIDCIdentity(oidcProvider.Name(), claims.Subject, strings.ToLower(claims.Email), claims.EmailVerified)
+	if err != nil {
+		switch err.Error() {
+		case "email not verified by identity provider":
+			oidcRedirect(w, r, url.Values{"oidc_error": {"email_not_verified"}}, false)
+		case "no account matches this email":
+			oidcRedirect(w, r, url.Values{"oidc_error": {"account_not_found"}}, false)
+		default:
+			oidcRedirect(w, r, url.Values{"oidc_error": {"server_error"}}, false)
+		}
+		return
+	}
+
+	// A locked account stays locked whatever the way of signing in
+	wait, err := db.DBService.GetLoginRetryAfter(userID)
+	if err != nil {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"server_error"}}, false)
+		return
+	}
+	if wait > 0 {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"account_locked"}}, false)
+		return
+	}
+
+	// The provider login does not replace our own second factor
+	twoFactorEnabled, err := db.DBService.IsTwoFactorEnabled(userID)
+	if err != nil {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"server_error"}}, false)
+		return
+	}
+	if twoFactorEnabled {
+		challengeToken, err := createLoginChallenge(userID)
+		if err != nil {
+			oidcRedirect(w, r, url.Values{"oidc_error": {"server_error"}}, false)
+			return
+		}
+		// Fragment so the challenge token is never sent to a server or logged
+		oidcRedirect(w, r, url.Values{"two_factor_required": {"1"}, "challenge_token": {challengeToken}}, true)
+		return
+	}
+
+	username, err := db.DBService.GetUsernameByID(int(userID))
+	if err == sql.ErrNoRows {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"account_not_found"}}, false)
+		return
+	} else if err != nil {
+		oidcRedirect(w, r, url.Values{"oidc_error": {"server_error"}}, false)
+		return
+	}
+
+	if _, err := startSession(w, username, userID); err != nil {
+		return
+	}
+
+	oidcRedirect(w, r, url.Values{"login": {"success"}}, false)
+}
+
+// setOIDCStateCookie stores the state of a pending sign-in, a negative maxAge clears it
+func setOIDCStateCookie(w http.ResponseWriter, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    state,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		// Lax so the cookie is still sent on the top-level redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// oidcRedirect sends the browser back to the client application with the outcome of the sign-in
+func oidcRedirect(w http.ResponseWriter, r *http.Request, params url.Values, fragment bool) {
+	target, err := url.Parse(oidcPostLoginRedirect)
+	if err != nil || oidcPostLoginRedirect == "" {
+		http.Error(w, "Post-login redirect is not configured", http.StatusInternalServerError)
+		return
+	}
+
+	if fragment {
+		target.Fragment = params.Encode()
+	} else {
+		q := target.Query()
+		for key, values := range params {
+			for _, v := range values {
+				q.Add(key, v)
+			}
+		}
+		target.RawQuery = q.Encode()
+	}
+
+	http.Redirect(w, r, target.String(), http.StatusFound)
 }
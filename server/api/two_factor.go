@@ -0,0 +1,383 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+)
+
+const (
+	loginChallengeTTL         = 5 * time.Minute
+	loginChallengeMaxAttempts = 5
+	recoveryCodeCount         = 10
+)
+
+// createLoginChallenge stores a pending second login step and returns its token
+func createLoginChallenge(userID int64) (string, error) {
+	challengeToken, err := utils.RandomURLToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := db.DBService.CreateLoginChallenge(userID, challengeToken, loginChallengeTTL); err != nil {
+		return "", err
+	}
+	return challengeToken, nil
+}
+
+// sendLoginChallenge answers a correct password login on a 2FA account with a challenge token
+func sendLoginChallenge(w http.ResponseWriter, userID int64) {
+	challengeToken, err := createLoginChallenge(userID)
+	if err != nil {
+		fmt.Printf("[API] Error creating login challenge: %v\n", err)
+		http.Error(w, "Error creating login challenge", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":             "Two-factor authentication required",
+		"two_factor_required": true,
+		"challenge_token":     challengeToken,
+		"expires_in":          int(loginChallengeTTL.Seconds()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// verifySecondFactor checks either a TOTP code or a recovery code for the user
+func verifySecondFactor(userID int64, code, recoveryCode string) (bool, error) {
+	if strings.TrimSpace(code) != "" {
+		tf, err := db.DBService.GetTwoFactor(userID)
+		if err != nil {
+			return false, err
+		}
+		step, ok := utils.ValidateTOTPCode(tf.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		// Reject a code that was already accepted once
+		return db.DBService.MarkTOTPStepUsed(userID, step)
+	}
+
+	if strings.TrimSpace(recoveryCode) != "" {
+		return db.DBService.ConsumeRecoveryCode(userID, utils.HashRecoveryCode(recoveryCode))
+	}
+
+	return false, nil
+}
+
+// newRecoveryCodes generates plaintext codes for the user and their hashes for storage
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// Handler for the second login step, the session is only issued once the code is verified
+func LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req models.LoginTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		http.Error(w, "Challenge token and a code or recovery code are required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _, err := db.DBService.GetLoginChallenge(req.ChallengeToken)
+	if err != nil {
+		http.Error(w, "Login challenge is invalid or expired, please log in again", http.StatusUnauthorized)
+		return
+	}
+
+	if rejectLockedLogin(w, userID) {
+		return
+	}
+
+	// The attempt is counted before the code is checked so parallel guesses share the limit
+	reserved, err := db.DBService.ReserveLoginChallengeAttempt(req.ChallengeToken, loginChallengeMaxAttempts)
+	if err != nil {
+		fmt.Printf("[API] Error counting login challenge attempt: %v\n", err)
+		http.Error(w, "Error verifying code", http.StatusInternalServerError)
+		return
+	}
+	if !reserved {
+		if err := db.DBService.DeleteLoginChallenge(req.ChallengeToken); err != nil {
+			fmt.Printf("[API] Error deleting login challenge: %v\n", err)
+		}
+		http.Error(w, "Too many invalid codes, please log in again", http.StatusUnauthorized)
+		return
+	}
+
+	ok, err := verifySecondFactor(userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		fmt.Printf("[API] Error verifying second factor: %v\n", err)
+		http.Error(w, "Error verifying code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		recordFailedLogin(r, userID)
+		http.Error(w, "Invalid authentication code", http.StatusUnauthorized)
+		return
+	}
+
+	_ = db.DBService.DeleteLoginChallenge(req.ChallengeToken)
+	_ = db.DBService.ResetFailedLogins(userID)
+
+	username, err := db.DBService.GetUsernameByID(int(userID))
+	if err != nil {
+		http.Error(w, "Error getting user", http.StatusInternalServerError)
+		return
+	}
+
+	issueSession(w, username, userID)
+}
+
+// Handler returning whether 2FA is enabled for the current user
+func GetTwoFactorStatusHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID := int64(currentUserID)
+
+	enabled, err := db.DBService.IsTwoFactorEnabled(userID)
+	if err != nil {
+		http.Error(w, "Error getting two-factor status", http.StatusInternalServerError)
+		return
+	}
+
+	response := models.TwoFactorStatusResponse{Enabled: enabled}
+	if enabled {
+		response.RemainingRecoveryCodes, err = db.DBService.CountRemainingRecoveryCodes(userID)
+		if err != nil {
+			http.Error(w, "Error getting two-factor status", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Handler starting 2FA enrollment, returns the secret and the otpauth:// URI to render as a QR code
+func SetupTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID := int64(currentUserID)
+
+	user, err := db.DBService.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		http.Error(w, "Error generating secret", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DBService.SaveTwoFactorSecret(userID, secret); err != nil {
+		if err.Error() == "two-factor authentication already enabled" {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Error saving secret", http.StatusInternalServerError)
+		return
+	}
+
+	cfg := config.GetConfig()
+	response := models.TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(cfg.TwoFactorIssuer, user.Email, secret),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Handler confirming enrollment with a first valid code, returns the recovery codes once
+func EnableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID := int64(currentUserID)
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tf, err := db.DBService.GetTwoFactor(userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Start two-factor setup first", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, "Error getting two-factor status", http.StatusInternalServerError)
+		return
+	}
+	if tf.Enabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	step, valid := utils.ValidateTOTPCode(tf.Secret, req.Code, time.Now())
+	if !valid {
+		http.Error(w, "Invalid authentication code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Error generating recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DBService.EnableTwoFactor(userID, step, hashes); err != nil {
+		http.Error(w, "Error enabling two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// Handler disabling 2FA, requires the password and a current code or recovery code
+func DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID := int64(currentUserID)
+
+	var req models.DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DBService.VerifyUserPassword(userID, req.Password); err != nil {
+		http.Error(w, "Invalid password", http.StatusUnauthorized)
+		return
+	}
+
+	valid, err := verifySecondFactor(userID, req.Code, req.RecoveryCode)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Error verifying code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		http.Error(w, "Invalid authentication code", http.StatusUnauthorized)
+		return
+	}
+
+	// Admins of groups requiring 2FA must step down before disabling it
+	required, err := db.DBService.IsAdminOfGroupRequiring2FA(userID)
+	if err != nil {
+		http.Error(w, "Error checking group requirements", http.StatusInternalServerError)
+		return
+	}
+	if required {
+		http.Error(w, "You are admin of a group that requires two-factor authentication", http.StatusConflict)
+		return
+	}
+
+	if err := db.DBService.DisableTwoFactor(userID); err != nil {
+		http.Error(w, "Error disabling two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"response": "Two-factor authentication disabled"}`))
+}
+
+// Handler replacing every recovery code, requires a current TOTP code
+func RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID := int64(currentUserID)
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	enabled, err := db.DBService.IsTwoFactorEnabled(userID)
+	if err != nil {
+		http.Error(w, "Error getting two-factor status", http.StatusInternalServerError)
+		return
+	}
+	if !enabled {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		return
+	}
+
+	valid, err := verifySecondFactor(userID, req.Code, "")
+	if err != nil {
+		http.Error(w, "Error verifying code", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		http.Error(w, "Invalid authentication code", http.StatusUnauthorized)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Error generating recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DBService.RegenerateRecoveryCodes(userID, hashes); err != nil {
+		http.Error(w, "Error saving recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recovery_codes": codes,
+	})
+}
@@ -0,0 +1,230 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // Timezones must resolve on hosts without a zoneinfo database
+	"unicode/utf8"
+)
+
+const prodID = "-//Social Network//Group Events//EN"
+
+// vtimezoneYearsAhead is how far past the last start the exported timezone rules go,
+// clients extrapolate from the last observance after that
+const vtimezoneYearsAhead = 5
+
+// Event is one VEVENT. Start carries the timezone of the event, UTC events are written with a Z suffix.
+// A non zero RecurrenceID makes it an override of that occurrence of the event sharing its UID.
+type Event struct {
+	UID          string
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID time.Time
+	Stamp        time.Time
+	// Status is CONFIRMED, TENTATIVE or CANCELLED, left out when empty
+	Status string
+}
+
+// LoadLocation resolves an IANA timezone name, the empty name is UTC
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "UTC" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %s", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// Write renders the events as an RFC 5545 calendar named name
+func Write(w io.Writer, name string, events []Event) error {
+	cw := &contentWriter{w: bufio.NewWriter(w)}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + prodID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	if name != "" {
+		cw.line("X-WR-CALNAME:" + escapeText(name))
+	}
+
+	for _, tz := range timezonesOf(events) {
+		writeTimezone(cw, tz.loc, tz.from, tz.to)
+	}
+	for _, e := range events {
+		writeEvent(cw, e)
+	}
+
+	cw.line("END:VCALENDAR")
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+func writeEvent(cw *contentWriter, e Event) {
+	cw.line("BEGIN:VEVENT")
+	cw.line("UID:" + escapeText(e.UID))
+	cw.line("DTSTAMP:" + e.Stamp.UTC().Format("20060102T150405Z"))
+	if !e.RecurrenceID.IsZero() {
+		cw.line(dateTimeProperty("RECURRENCE-ID", e.RecurrenceID))
+	}
+	cw.line(dateTimeProperty("DTSTART", e.Start))
+	if !e.End.IsZero() {
+		cw.line(dateTimeProperty("DTEND", e.End))
+	}
+	if e.RRule != "" {
+		cw.line("RRULE:" + e.RRule)
+	}
+	for _, ex := range e.ExDates {
+		cw.line(dateTimeProperty("EXDATE", ex))
+	}
+	cw.line("SUMMARY:" + escapeText(e.Summary))
+	if e.Description != "" {
+		cw.line("DESCRIPTION:" + escapeText(e.Description))
+	}
+	if e.Location != "" {
+		cw.line("LOCATION:" + escapeText(e.Location))
+	}
+	if e.Status != "" {
+		cw.line("STATUS:" + e.Status)
+	}
+	cw.line("END:VEVENT")
+}
+
+// dateTimeProperty writes t as UTC or as local time with the TZID of its location
+func dateTimeProperty(name string, t time.Time) string {
+	if t.Location() == time.UTC {
+		return name + ":" + t.Format("20060102T150405Z")
+	}
+	return name + ";TZID=" + t.Location().String() + ":" + t.Format("20060102T150405")
+}
+
+type timezoneRange struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// timezonesOf lists the non UTC locations of the events with the span of their starts
+func timezonesOf(events []Event) []timezoneRange {
+	byName := map[string]*timezoneRange{}
+	for _, e := range events {
+		loc := e.Start.Location()
+		if loc == time.UTC {
+			continue
+		}
+		tz, ok := byName[loc.String()]
+		if !ok {
+			tz = &timezoneRange{loc: loc, from: e.Start, to: e.Start}
+			byName[loc.String()] = tz
+		}
+		if e.Start.Before(tz.from) {
+			tz.from = e.Start
+		}
+		if e.Start.After(tz.to) {
+			tz.to = e.Start
+		}
+	}
+
+	zones := make([]timezoneRange, 0, len(byName))
+	for _, tz := range byName {
+		zones = append(zones, *tz)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].loc.String() < zones[j].loc.String() })
+	return zones
+}
+
+// writeTimezone writes a VTIMEZONE with the observances of loc from the start of the year of from
+// until vtimezoneYearsAhead years after to
+func writeTimezone(cw *contentWriter, loc *time.Location, from, to time.Time) {
+	start := time.Date(from.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	limit := time.Date(to.In(loc).Year()+vtimezoneYearsAhead+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	cw.line("BEGIN:VTIMEZONE")
+	cw.line("TZID:" + loc.String())
+
+	_, offset := start.Zone()
+	writeObservance(cw, start, offset)
+	for t := start; ; {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || !end.Before(limit) {
+			break
+		}
+		writeObservance(cw, end, offset)
+		_, offset = end.Zone()
+		t = end
+	}
+
+	cw.line("END:VTIMEZONE")
+}
+
+// writeObservance writes the zone starting at onset, reached from the offset in effect before it
+func writeObservance(cw *contentWriter, onset time.Time, offsetFrom int) {
+	abbr, offsetTo := onset.Zone()
+	kind := "STANDARD"
+	if onset.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	cw.line("BEGIN:" + kind)
+	// The onset is given in the local time that was in effect before it
+	cw.line("DTSTART:" + onset.In(time.FixedZone("", offsetFrom)).Format("20060102T150405"))
+	cw.line("TZOFFSETFROM:" + formatOffset(offsetFrom))
+	cw.line("TZOFFSETTO:" + formatOffset(offsetTo))
+	if abbr != "" && !strings.ContainsAny(abbr, "+-") {
+		cw.line("TZNAME:" + escapeText(abbr))
+	}
+	cw.line("END:" + kind)
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+// escapeText escapes a TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// contentWriter writes content lines folded at 75 octets with CRLF endings, keeping the first error
+type contentWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (cw *contentWriter) line(s string) {
+	if cw.err != nil {
+		return
+	}
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, cw.err = cw.w.WriteString(s[:cut] + "\r\n "); cw.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines lose one octet to the leading space
+		limit = 74
+	}
+	_, cw.err = cw.w.WriteString(s + "\r\n")
+}
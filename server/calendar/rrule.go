@@ -0,0 +1,398 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies supported from RFC 5545
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxPeriods bounds the expansion of rules that can never produce another occurrence,
+// like BYMONTHDAY=31 with BYMONTH=2
+const maxPeriods = 10000
+
+// ErrInvalidRule is wrapped by every parsing error
+var ErrInvalidRule = errors.New("invalid RRULE")
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum is a BYDAY entry, N is the ordinal within the month (-1 for the last), 0 for every week
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is the subset of an RFC 5545 recurrence rule the events support:
+// FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+}
+
+// ParseRule reads a rule like "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10", the "RRULE:" prefix is optional.
+// A date-only UNTIL covers the whole day, a floating one is read in loc.
+func ParseRule(value string, loc *time.Location) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, fmt.Errorf("%w: empty rule", ErrInvalidRule)
+	}
+
+	r := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			switch val {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = val
+			default:
+				err = fmt.Errorf("unsupported frequency %s", val)
+			}
+		case "INTERVAL":
+			r.Interval, err = parsePositive(val)
+		case "COUNT":
+			r.Count, err = parsePositive(val)
+		case "UNTIL":
+			r.Until, err = parseUntil(val, loc)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseIntList(val, -31, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(val, 1, 12)
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "WKST":
+			if val != "MO" {
+				err = errors.New("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %s", name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot be combined", ErrInvalidRule)
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly && !(r.Freq == Yearly && len(r.ByMonth) > 0) {
+			return nil, fmt.Errorf("%w: numbered BYDAY needs FREQ=MONTHLY or BYMONTH", ErrInvalidRule)
+		}
+	}
+	if r.Freq == Yearly && len(r.ByMonth) == 0 && (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0) {
+		return nil, fmt.Errorf("%w: BYDAY and BYMONTHDAY need BYMONTH with FREQ=YEARLY", ErrInvalidRule)
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return nil, fmt.Errorf("%w: BYMONTHDAY cannot be used with FREQ=WEEKLY", ErrInvalidRule)
+	}
+	return r, nil
+}
+
+func parsePositive(val string) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s is not a positive number", val)
+	}
+	return n, nil
+}
+
+func parseIntList(val string, min, max int) ([]int, error) {
+	var list []int
+	for _, item := range strings.Split(val, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil || n < min || n > max || n == 0 {
+			return nil, fmt.Errorf("%s is out of range", item)
+		}
+		list = append(list, n)
+	}
+	return list, nil
+}
+
+func parseByDay(val string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(val, ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("%s is not a weekday", item)
+		}
+		code := item[len(item)-2:]
+		weekday, ok := weekdayCodes[code]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a weekday", item)
+		}
+		day := WeekdayNum{Weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("%s has an invalid ordinal", item)
+			}
+			day.N = n
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseUntil(val string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", val, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", val, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %s is not a date", val)
+}
+
+// String gives the canonical form of the rule, the one stored and exported
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayNames[d.Weekday]
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the starts of the occurrences within [from, to] in order, at most max of them
+// when max > 0. A zero to leaves the range open, maxPeriods still bounds endless rules.
+// dtstart is always the first occurrence, later ones keep its wall clock time in its location
+// so they follow daylight saving changes.
+func (r *Rule) Occurrences(dtstart, from, to time.Time, max int) []time.Time {
+	var out []time.Time
+	emitted := 0
+	done := func(t time.Time) bool {
+		return (r.Count > 0 && emitted >= r.Count) ||
+			(!r.Until.IsZero() && t.After(r.Until)) ||
+			(!to.IsZero() && t.After(to)) ||
+			(max > 0 && len(out) >= max)
+	}
+	emit := func(t time.Time) {
+		emitted++
+		if !t.Before(from) {
+			out = append(out, t)
+		}
+	}
+
+	if done(dtstart) {
+		return out
+	}
+	emit(dtstart)
+
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.candidates(dtstart, period) {
+			if !t.After(dtstart) {
+				continue
+			}
+			if done(t) {
+				return out
+			}
+			emit(t)
+		}
+	}
+	return out
+}
+
+// candidates lists the sorted occurrences of the nth period after the one holding dtstart
+func (r *Rule) candidates(dtstart time.Time, n int) []time.Time {
+	y, m, d := dtstart.Date()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	}
+	step := n * r.Interval
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := at(y, m, d+step)
+		if r.matchesMonth(day.Month()) && r.matchesWeekday(day) && r.matchesMonthDay(day) {
+			days = append(days, day)
+		}
+	case Weekly:
+		// Weeks start on monday
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := at(y, m, d-offset+7*step)
+		weekdays := r.ByDay
+		if len(weekdays) == 0 {
+			weekdays = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+		}
+		for _, wd := range weekdays {
+			day := monday.AddDate(0, 0, (int(wd.Weekday)+6)%7)
+			if r.matchesMonth(day.Month()) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		first := at(y, m+time.Month(step), 1)
+		if r.matchesMonth(first.Month()) {
+			days = r.daysInMonth(first, d)
+		}
+	case Yearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{m}
+		}
+		for _, month := range months {
+			days = append(days, r.daysInMonth(at(y+step, month, 1), d)...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// daysInMonth expands BYMONTHDAY and BYDAY over the month starting at first,
+// defaulting to the day of the month of dtstart, which some months do not have
+func (r *Rule) daysInMonth(first time.Time, defaultDay int) []time.Time {
+	year, month, _ := first.Date()
+	length := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	onDay := func(day int) time.Time { return first.AddDate(0, 0, day-1) }
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			day := md
+			if md < 0 {
+				day = length + md + 1
+			}
+			if day >= 1 && day <= length && r.matchesWeekday(onDay(day)) && r.matchesOrdinal(onDay(day), length) {
+				days = append(days, onDay(day))
+			}
+		}
+	case len(r.ByDay) > 0:
+		for day := 1; day <= length; day++ {
+			if t := onDay(day); r.matchesWeekday(t) && r.matchesOrdinal(t, length) {
+				days = append(days, t)
+			}
+		}
+	case defaultDay <= length:
+		days = append(days, onDay(defaultDay))
+	}
+	return days
+}
+
+func (r *Rule) matchesMonth(month time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	length := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md == t.Day() || length+md+1 == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday ignores the ordinals, matchesOrdinal checks them
+func (r *Rule) matchesWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchesOrdinal(t time.Time, monthLength int) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday != t.Weekday() {
+			continue
+		}
+		if wd.N == 0 ||
+			(wd.N > 0 && (t.Day()-1)/7+1 == wd.N) ||
+			(wd.N < 0 && (monthLength-t.Day())/7+1 == -wd.N) {
+			return true
+		}
+	}
+	return false
+}
@@ -39,11 +39,63 @@ type Config struct {
 	FeedPostLimit          int
 	MaxFileSizeMB          int
 	SessionCleanupInterval time.Duration
+	GroupRestoreWindow     time.Duration
+	GroupPurgeInterval     time.Duration
 
 	// Security
 	BcryptCost             int
 	RateLimitRequests      int
 	RateLimitWindowMinutes int
+	TwoFactorIssuer        string
+	LoginBackoffThreshold  int
+	LoginMaxAttempts       int
+	LoginLockoutDuration   time.Duration
+	CSRFSecret             string
+	CSRFTokenTTL           time.Duration
+	// Reverse proxies allowed to set X-Forwarded-For, addresses or CIDR ranges
+	TrustedProxies []string
+
+	// OpenID Connect sign-in (any provider exposing a discovery document)
+	OIDCEnabled           bool
+	OIDCProviderName      string
+	OIDCIssuerURL         string
+	OIDCClientID          string
+	OIDCClientSecret      string
+	OIDCRedirectURL       string
+	OIDCScopes            []string
+	OIDCPostLoginRedirect string
+
+	// Email and notification digests
+	PublicBaseURL          string
+	ClientBaseURL          string
+	MailDriver             string
+	MailFrom               string
+	MailFileDir            string
+	SMTPHost               string
+	SMTPPort               string
+	SMTPUsername           string
+	SMTPPassword           string
+	DigestEnabled          bool
+	DigestInterval         time.Duration
+	DigestDefaultFrequency string
+	DigestMaxItems         int
+
+	// Event reminders, sent the given durations before an event starts
+	EventRemindersEnabled  bool
+	EventReminderLeadTimes []time.Duration
+	EventReminderInterval  time.Duration
+	// How far an event moves before its RSVPs must be confirmed again
+	EventRescheduleThreshold time.Duration
+
+	// Scheduled posts, the publisher looks for due drafts every interval
+	ScheduledPostsEnabled  bool
+	ScheduledPostsInterval time.Duration
+
+	// Web Push for users without an open socket
+	WebPushEnabled bool
+	VAPIDKeys      *utils.VAPIDKeys
+	VAPIDSubject   string
+	WebPushWorkers int
 
 	// Features
 	EnableRegistration bool
@@ -102,6 +154,19 @@ func Load() error {
 			jwtExpiration = 2 * time.Hour
 		}
 
+		// VAPID keys must stay stable or every browser subscription breaks, generate them only as a fallback
+		vapidKeys, keyErr := loadVAPIDKeys(environment)
+		if keyErr != nil {
+			err = keyErr
+			return
+		}
+
+		reminderLeadTimes, parseErr := getEnvAsDurations("EVENT_REMINDER_LEAD_TIMES", []time.Duration{24 * time.Hour, time.Hour})
+		if parseErr != nil {
+			err = parseErr
+			return
+		}
+
 		// Update utils.Settings for backward compatibility
 		utils.Settings = &utils.ServerSettings{
 			JwtKey: key,
@@ -131,11 +196,61 @@ func Load() error {
 			FeedPostLimit:          getEnvAsInt("FEED_POST_LIMIT", 20),
 			MaxFileSizeMB:          getEnvAsInt("MAX_FILE_SIZE_MB", 10),
 			SessionCleanupInterval: time.Duration(getEnvAsInt("SESSION_CLEANUP_INTERVAL_HOURS", 1)) * time.Hour,
+			GroupRestoreWindow:     time.Duration(getEnvAsInt("GROUP_RESTORE_DAYS", 30)) * 24 * time.Hour,
+			GroupPurgeInterval:     time.Duration(getEnvAsInt("GROUP_PURGE_INTERVAL_HOURS", 1)) * time.Hour,
 
 			// Security
 			BcryptCost:             getEnvAsInt("BCRYPT_COST", 12),
-			RateLimitRequests:      getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
-			RateLimitWindowMinutes: getEnvAsInt("RATE_LIMIT_WINDOW_MINUTES", 15),
+			RateLimitRequests:      getEnvAsInt("RATE_LIMIT_REQUESTS", 300),
+			RateLimitWindowMinutes: getEnvAsInt("RATE_LIMIT_WINDOW_MINUTES", 1),
+			TwoFactorIssuer:        getEnv("TWO_FACTOR_ISSUER", "Social Network"),
+			LoginBackoffThreshold:  getEnvAsInt("LOGIN_BACKOFF_THRESHOLD", 3),
+			LoginMaxAttempts:       getEnvAsInt("LOGIN_MAX_ATTEMPTS", 10),
+			LoginLockoutDuration:   time.Duration(getEnvAsInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+			CSRFSecret:             getEnv("CSRF_SECRET", ""),
+			CSRFTokenTTL:           time.Duration(getEnvAsInt("CSRF_TOKEN_TTL_MINUTES", 240)) * time.Minute,
+			TrustedProxies:         getEnvAsSlice("TRUSTED_PROXIES", nil),
+
+			// OpenID Connect
+			OIDCEnabled:           getEnvAsBool("OIDC_ENABLED", false),
+			OIDCProviderName:      getEnv("OIDC_PROVIDER_NAME", "google"),
+			OIDCIssuerURL:         getEnv("OIDC_ISSUER_URL", "https://accounts.google.com"),
+			OIDCClientID:          getEnv("OIDC_CLIENT_ID", ""),
+			OIDCClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+			OIDCRedirectURL:       getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback"),
+			OIDCScopes:            getEnvAsSlice("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			OIDCPostLoginRedirect: getEnv("OIDC_POST_LOGIN_REDIRECT", "http://localhost:3000/"),
+
+			// Email and notification digests
+			PublicBaseURL:          getEnv("PUBLIC_BASE_URL", "http://localhost:8080"),
+			ClientBaseURL:          getEnv("CLIENT_BASE_URL", "http://localhost:3000"),
+			MailDriver:             getEnv("MAIL_DRIVER", "file"),
+			MailFrom:               getEnv("MAIL_FROM", "Social Network <no-reply@localhost>"),
+			MailFileDir:            getEnv("MAIL_FILE_DIR", "mail_outbox"),
+			SMTPHost:               getEnv("SMTP_HOST", ""),
+			SMTPPort:               getEnv("SMTP_PORT", "587"),
+			SMTPUsername:           getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:           getEnv("SMTP_PASSWORD", ""),
+			DigestEnabled:          getEnvAsBool("DIGEST_ENABLED", true),
+			DigestInterval:         time.Duration(getEnvAsInt("DIGEST_INTERVAL_MINUTES", 60)) * time.Minute,
+			DigestDefaultFrequency: getEnv("DIGEST_DEFAULT_FREQUENCY", "daily"),
+			DigestMaxItems:         getEnvAsInt("DIGEST_MAX_ITEMS", 20),
+
+			// Event reminders
+			EventRemindersEnabled:    getEnvAsBool("EVENT_REMINDERS_ENABLED", true),
+			EventReminderLeadTimes:   reminderLeadTimes,
+			EventReminderInterval:    time.Duration(getEnvAsInt("EVENT_REMINDER_INTERVAL_MINUTES", 1)) * time.Minute,
+			EventRescheduleThreshold: time.Duration(getEnvAsInt("EVENT_RESCHEDULE_THRESHOLD_MINUTES", 60)) * time.Minute,
+
+			// Scheduled posts
+			ScheduledPostsEnabled:  getEnvAsBool("SCHEDULED_POSTS_ENABLED", true),
+			ScheduledPostsInterval: time.Duration(getEnvAsInt("SCHEDULED_POSTS_INTERVAL_MINUTES", 1)) * time.Minute,
+
+			// Web Push
+			WebPushEnabled: getEnvAsBool("WEB_PUSH_ENABLED", true),
+			VAPIDKeys:      vapidKeys,
+			VAPIDSubject:   getEnv("VAPID_SUBJECT", "mailto:admin@localhost"),
+			WebPushWorkers: getEnvAsInt("WEB_PUSH_WORKERS", 4),
 
 			// Features
 			EnableRegistration: getEnvAsBool("ENABLE_REGISTRATION", true),
@@ -156,6 +271,40 @@ func Load() error {
 	return err
 }
 
+// loadVAPIDKeys reads VAPID_PRIVATE_KEY, or outside production keeps a generated key pair in VAPID_KEY_FILE
+func loadVAPIDKeys(environment string) (*utils.VAPIDKeys, error) {
+	if encoded := getEnv("VAPID_PRIVATE_KEY", ""); encoded != "" {
+		keys, err := utils.ParseVAPIDPrivateKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		if public := getEnv("VAPID_PUBLIC_KEY", ""); public != "" && public != keys.PublicKey() {
+			return nil, fmt.Errorf("VAPID_PUBLIC_KEY does not match VAPID_PRIVATE_KEY")
+		}
+		return keys, nil
+	}
+
+	if environment == "production" {
+		log.Println("[WARN] VAPID_PRIVATE_KEY is not set, push subscriptions will break on restart")
+		return utils.GenerateVAPIDKeys()
+	}
+
+	// The private key is a secret, it goes to a file only its owner can read and never to the logs
+	path := getEnv("VAPID_KEY_FILE", "")
+	if path == "" {
+		log.Println("[INFO] Generated VAPID keys for this run, set VAPID_KEY_FILE or VAPID_PRIVATE_KEY to keep them")
+		return utils.GenerateVAPIDKeys()
+	}
+	keys, created, err := utils.LoadOrCreateVAPIDKeys(path)
+	if err != nil {
+		return nil, fmt.Errorf("VAPID key file %s: %w", path, err)
+	}
+	if created {
+		log.Printf("[INFO] Generated VAPID keys, the private key is saved in %s", path)
+	}
+	return keys, nil
+}
+
 // Get returns the globally loaded config instance.
 func GetConfig() *Config {
 	if configInstance == nil {
@@ -201,6 +350,23 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// getEnvAsDurations reads a comma separated list of Go durations like "24h,1h30m"
+func getEnvAsDurations(key string, defaultValue []time.Duration) ([]time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("%s: %q is not a duration of at least a minute", key, part)
+		}
+		durations = append(durations, d)
+	}
+	return durations, nil
+}
+
 func isEnvSet(key string) bool {
 	_, exists := os.LookupEnv(key)
 	return exists
@@ -0,0 +1,276 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+var (
+	// ErrAudienceListNotFound is returned for a list that does not exist or belongs to someone else
+	ErrAudienceListNotFound = errors.New("audience list not found")
+	// ErrAudienceListExists is returned when the user already has a list with that name
+	ErrAudienceListExists = errors.New("audience list already exists")
+)
+
+// CreateAudienceList creates a named list of followers for the user
+func (s *Service) CreateAudienceList(userID int64, name string, members []int64) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	if err = ensureAudienceListNameFree(tx, userID, 0, name); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec(`INSERT INTO audience_lists (user_id, name) VALUES (?, ?)`, userID, name)
+	if err != nil {
+		return 0, err
+	}
+	listID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for _, memberID := range members {
+		if _, err = tx.Exec(`INSERT OR IGNORE INTO audience_list_members (list_id, user_id) VALUES (?, ?)`, listID, memberID); err != nil {
+			return 0, err
+		}
+	}
+	return listID, nil
+}
+
+// GetAudienceList returns a list of the user with its members
+func (s *Service) GetAudienceList(userID, listID int64) (*models.AudienceList, error) {
+	lists, err := s.queryAudienceLists(` AND id = ?`, userID, listID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, ErrAudienceListNotFound
+	}
+	return &lists[0], nil
+}
+
+// GetAudienceLists returns the lists of the user by name
+func (s *Service) GetAudienceLists(userID int64) ([]models.AudienceList, error) {
+	return s.queryAudienceLists(``, userID)
+}
+
+func (s *Service) queryAudienceLists(where string, args ...any) ([]models.AudienceList, error) {
+	rows, err := s.DB.Query(`SELECT id, name, created_at, updated_at FROM audience_lists WHERE user_id = ?`+where+` ORDER BY name`, args...)
+	if err != nil {
+		return nil, err
+	}
+	lists := []models.AudienceList{}
+	for rows.Next() {
+		var list models.AudienceList
+		var createdAt, updatedAt time.Time
+		if err := rows.Scan(&list.ID, &list.Name, &createdAt, &updatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		list.UpdatedAt = updatedAt.UTC().Format(time.RFC3339)
+		lists = append(lists, list)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range lists {
+		if lists[i].Members, err = audienceListMembers(s.DB, lists[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return lists, nil
+}
+
+// queryer is what audienceListMembers needs of a database or a transaction
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func audienceListMembers(q queryer, listID int64) ([]int64, error) {
+	rows, err := q.Query(`SELECT user_id FROM audience_list_members WHERE list_id = ? ORDER BY added_at, user_id`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []int64{}
+	for rows.Next() {
+		var memberID int64
+		if err := rows.Scan(&memberID); err != nil {
+			return nil, err
+		}
+		members = append(members, memberID)
+	}
+	return members, rows.Err()
+}
+
+// UpdateAudienceList renames a list of the user or replaces its members.
+// With ApplyToPosts, the posts shared with the list gain the added members and lose the removed ones,
+// anyone else in the audience of these posts is left as is.
+func (s *Service) UpdateAudienceList(userID int64, req models.UpdateAudienceListRequest) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	var owned bool
+	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM audience_lists WHERE id = ? AND user_id = ?)`, req.ID, userID).Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		err = ErrAudienceListNotFound
+		return err
+	}
+
+	if req.Name != nil {
+		if err = ensureAudienceListNameFree(tx, userID, req.ID, *req.Name); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(`UPDATE audience_lists SET name = ? WHERE id = ?`, *req.Name, req.ID); err != nil {
+			return err
+		}
+	}
+
+	if req.Members != nil {
+		var current []int64
+		if current, err = audienceListMembers(tx, req.ID); err != nil {
+			return err
+		}
+		wanted := make(map[int64]bool, len(*req.Members))
+		for _, memberID := range *req.Members {
+			wanted[memberID] = true
+		}
+		kept := make(map[int64]bool, len(current))
+		var removed []int64
+		for _, memberID := range current {
+			if wanted[memberID] {
+				kept[memberID] = true
+			} else {
+				removed = append(removed, memberID)
+			}
+		}
+
+		if err = updateAudienceMembers(tx, userID, req, kept, removed); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE audience_lists SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, req.ID)
+	return err
+}
+
+// updateAudienceMembers removes and adds the members of a list, and in the posts shared with it when asked
+func updateAudienceMembers(tx *sql.Tx, userID int64, req models.UpdateAudienceListRequest, kept map[int64]bool, removed []int64) error {
+	for _, memberID := range removed {
+		if _, err := tx.Exec(`DELETE FROM audience_list_members WHERE list_id = ? AND user_id = ?`, req.ID, memberID); err != nil {
+			return err
+		}
+		if !req.ApplyToPosts {
+			continue
+		}
+		_, err := tx.Exec(`
+			DELETE FROM post_visibility
+			WHERE user_id = ? AND post_id IN (SELECT id FROM posts WHERE user_id = ? AND audience_list_id = ?)`,
+			memberID, userID, req.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, memberID := range *req.Members {
+		if kept[memberID] {
+			continue
+		}
+		kept[memberID] = true
+		if _, err := tx.Exec(`INSERT INTO audience_list_members (list_id, user_id) VALUES (?, ?)`, req.ID, memberID); err != nil {
+			return err
+		}
+		if !req.ApplyToPosts {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO post_visibility (post_id, user_id)
+			SELECT p.id, ? FROM posts p
+			WHERE p.user_id = ? AND p.audience_list_id = ?
+			AND NOT EXISTS (SELECT 1 FROM post_visibility pv WHERE pv.post_id = p.id AND pv.user_id = ?)`,
+			memberID, userID, req.ID, memberID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAudienceList deletes a list of the user, the posts shared with it keep their audience
+func (s *Service) DeleteAudienceList(userID, listID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	result, err := tx.Exec(`DELETE FROM audience_lists WHERE id = ? AND user_id = ?`, listID, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		err = ErrAudienceListNotFound
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM audience_list_members WHERE list_id = ?`, listID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE posts SET audience_list_id = NULL WHERE audience_list_id = ?`, listID)
+	return err
+}
+
+// SetPostAudienceList records the list the audience of a post was taken from, nil when it was picked by hand
+func (s *Service) SetPostAudienceList(postID int64, listID *int64) error {
+	_, err := s.DB.Exec(`UPDATE posts SET audience_list_id = ? WHERE id = ?`, listID, postID)
+	return err
+}
+
+func ensureAudienceListNameFree(tx *sql.Tx, userID, listID int64, name string) error {
+	var taken bool
+	err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM audience_lists WHERE user_id = ? AND name = ? AND id != ?)`,
+		userID, name, listID).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrAudienceListExists
+	}
+	return nil
+}
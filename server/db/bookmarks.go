@@ -0,0 +1,343 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+var (
+	// ErrBookmarkExists is returned when saving a post the user already saved
+	ErrBookmarkExists = errors.New("already bookmarked")
+	// ErrBookmarkNotFound is returned for a post the user did not save
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+	// ErrCollectionNotFound is returned for a collection that does not exist or belongs to someone else
+	ErrCollectionNotFound = errors.New("collection not found")
+	// ErrCollectionExists is returned when the user already has a collection with that name
+	ErrCollectionExists = errors.New("collection already exists")
+)
+
+func validBookmarkTarget(target string) bool {
+	return target == models.BookmarkTargetPost || target == models.BookmarkTargetGroupPost
+}
+
+// ensureOwnCollection checks a collection belongs to the user, a nil collection is always fine
+func (s *Service) ensureOwnCollection(userID int64, collectionID *int64) error {
+	if collectionID == nil {
+		return nil
+	}
+	var owned bool
+	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM bookmark_collections WHERE id = ? AND user_id = ?)`,
+		*collectionID, userID).Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// CreateBookmark saves a post or a group post for the user, the caller checks the user can see it
+func (s *Service) CreateBookmark(userID int64, target string, contentID int64, collectionID *int64) (int64, error) {
+	if !validBookmarkTarget(target) {
+		return 0, errors.New("invalid bookmark target")
+	}
+	if err := s.ensureOwnCollection(userID, collectionID); err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM bookmarks WHERE user_id = ? AND `+target+` = ?)`,
+		userID, contentID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrBookmarkExists
+	}
+
+	result, err := s.DB.Exec(`INSERT INTO bookmarks (user_id, `+target+`, collection_id) VALUES (?, ?, ?)`,
+		userID, contentID, collectionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// MoveBookmark puts a bookmark in another collection, or in none when collectionID is nil
+func (s *Service) MoveBookmark(userID int64, target string, contentID int64, collectionID *int64) error {
+	if !validBookmarkTarget(target) {
+		return errors.New("invalid bookmark target")
+	}
+	if err := s.ensureOwnCollection(userID, collectionID); err != nil {
+		return err
+	}
+	result, err := s.DB.Exec(`UPDATE bookmarks SET collection_id = ? WHERE user_id = ? AND `+target+` = ?`,
+		collectionID, userID, contentID)
+	if err != nil {
+		return err
+	}
+	return bookmarkAffected(result)
+}
+
+// DeleteBookmark removes a post or a group post from the bookmarks of the user
+func (s *Service) DeleteBookmark(userID int64, target string, contentID int64) error {
+	if !validBookmarkTarget(target) {
+		return errors.New("invalid bookmark target")
+	}
+	result, err := s.DB.Exec(`DELETE FROM bookmarks WHERE user_id = ? AND `+target+` = ?`, userID, contentID)
+	if err != nil {
+		return err
+	}
+	return bookmarkAffected(result)
+}
+
+func bookmarkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+// GetBookmarks returns the bookmarks of the user, newest first, in one collection when collectionID is set.
+// Bookmarks of content the user can no longer see are left out, they come back if access does.
+func (s *Service) GetBookmarks(userID int64, collectionID *int64, limit, offset int) ([]models.Bookmark, error) {
+	if err := s.ensureOwnCollection(userID, collectionID); err != nil {
+		return nil, err
+	}
+	reviewable, err := s.groupsReviewableBy(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same rules as the feed and the post pages, so a page is only cut from content the user can see
+	query := `
+		SELECT b.id, b.post_id, b.group_post_id, b.collection_id, b.created_at
+		FROM bookmarks b
+		LEFT JOIN posts p ON p.id = b.post_id
+		LEFT JOIN posts o ON o.id = p.original_post_id
+		LEFT JOIN group_posts gp ON gp.id = b.group_post_id
+		WHERE b.user_id = ?`
+	args := []any{userID}
+	if collectionID != nil {
+		query += ` AND b.collection_id = ?`
+		args = append(args, *collectionID)
+	}
+	groupPostVisible := `gp.status = 'published' OR gp.user_id = ?`
+	groupArgs := []any{userID}
+	if len(reviewable) > 0 {
+		groupPostVisible += ` OR gp.group_id IN (?` + strings.Repeat(`, ?`, len(reviewable)-1) + `)`
+		for _, groupID := range reviewable {
+			groupArgs = append(groupArgs, groupID)
+		}
+	}
+	query += ` AND (
+			(p.id IS NOT NULL AND ` + visiblePostSQL("p") + ` AND (p.kind != 'repost' OR (o.id IS NOT NULL AND ` + visiblePostSQL("o") + `)))
+			OR (gp.id IS NOT NULL
+				AND EXISTS (SELECT 1 FROM group_members WHERE group_id = gp.group_id AND user_id = ?)
+				AND (` + groupPostVisible + `))
+		)
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT ? OFFSET ?`
+	args = append(args, userID, userID, userID, userID, userID, userID, userID)
+	args = append(args, groupArgs...)
+	args = append(args, limit, offset)
+
+	type savedContent struct {
+		bookmark    models.Bookmark
+		postID      sql.NullInt64
+		groupPostID sql.NullInt64
+	}
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var saved []savedContent
+	for rows.Next() {
+		var item savedContent
+		var collection sql.NullInt64
+		var createdAt time.Time
+		if err := rows.Scan(&item.bookmark.ID, &item.postID, &item.groupPostID, &collection, &createdAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if collection.Valid {
+			item.bookmark.CollectionID = &collection.Int64
+		}
+		item.bookmark.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		saved = append(saved, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Only the page is read, once the rows are closed. A bookmark that cannot be read is left out
+	// rather than failing the whole list.
+	bookmarks := []models.Bookmark{}
+	for _, item := range saved {
+		bookmark := item.bookmark
+		if item.postID.Valid {
+			post, err := s.GetPostByID(item.postID.Int64, userID)
+			if err != nil {
+				if !errors.Is(err, sql.ErrNoRows) && err.Error() != "unauthorized" {
+					log.Printf("Error reading bookmarked post %d: %v", item.postID.Int64, err)
+				}
+				continue
+			}
+			bookmark.Post = post
+		} else {
+			post, err := s.GetGroupPostWithImagesByID(item.groupPostID.Int64, userID)
+			if err != nil {
+				if err.Error() != "post not found" && err.Error() != "user is not a member of the group" {
+					log.Printf("Error reading bookmarked group post %d: %v", item.groupPostID.Int64, err)
+				}
+				continue
+			}
+			bookmark.GroupPost = post
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	return bookmarks, nil
+}
+
+// groupsReviewableBy returns the groups where the user sees the posts waiting for review
+func (s *Service) groupsReviewableBy(userID int64) ([]int64, error) {
+	rows, err := s.DB.Query(`SELECT group_id, role FROM group_members WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	type membership struct {
+		groupID int64
+		role    string
+	}
+	var memberships []membership
+	for rows.Next() {
+		var m membership
+		if err := rows.Scan(&m.groupID, &m.role); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var groupIDs []int64
+	for _, m := range memberships {
+		allowed, err := s.RoleHasGroupPermission(m.groupID, m.role, models.GroupPermApprovePosts)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			groupIDs = append(groupIDs, m.groupID)
+		}
+	}
+	return groupIDs, nil
+}
+
+// CreateBookmarkCollection creates a named collection for the user
+func (s *Service) CreateBookmarkCollection(userID int64, name string) (int64, error) {
+	if err := s.ensureCollectionNameFree(userID, 0, name); err != nil {
+		return 0, err
+	}
+	result, err := s.DB.Exec(`INSERT INTO bookmark_collections (user_id, name) VALUES (?, ?)`, userID, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// RenameBookmarkCollection renames a collection of the user
+func (s *Service) RenameBookmarkCollection(userID, collectionID int64, name string) error {
+	if err := s.ensureCollectionNameFree(userID, collectionID, name); err != nil {
+		return err
+	}
+	result, err := s.DB.Exec(`UPDATE bookmark_collections SET name = ? WHERE id = ? AND user_id = ?`,
+		name, collectionID, userID)
+	if err != nil {
+		return err
+	}
+	return collectionAffected(result)
+}
+
+// DeleteBookmarkCollection deletes a collection of the user, its bookmarks are kept outside of any collection
+func (s *Service) DeleteBookmarkCollection(userID, collectionID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	result, err := tx.Exec(`DELETE FROM bookmark_collections WHERE id = ? AND user_id = ?`, collectionID, userID)
+	if err != nil {
+		return err
+	}
+	if err = collectionAffected(result); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE bookmarks SET collection_id = NULL WHERE collection_id = ?`, collectionID)
+	return err
+}
+
+// GetBookmarkCollections returns the collections of the user by name
+func (s *Service) GetBookmarkCollections(userID int64) ([]models.BookmarkCollection, error) {
+	rows, err := s.DB.Query(`SELECT id, name, created_at FROM bookmark_collections WHERE user_id = ? ORDER BY name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.BookmarkCollection{}
+	for rows.Next() {
+		var collection models.BookmarkCollection
+		var createdAt time.Time
+		if err := rows.Scan(&collection.ID, &collection.Name, &createdAt); err != nil {
+			return nil, err
+		}
+		collection.CreatedAt = createdAt.UTC().Format(time.RFC3339)
+		collections = append(collections, collection)
+	}
+	return collections, rows.Err()
+}
+
+func (s *Service) ensureCollectionNameFree(userID, collectionID int64, name string) error {
+	var taken bool
+	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM bookmark_collections WHERE user_id = ? AND name = ? AND id != ?)`,
+		userID, name, collectionID).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrCollectionExists
+	}
+	return nil
+}
+
+func collectionAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
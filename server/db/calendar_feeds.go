@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/Golden76z/social-network/calendar"
+)
+
+// calendarEvents turns a series into its VEVENT and one override per moved occurrence
+func (es *eventSeries) calendarEvents() []calendar.Event {
+	uid := fmt.Sprintf("group-event-%d@social-network", es.event.ID)
+	main := calendar.Event{
+		UID:         uid,
+		Start:       es.start,
+		End:         es.end,
+		Summary:     es.event.Title,
+		Description: es.event.Description,
+		Location:    es.event.Location,
+		Stamp:       es.created,
+	}
+	// Calendar apps drop cancelled events once they see the status
+	if es.event.Status == "cancelled" {
+		main.Status = "CANCELLED"
+	}
+	if es.rule == nil {
+		return []calendar.Event{main}
+	}
+	main.RRule = es.rule.String()
+
+	keys := make([]string, 0, len(es.exceptions))
+	for key := range es.exceptions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	events := []calendar.Event{main}
+	for _, key := range keys {
+		ex := es.exceptions[key]
+		ge, ok := es.occurrence(ex.occurrence)
+		if !ok {
+			events[0].ExDates = append(events[0].ExDates, ex.occurrence)
+			continue
+		}
+		events = append(events, calendar.Event{
+			UID:          uid,
+			RecurrenceID: ex.occurrence,
+			Start:        ge.startsAt,
+			End:          ge.endsAt,
+			Summary:      ge.Title,
+			Description:  ge.Description,
+			Location:     ge.Location,
+			Stamp:        es.created,
+			Status:       main.Status,
+		})
+	}
+	return events
+}
+
+// GetGroupEventCalendar returns the event with its exceptions ready to be exported
+func (s *Service) GetGroupEventCalendar(eventID int64) ([]calendar.Event, error) {
+	series, err := s.queryEventSeries(` WHERE ge.id = ?`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return series[0].calendarEvents(), nil
+}
+
+// GetUserCalendarEvents returns the events of every group the user belongs to, deleted groups aside
+func (s *Service) GetUserCalendarEvents(userID int64) ([]calendar.Event, error) {
+	series, err := s.queryEventSeries(` WHERE ge.group_id IN (
+			SELECT gm.group_id FROM group_members gm
+			JOIN groups g ON g.id = gm.group_id
+			WHERE gm.user_id = ? AND g.status != 'deleted'
+		)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	events := []calendar.Event{}
+	for _, es := range series {
+		events = append(events, es.calendarEvents()...)
+	}
+	return events, nil
+}
+
+// EnsureCalendarFeedToken creates the feed token of a user if missing and returns the stored one
+func (s *Service) EnsureCalendarFeedToken(userID int64, token string) (string, error) {
+	_, err := s.DB.Exec(`
+		INSERT INTO calendar_feed_tokens (user_id, token) VALUES (?, ?)
+		ON CONFLICT(user_id) DO NOTHING`, userID, token)
+	if err != nil {
+		return "", err
+	}
+	var stored string
+	err = s.DB.QueryRow(`SELECT token FROM calendar_feed_tokens WHERE user_id = ?`, userID).Scan(&stored)
+	return stored, err
+}
+
+// DeleteCalendarFeedToken revokes the feed URL of a user, the next one gets a new token
+func (s *Service) DeleteCalendarFeedToken(userID int64) error {
+	_, err := s.DB.Exec(`DELETE FROM calendar_feed_tokens WHERE user_id = ?`, userID)
+	return err
+}
+
+// GetUserIDByCalendarFeedToken returns the owner of a feed token, 0 for an unknown token
+func (s *Service) GetUserIDByCalendarFeedToken(token string) (int64, error) {
+	var userID int64
+	err := s.DB.QueryRow(`SELECT user_id FROM calendar_feed_tokens WHERE token = ?`, token).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return userID, err
+}
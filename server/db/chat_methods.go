@@ -119,7 +119,9 @@ func (s *Service) CheckCanSendMessage(senderID, receiverID int) (bool, error) {
 // CheckGroupMembership checks if a user is a member of a group
 func (s *Service) CheckGroupMembership(userID, groupID int) (bool, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM group_members WHERE user_id = ? AND group_id = ?`
+	query := `
+		SELECT COUNT(*) FROM group_members gm JOIN groups g ON g.id = gm.group_id
+		WHERE gm.user_id = ? AND gm.group_id = ? AND g.status != 'deleted'`
 	err := s.DB.QueryRow(query, userID, groupID).Scan(&count)
 	if err != nil {
 		return false, err
@@ -189,7 +191,7 @@ func (s *Service) GetGroupConversations(userID int) ([]GroupConversation, error)
 		FROM groups g
 		JOIN group_members gm ON g.id = gm.group_id
 		LEFT JOIN latest_group_messages lgm ON g.id = lgm.group_id AND lgm.rn = 1
-		WHERE gm.user_id = ?
+		WHERE gm.user_id = ? AND g.status != 'deleted'
 		ORDER BY COALESCE(lgm.last_message_time, g.created_at) DESC
 	`
 
@@ -51,13 +51,16 @@ func (s *Service) CreateComment(req models.CreateCommentRequest) error {
 
 func (s *Service) GetCommentByID(commentID int64) (*models.Comment, error) {
 	row := s.DB.QueryRow(`
-        SELECT id, post_id, user_id, body, created_at, updated_at
+        SELECT id, post_id, user_id, body, created_at, updated_at,
+               (SELECT COUNT(*) FROM content_revisions WHERE comment_id = comments.id) AS revision_count
         FROM comments WHERE id = ?`, commentID)
 	var comment models.Comment
-	err := row.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt)
+	err := row.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Body, &comment.CreatedAt, &comment.UpdatedAt,
+		&comment.RevisionCount)
 	if err != nil {
 		return nil, err
 	}
+	comment.Edited = comment.RevisionCount > 0
 
 	// Get comment images
 	imageRows, err := s.DB.Query(`
@@ -99,6 +102,10 @@ func (s *Service) UpdateComment(commentID int64, req models.UpdateCommentRequest
 		return fmt.Errorf("no fields to update")
 	}
 
+	if err = recordRevision(tx, models.RevisionTargetComment, commentID, 0, nil, req.Body, nil); err != nil {
+		return err
+	}
+
 	_, err = tx.Exec(`
         UPDATE comments SET body = ?, updated_at = CURRENT_TIMESTAMP
         WHERE id = ?`,
@@ -126,7 +133,8 @@ func (s *Service) DeleteComment(commentID int64) error {
 func (s *Service) GetCommentsByPostID(postID int64, limit, offset int) ([]models.Comment, error) {
 	rows, err := s.DB.Query(`
         SELECT c.id, c.post_id, c.user_id, c.body, c.created_at, c.updated_at,
-               u.nickname, u.first_name, u.last_name, u.avatar
+               u.nickname, u.first_name, u.last_name, u.avatar,
+               (SELECT COUNT(*) FROM content_revisions WHERE comment_id = c.id) AS revision_count
         FROM comments c
         JOIN users u ON c.user_id = u.id
         WHERE c.post_id = ?
@@ -144,10 +152,11 @@ func (s *Service) GetCommentsByPostID(postID int64, limit, offset int) ([]models
 		var avatar sql.NullString
 
 		err := rows.Scan(&c.ID, &c.PostID, &c.UserID, &c.Body, &c.CreatedAt, &c.UpdatedAt,
-			&username, &firstName, &lastName, &avatar)
+			&username, &firstName, &lastName, &avatar, &c.RevisionCount)
 		if err != nil {
 			return nil, err
 		}
+		c.Edited = c.RevisionCount > 0
 
 		// Populate user details
 		if username.Valid {
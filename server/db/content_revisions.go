@@ -0,0 +1,191 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// revisionSource is the table a revision target points to and what of its content an edit can change
+type revisionSource struct {
+	table string
+	title bool
+	// images selects the images of the content when they are part of it
+	images string
+}
+
+// revisionSources are the contents whose edits are kept, by the column a revision is attached through
+var revisionSources = map[string]revisionSource{
+	models.RevisionTargetPost: {
+		table:  "posts",
+		title:  true,
+		images: `SELECT image_url FROM post_images WHERE post_id = ? AND is_group_post = 0 ORDER BY id`,
+	},
+	models.RevisionTargetComment: {table: "comments"},
+	models.RevisionTargetGroupPost: {
+		table:  "group_posts",
+		title:  true,
+		images: `SELECT image_url FROM post_images WHERE post_id = ? AND is_group_post = 1 ORDER BY id`,
+	},
+	models.RevisionTargetGroupComment: {table: "group_comments"},
+}
+
+// recordRevision keeps the current version of a content before an edit replaces it, in the transaction of the edit.
+// Nil fields are left as they are by the edit, nothing is kept when it changes nothing.
+// A zero editorID stands for the author of the content.
+func recordRevision(tx *sql.Tx, target string, contentID, editorID int64, title, body *string, images *[]string) error {
+	source, ok := revisionSources[target]
+	if !ok {
+		return errors.New("invalid revision target")
+	}
+
+	titleColumn := "NULL"
+	if source.title {
+		titleColumn = "title"
+	}
+	var authorID int64
+	var currentTitle, currentBody sql.NullString
+	var createdAt, updatedAt sql.NullTime
+	err := tx.QueryRow(`SELECT user_id, `+titleColumn+`, body, created_at, updated_at FROM `+source.table+` WHERE id = ?`,
+		contentID).Scan(&authorID, &currentTitle, &currentBody, &createdAt, &updatedAt)
+	if err != nil {
+		return err
+	}
+
+	var currentImages []string
+	if source.images != "" {
+		if currentImages, err = queryStrings(tx, source.images, contentID); err != nil {
+			return err
+		}
+	}
+
+	changed := (source.title && title != nil && *title != currentTitle.String) ||
+		(body != nil && *body != currentBody.String) ||
+		(source.images != "" && images != nil && !sameStrings(*images, currentImages))
+	if !changed {
+		return nil
+	}
+
+	var titleValue, imagesValue any
+	if source.title {
+		titleValue = currentTitle.String
+	}
+	if source.images != "" {
+		encoded, err := json.Marshal(append([]string{}, currentImages...))
+		if err != nil {
+			return err
+		}
+		imagesValue = string(encoded)
+	}
+	writtenAt := createdAt
+	if updatedAt.Valid {
+		writtenAt = updatedAt
+	}
+	if editorID == 0 {
+		editorID = authorID
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO content_revisions (`+target+`, revision, title, body, images, written_at, edited_by)
+		VALUES (?, (SELECT COALESCE(MAX(revision), 0) + 1 FROM content_revisions WHERE `+target+` = ?), ?, ?, ?, ?, ?)`,
+		contentID, contentID, titleValue, currentBody.String, imagesValue, writtenAt, editorID)
+	return err
+}
+
+func queryStrings(tx *sql.Tx, query string, args ...any) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetContentHistory returns the previous versions of a content, oldest first
+func (s *Service) GetContentHistory(target string, contentID int64) (*models.ContentHistory, error) {
+	revisions, err := s.queryContentRevisions(target, ``, contentID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ContentHistory{CurrentRevision: len(revisions) + 1, Revisions: revisions}, nil
+}
+
+// GetContentRevision returns one previous version of a content, sql.ErrNoRows when there is no such revision
+func (s *Service) GetContentRevision(target string, contentID int64, revision int) (*models.ContentRevision, error) {
+	revisions, err := s.queryContentRevisions(target, ` AND r.revision = ?`, contentID, revision)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &revisions[0], nil
+}
+
+func (s *Service) queryContentRevisions(target, where string, args ...any) ([]models.ContentRevision, error) {
+	if _, ok := revisionSources[target]; !ok {
+		return nil, errors.New("invalid revision target")
+	}
+	rows, err := s.DB.Query(`
+		SELECT r.revision, r.title, r.body, r.images, r.written_at, r.edited_at, COALESCE(r.edited_by, 0), COALESCE(u.nickname, '')
+		FROM content_revisions r
+		LEFT JOIN users u ON u.id = r.edited_by
+		WHERE r.`+target+` = ?`+where+`
+		ORDER BY r.revision`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.ContentRevision{}
+	for rows.Next() {
+		var revision models.ContentRevision
+		var title, images sql.NullString
+		var writtenAt, editedAt sql.NullTime
+		err := rows.Scan(&revision.Revision, &title, &revision.Body, &images, &writtenAt, &editedAt,
+			&revision.EditedBy, &revision.EditorNickname)
+		if err != nil {
+			return nil, err
+		}
+		if title.Valid {
+			revision.Title = &title.String
+		}
+		if images.Valid {
+			if err := json.Unmarshal([]byte(images.String), &revision.Images); err != nil {
+				return nil, err
+			}
+		}
+		if writtenAt.Valid {
+			revision.WrittenAt = writtenAt.Time.UTC().Format(time.RFC3339)
+		}
+		if editedAt.Valid {
+			revision.EditedAt = editedAt.Time.UTC().Format(time.RFC3339)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
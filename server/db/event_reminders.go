@@ -0,0 +1,116 @@
+package db
+
+import (
+	"sort"
+	"time"
+)
+
+// EventReminder is a reminder due to a user for one occurrence of an event
+type EventReminder struct {
+	Event    GroupEvent
+	UserID   int64
+	LeadTime time.Duration
+}
+
+// StartsAt returns the start of the event or occurrence in the timezone of the event
+func (ge *GroupEvent) StartsAt() time.Time {
+	return ge.startsAt
+}
+
+// dueLeadTime picks the shortest lead time already reached by an occurrence starting in remaining
+func dueLeadTime(remaining time.Duration, leadTimes []time.Duration) (time.Duration, bool) {
+	for _, lead := range leadTimes {
+		if remaining <= lead {
+			return lead, true
+		}
+	}
+	return 0, false
+}
+
+// GetDueEventReminders returns the reminders due at now to the members who answered "come" or "interested",
+// waitlisted ones and cancelled events aside. Only the shortest lead time reached is due for an occurrence,
+// so a server that was down sends one late reminder instead of every missed one, and none once a shorter
+// one went out.
+func (s *Service) GetDueEventReminders(now time.Time, leadTimes []time.Duration) ([]EventReminder, error) {
+	if len(leadTimes) == 0 {
+		return nil, nil
+	}
+	leads := append([]time.Duration(nil), leadTimes...)
+	sort.Slice(leads, func(i, j int) bool { return leads[i] < leads[j] })
+	horizon := now.Add(leads[len(leads)-1])
+
+	// Single events are filtered in SQL with a day of margin for the timezone offsets
+	series, err := s.queryEventSeries(` WHERE ge.status != 'cancelled' AND ge.group_id IN (SELECT id FROM groups WHERE status != 'deleted')
+		AND (ge.rrule IS NOT NULL OR ge.event_datetime >= ?) AND ge.event_datetime <= ?`,
+		now.UTC().AddDate(0, 0, -1).Format(EventTimeLayout), horizon.UTC().AddDate(0, 0, 1).Format(EventTimeLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	var reminders []EventReminder
+	for _, es := range series {
+		for _, ge := range es.between(now, horizon, 0) {
+			lead, ok := dueLeadTime(ge.startsAt.Sub(now), leads)
+			if !ok {
+				continue
+			}
+			userIDs, err := s.eventReminderRecipients(ge, lead)
+			if err != nil {
+				return nil, err
+			}
+			for _, userID := range userIDs {
+				reminders = append(reminders, EventReminder{Event: ge, UserID: userID, LeadTime: lead})
+			}
+		}
+	}
+	return reminders, nil
+}
+
+// eventReminderRecipients lists the members to remind of an occurrence who did not get this reminder or a later one
+func (s *Service) eventReminderRecipients(ge GroupEvent, lead time.Duration) ([]int64, error) {
+	rows, err := s.DB.Query(`
+		SELECT er.user_id FROM event_rsvps er
+		JOIN group_members gm ON gm.group_id = ? AND gm.user_id = er.user_id
+		WHERE er.event_id = ? AND er.status IN ('come', 'interested') AND er.waitlisted_at IS NULL
+		AND NOT EXISTS (
+			SELECT 1 FROM event_reminders r
+			WHERE r.event_id = er.event_id AND r.user_id = er.user_id AND r.starts_at = ? AND r.lead_seconds <= ?
+		)
+		ORDER BY er.user_id`,
+		ge.GroupID, ge.ID, ge.startsAt.UTC().Format(EventTimeLayout), int64(lead/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// ClaimEventReminder records a reminder before it is sent, false when it was already claimed
+func (s *Service) ClaimEventReminder(reminder EventReminder) (bool, error) {
+	res, err := s.DB.Exec(`
+		INSERT INTO event_reminders (event_id, user_id, starts_at, lead_seconds)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`,
+		reminder.Event.ID, reminder.UserID, reminder.Event.startsAt.UTC().Format(EventTimeLayout),
+		int64(reminder.LeadTime/time.Second))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// PruneEventReminders forgets the reminders of occurrences started before now, none is due for them anymore
+func (s *Service) PruneEventReminders(now time.Time) error {
+	_, err := s.DB.Exec(`DELETE FROM event_reminders WHERE starts_at < ?`, now.UTC().Format(EventTimeLayout))
+	return err
+}
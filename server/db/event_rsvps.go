@@ -2,17 +2,25 @@ package db
 
 import (
 	"database/sql"
+	"errors"
+	"time"
 
+	"github.com/Golden76z/social-network/calendar"
 	"github.com/Golden76z/social-network/models"
 )
 
 // EventRSVP represents an event RSVP in the database
 type EventRSVP struct {
-	ID        int64  `json:"id"`
-	EventID   int64  `json:"event_id"`
-	UserID    int64  `json:"user_id"`
-	Status    string `json:"status"`
-	CreatedAt string `json:"created_at"`
+	ID      int64  `json:"id"`
+	EventID int64  `json:"event_id"`
+	UserID  int64  `json:"user_id"`
+	Status  string `json:"status"`
+	Guests  int    `json:"guests"`
+	// Waitlisted is set on a "come" RSVP still waiting for a seat
+	Waitlisted bool `json:"waitlisted"`
+	// NeedsConfirmation is set when the event moved since the answer was given
+	NeedsConfirmation bool   `json:"needs_confirmation"`
+	CreatedAt         string `json:"created_at"`
 	// User information
 	Nickname  string `json:"nickname,omitempty"`
 	FirstName string `json:"first_name,omitempty"`
@@ -20,10 +28,147 @@ type EventRSVP struct {
 	Avatar    string `json:"avatar,omitempty"`
 }
 
-func (s *Service) CreateEventRSVP(request models.RSVPToEventRequest) error {
+// Errors returned when an RSVP does not fit the limits of its event
+var (
+	ErrRSVPClosed    = errors.New("the RSVP deadline has passed")
+	ErrEventFull     = errors.New("not enough seats left")
+	ErrTooManyGuests = errors.New("too many guests")
+)
+
+// rsvpLimits are the settings of an event an RSVP is checked against
+type rsvpLimits struct {
+	cancelled bool
+	capacity  sql.NullInt64
+	maxGuests int
+	deadline  time.Time
+}
+
+func eventRSVPLimits(tx *sql.Tx, eventID int64) (*rsvpLimits, error) {
+	var limits rsvpLimits
+	var deadline sql.NullTime
+	var timezone, status string
+	err := tx.QueryRow(`SELECT capacity, max_guests, rsvp_deadline, timezone, status FROM group_events WHERE id = ?`, eventID).
+		Scan(&limits.capacity, &limits.maxGuests, &deadline, &timezone, &status)
+	if err != nil {
+		return nil, err
+	}
+	limits.cancelled = status == "cancelled"
+	if deadline.Valid {
+		loc, err := calendar.LoadLocation(timezone)
+		if err != nil {
+			return nil, err
+		}
+		limits.deadline = wallClock(deadline.Time, loc)
+	}
+	return &limits, nil
+}
+
+// check refuses any change once the event is cancelled or the deadline passed, and more guests than the event allows
+func (l *rsvpLimits) check(guests int) error {
+	if l.cancelled {
+		return ErrEventCancelled
+	}
+	if !l.deadline.IsZero() && time.Now().After(l.deadline) {
+		return ErrRSVPClosed
+	}
+	if guests > l.maxGuests {
+		return ErrTooManyGuests
+	}
+	return nil
+}
+
+// seatsTaken counts the confirmed attendees of an event with their guests, leaving out the RSVP exceptID
+func seatsTaken(tx *sql.Tx, eventID, exceptID int64) (int64, error) {
+	var taken int64
+	err := tx.QueryRow(`
+		SELECT COALESCE(SUM(1 + guests), 0) FROM event_rsvps
+		WHERE event_id = ? AND id != ? AND status = 'come' AND waitlisted_at IS NULL`, eventID, exceptID).Scan(&taken)
+	return taken, err
+}
+
+// mustWait tells whether a new attendee taking seats goes to the waitlist, which happens when they do
+// not fit or when others are already waiting
+func (l *rsvpLimits) mustWait(tx *sql.Tx, eventID, rsvpID int64, seats int) (bool, error) {
+	if !l.capacity.Valid {
+		return false, nil
+	}
+	var waiting bool
+	err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM event_rsvps WHERE event_id = ? AND id != ? AND waitlisted_at IS NOT NULL)`,
+		eventID, rsvpID).Scan(&waiting)
+	if err != nil || waiting {
+		return waiting, err
+	}
+	taken, err := seatsTaken(tx, eventID, rsvpID)
+	if err != nil {
+		return false, err
+	}
+	return taken+int64(seats) > l.capacity.Int64, nil
+}
+
+// promoteWaitlist confirms waitlisted RSVPs oldest first while seats are left and returns them. The
+// waitlist is served in order, an RSVP too large for the seats left holds back the ones behind it.
+func promoteWaitlist(tx *sql.Tx, eventID int64) ([]EventRSVP, error) {
+	var capacity sql.NullInt64
+	if err := tx.QueryRow(`SELECT capacity FROM group_events WHERE id = ?`, eventID).Scan(&capacity); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`
+		SELECT id, event_id, user_id, status, guests, created_at FROM event_rsvps
+		WHERE event_id = ? AND waitlisted_at IS NOT NULL
+		ORDER BY waitlisted_at ASC, id ASC`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	var waiting []EventRSVP
+	for rows.Next() {
+		var rsvp EventRSVP
+		if err := rows.Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.Guests, &rsvp.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		waiting = append(waiting, rsvp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(waiting) == 0 {
+		return nil, err
+	}
+
+	free := int64(len(waiting))
+	if capacity.Valid {
+		taken, err := seatsTaken(tx, eventID, 0)
+		if err != nil {
+			return nil, err
+		}
+		free = capacity.Int64 - taken
+	}
+	var promoted []EventRSVP
+	for _, rsvp := range waiting {
+		if capacity.Valid {
+			seats := int64(1 + rsvp.Guests)
+			if seats > free {
+				break
+			}
+			free -= seats
+		}
+		if _, err := tx.Exec(`UPDATE event_rsvps SET waitlisted_at = NULL WHERE id = ?`, rsvp.ID); err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, rsvp)
+	}
+	return promoted, nil
+}
+
+// CreateEventRSVP records the answer of a user. Coming to a full event, or to one with a waitlist,
+// puts the RSVP on the waitlist.
+func (s *Service) CreateEventRSVP(request models.RSVPToEventRequest) (*EventRSVP, error) {
+	rsvp := &EventRSVP{EventID: request.EventID, UserID: request.UserID, Status: request.Status}
+	if request.Guests != nil && request.Status == "come" {
+		rsvp.Guests = *request.Guests
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -32,18 +177,35 @@ func (s *Service) CreateEventRSVP(request models.RSVPToEventRequest) error {
 			_ = tx.Commit()
 		}
 	}()
-	_, err = tx.Exec(`
-        INSERT INTO event_rsvps (event_id, user_id, status)
-        VALUES (?, ?, ?)`, request.EventID, request.UserID, request.Status)
-	return err
+	limits, err := eventRSVPLimits(tx, request.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if err = limits.check(rsvp.Guests); err != nil {
+		return nil, err
+	}
+	if rsvp.Status == "come" {
+		if rsvp.Waitlisted, err = limits.mustWait(tx, request.EventID, 0, 1+rsvp.Guests); err != nil {
+			return nil, err
+		}
+	}
+	res, err := tx.Exec(`
+        INSERT INTO event_rsvps (event_id, user_id, status, guests, waitlisted_at)
+        VALUES (?, ?, ?, ?, CASE WHEN ? THEN CURRENT_TIMESTAMP END)`,
+		request.EventID, request.UserID, rsvp.Status, rsvp.Guests, rsvp.Waitlisted)
+	if err != nil {
+		return nil, err
+	}
+	rsvp.ID, err = res.LastInsertId()
+	return rsvp, err
 }
 
 func (s *Service) GetEventRSVPByID(id int64) (*EventRSVP, error) {
 	row := s.DB.QueryRow(`
-        SELECT id, event_id, user_id, status, created_at
+        SELECT id, event_id, user_id, status, guests, waitlisted_at IS NOT NULL, needs_confirmation, created_at
         FROM event_rsvps WHERE id = ?`, id)
 	var rsvp EventRSVP
-	err := row.Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.CreatedAt)
+	err := row.Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.Guests, &rsvp.Waitlisted, &rsvp.NeedsConfirmation, &rsvp.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -52,30 +214,44 @@ func (s *Service) GetEventRSVPByID(id int64) (*EventRSVP, error) {
 
 // Get RSVP by user and event
 func (s *Service) GetEventRSVPByUserAndEvent(eventID, userID int64) (*EventRSVP, error) {
-	row := s.DB.QueryRow(`SELECT id, event_id, user_id, status, created_at FROM event_rsvps WHERE event_id = ? AND user_id = ?`, eventID, userID)
+	row := s.DB.QueryRow(`SELECT id, event_id, user_id, status, guests, waitlisted_at IS NOT NULL, needs_confirmation, created_at
+		FROM event_rsvps WHERE event_id = ? AND user_id = ?`, eventID, userID)
 	var rsvp EventRSVP
-	err := row.Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.CreatedAt)
+	err := row.Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.Guests, &rsvp.Waitlisted, &rsvp.NeedsConfirmation, &rsvp.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return &rsvp, nil
 }
 
-// Get RSVPs for an event with optional status filter and pagination
+// Get RSVPs for an event with optional status filter and pagination.
+// "come" lists the confirmed attendees, "waitlisted" the waitlist in the order it is served
+// and "pending" the answers to confirm again since the event moved.
 func (s *Service) GetEventRSVPs(eventID int64, status string, limit, offset int) ([]EventRSVP, error) {
 	query := `
 		SELECT 
-			er.id, er.event_id, er.user_id, er.status, er.created_at,
+			er.id, er.event_id, er.user_id, er.status, er.guests, er.waitlisted_at IS NOT NULL, er.needs_confirmation, er.created_at,
 			u.nickname, u.first_name, u.last_name, u.avatar
 		FROM event_rsvps er
 		JOIN users u ON er.user_id = u.id
 		WHERE er.event_id = ?`
 	args := []interface{}{eventID}
-	if status != "" {
+	order := " ORDER BY er.created_at DESC"
+	switch status {
+	case "":
+	case "waitlisted":
+		query += " AND er.waitlisted_at IS NOT NULL"
+		order = " ORDER BY er.waitlisted_at ASC, er.id ASC"
+	case "pending":
+		query += " AND er.needs_confirmation"
+	case "come":
+		query += " AND er.status = ? AND er.waitlisted_at IS NULL"
+		args = append(args, status)
+	default:
 		query += " AND er.status = ?"
 		args = append(args, status)
 	}
-	query += " ORDER BY er.created_at DESC LIMIT ? OFFSET ?"
+	query += order + " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 	rows, err := s.DB.Query(query, args...)
 	if err != nil {
@@ -86,7 +262,7 @@ func (s *Service) GetEventRSVPs(eventID int64, status string, limit, offset int)
 	for rows.Next() {
 		var rsvp EventRSVP
 		var nickname, firstName, lastName, avatar sql.NullString
-		if err := rows.Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.CreatedAt,
+		if err := rows.Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.Guests, &rsvp.Waitlisted, &rsvp.NeedsConfirmation, &rsvp.CreatedAt,
 			&nickname, &firstName, &lastName, &avatar); err != nil {
 			return nil, err
 		}
@@ -108,10 +284,17 @@ func (s *Service) GetEventRSVPs(eventID int64, status string, limit, offset int)
 	return rsvps, nil
 }
 
-func (s *Service) UpdateEventRSVPStatus(id int64, status string) error {
+// UpdateEventRSVPStatus changes or confirms the answer and the guests of an RSVP. A waitlisted RSVP keeps its place,
+// a confirmed one only grows within the seats left and leaving frees its seats. Returns the RSVP and
+// the waitlisted ones promoted by the change.
+func (s *Service) UpdateEventRSVPStatus(id int64, status string, guests int) (*EventRSVP, []EventRSVP, error) {
+	if status != "come" {
+		guests = 0
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -120,14 +303,73 @@ func (s *Service) UpdateEventRSVPStatus(id int64, status string) error {
 			_ = tx.Commit()
 		}
 	}()
-	_, err = tx.Exec(`UPDATE event_rsvps SET status = ? WHERE id = ?`, status, id)
-	return err
+	var rsvp EventRSVP
+	err = tx.QueryRow(`SELECT id, event_id, user_id, status, guests, waitlisted_at IS NOT NULL, needs_confirmation, created_at
+		FROM event_rsvps WHERE id = ?`, id).
+		Scan(&rsvp.ID, &rsvp.EventID, &rsvp.UserID, &rsvp.Status, &rsvp.Guests, &rsvp.Waitlisted, &rsvp.NeedsConfirmation, &rsvp.CreatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	limits, err := eventRSVPLimits(tx, rsvp.EventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Guests kept from before the limit was lowered are not refused again
+	if guests > rsvp.Guests || rsvp.Status != "come" {
+		err = limits.check(guests)
+	} else {
+		err = limits.check(0)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case status != "come":
+		rsvp.Waitlisted = false
+	case rsvp.Status != "come":
+		if rsvp.Waitlisted, err = limits.mustWait(tx, rsvp.EventID, id, 1+guests); err != nil {
+			return nil, nil, err
+		}
+	case !rsvp.Waitlisted && guests > rsvp.Guests && limits.capacity.Valid:
+		var taken int64
+		if taken, err = seatsTaken(tx, rsvp.EventID, id); err != nil {
+			return nil, nil, err
+		}
+		if taken+int64(1+guests) > limits.capacity.Int64 {
+			err = ErrEventFull
+			return nil, nil, err
+		}
+	}
+	rsvp.Status, rsvp.Guests, rsvp.NeedsConfirmation = status, guests, false
+	_, err = tx.Exec(`UPDATE event_rsvps SET status = ?, guests = ?, needs_confirmation = FALSE,
+		waitlisted_at = CASE WHEN ? THEN COALESCE(waitlisted_at, CURRENT_TIMESTAMP) END
+		WHERE id = ?`, status, guests, rsvp.Waitlisted, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	promoted, err := promoteWaitlist(tx, rsvp.EventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Fewer guests can make room for the RSVP itself, that is no news to its author
+	others := promoted[:0]
+	for _, p := range promoted {
+		if p.ID == id {
+			rsvp.Waitlisted = false
+			continue
+		}
+		others = append(others, p)
+	}
+	return &rsvp, others, nil
 }
 
-func (s *Service) DeleteEventRSVP(id int64) error {
+// DeleteEventRSVP removes an RSVP and returns the waitlisted ones promoted to the seats it frees
+func (s *Service) DeleteEventRSVP(id int64) ([]EventRSVP, error) {
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -136,6 +378,47 @@ func (s *Service) DeleteEventRSVP(id int64) error {
 			_ = tx.Commit()
 		}
 	}()
-	_, err = tx.Exec(`DELETE FROM event_rsvps WHERE id = ?`, id)
+	var eventID int64
+	if err = tx.QueryRow(`SELECT event_id FROM event_rsvps WHERE id = ?`, id).Scan(&eventID); err != nil {
+		return nil, err
+	}
+	limits, err := eventRSVPLimits(tx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err = limits.check(0); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(`DELETE FROM event_rsvps WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	promoted, err := promoteWaitlist(tx, eventID)
+	return promoted, err
+}
+
+// GetEventRSVPUserIDs returns the users who answered an event, whatever the answer
+func (s *Service) GetEventRSVPUserIDs(eventID int64) ([]int64, error) {
+	rows, err := s.DB.Query(`SELECT user_id FROM event_rsvps WHERE event_id = ? ORDER BY user_id`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// requestRSVPConfirmation asks the users coming or interested to answer again, they keep their seat meanwhile
+func requestRSVPConfirmation(tx *sql.Tx, eventID int64) error {
+	_, err := tx.Exec(`
+		UPDATE event_rsvps SET needs_confirmation = TRUE
+		WHERE event_id = ? AND status IN ('come', 'interested')`, eventID)
 	return err
 }
@@ -3,12 +3,19 @@ package db
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/Golden76z/social-network/models"
 )
 
 // Method to insert a comment on a group post
 func (s *Service) CreateGroupComment(request models.CreateGroupCommentRequest, userID int64) error {
+	if announcement, err := s.IsGroupPostAnnouncement(request.GroupPostID); err != nil {
+		return err
+	} else if announcement {
+		return ErrCommentsClosed
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return err
@@ -27,7 +34,7 @@ func (s *Service) CreateGroupComment(request models.CreateGroupCommentRequest, u
 		SELECT ?, ?, ?
 		FROM group_posts gp
 		JOIN group_members gm ON gp.group_id = gm.group_id
-		WHERE gp.id = ? AND gm.user_id = ?
+		WHERE gp.id = ? AND gp.status = 'published' AND gm.user_id = ?
 	`, request.GroupPostID, userID, request.Body, request.GroupPostID, userID)
 	if err != nil {
 		return err
@@ -64,7 +71,8 @@ func (s *Service) CreateGroupComment(request models.CreateGroupCommentRequest, u
 // Method to retrieve a single comment with its ID (access check included)
 func (s *Service) GetGroupCommentByID(id, userID int64) (*models.Comment, error) {
 	row := s.DB.QueryRow(`
-		SELECT gc.id, gc.group_post_id, gc.user_id, gc.body, gc.created_at, gc.updated_at
+		SELECT gc.id, gc.group_post_id, gc.user_id, gc.body, gc.created_at, gc.updated_at,
+			   (SELECT COUNT(*) FROM content_revisions WHERE group_comment_id = gc.id) AS revision_count
 		FROM group_comments gc
 		JOIN group_posts gp ON gc.group_post_id = gp.id
 		JOIN group_members gm ON gp.group_id = gm.group_id
@@ -72,20 +80,22 @@ func (s *Service) GetGroupCommentByID(id, userID int64) (*models.Comment, error)
 	`, id, userID)
 
 	var gc models.Comment
-	err := row.Scan(&gc.ID, &gc.PostID, &gc.UserID, &gc.Body, &gc.CreatedAt, &gc.UpdatedAt)
+	err := row.Scan(&gc.ID, &gc.PostID, &gc.UserID, &gc.Body, &gc.CreatedAt, &gc.UpdatedAt, &gc.RevisionCount)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("comment not found or access denied")
 		}
 		return nil, err
 	}
+	gc.Edited = gc.RevisionCount > 0
 	return &gc, nil
 }
 
 func (s *Service) GetGroupComments(groupPostID, userID int64, offset int) ([]models.Comment, error) {
 	rows, err := s.DB.Query(`
 		SELECT gc.id, gc.group_post_id, gc.user_id, gc.body, gc.created_at, gc.updated_at,
-			   u.nickname, u.first_name, u.last_name, u.avatar
+			   u.nickname, u.first_name, u.last_name, u.avatar,
+			   (SELECT COUNT(*) FROM content_revisions WHERE group_comment_id = gc.id) AS revision_count
 		FROM group_comments gc
 		JOIN users u ON gc.user_id = u.id
 		JOIN group_posts gp ON gc.group_post_id = gp.id
@@ -113,10 +123,11 @@ func (s *Service) GetGroupComments(groupPostID, userID int64, offset int) ([]mod
 		var avatar sql.NullString
 
 		err := rows.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Body,
-			&comment.CreatedAt, &comment.UpdatedAt, &username, &firstName, &lastName, &avatar)
+			&comment.CreatedAt, &comment.UpdatedAt, &username, &firstName, &lastName, &avatar, &comment.RevisionCount)
 		if err != nil {
 			return nil, err
 		}
+		comment.Edited = comment.RevisionCount > 0
 
 		if username.Valid {
 			comment.Username = username.String
@@ -175,6 +186,14 @@ func (s *Service) IsGroupCommentOwner(commentID, userID int64) (bool, error) {
 
 // UpdateGroupComment updates a comment with authorization and access check
 func (s *Service) UpdateGroupComment(commentID, userID int64, request models.UpdateGroupCommentRequest) error {
+	groupID, err := s.groupIDFromComment(commentID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureGroupWritable(groupID); err != nil {
+		return err
+	}
+
 	// First check if user owns the comment
 	isOwner, err := s.IsGroupCommentOwner(commentID, userID)
 	if err != nil {
@@ -211,6 +230,11 @@ func (s *Service) UpdateGroupComment(commentID, userID int64, request models.Upd
 		}
 	}()
 
+	err = recordRevision(tx, models.RevisionTargetGroupComment, commentID, userID, nil, request.Body, nil)
+	if err != nil {
+		return err
+	}
+
 	query := "UPDATE group_comments SET updated_at = CURRENT_TIMESTAMP"
 	args := []any{}
 
@@ -238,15 +262,30 @@ func (s *Service) UpdateGroupComment(commentID, userID int64, request models.Upd
 	return nil
 }
 
-// DeleteGroupComment deletes a comment with authorization and access check
+// DeleteGroupComment deletes a comment with authorization and access check.
+// Authors delete their comments, members allowed to delete content delete any.
 func (s *Service) DeleteGroupComment(commentID, userID int64) error {
+	groupID, err := s.groupIDFromComment(commentID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureGroupWritable(groupID); err != nil {
+		return err
+	}
+
 	// First check if user owns the comment
 	isOwner, err := s.IsGroupCommentOwner(commentID, userID)
 	if err != nil {
 		return err
 	}
 	if !isOwner {
-		return errors.New("unauthorized: user is not the owner of this comment")
+		canModerate, err := s.HasGroupPermission(userID, groupID, models.GroupPermDeleteContent)
+		if err != nil {
+			return err
+		}
+		if !canModerate {
+			return errors.New("unauthorized: user is not the owner of this comment")
+		}
 	}
 
 	// Check if user still has access to the group
@@ -276,6 +315,11 @@ func (s *Service) DeleteGroupComment(commentID, userID int64) error {
 		}
 	}()
 
+	var authorID int64
+	if err = tx.QueryRow(`SELECT user_id FROM group_comments WHERE id = ?`, commentID).Scan(&authorID); err != nil {
+		return err
+	}
+
 	result, err := tx.Exec(`DELETE FROM group_comments WHERE id = ?`, commentID)
 	if err != nil {
 		return err
@@ -289,14 +333,18 @@ func (s *Service) DeleteGroupComment(commentID, userID int64) error {
 		return errors.New("comment not found")
 	}
 
-	return nil
+	if !isOwner {
+		err = logModeration(tx, groupID, userID, authorID, models.ModerationDeleteComment, fmt.Sprintf("comment %d", commentID))
+	}
+	return err
 }
 
 // GetGroupCommentWithUserDetails retrieves a comment and user info with access control
 func (s *Service) GetGroupCommentWithUserDetails(commentID, userID int64) (*models.Comment, error) {
 	row := s.DB.QueryRow(`
 		SELECT gc.id, gc.group_post_id, gc.user_id, gc.body, gc.created_at, gc.updated_at,
-			   u.username, u.first_name, u.last_name, u.avatar
+			   u.username, u.first_name, u.last_name, u.avatar,
+			   (SELECT COUNT(*) FROM content_revisions WHERE group_comment_id = gc.id) AS revision_count
 		FROM group_comments gc
 		JOIN users u ON gc.user_id = u.id
 		JOIN group_posts gp ON gc.group_post_id = gp.id
@@ -309,13 +357,14 @@ func (s *Service) GetGroupCommentWithUserDetails(commentID, userID int64) (*mode
 	var avatar sql.NullString
 
 	err := row.Scan(&comment.ID, &comment.PostID, &comment.UserID, &comment.Body,
-		&comment.CreatedAt, &comment.UpdatedAt, &username, &firstName, &lastName, &avatar)
+		&comment.CreatedAt, &comment.UpdatedAt, &username, &firstName, &lastName, &avatar, &comment.RevisionCount)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.New("comment not found or access denied")
 		}
 		return nil, err
 	}
+	comment.Edited = comment.RevisionCount > 0
 
 	if username.Valid {
 		comment.Username = username.String
@@ -332,3 +381,16 @@ func (s *Service) GetGroupCommentWithUserDetails(commentID, userID int64) (*mode
 
 	return &comment, nil
 }
+
+// groupIDFromComment returns the group of the post a comment was made on
+func (s *Service) groupIDFromComment(commentID int64) (int64, error) {
+	var groupID int64
+	err := s.DB.QueryRow(`
+		SELECT gp.group_id FROM group_comments gc
+		JOIN group_posts gp ON gc.group_post_id = gp.id
+		WHERE gc.id = ?`, commentID).Scan(&groupID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("comment not found")
+	}
+	return groupID, err
+}
@@ -2,80 +2,288 @@ package db
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/Golden76z/social-network/calendar"
 	"github.com/Golden76z/social-network/models"
 )
 
-// GetGroupEvents retrieves group events with optional filters and pagination
+// EventTimeLayout is how event times are stored, as wall clock times in the timezone of the event
+const EventTimeLayout = "2006-01-02 15:04:05"
+
+// ErrInvalidEventDate is returned for a from or to bound that is not a date
+var ErrInvalidEventDate = errors.New("invalid date")
+
+const groupEventColumns = `ge.id, ge.group_id, ge.creator_id, ge.title, COALESCE(ge.description, ''), ge.event_datetime,
+	ge.end_datetime, ge.timezone, COALESCE(ge.location, ''), COALESCE(ge.rrule, ''), ge.created_at,
+	COALESCE(ge.capacity, 0), ge.max_guests, ge.rsvp_deadline, ge.status, COALESCE(ge.cancel_reason, ''),
+	(SELECT COALESCE(SUM(1 + er.guests), 0) FROM event_rsvps er
+		WHERE er.event_id = ge.id AND er.status = 'come' AND er.waitlisted_at IS NULL),
+	(SELECT COUNT(*) FROM event_rsvps er WHERE er.event_id = ge.id AND er.waitlisted_at IS NOT NULL),
+	u.nickname, u.first_name, u.last_name, u.avatar`
+
+// eventSeries is an event with the times its occurrences are computed from
+type eventSeries struct {
+	event      GroupEvent
+	start, end time.Time
+	created    time.Time
+	rule       *calendar.Rule
+	exceptions map[string]GroupEventException
+}
+
+// wallClock reads a stored time, which the driver labels UTC, as a wall clock time in loc
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+// ParseEventBound reads a from or to date, RFC 3339 or a UTC "YYYY-MM-DD[ HH:MM:SS]". Empty gives the zero time.
+func ParseEventBound(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(EventTimeLayout, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidEventDate, value)
+}
+
+// GetGroupEvents retrieves group events with optional filters and pagination.
+// Recurring events are expanded into their occurrences between fromDate and toDate.
 func (s *Service) GetGroupEvents(groupID int64, upcoming bool, limit, offset int, fromDate, toDate string) ([]GroupEvent, error) {
-	query := `SELECT ge.id, ge.group_id, ge.creator_id, ge.title, ge.description, ge.event_datetime, ge.created_at,
-	          u.nickname, u.first_name, u.last_name, u.avatar
-	          FROM group_events ge
-	          JOIN users u ON ge.creator_id = u.id
-	          WHERE 1=1`
-	args := []interface{}{}
+	from, err := ParseEventBound(fromDate, false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ParseEventBound(toDate, true)
+	if err != nil {
+		return nil, err
+	}
+	if now := time.Now(); upcoming && now.After(from) {
+		from = now
+	}
 
+	query := ` WHERE 1=1`
+	args := []interface{}{}
 	if groupID != 0 {
 		query += " AND ge.group_id = ?"
 		args = append(args, groupID)
 	}
-	if upcoming {
-		query += " AND ge.event_datetime > datetime('now')"
+	// Single events are filtered in SQL with a day of margin for the timezone offsets
+	if !from.IsZero() {
+		query += " AND (ge.rrule IS NOT NULL OR ge.event_datetime >= ?)"
+		args = append(args, from.UTC().AddDate(0, 0, -1).Format(EventTimeLayout))
 	}
-	if fromDate != "" {
-		query += " AND ge.event_datetime >= ?"
-		args = append(args, fromDate)
-	}
-	if toDate != "" {
+	if !to.IsZero() {
 		query += " AND ge.event_datetime <= ?"
-		args = append(args, toDate)
+		args = append(args, to.UTC().AddDate(0, 0, 1).Format(EventTimeLayout))
+	}
+
+	series, err := s.queryEventSeries(query, args...)
+	if err != nil {
+		return nil, err
 	}
-	query += " ORDER BY ge.event_datetime ASC"
+
+	max := 0
 	if limit > 0 {
-		query += " LIMIT ?"
-		args = append(args, limit)
+		max = offset + limit
 	}
+	events := []GroupEvent{}
+	for _, es := range series {
+		events = append(events, es.between(from, to, max)...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].startsAt.Before(events[j].startsAt) })
+
 	if offset > 0 {
-		query += " OFFSET ?"
-		args = append(args, offset)
+		if offset >= len(events) {
+			return []GroupEvent{}, nil
+		}
+		events = events[offset:]
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
 	}
+	return events, nil
+}
 
-	rows, err := s.DB.Query(query, args...)
+// queryEventSeries loads the events matching where along with the exceptions of the recurring ones
+func (s *Service) queryEventSeries(where string, args ...any) ([]*eventSeries, error) {
+	rows, err := s.DB.Query(`SELECT `+groupEventColumns+`
+		FROM group_events ge
+		LEFT JOIN users u ON ge.creator_id = u.id`+where+`
+		ORDER BY ge.event_datetime ASC, ge.id ASC`, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var events []GroupEvent
+	var series []*eventSeries
 	for rows.Next() {
-		var ge GroupEvent
-		var nickname, firstName, lastName, avatar sql.NullString
-		err := rows.Scan(&ge.ID, &ge.GroupID, &ge.CreatorID, &ge.Title, &ge.Description, &ge.EventDateTime, &ge.CreatedAt,
-			&nickname, &firstName, &lastName, &avatar)
+		es, err := scanEventSeries(rows)
 		if err != nil {
 			return nil, err
 		}
+		series = append(series, es)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-		// Set creator information
-		if nickname.Valid {
-			ge.CreatorNickname = nickname.String
+	for _, es := range series {
+		if es.rule == nil {
+			continue
 		}
-		if firstName.Valid {
-			ge.CreatorFirstName = firstName.String
+		exceptions, err := s.GetGroupEventExceptions(es.event.ID)
+		if err != nil {
+			return nil, err
 		}
-		if lastName.Valid {
-			ge.CreatorLastName = lastName.String
+		es.exceptions = make(map[string]GroupEventException, len(exceptions))
+		for _, ex := range exceptions {
+			es.exceptions[ex.occurrence.Format(EventTimeLayout)] = ex
 		}
-		if avatar.Valid {
-			ge.CreatorAvatar = avatar.String
+	}
+	return series, nil
+}
+
+func scanEventSeries(row interface{ Scan(...any) error }) (*eventSeries, error) {
+	var es eventSeries
+	ge := &es.event
+	var start, createdAt time.Time
+	var end, deadline sql.NullTime
+	var nickname, firstName, lastName, avatar sql.NullString
+	err := row.Scan(&ge.ID, &ge.GroupID, &ge.CreatorID, &ge.Title, &ge.Description, &start,
+		&end, &ge.Timezone, &ge.Location, &ge.RRule, &createdAt,
+		&ge.Capacity, &ge.MaxGuests, &deadline, &ge.Status, &ge.CancelReason, &ge.Attending, &ge.Waitlisted,
+		&nickname, &firstName, &lastName, &avatar)
+	if err != nil {
+		return nil, err
+	}
+	ge.CreatedAt = createdAt.Format(time.RFC3339)
+	es.created = createdAt
+
+	// Set creator information
+	if nickname.Valid {
+		ge.CreatorNickname = nickname.String
+	}
+	if firstName.Valid {
+		ge.CreatorFirstName = firstName.String
+	}
+	if lastName.Valid {
+		ge.CreatorLastName = lastName.String
+	}
+	if avatar.Valid {
+		ge.CreatorAvatar = avatar.String
+	}
+
+	loc, err := calendar.LoadLocation(ge.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("event %d: %w", ge.ID, err)
+	}
+	es.start = wallClock(start, loc)
+	if end.Valid {
+		es.end = wallClock(end.Time, loc)
+	}
+	if deadline.Valid {
+		ge.RSVPDeadline = wallClock(deadline.Time, loc).Format(time.RFC3339)
+	}
+	if ge.RRule != "" {
+		if es.rule, err = calendar.ParseRule(ge.RRule, loc); err != nil {
+			return nil, fmt.Errorf("event %d: %w", ge.ID, err)
+		}
+	}
+	ge.setTimes(es.start, es.end)
+	return &es, nil
+}
+
+// between returns the occurrences starting within [from, to], at most max of them when max > 0
+func (es *eventSeries) between(from, to time.Time, max int) []GroupEvent {
+	inRange := func(t time.Time) bool {
+		return !t.Before(from) && (to.IsZero() || !t.After(to))
+	}
+	if es.rule == nil {
+		if inRange(es.start) {
+			return []GroupEvent{es.event}
 		}
+		return nil
+	}
 
-		events = append(events, ge)
+	if max > 0 {
+		// Cancelled or moved occurrences leave room for later ones
+		max += len(es.exceptions)
 	}
-	return events, nil
+	var occurrences []GroupEvent
+	listed := map[string]bool{}
+	for _, occ := range es.rule.Occurrences(es.start, from, to, max) {
+		key := occ.Format(EventTimeLayout)
+		listed[key] = true
+		if ge, ok := es.occurrence(occ); ok && inRange(ge.startsAt) {
+			occurrences = append(occurrences, ge)
+		}
+	}
+	// Occurrences moved into the range from outside of it
+	for key, ex := range es.exceptions {
+		if listed[key] || ex.Cancelled || ex.start.IsZero() {
+			continue
+		}
+		if ge, ok := es.occurrence(ex.occurrence); ok && inRange(ge.startsAt) {
+			occurrences = append(occurrences, ge)
+		}
+	}
+	return occurrences
 }
 
-// GroupEvent represents a group event in the database
+// occurrence builds the occurrence originally starting at occ, false when it is cancelled
+func (es *eventSeries) occurrence(occ time.Time) (GroupEvent, bool) {
+	ge := es.event
+	start, end := occ, time.Time{}
+	if !es.end.IsZero() {
+		end = occ.Add(es.end.Sub(es.start))
+	}
+	if ex, ok := es.exceptions[occ.Format(EventTimeLayout)]; ok {
+		if ex.Cancelled {
+			return ge, false
+		}
+		if !ex.start.IsZero() {
+			if !end.IsZero() {
+				end = ex.start.Add(end.Sub(start))
+			}
+			start = ex.start
+		}
+		if !ex.end.IsZero() {
+			end = ex.end
+		}
+		if ex.Title != nil {
+			ge.Title = *ex.Title
+		}
+		if ex.Description != nil {
+			ge.Description = *ex.Description
+		}
+		if ex.Location != nil {
+			ge.Location = *ex.Location
+		}
+		ge.Modified = true
+	}
+	ge.Occurrence = occ.Format(time.RFC3339)
+	ge.setTimes(start, end)
+	return ge, true
+}
+
+// GroupEvent represents a group event in the database. The times carry the offset of the event timezone,
+// for recurring events EventDateTime is the start of the occurrence and Occurrence the start the rule gave it.
+// Attending counts the confirmed seats, guests included, and Waitlisted the RSVPs waiting for one.
 type GroupEvent struct {
 	ID               int64  `json:"id"`
 	GroupID          int64  `json:"group_id"`
@@ -83,18 +291,60 @@ type GroupEvent struct {
 	Title            string `json:"title"`
 	Description      string `json:"description"`
 	EventDateTime    string `json:"event_datetime"`
+	EndDateTime      string `json:"end_datetime,omitempty"`
+	Timezone         string `json:"timezone"`
+	Location         string `json:"location,omitempty"`
+	RRule            string `json:"rrule,omitempty"`
+	Occurrence       string `json:"occurrence,omitempty"`
+	Modified         bool   `json:"modified,omitempty"`
+	Status           string `json:"status"`
+	CancelReason     string `json:"cancel_reason,omitempty"`
+	Capacity         int    `json:"capacity,omitempty"`
+	MaxGuests        int    `json:"max_guests"`
+	RSVPDeadline     string `json:"rsvp_deadline,omitempty"`
+	Attending        int    `json:"attending"`
+	Waitlisted       int    `json:"waitlisted"`
 	CreatedAt        string `json:"created_at"`
 	CreatorNickname  string `json:"creator_nickname,omitempty"`
 	CreatorFirstName string `json:"creator_first_name,omitempty"`
 	CreatorLastName  string `json:"creator_last_name,omitempty"`
 	CreatorAvatar    string `json:"creator_avatar,omitempty"`
+
+	startsAt, endsAt time.Time
+}
+
+func (ge *GroupEvent) setTimes(start, end time.Time) {
+	ge.startsAt, ge.endsAt = start, end
+	ge.EventDateTime = start.Format(time.RFC3339)
+	ge.EndDateTime = ""
+	if !end.IsZero() {
+		ge.EndDateTime = end.Format(time.RFC3339)
+	}
+}
+
+// GroupEventException cancels or moves one occurrence of a recurring event
+type GroupEventException struct {
+	EventID       int64   `json:"event_id"`
+	Occurrence    string  `json:"occurrence"`
+	Cancelled     bool    `json:"cancelled"`
+	EventDateTime string  `json:"event_datetime,omitempty"`
+	EndDateTime   string  `json:"end_datetime,omitempty"`
+	Title         *string `json:"title,omitempty"`
+	Description   *string `json:"description,omitempty"`
+	Location      *string `json:"location,omitempty"`
+
+	occurrence, start, end time.Time
 }
 
 // CreateGroupEvent inserts a new group event into the database.
-func (s *Service) CreateGroupEvent(request models.CreateGroupEventRequest, creatorID int64) error {
+func (s *Service) CreateGroupEvent(request models.CreateGroupEventRequest, creatorID int64) (int64, error) {
+	if request.Timezone == "" {
+		request.Timezone = "UTC"
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer func() {
 		if err != nil {
@@ -103,40 +353,120 @@ func (s *Service) CreateGroupEvent(request models.CreateGroupEventRequest, creat
 			_ = tx.Commit()
 		}
 	}()
-	_, err = tx.Exec(`
-		INSERT INTO group_events (group_id, creator_id, title, description, event_datetime)
-		VALUES (?, ?, ?, ?, ?)`,
-		request.GroupID, creatorID, request.Title, request.Description, request.EventDateTime)
-	return err
+	result, err := tx.Exec(`
+		INSERT INTO group_events (group_id, creator_id, title, description, event_datetime, end_datetime, timezone, location, rrule,
+			capacity, max_guests, rsvp_deadline)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		request.GroupID, creatorID, request.Title, request.Description, request.EventDateTime,
+		nullIfEmpty(request.EndDateTime), request.Timezone, nullIfEmpty(request.Location), nullIfEmpty(request.RRule),
+		nullIfZero(request.Capacity), request.MaxGuests, nullIfEmpty(request.RSVPDeadline))
+	if err != nil {
+		return 0, err
+	}
+	eventID, err := result.LastInsertId()
+	return eventID, err
+}
+
+func nullIfEmpty(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func nullIfZero(value int) any {
+	if value == 0 {
+		return nil
+	}
+	return value
 }
 
 // GetGroupEventByID retrieves a group event by its ID.
 func (s *Service) GetGroupEventByID(id int64) (*GroupEvent, error) {
-	row := s.DB.QueryRow(`
-		SELECT id, group_id, creator_id, title, description, event_datetime, created_at
-		FROM group_events WHERE id = ?`, id)
-
-	var ge GroupEvent
-	err := row.Scan(
-		&ge.ID,
-		&ge.GroupID,
-		&ge.CreatorID,
-		&ge.Title,
-		&ge.Description,
-		&ge.EventDateTime,
-		&ge.CreatedAt,
-	)
+	es, err := s.getEventSeries(id)
 	if err != nil {
 		return nil, err
 	}
-	return &ge, nil
+	return &es.event, nil
 }
 
-// UpdateGroupEvent updates a group event
-func (s *Service) UpdateGroupEvent(id int64, request models.UpdateGroupEventRequest) error {
+func (s *Service) getEventSeries(id int64) (*eventSeries, error) {
+	return scanEventSeries(s.DB.QueryRow(`SELECT `+groupEventColumns+`
+		FROM group_events ge
+		LEFT JOIN users u ON ge.creator_id = u.id
+		WHERE ge.id = ?`, id))
+}
+
+// ErrEventCancelled is returned when changing a cancelled event or its RSVPs
+var ErrEventCancelled = errors.New("event is cancelled")
+
+// eventFields are the columns an update can change, in the order changes are logged
+var eventFields = []string{"title", "description", "event_datetime", "end_datetime", "timezone", "location", "rrule",
+	"capacity", "max_guests", "rsvp_deadline"}
+
+// GroupEventChange is one field of an event changed by an update or the cancellation
+type GroupEventChange struct {
+	ID                int64  `json:"id"`
+	EventID           int64  `json:"event_id"`
+	Field             string `json:"field"`
+	OldValue          string `json:"old_value"`
+	NewValue          string `json:"new_value"`
+	ChangedBy         int64  `json:"changed_by,omitempty"`
+	ChangedByNickname string `json:"changed_by_nickname,omitempty"`
+	CreatedAt         string `json:"created_at"`
+}
+
+// GroupEventUpdate is what an update changed. Shift is how far the start moved in absolute time.
+type GroupEventUpdate struct {
+	Changes     []GroupEventChange
+	Promoted    []EventRSVP
+	Shift       time.Duration
+	RuleChanged bool
+	// RSVPsReset is set when the RSVPs were asked to be confirmed again
+	RSVPsReset bool
+}
+
+// requestedEventFields lists the fields set by an update request as they are stored, "" for NULL
+func requestedEventFields(request models.UpdateGroupEventRequest) map[string]string {
+	set := map[string]string{}
+	for field, value := range map[string]*string{
+		"title": request.Title, "description": request.Description, "event_datetime": request.EventDateTime,
+		"end_datetime": request.EndDateTime, "timezone": request.Timezone, "location": request.Location,
+		"rrule": request.RRule, "rsvp_deadline": request.RSVPDeadline,
+	} {
+		if value != nil {
+			set[field] = *value
+		}
+	}
+	if request.Capacity != nil {
+		set["capacity"] = ""
+		if *request.Capacity > 0 {
+			set["capacity"] = strconv.Itoa(*request.Capacity)
+		}
+	}
+	if request.MaxGuests != nil {
+		set["max_guests"] = strconv.Itoa(*request.MaxGuests)
+	}
+	return set
+}
+
+// eventStart reads a stored start as an absolute time
+func eventStart(value, timezone string) (time.Time, error) {
+	loc, err := calendar.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(EventTimeLayout, value, loc)
+}
+
+// UpdateGroupEvent updates a group event and logs the fields that changed. Changing when or how it recurs
+// drops the exceptions, they were made for the previous occurrences. A raised or removed capacity promotes
+// waitlisted RSVPs while a lowered one keeps the seats already confirmed. Moving it by confirmShift or more,
+// or changing how it recurs, asks the RSVPs to be confirmed again.
+func (s *Service) UpdateGroupEvent(id int64, request models.UpdateGroupEventRequest, actorID int64, confirmShift time.Duration) (*GroupEventUpdate, error) {
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		if err != nil {
@@ -146,40 +476,177 @@ func (s *Service) UpdateGroupEvent(id int64, request models.UpdateGroupEventRequ
 		}
 	}()
 
-	// Build dynamic update query
+	var status string
+	var title, description, start, end, timezone, location, rrule, capacity, maxGuests, deadline string
+	err = tx.QueryRow(`SELECT title, COALESCE(description, ''), strftime('%Y-%m-%d %H:%M:%S', event_datetime),
+		COALESCE(strftime('%Y-%m-%d %H:%M:%S', end_datetime), ''), timezone, COALESCE(location, ''), COALESCE(rrule, ''),
+		COALESCE(capacity, ''), max_guests, COALESCE(strftime('%Y-%m-%d %H:%M:%S', rsvp_deadline), ''), status
+		FROM group_events WHERE id = ?`, id).
+		Scan(&title, &description, &start, &end, &timezone, &location, &rrule, &capacity, &maxGuests, &deadline, &status)
+	if err != nil {
+		return nil, err
+	}
+	if status == "cancelled" {
+		err = ErrEventCancelled
+		return nil, err
+	}
+	old := map[string]string{
+		"title": title, "description": description, "event_datetime": start, "end_datetime": end, "timezone": timezone,
+		"location": location, "rrule": rrule, "capacity": capacity, "max_guests": maxGuests, "rsvp_deadline": deadline,
+	}
+	updated := map[string]string{}
+	for field, value := range old {
+		updated[field] = value
+	}
+	for field, value := range requestedEventFields(request) {
+		updated[field] = value
+	}
+	if updated["end_datetime"] != "" && updated["end_datetime"] <= updated["event_datetime"] {
+		err = errors.New("event must end after it starts")
+		return nil, err
+	}
+	if updated["rsvp_deadline"] != "" && updated["rsvp_deadline"] > updated["event_datetime"] {
+		err = errors.New("RSVP deadline must not be after the event starts")
+		return nil, err
+	}
+
+	update := &GroupEventUpdate{}
 	query := "UPDATE group_events SET"
 	args := []interface{}{}
-	updates := []string{}
+	for _, field := range eventFields {
+		if updated[field] == old[field] {
+			continue
+		}
+		update.Changes = append(update.Changes, GroupEventChange{
+			EventID: id, Field: field, OldValue: old[field], NewValue: updated[field], ChangedBy: actorID,
+		})
+		if len(args) > 0 {
+			query += ","
+		}
+		query += " " + field + " = ?"
+		switch field {
+		case "title", "description", "event_datetime", "timezone", "max_guests":
+			args = append(args, updated[field])
+		default:
+			args = append(args, nullIfEmpty(updated[field]))
+		}
+	}
+	if len(update.Changes) == 0 {
+		return update, nil // Nothing changed
+	}
+	query += " WHERE id = ?"
+	args = append(args, id)
+	if _, err = tx.Exec(query, args...); err != nil {
+		return nil, err
+	}
 
-	if request.Title != nil {
-		updates = append(updates, " title = ?")
-		args = append(args, *request.Title)
+	for i := range update.Changes {
+		if update.Changes[i].ID, err = logEventChange(tx, update.Changes[i]); err != nil {
+			return nil, err
+		}
 	}
 
-	if request.Description != nil {
-		updates = append(updates, " description = ?")
-		args = append(args, *request.Description)
+	if updated["event_datetime"] != old["event_datetime"] || updated["timezone"] != old["timezone"] {
+		var before, after time.Time
+		if before, err = eventStart(old["event_datetime"], old["timezone"]); err != nil {
+			return nil, err
+		}
+		if after, err = eventStart(updated["event_datetime"], updated["timezone"]); err != nil {
+			return nil, err
+		}
+		update.Shift = after.Sub(before)
+	}
+	update.RuleChanged = updated["rrule"] != old["rrule"]
+	if updated["event_datetime"] != old["event_datetime"] || updated["timezone"] != old["timezone"] || update.RuleChanged {
+		if _, err = tx.Exec(`DELETE FROM group_event_exceptions WHERE event_id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+	if updated["capacity"] != old["capacity"] {
+		if update.Promoted, err = promoteWaitlist(tx, id); err != nil {
+			return nil, err
+		}
 	}
 
-	if request.EventDateTime != nil {
-		updates = append(updates, " event_datetime = ?")
-		args = append(args, *request.EventDateTime)
+	// Answers given for another time are asked again
+	shift := update.Shift
+	if shift < 0 {
+		shift = -shift
+	}
+	if update.RuleChanged || shift >= confirmShift {
+		if err = requestRSVPConfirmation(tx, id); err != nil {
+			return nil, err
+		}
+		update.RSVPsReset = true
 	}
+	return update, nil
+}
 
-	if len(updates) == 0 {
-		return nil // No updates to perform
+func logEventChange(tx *sql.Tx, change GroupEventChange) (int64, error) {
+	var changedBy any
+	if change.ChangedBy != 0 {
+		changedBy = change.ChangedBy
+	}
+	res, err := tx.Exec(`
+		INSERT INTO group_event_changes (event_id, changed_by, field, old_value, new_value)
+		VALUES (?, ?, ?, ?, ?)`, change.EventID, changedBy, change.Field, change.OldValue, change.NewValue)
+	if err != nil {
+		return 0, err
 	}
+	return res.LastInsertId()
+}
 
-	query += updates[0]
-	for i := 1; i < len(updates); i++ {
-		query += "," + updates[i]
+// CancelGroupEvent marks an event cancelled. It stays listed and exported with its RSVPs locked.
+func (s *Service) CancelGroupEvent(id, actorID int64, reason string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+	res, err := tx.Exec(`
+		UPDATE group_events SET status = 'cancelled', cancelled_at = CURRENT_TIMESTAMP, cancel_reason = ?
+		WHERE id = ? AND status != 'cancelled'`, nullIfEmpty(reason), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = ErrEventCancelled
+		return err
+	}
+	_, err = logEventChange(tx, GroupEventChange{EventID: id, Field: "status", OldValue: "scheduled", NewValue: "cancelled", ChangedBy: actorID})
+	return err
+}
 
-	query += " WHERE id = ?"
-	args = append(args, id)
+// GetGroupEventChanges returns the change log of an event, latest first
+func (s *Service) GetGroupEventChanges(eventID int64, limit, offset int) ([]GroupEventChange, error) {
+	rows, err := s.DB.Query(`
+		SELECT c.id, c.event_id, c.field, COALESCE(c.old_value, ''), COALESCE(c.new_value, ''),
+			COALESCE(c.changed_by, 0), COALESCE(u.nickname, ''), c.created_at
+		FROM group_event_changes c
+		LEFT JOIN users u ON u.id = c.changed_by
+		WHERE c.event_id = ?
+		ORDER BY c.id DESC
+		LIMIT ? OFFSET ?`, eventID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	_, err = tx.Exec(query, args...)
-	return err
+	changes := []GroupEventChange{}
+	for rows.Next() {
+		var c GroupEventChange
+		if err := rows.Scan(&c.ID, &c.EventID, &c.Field, &c.OldValue, &c.NewValue, &c.ChangedBy, &c.ChangedByNickname, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
 }
 
 // DeleteGroupEvent removes a group event from the database by its ID.
@@ -195,6 +662,138 @@ func (s *Service) DeleteGroupEvent(id int64) error {
 			_ = tx.Commit()
 		}
 	}()
+	if _, err = tx.Exec(`DELETE FROM group_event_exceptions WHERE event_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(`DELETE FROM group_event_changes WHERE event_id = ?`, id); err != nil {
+		return err
+	}
 	_, err = tx.Exec(`DELETE FROM group_events WHERE id = ?`, id)
 	return err
 }
+
+// GetGroupEventExceptions returns the cancelled and moved occurrences of an event
+func (s *Service) GetGroupEventExceptions(eventID int64) ([]GroupEventException, error) {
+	var timezone string
+	if err := s.DB.QueryRow(`SELECT timezone FROM group_events WHERE id = ?`, eventID).Scan(&timezone); err != nil {
+		return nil, err
+	}
+	loc, err := calendar.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT event_id, occurrence, cancelled, event_datetime, end_datetime, title, description, location
+		FROM group_event_exceptions WHERE event_id = ?
+		ORDER BY occurrence ASC`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exceptions := []GroupEventException{}
+	for rows.Next() {
+		var ex GroupEventException
+		var occurrence time.Time
+		var start, end sql.NullTime
+		var title, description, location sql.NullString
+		if err := rows.Scan(&ex.EventID, &occurrence, &ex.Cancelled, &start, &end, &title, &description, &location); err != nil {
+			return nil, err
+		}
+		ex.occurrence = wallClock(occurrence, loc)
+		ex.Occurrence = ex.occurrence.Format(time.RFC3339)
+		if start.Valid {
+			ex.start = wallClock(start.Time, loc)
+			ex.EventDateTime = ex.start.Format(time.RFC3339)
+		}
+		if end.Valid {
+			ex.end = wallClock(end.Time, loc)
+			ex.EndDateTime = ex.end.Format(time.RFC3339)
+		}
+		if title.Valid {
+			ex.Title = &title.String
+		}
+		if description.Valid {
+			ex.Description = &description.String
+		}
+		if location.Valid {
+			ex.Location = &location.String
+		}
+		exceptions = append(exceptions, ex)
+	}
+	return exceptions, rows.Err()
+}
+
+// SetGroupEventException cancels or moves the occurrence of a recurring event. The times of
+// the request are stored wall clock times, a rescheduled occurrence keeps its length unless given an end.
+func (s *Service) SetGroupEventException(request models.GroupEventExceptionRequest) error {
+	es, err := s.getEventSeries(request.EventID)
+	if err != nil {
+		return err
+	}
+	if es.rule == nil {
+		return errors.New("event is not recurring")
+	}
+	occurrence, err := time.ParseInLocation(EventTimeLayout, request.Occurrence, es.start.Location())
+	if err != nil {
+		return err
+	}
+	if occ := es.rule.Occurrences(es.start, occurrence, occurrence, 1); len(occ) == 0 {
+		return errors.New("occurrence not found")
+	}
+
+	var start, end any
+	if request.EventDateTime != nil {
+		start = *request.EventDateTime
+	}
+	if request.EndDateTime != nil {
+		end = *request.EndDateTime
+		if request.EventDateTime != nil && *request.EndDateTime <= *request.EventDateTime {
+			return errors.New("event must end after it starts")
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+	_, err = tx.Exec(`
+		INSERT INTO group_event_exceptions (event_id, occurrence, cancelled, event_datetime, end_datetime, title, description, location)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(event_id, occurrence) DO UPDATE SET
+			cancelled = excluded.cancelled, event_datetime = excluded.event_datetime, end_datetime = excluded.end_datetime,
+			title = excluded.title, description = excluded.description, location = excluded.location`,
+		request.EventID, request.Occurrence, request.Cancelled, start, end, request.Title, request.Description, request.Location)
+	return err
+}
+
+// DeleteGroupEventException restores the occurrence as the rule gives it
+func (s *Service) DeleteGroupEventException(eventID int64, occurrence string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+	res, err := tx.Exec(`DELETE FROM group_event_exceptions WHERE event_id = ? AND occurrence = ?`, eventID, occurrence)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("exception not found")
+	}
+	return err
+}
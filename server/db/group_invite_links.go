@@ -0,0 +1,236 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// CreateGroupInviteLink stores a link under code, a nil expiresAt or zero maxUses means unlimited
+func (s *Service) CreateGroupInviteLink(groupID, createdBy int64, code, mode string, maxUses int, expiresAt *time.Time) (*models.GroupInviteLink, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var uses, expiry any
+	details := mode
+	if maxUses > 0 {
+		uses = maxUses
+		details += fmt.Sprintf(", %d uses", maxUses)
+	}
+	if expiresAt != nil {
+		expiry = expiresAt.UTC()
+		details += ", until " + expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO group_invite_links (group_id, code, created_by, mode, max_uses, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, groupID, code, createdBy, mode, uses, expiry)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = logModeration(tx, groupID, createdBy, 0, models.ModerationCreateInviteLink, details); err != nil {
+		return nil, err
+	}
+
+	links, err := queryGroupInviteLinks(tx, `WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &links[0], nil
+}
+
+// GetGroupInviteLinks returns every link of the group, newest first
+func (s *Service) GetGroupInviteLinks(groupID int64) ([]models.GroupInviteLink, error) {
+	return queryGroupInviteLinks(s.DB, `WHERE group_id = ? ORDER BY created_at DESC, id DESC`, groupID)
+}
+
+// GetGroupInviteLinkByCode returns the link behind a code, nil when there is none
+func (s *Service) GetGroupInviteLinkByCode(code string) (*models.GroupInviteLink, error) {
+	links, err := queryGroupInviteLinks(s.DB, `WHERE code = ?`, code)
+	if err != nil || len(links) == 0 {
+		return nil, err
+	}
+	return &links[0], nil
+}
+
+func queryGroupInviteLinks(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}, where string, args ...any) ([]models.GroupInviteLink, error) {
+	rows, err := q.Query(`
+		SELECT id, group_id, code, COALESCE(created_by, 0), mode, COALESCE(max_uses, 0), uses,
+		       expires_at, revoked_at, created_at
+		FROM group_invite_links `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now().UTC()
+	links := make([]models.GroupInviteLink, 0)
+	for rows.Next() {
+		var l models.GroupInviteLink
+		var expiresAt, revokedAt sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&l.ID, &l.GroupID, &l.Code, &l.CreatedBy, &l.Mode, &l.MaxUses, &l.Uses,
+			&expiresAt, &revokedAt, &createdAt); err != nil {
+			return nil, err
+		}
+		l.CreatedAt = createdAt.Format(time.RFC3339)
+		l.Active = !revokedAt.Valid && (!expiresAt.Valid || expiresAt.Time.After(now)) && (l.MaxUses == 0 || l.Uses < l.MaxUses)
+		if expiresAt.Valid {
+			l.ExpiresAt = expiresAt.Time.Format(time.RFC3339)
+		}
+		if revokedAt.Valid {
+			l.RevokedAt = revokedAt.Time.Format(time.RFC3339)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// RevokeGroupInviteLinks revokes the outstanding links of a group, only linkID when it is set.
+// It returns the number of links revoked.
+func (s *Service) RevokeGroupInviteLinks(groupID, linkID, actorID int64) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	query := `UPDATE group_invite_links SET revoked_at = CURRENT_TIMESTAMP WHERE group_id = ? AND revoked_at IS NULL`
+	args := []any{groupID}
+	details := "all links"
+	if linkID > 0 {
+		query += ` AND id = ?`
+		args = append(args, linkID)
+		details = fmt.Sprintf("link %d", linkID)
+	}
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		err = errors.New("invite link not found")
+		return 0, err
+	}
+
+	err = logModeration(tx, groupID, actorID, 0, models.ModerationRevokeInviteLink, details)
+	return n, err
+}
+
+// RedeemGroupInviteLink uses one of the remaining uses of the link. Auto links add the user to the
+// group, the others file a join request. It returns the link so the caller knows which happened.
+func (s *Service) RedeemGroupInviteLink(code string, userID int64) (*models.GroupInviteLink, error) {
+	link, err := s.GetGroupInviteLinkByCode(code)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return nil, errors.New("invite link not found")
+	}
+	if !link.Active {
+		return nil, errors.New("invite link is no longer valid")
+	}
+	if err := s.ensureGroupWritable(link.GroupID); err != nil {
+		return nil, err
+	}
+	if banned, err := s.IsBannedFromGroup(userID, link.GroupID); err != nil {
+		return nil, err
+	} else if banned {
+		return nil, ErrGroupBanned
+	}
+	if role, err := s.GetGroupRole(userID, link.GroupID); err != nil {
+		return nil, err
+	} else if role != "" {
+		return nil, errors.New("user is already a member of the group")
+	}
+	if link.Mode == models.GroupInviteLinkRequest {
+		if pending, err := s.HasPendingGroupRequest(userID, link.GroupID); err != nil {
+			return nil, err
+		} else if pending {
+			return nil, errors.New("a pending request already exists")
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	// Counting the use in the same statement as the checks keeps concurrent redemptions within max_uses
+	res, err := tx.Exec(`
+		UPDATE group_invite_links SET uses = uses + 1
+		WHERE id = ? AND revoked_at IS NULL
+		  AND (max_uses IS NULL OR uses < max_uses)
+		  AND (expires_at IS NULL OR expires_at > ?)`, link.ID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("invite link is no longer valid")
+		return nil, err
+	}
+
+	if link.Mode == models.GroupInviteLinkAuto {
+		var invitedBy any
+		if link.CreatedBy > 0 {
+			invitedBy = link.CreatedBy
+		}
+		_, err = tx.Exec(`
+			INSERT INTO group_members (group_id, user_id, role, invited_by)
+			VALUES (?, ?, ?, ?)`, link.GroupID, userID, models.GroupRoleMember, invitedBy)
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			err = errors.New("user is already a member of the group")
+		}
+		if err != nil {
+			return nil, err
+		}
+		// A pending join request is settled by joining
+		_, err = tx.Exec(`DELETE FROM group_requests WHERE group_id = ? AND user_id = ? AND status = 'pending'`, link.GroupID, userID)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO group_requests (group_id, user_id, status)
+			VALUES (?, ?, 'pending')`, link.GroupID, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	link.Uses++
+	return link, nil
+}
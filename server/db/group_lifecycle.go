@@ -0,0 +1,265 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// ErrGroupArchived is returned by writes to an archived, read-only group
+var ErrGroupArchived = errors.New("group is archived")
+
+// GetGroupStatus returns the lifecycle status of a group, empty when it does not exist
+func (s *Service) GetGroupStatus(groupID int64) (string, error) {
+	var status string
+	err := s.DB.QueryRow(`SELECT status FROM groups WHERE id = ?`, groupID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status, err
+}
+
+// ensureGroupWritable refuses writes to archived and deleted groups
+func (s *Service) ensureGroupWritable(groupID int64) error {
+	status, err := s.GetGroupStatus(groupID)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case models.GroupStatusActive:
+		return nil
+	case models.GroupStatusArchived:
+		return ErrGroupArchived
+	default:
+		return errors.New("group does not exist")
+	}
+}
+
+// GetGroupMemberIDs returns the user ID of every member of the group
+func (s *Service) GetGroupMemberIDs(groupID int64) ([]int64, error) {
+	rows, err := s.DB.Query(`SELECT user_id FROM group_members WHERE group_id = ?`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ArchiveGroup makes an active group read-only
+func (s *Service) ArchiveGroup(groupID int64) error {
+	res, err := s.DB.Exec(`
+		UPDATE groups SET status = 'archived', archived_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'active'`, groupID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("group is not active")
+	}
+	return nil
+}
+
+// UnarchiveGroup makes an archived group writable again
+func (s *Service) UnarchiveGroup(groupID int64) error {
+	res, err := s.DB.Exec(`
+		UPDATE groups SET status = 'active', archived_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'archived'`, groupID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("group is not archived")
+	}
+	return nil
+}
+
+// DeleteGroup hides the group until restoreWindow has passed, then PurgeDeletedGroups removes it for good.
+// It returns the time until which the owner can restore the group.
+func (s *Service) DeleteGroup(groupID int64, restoreWindow time.Duration) (time.Time, error) {
+	now := time.Now().UTC()
+	purgeAfter := now.Add(restoreWindow)
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	res, err := tx.Exec(`
+		UPDATE groups SET status = 'deleted', deleted_at = ?, purge_after = ?
+		WHERE id = ? AND status != 'deleted'`, now, purgeAfter, groupID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n == 0 {
+		err = errors.New("group does not exist")
+		return time.Time{}, err
+	}
+
+	// A deleted group cannot change hands
+	_, err = tx.Exec(`
+		UPDATE group_ownership_transfers SET status = 'cancelled', responded_at = CURRENT_TIMESTAMP
+		WHERE group_id = ? AND status = 'pending'`, groupID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return purgeAfter, nil
+}
+
+// RestoreGroup brings back a deleted group before its purge, in the state it was deleted from
+func (s *Service) RestoreGroup(groupID int64) error {
+	res, err := s.DB.Exec(`
+		UPDATE groups
+		SET status = CASE WHEN archived_at IS NULL THEN 'active' ELSE 'archived' END,
+		    deleted_at = NULL, purge_after = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'deleted' AND purge_after > ?`, groupID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errors.New("group cannot be restored")
+	}
+	return nil
+}
+
+// GetDeletedGroups returns the deleted groups the user owns and can still restore
+func (s *Service) GetDeletedGroups(ownerID int64) ([]models.DeletedGroupResponse, error) {
+	rows, err := s.DB.Query(`
+		SELECT g.id, g.title, g.deleted_at, g.purge_after
+		FROM groups g
+		JOIN group_members gm ON gm.group_id = g.id
+		WHERE gm.user_id = ? AND gm.role = 'owner' AND g.status = 'deleted' AND g.purge_after > ?
+		ORDER BY g.deleted_at DESC`, ownerID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]models.DeletedGroupResponse, 0)
+	for rows.Next() {
+		var g models.DeletedGroupResponse
+		var deletedAt, purgeAfter time.Time
+		if err := rows.Scan(&g.ID, &g.Title, &deletedAt, &purgeAfter); err != nil {
+			return nil, err
+		}
+		g.DeletedAt = deletedAt.Format(time.RFC3339)
+		g.PurgeAfter = purgeAfter.Format(time.RFC3339)
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// PurgeDeletedGroups removes the groups whose restore window ended before now, with all their content
+func (s *Service) PurgeDeletedGroups(now time.Time) (int, error) {
+	rows, err := s.DB.Query(`SELECT id FROM groups WHERE status = 'deleted' AND purge_after <= ?`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := s.purgeGroup(id); err != nil {
+			return purged, fmt.Errorf("group %d: %w", id, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeGroup deletes a group and everything posted in it
+func (s *Service) purgeGroup(groupID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	statements := []struct{ query, what string }{
+		{`DELETE FROM group_messages WHERE group_id = ?`, "group messages"},
+		{`DELETE FROM group_comments WHERE group_post_id IN (SELECT id FROM group_posts WHERE group_id = ?)`, "group comments"},
+		{`DELETE FROM group_posts WHERE group_id = ?`, "group posts"},
+		{`DELETE FROM group_requests WHERE group_id = ?`, "group requests"},
+		{`DELETE FROM group_invitations WHERE group_id = ?`, "group invitations"},
+		{`DELETE FROM group_event_exceptions WHERE event_id IN (SELECT id FROM group_events WHERE group_id = ?)`, "event exceptions"},
+		{`DELETE FROM group_event_changes WHERE event_id IN (SELECT id FROM group_events WHERE group_id = ?)`, "event changes"},
+		{`DELETE FROM group_events WHERE group_id = ?`, "group events"},
+		{`DELETE FROM group_role_permissions WHERE group_id = ?`, "group permissions"},
+		{`DELETE FROM group_ownership_transfers WHERE group_id = ?`, "ownership transfers"},
+		{`DELETE FROM group_bans WHERE group_id = ?`, "group bans"},
+		{`DELETE FROM group_mutes WHERE group_id = ?`, "group mutes"},
+		{`DELETE FROM group_invite_links WHERE group_id = ?`, "invite links"},
+		{`DELETE FROM group_members WHERE group_id = ?`, "group members"},
+		{`DELETE FROM groups WHERE id = ?`, "group"},
+		// The log refuses deletes while its group exists
+		{`DELETE FROM group_moderation_log WHERE group_id = ?`, "moderation log"},
+	}
+	for _, stmt := range statements {
+		if _, err = tx.Exec(stmt.query, groupID); err != nil {
+			err = fmt.Errorf("failed to delete %s: %w", stmt.what, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// StartGroupPurgeJob purges the groups past their restore window every interval
+func StartGroupPurgeJob(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if purged, err := DBService.PurgeDeletedGroups(now); err != nil {
+			log.Printf("Group purge failed: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted groups", purged)
+		}
+	}
+}
@@ -11,6 +11,12 @@ import (
 
 // Method to create a new user after he accepted the invitation
 func (s *Service) CreateGroupMember(req models.GroupMember) error {
+	if banned, err := s.IsBannedFromGroup(req.UserID, req.GroupID); err != nil {
+		return err
+	} else if banned {
+		return ErrGroupBanned
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return err
@@ -44,7 +50,7 @@ func (s *Service) GetUserGroups(userID int) ([]string, error) {
 	query := `
 		SELECT g.id FROM groups g
 		JOIN group_members gm ON g.id = gm.group_id
-		WHERE gm.user_id = ?
+		WHERE gm.user_id = ? AND g.status != 'deleted'
 	`
 
 	rows, err := s.DB.Query(query, userID)
@@ -153,8 +159,32 @@ func (s *Service) GetGroupMembers(groupID int64, offset int, userID int64) ([]*m
 	return members, nil
 }
 
-// Method to promote someone to group admin, checking the one making the request is admin itself
+// Method to change the role of a member. The requester needs the manage_roles permission
+// and can only manage members and assign roles below their own.
 func (s *Service) UpdateGroupMemberRole(req models.UpdateGroupMemberRequest, userID int64) error {
+	if req.Role == models.GroupRoleOwner {
+		return errors.New("ownership must be transferred")
+	}
+
+	// Checked before the transaction, the permission lookups use their own connection
+	requesterRole, err := s.GetGroupRole(userID, req.GroupID)
+	if err != nil {
+		return err
+	}
+	canManage := false
+	if requesterRole != "" {
+		canManage, err = s.RoleHasGroupPermission(req.GroupID, requesterRole, models.GroupPermManageRoles)
+		if err != nil {
+			return err
+		}
+	}
+	if !canManage {
+		return errors.New("not authorized: requester cannot manage roles in this group")
+	}
+	if GroupRoleRank(req.Role) >= GroupRoleRank(requesterRole) {
+		return errors.New("cannot assign a role equal to or above your own")
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return err
@@ -167,21 +197,6 @@ func (s *Service) UpdateGroupMemberRole(req models.UpdateGroupMemberRequest, use
 		}
 	}()
 
-	// First, verify that the requester is an admin of the group
-	var requesterIsAdmin bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM group_members 
-			WHERE group_id = ? AND user_id = ? AND role = 'admin'
-		)
-	`, req.GroupID, userID).Scan(&requesterIsAdmin)
-	if err != nil {
-		return err
-	}
-	if !requesterIsAdmin {
-		return errors.New("not authorized: requester is not an admin of this group")
-	}
-
 	// Get current role of the target member and verify they're in the group
 	var currentRole string
 	err = tx.QueryRow(`
@@ -195,17 +210,39 @@ func (s *Service) UpdateGroupMemberRole(req models.UpdateGroupMemberRequest, use
 		return err
 	}
 
+	if GroupRoleRank(currentRole) >= GroupRoleRank(requesterRole) {
+		err = errors.New("cannot change the role of a member at or above your own role")
+		return err
+	}
+
 	// If role is already what we want, no change needed
 	if currentRole == req.Role {
 		return errors.New("member already has the requested role")
 	}
 
-	// If demoting from admin to member, check that there will be at least one admin left
-	if currentRole == "admin" && req.Role == "member" {
+	// Groups can require their admins to use two-factor authentication
+	if req.Role == models.GroupRoleAdmin {
+		var missing2FA bool
+		err = tx.QueryRow(`
+			SELECT g.require_admin_2fa AND NOT EXISTS(
+				SELECT 1 FROM user_two_factor WHERE user_id = ? AND enabled = TRUE
+			)
+			FROM groups g WHERE g.id = ?
+		`, req.MemberID, req.GroupID).Scan(&missing2FA)
+		if err != nil {
+			return err
+		}
+		if missing2FA {
+			return errors.New("member must enable two-factor authentication to become admin")
+		}
+	}
+
+	// Groups created before owners existed may be run by admins only, keep at least one of them
+	if currentRole == models.GroupRoleAdmin {
 		var adminCount int
 		err = tx.QueryRow(`
 			SELECT COUNT(*) FROM group_members 
-			WHERE group_id = ? AND role = 'admin'
+			WHERE group_id = ? AND role IN ('owner', 'admin')
 		`, req.GroupID).Scan(&adminCount)
 		if err != nil {
 			return err
@@ -233,11 +270,29 @@ func (s *Service) UpdateGroupMemberRole(req models.UpdateGroupMemberRequest, use
 		return errors.New("failed to update member role")
 	}
 
-	return nil
+	err = logModeration(tx, req.GroupID, userID, req.MemberID, models.ModerationChangeRole, currentRole+" -> "+req.Role)
+	return err
 }
 
 // LeaveGroup removes a user from a group
 func (s *Service) DeleteGroupMember(request models.LeaveGroupRequest, userID int) error {
+	canRemove := false
+	if int64(userID) != request.UserID {
+		requesterRole, err := s.GetGroupRole(int64(userID), request.GroupID)
+		if err != nil {
+			return err
+		}
+		targetRole, err := s.GetGroupRole(request.UserID, request.GroupID)
+		if err != nil {
+			return err
+		}
+		if requesterRole != "" && GroupRoleRank(targetRole) < GroupRoleRank(requesterRole) {
+			if canRemove, err = s.RoleHasGroupPermission(request.GroupID, requesterRole, models.GroupPermManageRoles); err != nil {
+				return err
+			}
+		}
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return err
@@ -250,77 +305,33 @@ func (s *Service) DeleteGroupMember(request models.LeaveGroupRequest, userID int
 		}
 	}()
 
-	// Check if the user is the group admin
-	var isAdmin bool
+	// Check if the user leaving is the group owner
+	var isOwner bool
 	err = tx.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM group_members 
-			WHERE group_id = ? AND user_id = ? AND role = 'admin'
+			WHERE group_id = ? AND user_id = ? AND role = 'owner'
 		)
-	`, request.GroupID, request.UserID).Scan(&isAdmin)
+	`, request.GroupID, request.UserID).Scan(&isOwner)
 	if err != nil {
-		return fmt.Errorf("failed to check admin status: %w", err)
+		return fmt.Errorf("failed to check owner status: %w", err)
 	}
 
-	// If the user is the admin, delete the entire group
-	if isAdmin {
-		// Delete all group-related data
-		_, err = tx.Exec(`DELETE FROM group_messages WHERE group_id = ?`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group messages: %w", err)
-		}
-
-		_, err = tx.Exec(`DELETE FROM group_comments WHERE group_post_id IN (SELECT id FROM group_posts WHERE group_id = ?)`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group comments: %w", err)
-		}
-
-		_, err = tx.Exec(`DELETE FROM group_posts WHERE group_id = ?`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group posts: %w", err)
-		}
-
-		_, err = tx.Exec(`DELETE FROM group_requests WHERE group_id = ?`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group requests: %w", err)
-		}
-
-		_, err = tx.Exec(`DELETE FROM group_invitations WHERE group_id = ?`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group invitations: %w", err)
-		}
-
-		_, err = tx.Exec(`DELETE FROM group_events WHERE group_id = ?`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group events: %w", err)
-		}
-
-		_, err = tx.Exec(`DELETE FROM group_members WHERE group_id = ?`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group members: %w", err)
-		}
-
-		_, err = tx.Exec(`DELETE FROM groups WHERE id = ?`, request.GroupID)
-		if err != nil {
-			return fmt.Errorf("failed to delete group: %w", err)
-		}
-
-		return nil
+	// Leaving would orphan the group, the owner hands it over or deletes it instead
+	if isOwner && int64(userID) == request.UserID {
+		err = errors.New("owner must transfer ownership before leaving")
+		return err
 	}
 
-	// For non-admin users, just remove them from the group
+	// Members leave by themselves, removing someone else needs manage_roles and a higher role
+	if int64(userID) != request.UserID && !canRemove {
+		err = fmt.Errorf("permission denied or user not found in group")
+		return err
+	}
 	res, err := tx.Exec(`
 		DELETE FROM group_members
 		WHERE group_id = ? AND user_id = ?
-		  AND (
-		    ? = user_id OR EXISTS (
-		      SELECT 1 FROM group_members AS gm_admin
-		      WHERE gm_admin.group_id = group_members.group_id
-		        AND gm_admin.user_id = ?
-		        AND gm_admin.role = 'admin'
-		    )
-		  )
-	`, request.GroupID, request.UserID, userID, userID)
+	`, request.GroupID, request.UserID)
 	if err != nil {
 		return fmt.Errorf("failed to delete group member: %w", err)
 	}
@@ -333,7 +344,10 @@ func (s *Service) DeleteGroupMember(request models.LeaveGroupRequest, userID int
 		return fmt.Errorf("permission denied or user not found in group")
 	}
 
-	return nil
+	if int64(userID) != request.UserID {
+		err = logModeration(tx, request.GroupID, int64(userID), request.UserID, models.ModerationRemoveMember, "")
+	}
+	return err
 }
 
 // IsGroupMember checks if a user is a member of a group
@@ -2,6 +2,8 @@ package db
 
 import (
 	"database/sql"
+
+	"github.com/Golden76z/social-network/models"
 )
 
 // GroupMessage represents a group message in the database
@@ -26,6 +28,18 @@ type GroupMessageWithUser struct {
 }
 
 func (s *Service) CreateGroupMessage(groupID, senderID int, body string) (int64, error) {
+	return s.CreateGroupMessageWithPoll(groupID, senderID, body, nil)
+}
+
+// CreateGroupMessageWithPoll sends a group message, with a poll attached unless poll is nil
+func (s *Service) CreateGroupMessageWithPoll(groupID, senderID int, body string, poll *models.CreatePollRequest) (int64, error) {
+	if err := s.ensureGroupWritable(int64(groupID)); err != nil {
+		return 0, err
+	}
+	if err := s.ensureNotMuted(int64(senderID), int64(groupID)); err != nil {
+		return 0, err
+	}
+
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return 0, err
@@ -51,6 +65,12 @@ func (s *Service) CreateGroupMessage(groupID, senderID int, body string) (int64,
 		return 0, lastIDErr
 	}
 
+	if poll != nil {
+		if err = insertPoll(tx, models.PollTargetGroupMessage, messageID, int64(senderID), poll); err != nil {
+			return 0, err
+		}
+	}
+
 	return messageID, nil
 }
 
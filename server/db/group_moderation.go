@@ -0,0 +1,344 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// ErrGroupMuted is returned when a muted member tries to post, comment or chat
+var ErrGroupMuted = errors.New("user is muted in this group")
+
+// ErrGroupBanned is returned when a banned user would join the group
+var ErrGroupBanned = errors.New("user is banned from this group")
+
+// logModeration appends an entry to the moderation log, targetID 0 means the action has no target user
+func logModeration(tx *sql.Tx, groupID, actorID, targetID int64, action, details string) error {
+	var target any
+	if targetID > 0 {
+		target = targetID
+	}
+	_, err := tx.Exec(`
+		INSERT INTO group_moderation_log (group_id, actor_id, target_user_id, action, details)
+		VALUES (?, ?, ?, ?, ?)`, groupID, actorID, target, action, details)
+	return err
+}
+
+// LogModerationAction records an action done outside of a moderation method, like archiving the group
+func (s *Service) LogModerationAction(groupID, actorID, targetID int64, action, details string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err = logModeration(tx, groupID, actorID, targetID, action, details); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetModerationLog returns the most recent entries of the moderation log of a group
+func (s *Service) GetModerationLog(groupID int64, limit, offset int) ([]models.ModerationLogEntry, error) {
+	rows, err := s.DB.Query(`
+		SELECT l.id, l.group_id, l.actor_id, COALESCE(a.nickname, ''), COALESCE(l.target_user_id, 0),
+		       COALESCE(t.nickname, ''), l.action, COALESCE(l.details, ''), l.created_at
+		FROM group_moderation_log l
+		LEFT JOIN users a ON a.id = l.actor_id
+		LEFT JOIN users t ON t.id = l.target_user_id
+		WHERE l.group_id = ?
+		ORDER BY l.created_at DESC, l.id DESC
+		LIMIT ? OFFSET ?`, groupID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.ModerationLogEntry, 0)
+	for rows.Next() {
+		var e models.ModerationLogEntry
+		var createdAt time.Time
+		if err := rows.Scan(&e.ID, &e.GroupID, &e.ActorID, &e.ActorNickname, &e.TargetUserID,
+			&e.TargetNickname, &e.Action, &e.Details, &createdAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = createdAt.Format(time.RFC3339)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// checkSanction verifies the actor may use permission on the target, who must rank below them
+func (s *Service) checkSanction(groupID, actorID, targetID int64, permission string) error {
+	if actorID == targetID {
+		return errors.New("cannot moderate yourself")
+	}
+	actorRole, err := s.GetGroupRole(actorID, groupID)
+	if err != nil {
+		return err
+	}
+	allowed := false
+	if actorRole != "" {
+		if allowed, err = s.RoleHasGroupPermission(groupID, actorRole, permission); err != nil {
+			return err
+		}
+	}
+	if !allowed {
+		return errors.New("not authorized")
+	}
+
+	targetRole, err := s.GetGroupRole(targetID, groupID)
+	if err != nil {
+		return err
+	}
+	if GroupRoleRank(targetRole) >= GroupRoleRank(actorRole) {
+		return errors.New("cannot moderate a member at or above your own role")
+	}
+	return nil
+}
+
+// BanFromGroup removes the user from the group and keeps them out until expiresAt, nil bans for good.
+// Their pending join requests and invitations are dropped.
+func (s *Service) BanFromGroup(groupID, actorID, userID int64, reason string, expiresAt *time.Time) error {
+	if err := s.checkSanction(groupID, actorID, userID, models.GroupPermBanMembers); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var expiry any
+	details := "permanent"
+	if expiresAt != nil {
+		expiry = expiresAt.UTC()
+		details = "until " + expiresAt.UTC().Format(time.RFC3339)
+	}
+	if reason != "" {
+		details += ": " + reason
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO group_bans (group_id, user_id, banned_by, reason, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(group_id, user_id) DO UPDATE SET
+			banned_by = excluded.banned_by, reason = excluded.reason,
+			expires_at = excluded.expires_at, created_at = CURRENT_TIMESTAMP`,
+		groupID, userID, actorID, reason, expiry)
+	if err != nil {
+		return err
+	}
+
+	cleanup := []string{
+		`DELETE FROM group_members WHERE group_id = ? AND user_id = ?`,
+		`DELETE FROM group_requests WHERE group_id = ? AND user_id = ? AND status = 'pending'`,
+		`DELETE FROM group_invitations WHERE group_id = ? AND invited_user_id = ? AND status = 'pending'`,
+		`DELETE FROM group_mutes WHERE group_id = ? AND user_id = ?`,
+	}
+	for _, query := range cleanup {
+		if _, err = tx.Exec(query, groupID, userID); err != nil {
+			return err
+		}
+	}
+
+	err = logModeration(tx, groupID, actorID, userID, models.ModerationBan, details)
+	return err
+}
+
+// UnbanFromGroup lifts a ban before it expires
+func (s *Service) UnbanFromGroup(groupID, actorID, userID int64) error {
+	if err := s.checkSanction(groupID, actorID, userID, models.GroupPermBanMembers); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	res, err := tx.Exec(`DELETE FROM group_bans WHERE group_id = ? AND user_id = ?`, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("user is not banned")
+		return err
+	}
+
+	err = logModeration(tx, groupID, actorID, userID, models.ModerationUnban, "")
+	return err
+}
+
+// MuteInGroup stops a member from posting, commenting and chatting until expiresAt
+func (s *Service) MuteInGroup(groupID, actorID, userID int64, reason string, expiresAt time.Time) error {
+	if err := s.checkSanction(groupID, actorID, userID, models.GroupPermMuteMembers); err != nil {
+		return err
+	}
+	if role, err := s.GetGroupRole(userID, groupID); err != nil {
+		return err
+	} else if role == "" {
+		return errors.New("user is not a group member")
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	_, err = tx.Exec(`
+		INSERT INTO group_mutes (group_id, user_id, muted_by, reason, expires_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(group_id, user_id) DO UPDATE SET
+			muted_by = excluded.muted_by, reason = excluded.reason,
+			expires_at = excluded.expires_at, created_at = CURRENT_TIMESTAMP`,
+		groupID, userID, actorID, reason, expiresAt.UTC())
+	if err != nil {
+		return err
+	}
+
+	details := "until " + expiresAt.UTC().Format(time.RFC3339)
+	if reason != "" {
+		details += ": " + reason
+	}
+	err = logModeration(tx, groupID, actorID, userID, models.ModerationMute, details)
+	return err
+}
+
+// UnmuteInGroup lifts a mute before it expires
+func (s *Service) UnmuteInGroup(groupID, actorID, userID int64) error {
+	if err := s.checkSanction(groupID, actorID, userID, models.GroupPermMuteMembers); err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	res, err := tx.Exec(`DELETE FROM group_mutes WHERE group_id = ? AND user_id = ? AND expires_at > ?`,
+		groupID, userID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("user is not muted")
+		return err
+	}
+
+	err = logModeration(tx, groupID, actorID, userID, models.ModerationUnmute, "")
+	return err
+}
+
+// IsBannedFromGroup reports whether the user has an active ban in the group
+func (s *Service) IsBannedFromGroup(userID, groupID int64) (bool, error) {
+	var banned bool
+	err := s.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM group_bans
+			WHERE group_id = ? AND user_id = ? AND (expires_at IS NULL OR expires_at > ?)
+		)`, groupID, userID, time.Now().UTC()).Scan(&banned)
+	return banned, err
+}
+
+// GetGroupMuteExpiry returns when the mute of the user ends, nil when they are not muted
+func (s *Service) GetGroupMuteExpiry(userID, groupID int64) (*time.Time, error) {
+	var expiresAt time.Time
+	err := s.DB.QueryRow(`
+		SELECT expires_at FROM group_mutes
+		WHERE group_id = ? AND user_id = ? AND expires_at > ?`, groupID, userID, time.Now().UTC()).Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &expiresAt, nil
+}
+
+// ensureNotMuted refuses posts, comments and messages of muted members
+func (s *Service) ensureNotMuted(userID, groupID int64) error {
+	expiresAt, err := s.GetGroupMuteExpiry(userID, groupID)
+	if err != nil {
+		return err
+	}
+	if expiresAt != nil {
+		return ErrGroupMuted
+	}
+	return nil
+}
+
+// GetGroupBans returns the active bans of a group
+func (s *Service) GetGroupBans(groupID int64) ([]models.GroupSanction, error) {
+	return s.queryGroupSanctions(`
+		SELECT b.group_id, b.user_id, COALESCE(u.nickname, ''), COALESCE(b.banned_by, 0), COALESCE(b.reason, ''),
+		       b.created_at, b.expires_at
+		FROM group_bans b
+		LEFT JOIN users u ON u.id = b.user_id
+		WHERE b.group_id = ? AND (b.expires_at IS NULL OR b.expires_at > ?)
+		ORDER BY b.created_at DESC`, groupID)
+}
+
+// GetGroupMutes returns the active mutes of a group
+func (s *Service) GetGroupMutes(groupID int64) ([]models.GroupSanction, error) {
+	return s.queryGroupSanctions(`
+		SELECT m.group_id, m.user_id, COALESCE(u.nickname, ''), COALESCE(m.muted_by, 0), COALESCE(m.reason, ''),
+		       m.created_at, m.expires_at
+		FROM group_mutes m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = ? AND m.expires_at > ?
+		ORDER BY m.created_at DESC`, groupID)
+}
+
+func (s *Service) queryGroupSanctions(query string, groupID int64) ([]models.GroupSanction, error) {
+	rows, err := s.DB.Query(query, groupID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sanctions := make([]models.GroupSanction, 0)
+	for rows.Next() {
+		var sanction models.GroupSanction
+		var createdAt time.Time
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&sanction.GroupID, &sanction.UserID, &sanction.UserNickname, &sanction.ByID,
+			&sanction.Reason, &createdAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("scan sanction: %w", err)
+		}
+		sanction.CreatedAt = createdAt.Format(time.RFC3339)
+		if expiresAt.Valid {
+			sanction.ExpiresAt = expiresAt.Time.Format(time.RFC3339)
+		}
+		sanctions = append(sanctions, sanction)
+	}
+	return sanctions, rows.Err()
+}
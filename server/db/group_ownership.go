@@ -0,0 +1,222 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// OwnershipTransferTTL is how long the new owner has to accept a transfer
+const OwnershipTransferTTL = 7 * 24 * time.Hour
+
+// CreateOwnershipTransfer asks toUserID to become the owner of the group, replacing any pending request
+func (s *Service) CreateOwnershipTransfer(groupID, fromUserID, toUserID int64) (int64, error) {
+	if fromUserID == toUserID {
+		return 0, errors.New("cannot transfer ownership to yourself")
+	}
+
+	status, err := s.GetGroupStatus(groupID)
+	if err != nil {
+		return 0, err
+	}
+	if status == "" || status == models.GroupStatusDeleted {
+		return 0, errors.New("group does not exist")
+	}
+
+	fromRole, err := s.GetGroupRole(fromUserID, groupID)
+	if err != nil {
+		return 0, err
+	}
+	if fromRole != models.GroupRoleOwner {
+		return 0, errors.New("only the owner can transfer ownership")
+	}
+	toRole, err := s.GetGroupRole(toUserID, groupID)
+	if err != nil {
+		return 0, err
+	}
+	if toRole == "" {
+		return 0, errors.New("new owner must be a group member")
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	if err = checkNewOwner2FA(tx, groupID, toUserID); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE group_ownership_transfers SET status = 'cancelled', responded_at = CURRENT_TIMESTAMP
+		WHERE group_id = ? AND status = 'pending'`, groupID)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO group_ownership_transfers (group_id, from_user_id, to_user_id, expires_at)
+		VALUES (?, ?, ?, ?)`, groupID, fromUserID, toUserID, time.Now().UTC().Add(OwnershipTransferTTL))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetOwnershipTransferByID returns a transfer, nil when it does not exist
+func (s *Service) GetOwnershipTransferByID(id int64) (*models.GroupOwnershipTransfer, error) {
+	transfers, err := s.queryOwnershipTransfers(`WHERE t.id = ?`, id)
+	if err != nil || len(transfers) == 0 {
+		return nil, err
+	}
+	return &transfers[0], nil
+}
+
+// GetPendingOwnershipTransfers returns the unexpired transfers the user sent or must answer
+func (s *Service) GetPendingOwnershipTransfers(userID int64) ([]models.GroupOwnershipTransfer, error) {
+	return s.queryOwnershipTransfers(`
+		WHERE (t.from_user_id = ? OR t.to_user_id = ?) AND t.status = 'pending' AND t.expires_at > ?
+		ORDER BY t.created_at DESC`, userID, userID, time.Now().UTC())
+}
+
+func (s *Service) queryOwnershipTransfers(where string, args ...any) ([]models.GroupOwnershipTransfer, error) {
+	rows, err := s.DB.Query(`
+		SELECT t.id, t.group_id, g.title, t.from_user_id, t.to_user_id, t.status, t.created_at, t.expires_at, t.responded_at
+		FROM group_ownership_transfers t
+		JOIN groups g ON g.id = t.group_id `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := make([]models.GroupOwnershipTransfer, 0)
+	for rows.Next() {
+		var t models.GroupOwnershipTransfer
+		var createdAt, expiresAt time.Time
+		var respondedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.GroupID, &t.GroupTitle, &t.FromUserID, &t.ToUserID, &t.Status,
+			&createdAt, &expiresAt, &respondedAt); err != nil {
+			return nil, err
+		}
+		t.CreatedAt = createdAt.Format(time.RFC3339)
+		t.ExpiresAt = expiresAt.Format(time.RFC3339)
+		if respondedAt.Valid {
+			t.RespondedAt = respondedAt.Time.Format(time.RFC3339)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// RespondOwnershipTransfer lets the recipient accept or decline a pending transfer.
+// Accepting makes them the owner and the previous owner an admin.
+func (s *Service) RespondOwnershipTransfer(id, userID int64, accept bool) (*models.GroupOwnershipTransfer, error) {
+	transfer, err := s.GetOwnershipTransferByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if transfer == nil || transfer.ToUserID != userID {
+		return nil, errors.New("transfer not found")
+	}
+	if transfer.Status != models.OwnershipTransferPending {
+		return nil, errors.New("transfer is no longer pending")
+	}
+	if expiresAt, _ := time.Parse(time.RFC3339, transfer.ExpiresAt); time.Now().After(expiresAt) {
+		return nil, errors.New("transfer has expired")
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	status := models.OwnershipTransferDeclined
+	if accept {
+		status = models.OwnershipTransferAccepted
+	}
+
+	// Answered first, so a transfer cancelled or replaced meanwhile changes nothing
+	var res sql.Result
+	res, err = tx.Exec(`
+		UPDATE group_ownership_transfers SET status = ?, responded_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = 'pending'`, status, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("transfer is no longer pending")
+		return nil, err
+	}
+
+	if accept {
+		if err = checkNewOwner2FA(tx, transfer.GroupID, transfer.ToUserID); err != nil {
+			return nil, err
+		}
+
+		// The sender must still own the group and the recipient still be in it
+		res, err = tx.Exec(`
+			UPDATE group_members SET role = 'admin'
+			WHERE group_id = ? AND user_id = ? AND role = 'owner'`, transfer.GroupID, transfer.FromUserID)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = errors.New("transfer is no longer valid")
+			return nil, err
+		}
+		res, err = tx.Exec(`
+			UPDATE group_members SET role = 'owner'
+			WHERE group_id = ? AND user_id = ?`, transfer.GroupID, transfer.ToUserID)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			err = errors.New("transfer is no longer valid")
+			return nil, err
+		}
+		_, err = tx.Exec(`UPDATE groups SET creator_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			transfer.ToUserID, transfer.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		err = logModeration(tx, transfer.GroupID, transfer.FromUserID, transfer.ToUserID, models.ModerationTransferOwnership, "accepted")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transfer.Status = status
+	return transfer, nil
+}
+
+// CancelOwnershipTransfer withdraws a pending transfer, only its sender can
+func (s *Service) CancelOwnershipTransfer(id, userID int64) (*models.GroupOwnershipTransfer, error) {
+	res, err := s.DB.Exec(`
+		UPDATE group_ownership_transfers SET status = 'cancelled', responded_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND from_user_id = ? AND status = 'pending'`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, errors.New("transfer not found")
+	}
+	return s.GetOwnershipTransferByID(id)
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// ErrCommentsClosed is returned when commenting on an announcement
+var ErrCommentsClosed = errors.New("comments are closed on announcements")
+
+// ErrPostNotPublished is returned when pinning or announcing a post still waiting for or refused by review
+var ErrPostNotPublished = errors.New("post is not published")
+
+// checkGroupPostAction returns the group of the post once the user may use permission on it
+func (s *Service) checkGroupPostAction(postID, userID int64, permission string) (int64, error) {
+	groupID, err := s.GetGroupIDFromPost(postID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.ensureGroupWritable(groupID); err != nil {
+		return 0, err
+	}
+	if status, err := s.GetGroupPostStatus(postID); err != nil {
+		return 0, err
+	} else if status != models.GroupPostPublished {
+		// Only its author and the moderators know the post exists
+		var authorID int64
+		if err := s.DB.QueryRow(`SELECT user_id FROM group_posts WHERE id = ?`, postID).Scan(&authorID); err != nil {
+			return 0, err
+		}
+		canReview, err := s.HasGroupPermission(userID, groupID, models.GroupPermApprovePosts)
+		if err != nil {
+			return 0, err
+		}
+		if authorID != userID && !canReview {
+			return 0, errors.New("post not found")
+		}
+		return 0, ErrPostNotPublished
+	}
+	allowed, err := s.HasGroupPermission(userID, groupID, permission)
+	if err != nil {
+		return 0, err
+	}
+	if !allowed {
+		return 0, errors.New("not authorized")
+	}
+	return groupID, nil
+}
+
+// PinGroupPost lists a post ahead of the others until expiresAt, nil keeps it pinned until unpinned
+func (s *Service) PinGroupPost(postID, userID int64, expiresAt *time.Time) error {
+	groupID, err := s.checkGroupPostAction(postID, userID, models.GroupPermPin)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var expiry any
+	details := fmt.Sprintf("post %d", postID)
+	if expiresAt != nil {
+		expiry = expiresAt.UTC()
+		details += " until " + expiresAt.UTC().Format(time.RFC3339)
+	}
+	_, err = tx.Exec(`
+		UPDATE group_posts SET pinned_at = ?, pinned_by = ?, pin_expires_at = ?
+		WHERE id = ?`, time.Now().UTC(), userID, expiry, postID)
+	if err != nil {
+		return err
+	}
+
+	err = logModeration(tx, groupID, userID, 0, models.ModerationPinPost, details)
+	return err
+}
+
+// UnpinGroupPost puts a pinned post back in the chronological order
+func (s *Service) UnpinGroupPost(postID, userID int64) error {
+	groupID, err := s.checkGroupPostAction(postID, userID, models.GroupPermPin)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	res, err := tx.Exec(`
+		UPDATE group_posts SET pinned_at = NULL, pinned_by = NULL, pin_expires_at = NULL
+		WHERE id = ? AND pinned_at IS NOT NULL`, postID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("post is not pinned")
+		return err
+	}
+
+	err = logModeration(tx, groupID, userID, 0, models.ModerationUnpinPost, fmt.Sprintf("post %d", postID))
+	return err
+}
+
+// MarkGroupPostAnnouncement turns an existing post into an announcement, closing its comments
+func (s *Service) MarkGroupPostAnnouncement(postID, userID int64) error {
+	groupID, err := s.checkGroupPostAction(postID, userID, models.GroupPermAnnounce)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	res, err := tx.Exec(`UPDATE group_posts SET is_announcement = TRUE WHERE id = ? AND NOT is_announcement`, postID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("post is already an announcement")
+		return err
+	}
+
+	err = logModeration(tx, groupID, userID, 0, models.ModerationAnnouncePost, fmt.Sprintf("post %d", postID))
+	return err
+}
+
+// IsGroupPostAnnouncement reports whether comments are closed on the post because it is an announcement
+func (s *Service) IsGroupPostAnnouncement(postID int64) (bool, error) {
+	var announcement bool
+	err := s.DB.QueryRow(`SELECT COALESCE((SELECT is_announcement FROM group_posts WHERE id = ?), FALSE)`, postID).Scan(&announcement)
+	return announcement, err
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// newGroupPostStatus is pending when the group reviews posts and role cannot approve them
+func (s *Service) newGroupPostStatus(groupID int64, role string) (string, error) {
+	var required bool
+	err := s.DB.QueryRow(`SELECT require_post_approval FROM groups WHERE id = ?`, groupID).Scan(&required)
+	if err != nil {
+		return "", err
+	}
+	if !required {
+		return models.GroupPostPublished, nil
+	}
+	canApprove, err := s.RoleHasGroupPermission(groupID, role, models.GroupPermApprovePosts)
+	if err != nil {
+		return "", err
+	}
+	if canApprove {
+		return models.GroupPostPublished, nil
+	}
+	return models.GroupPostPending, nil
+}
+
+// GetGroupPostStatus returns the review state of a post
+func (s *Service) GetGroupPostStatus(postID int64) (string, error) {
+	var status string
+	err := s.DB.QueryRow(`SELECT status FROM group_posts WHERE id = ?`, postID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", errors.New("post not found")
+	}
+	return status, err
+}
+
+// GetGroupPostQueue returns the posts of the group waiting for review, oldest first.
+// Moderators get every pending post, other members their own pending and rejected posts.
+func (s *Service) GetGroupPostQueue(groupID, userID int64) ([]*models.GroupPost, error) {
+	isMember, err := s.IsUserInGroup(userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.New("user is not a member of the group")
+	}
+	canReview, err := s.HasGroupPermission(userID, groupID, models.GroupPermApprovePosts)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	where := `gp.user_id = ? AND gp.status IN ('pending', 'rejected')`
+	if canReview {
+		where = `(gp.status = 'pending' OR (gp.user_id = ? AND gp.status = 'rejected'))`
+	}
+	posts, err := queryGroupPosts(tx, time.Now().UTC(), `
+        SELECT `+groupPostColumns+`
+        FROM group_posts gp
+        JOIN users u ON gp.user_id = u.id
+        WHERE gp.group_id = ? AND `+where+`
+        ORDER BY gp.created_at ASC`, userID, userID, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if posts == nil {
+		posts = []*models.GroupPost{}
+	}
+	return posts, nil
+}
+
+// ReviewGroupPost publishes or rejects a pending post. It returns the reviewed post.
+func (s *Service) ReviewGroupPost(postID, reviewerID int64, approve bool, reason string) (*models.GroupPost, error) {
+	groupID, err := s.GetGroupIDFromPost(postID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureGroupWritable(groupID); err != nil {
+		return nil, err
+	}
+	canReview, err := s.HasGroupPermission(reviewerID, groupID, models.GroupPermApprovePosts)
+	if err != nil {
+		return nil, err
+	}
+	if !canReview {
+		return nil, errors.New("not authorized")
+	}
+	if !approve && reason == "" {
+		return nil, errors.New("a rejection needs a reason")
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	status, action, details := models.GroupPostPublished, models.ModerationApprovePost, fmt.Sprintf("post %d", postID)
+	var rejection any
+	if !approve {
+		status, action, rejection = models.GroupPostRejected, models.ModerationRejectPost, reason
+		details += ": " + reason
+	}
+
+	// Approved posts take their place in the feed as of their publication
+	res, err := tx.Exec(`
+		UPDATE group_posts
+		SET status = ?, reviewed_by = ?, reviewed_at = CURRENT_TIMESTAMP, rejection_reason = ?,
+		    created_at = CASE WHEN ? = 'published' THEN CURRENT_TIMESTAMP ELSE created_at END
+		WHERE id = ? AND status = 'pending'`, status, reviewerID, rejection, status, postID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = errors.New("post is not pending")
+		return nil, err
+	}
+
+	post, err := scanGroupPost(tx.QueryRow(`
+        SELECT `+groupPostColumns+`
+        FROM group_posts gp
+        JOIN users u ON gp.user_id = u.id
+        WHERE gp.id = ?`, reviewerID, reviewerID, postID), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	err = logModeration(tx, groupID, reviewerID, post.UserID, action, details)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
@@ -3,55 +3,41 @@ package db
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/Golden76z/social-network/models"
 )
 
 func (s *Service) GroupExists(groupID int64) (bool, error) {
 	var exists bool
-	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM groups WHERE id = ?)`, groupID).Scan(&exists)
+	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM groups WHERE id = ? AND status != 'deleted')`, groupID).Scan(&exists)
 	return exists, err
 }
 
 func (s *Service) IsUserInGroup(userID, groupID int64) (bool, error) {
 	var exists bool
-	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM group_members WHERE user_id = ? AND group_id = ?)`, userID, groupID).Scan(&exists)
+	err := s.DB.QueryRow(`SELECT EXISTS(
+		SELECT 1 FROM group_members gm JOIN groups g ON g.id = gm.group_id
+		WHERE gm.user_id = ? AND gm.group_id = ? AND g.status != 'deleted')`, userID, groupID).Scan(&exists)
 	return exists, err
 }
 
-func (s *Service) IsUserGroupAdmin(userID, groupID int64) (bool, error) {
-	var isAdmin bool
-	// In schema, group creator is the admin/owner; column is likely creator_id
-	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM groups WHERE id = ? AND creator_id = ?)`, groupID, userID).Scan(&isAdmin)
-	return isAdmin, err
-}
-
 func (s *Service) IsPostOwner(userID, postID int64) (bool, error) {
 	var exists bool
 	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM group_posts WHERE id = ? AND user_id = ?)`, postID, userID).Scan(&exists)
 	return exists, err
 }
 
-func (s *Service) CreateGroupPost(request models.CreateGroupPostRequest, userID int64) error {
-	exists, err := s.GroupExists(request.GroupID)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return errors.New("group does not exist")
-	}
-
-	isMember, err := s.IsUserInGroup(userID, request.GroupID)
+func (s *Service) CreateGroupPost(request models.CreateGroupPostRequest, userID int64) (int64, error) {
+	status, err := s.groupPostStatusFor(request, userID)
 	if err != nil {
-		return err
-	}
-	if !isMember {
-		return errors.New("user is not a member of the group")
+		return 0, err
 	}
 
 	tx, err := s.DB.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer func() {
 		if err != nil {
@@ -61,32 +47,163 @@ func (s *Service) CreateGroupPost(request models.CreateGroupPostRequest, userID
 		}
 	}()
 
+	postID, err := insertGroupPost(tx, request, userID, status)
+	return postID, err
+}
+
+// groupPostStatusFor checks the author may post the request in its group and returns the status of the new post
+func (s *Service) groupPostStatusFor(request models.CreateGroupPostRequest, userID int64) (string, error) {
+	exists, err := s.GroupExists(request.GroupID)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", errors.New("group does not exist")
+	}
+
+	role, err := s.GetGroupRole(userID, request.GroupID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", errors.New("user is not a member of the group")
+	}
+	canPost, err := s.RoleHasGroupPermission(request.GroupID, role, models.GroupPermPost)
+	if err != nil {
+		return "", err
+	}
+	if canPost && request.IsAnnouncement {
+		canPost, err = s.RoleHasGroupPermission(request.GroupID, role, models.GroupPermAnnounce)
+		if err != nil {
+			return "", err
+		}
+	}
+	if !canPost {
+		return "", errors.New("not authorized")
+	}
+	return s.newGroupPostStatus(request.GroupID, role)
+}
+
+// insertGroupPost creates a group post with its images and poll in the transaction
+func insertGroupPost(tx *sql.Tx, request models.CreateGroupPostRequest, userID int64, status string) (int64, error) {
 	result, err := tx.Exec(`
-		INSERT INTO group_posts (group_id, user_id, title, body)
-		VALUES (?, ?, ?, ?)`, request.GroupID, userID, request.Title, request.Body)
+		INSERT INTO group_posts (group_id, user_id, title, body, is_announcement, status)
+		VALUES (?, ?, ?, ?, ?, ?)`, request.GroupID, userID, request.Title, request.Body, request.IsAnnouncement, status)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	postID, err := result.LastInsertId()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Insert images if any
 	for _, imageURL := range request.Images {
-		_, err := tx.Exec(`
+		_, err = tx.Exec(`
 			INSERT INTO post_images (post_id, is_group_post, image_url)
 			VALUES (?, ?, ?)`,
 			postID, true, imageURL)
 		if err != nil {
-			return err
+			return 0, err
+		}
+	}
+
+	if request.Poll != nil {
+		if err = insertPoll(tx, models.PollTargetGroupPost, postID, userID, request.Poll); err != nil {
+			return 0, err
+		}
+	}
+
+	return postID, nil
+}
+
+// groupPostColumns are the columns scanned by scanGroupPost, the two user ID arguments come first
+const groupPostColumns = `
+            gp.id, 
+            gp.user_id, 
+            gp.title, 
+            gp.body, 
+            gp.created_at, 
+            gp.updated_at,
+            u.nickname,
+            u.first_name,
+            u.last_name,
+            u.avatar,
+            (SELECT COUNT(*) FROM likes_dislikes WHERE group_post_id = gp.id AND type = 'like') AS likes,
+            (SELECT COUNT(*) FROM likes_dislikes WHERE group_post_id = gp.id AND type = 'dislike') AS dislikes,
+            EXISTS(SELECT 1 FROM likes_dislikes WHERE group_post_id = gp.id AND user_id = ? AND type = 'like') AS user_liked,
+            EXISTS(SELECT 1 FROM likes_dislikes WHERE group_post_id = gp.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+            (SELECT COUNT(*) FROM content_revisions WHERE group_post_id = gp.id) AS revision_count,
+            gp.is_announcement,
+            gp.pinned_at,
+            gp.pin_expires_at,
+            gp.status,
+            COALESCE(gp.rejection_reason, '')`
+
+// activePin matches the posts pinned now, it takes the current time as argument
+const activePin = `gp.pinned_at IS NOT NULL AND (gp.pin_expires_at IS NULL OR gp.pin_expires_at > ?)`
+
+// scanGroupPost reads a row selected with groupPostColumns
+func scanGroupPost(row interface{ Scan(...any) error }, now time.Time) (*models.GroupPost, error) {
+	var gp models.GroupPost
+	var userLikedInt, userDislikedInt int
+	var nickname, firstName, lastName, avatar sql.NullString
+	var pinnedAt, pinExpiresAt sql.NullTime
+	err := row.Scan(&gp.ID, &gp.UserID, &gp.Title, &gp.Body, &gp.CreatedAt, &gp.UpdatedAt,
+		&nickname, &firstName, &lastName, &avatar,
+		&gp.Likes, &gp.Dislikes, &userLikedInt, &userDislikedInt, &gp.RevisionCount,
+		&gp.IsAnnouncement, &pinnedAt, &pinExpiresAt, &gp.Status, &gp.RejectionReason)
+	if err != nil {
+		return nil, err
+	}
+	gp.Visibility = "public"
+	gp.UserLiked = userLikedInt == 1
+	gp.UserDisliked = userDislikedInt == 1
+	gp.Edited = gp.RevisionCount > 0
+
+	// Set user information
+	gp.AuthorNickname = nickname.String
+	gp.AuthorFirstName = firstName.String
+	gp.AuthorLastName = lastName.String
+	gp.AuthorAvatar = avatar.String
+
+	// An expired pin is left in place and simply ignored
+	if pinnedAt.Valid && (!pinExpiresAt.Valid || pinExpiresAt.Time.After(now)) {
+		gp.Pinned = true
+		gp.PinnedAt = pinnedAt.Time.Format(time.RFC3339)
+		if pinExpiresAt.Valid {
+			gp.PinExpiresAt = pinExpiresAt.Time.Format(time.RFC3339)
 		}
 	}
+	return &gp, nil
+}
+
+// getGroupPostImages returns the images of a group post
+func getGroupPostImages(q interface {
+	Query(string, ...any) (*sql.Rows, error)
+}, postID int64) ([]string, error) {
+	rows, err := q.Query(`
+		SELECT image_url FROM post_images
+		WHERE post_id = ? AND is_group_post = 1`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	return nil
+	var images []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		images = append(images, url)
+	}
+	return images, rows.Err()
 }
 
+// GetGroupPostsWithImagesByGroupID returns a page of the published posts of a group, newest first.
+// The first page starts with the pinned posts, which the pages then leave out.
 func (s *Service) GetGroupPostsWithImagesByGroupID(groupID int64, offset int, userID int64) ([]*models.GroupPost, error) {
 	// Checking if the group id is valid
 	exists, err := s.GroupExists(groupID)
@@ -100,16 +217,12 @@ func (s *Service) GetGroupPostsWithImagesByGroupID(groupID int64, offset int, us
 		return nil, errors.New("group does not exist")
 	}
 
-	// Checking if the user making the request is part of the group or admin
+	// Checking if the user making the request is part of the group
 	isMember, err := s.IsUserInGroup(userID, groupID)
 	if err != nil {
 		return nil, err
 	}
-	isAdmin, err := s.IsUserGroupAdmin(userID, groupID)
-	if err != nil {
-		return nil, err
-	}
-	if !isMember && !isAdmin {
+	if !isMember {
 		return nil, errors.New("user is not a member of the group")
 	}
 
@@ -126,28 +239,37 @@ func (s *Service) GetGroupPostsWithImagesByGroupID(groupID int64, offset int, us
 		}
 	}()
 
-	rows, err := tx.Query(`
-        SELECT 
-            gp.id, 
-            gp.user_id, 
-            gp.title, 
-            gp.body, 
-            gp.created_at, 
-            gp.updated_at,
-            u.nickname,
-            u.first_name,
-            u.last_name,
-            u.avatar,
-            (SELECT COUNT(*) FROM likes_dislikes WHERE group_post_id = gp.id AND type = 'like') AS likes,
-            (SELECT COUNT(*) FROM likes_dislikes WHERE group_post_id = gp.id AND type = 'dislike') AS dislikes,
-            EXISTS(SELECT 1 FROM likes_dislikes WHERE group_post_id = gp.id AND user_id = ? AND type = 'like') AS user_liked,
-            EXISTS(SELECT 1 FROM likes_dislikes WHERE group_post_id = gp.id AND user_id = ? AND type = 'dislike') AS user_disliked
+	now := time.Now().UTC()
+	var posts []*models.GroupPost
+	if offset == 0 {
+		posts, err = queryGroupPosts(tx, now, `
+        SELECT `+groupPostColumns+`
         FROM group_posts gp
         JOIN users u ON gp.user_id = u.id
-        WHERE gp.group_id = ?
+        WHERE gp.group_id = ? AND gp.status = 'published' AND `+activePin+`
+        ORDER BY gp.pinned_at DESC`, userID, userID, groupID, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := queryGroupPosts(tx, now, `
+        SELECT `+groupPostColumns+`
+        FROM group_posts gp
+        JOIN users u ON gp.user_id = u.id
+        WHERE gp.group_id = ? AND gp.status = 'published' AND NOT (`+activePin+`)
         ORDER BY gp.created_at DESC
         LIMIT 20 OFFSET ?
-    `, userID, userID, groupID, offset)
+    `, userID, userID, groupID, now, offset)
+	if err != nil {
+		return nil, err
+	}
+	return append(posts, page...), nil
+}
+
+// queryGroupPosts runs a query selecting groupPostColumns and loads the images of each post
+func queryGroupPosts(tx *sql.Tx, now time.Time, query string, args ...any) ([]*models.GroupPost, error) {
+	rows, err := tx.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -156,53 +278,21 @@ func (s *Service) GetGroupPostsWithImagesByGroupID(groupID int64, offset int, us
 	// Ranging over the group post
 	var posts []*models.GroupPost
 	for rows.Next() {
-		var gp models.GroupPost
-		var userLikedInt, userDislikedInt int
-		var nickname, firstName, lastName, avatar sql.NullString
-		err = rows.Scan(&gp.ID, &gp.UserID, &gp.Title, &gp.Body, &gp.CreatedAt, &gp.UpdatedAt,
-			&nickname, &firstName, &lastName, &avatar,
-			&gp.Likes, &gp.Dislikes, &userLikedInt, &userDislikedInt)
+		gp, err := scanGroupPost(rows, now)
 		if err != nil {
 			return nil, err
 		}
-		gp.Visibility = "public"
-		gp.UserLiked = userLikedInt == 1
-		gp.UserDisliked = userDislikedInt == 1
-
-		// Set user information
-		if nickname.Valid {
-			gp.AuthorNickname = nickname.String
-		}
-		if firstName.Valid {
-			gp.AuthorFirstName = firstName.String
-		}
-		if lastName.Valid {
-			gp.AuthorLastName = lastName.String
-		}
-		if avatar.Valid {
-			gp.AuthorAvatar = avatar.String
-		}
+		posts = append(posts, gp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-		imageRows, err := tx.Query(`
-			SELECT image_url FROM post_images
-			WHERE post_id = ? AND is_group_post = 1`, gp.ID)
-		if err != nil {
+	for _, gp := range posts {
+		if gp.Images, err = getGroupPostImages(tx, gp.ID); err != nil {
 			return nil, err
 		}
-
-		// Ranging over the images of the post
-		var images []string
-		for imageRows.Next() {
-			var url string
-			if err = imageRows.Scan(&url); err != nil {
-				imageRows.Close()
-				return nil, err
-			}
-			images = append(images, url)
-		}
-		imageRows.Close()
-		gp.Images = images
-		posts = append(posts, &gp)
 	}
 	return posts, nil
 }
@@ -217,82 +307,41 @@ func (s *Service) GetGroupPostWithImagesByID(postID, userID int64) (*models.Grou
 		return nil, err
 	}
 
-	// Check if user is a member or admin of the group
+	// Check if user is a member of the group
 	isMember, err := s.IsUserInGroup(userID, groupID)
 	if err != nil {
 		return nil, err
 	}
-	isAdmin, err := s.IsUserGroupAdmin(userID, groupID)
-	if err != nil {
-		return nil, err
-	}
-	if !isMember && !isAdmin {
+	if !isMember {
 		return nil, errors.New("user is not a member of the group")
 	}
 
 	// Get post details with likes/dislikes and user information
-	var gp models.GroupPost
-	var userLikedInt, userDislikedInt int
-	var nickname, firstName, lastName string
-	var avatar sql.NullString
-	err = s.DB.QueryRow(`
-        SELECT 
-            gp.id, 
-            gp.user_id, 
-            gp.title, 
-            gp.body, 
-            gp.created_at, 
-            gp.updated_at,
-            u.nickname,
-            u.first_name,
-            u.last_name,
-            u.avatar,
-            (SELECT COUNT(*) FROM likes_dislikes WHERE group_post_id = gp.id AND type = 'like') AS likes,
-            (SELECT COUNT(*) FROM likes_dislikes WHERE group_post_id = gp.id AND type = 'dislike') AS dislikes,
-            EXISTS(SELECT 1 FROM likes_dislikes WHERE group_post_id = gp.id AND user_id = ? AND type = 'like') AS user_liked,
-            EXISTS(SELECT 1 FROM likes_dislikes WHERE group_post_id = gp.id AND user_id = ? AND type = 'dislike') AS user_disliked
+	gp, err := scanGroupPost(s.DB.QueryRow(`
+        SELECT `+groupPostColumns+`
         FROM group_posts gp
         JOIN users u ON gp.user_id = u.id
-        WHERE gp.id = ?`, userID, userID, postID).Scan(&gp.ID, &gp.UserID, &gp.Title, &gp.Body, &gp.CreatedAt, &gp.UpdatedAt,
-		&nickname, &firstName, &lastName, &avatar,
-		&gp.Likes, &gp.Dislikes, &userLikedInt, &userDislikedInt)
+        WHERE gp.id = ?`, userID, userID, postID), time.Now().UTC())
 	if err != nil {
 		return nil, err
 	}
-	gp.Visibility = "public"
-	gp.UserLiked = userLikedInt == 1
-	gp.UserDisliked = userDislikedInt == 1
 
-	// Set user information
-	gp.AuthorNickname = nickname
-	gp.AuthorFirstName = firstName
-	gp.AuthorLastName = lastName
-	if avatar.Valid {
-		gp.AuthorAvatar = avatar.String
-	} else {
-		gp.AuthorAvatar = ""
+	// Posts waiting for or refused by review are only shown to their author and the moderators
+	if gp.Status != models.GroupPostPublished && gp.UserID != userID {
+		canReview, err := s.HasGroupPermission(userID, groupID, models.GroupPermApprovePosts)
+		if err != nil {
+			return nil, err
+		}
+		if !canReview {
+			return nil, errors.New("post not found")
+		}
 	}
 
 	// Get post images
-	rows, err := s.DB.Query(`
-        SELECT image_url FROM post_images
-        WHERE post_id = ? AND is_group_post = 1`, postID)
-	if err != nil {
+	if gp.Images, err = getGroupPostImages(s.DB, postID); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var images []string
-	for rows.Next() {
-		var url string
-		if err := rows.Scan(&url); err != nil {
-			return nil, err
-		}
-		images = append(images, url)
-	}
-	gp.Images = images
-
-	return &gp, nil
+	return gp, nil
 }
 
 func (s *Service) UpdateGroupPost(request models.UpdateGroupPostRequest, userID int64) error {
@@ -300,6 +349,9 @@ func (s *Service) UpdateGroupPost(request models.UpdateGroupPostRequest, userID
 	if err != nil {
 		return errors.New("post not found")
 	}
+	if err := s.ensureGroupWritable(groupID); err != nil {
+		return err
+	}
 
 	// Checking if the user is the owner of the Post
 	isOwner, err := s.IsPostOwner(userID, request.ID)
@@ -307,11 +359,11 @@ func (s *Service) UpdateGroupPost(request models.UpdateGroupPostRequest, userID
 		return err
 	}
 
-	isAdmin, err := s.IsUserGroupAdmin(userID, groupID)
+	canModerate, err := s.HasGroupPermission(userID, groupID, models.GroupPermDeleteContent)
 	if err != nil {
 		return err
 	}
-	if !isOwner && !isAdmin {
+	if !isOwner && !canModerate {
 		return errors.New("not authorized")
 	}
 
@@ -327,6 +379,11 @@ func (s *Service) UpdateGroupPost(request models.UpdateGroupPostRequest, userID
 		}
 	}()
 
+	err = recordRevision(tx, models.RevisionTargetGroupPost, request.ID, userID, request.Title, request.Body, nil)
+	if err != nil {
+		return err
+	}
+
 	query := "UPDATE group_posts SET updated_at = CURRENT_TIMESTAMP"
 	args := []any{}
 
@@ -344,6 +401,14 @@ func (s *Service) UpdateGroupPost(request models.UpdateGroupPostRequest, userID
 	args = append(args, request.ID)
 
 	_, err = tx.Exec(query, args...)
+	if err != nil || isOwner {
+		return err
+	}
+	var authorID int64
+	if err = tx.QueryRow(`SELECT user_id FROM group_posts WHERE id = ?`, request.ID).Scan(&authorID); err != nil {
+		return err
+	}
+	err = logModeration(tx, groupID, userID, authorID, models.ModerationEditPost, fmt.Sprintf("post %d", request.ID))
 	return err
 }
 
@@ -352,6 +417,9 @@ func (s *Service) DeleteGroupPost(id int64, userID int64) error {
 	if err != nil {
 		return errors.New("post not found")
 	}
+	if err := s.ensureGroupWritable(groupID); err != nil {
+		return err
+	}
 
 	// Checking if the user is the owner of the Post
 	isOwner, err := s.IsPostOwner(userID, id)
@@ -359,11 +427,11 @@ func (s *Service) DeleteGroupPost(id int64, userID int64) error {
 		return err
 	}
 
-	isAdmin, err := s.IsUserGroupAdmin(userID, groupID)
+	canModerate, err := s.HasGroupPermission(userID, groupID, models.GroupPermDeleteContent)
 	if err != nil {
 		return err
 	}
-	if !isOwner && !isAdmin {
+	if !isOwner && !canModerate {
 		return errors.New("not authorized")
 	}
 
@@ -379,7 +447,15 @@ func (s *Service) DeleteGroupPost(id int64, userID int64) error {
 		}
 	}()
 
+	var authorID int64
+	if err = tx.QueryRow(`SELECT user_id FROM group_posts WHERE id = ?`, id).Scan(&authorID); err != nil {
+		return err
+	}
 	_, err = tx.Exec(`DELETE FROM group_posts WHERE id = ?`, id)
+	if err != nil || isOwner {
+		return err
+	}
+	err = logModeration(tx, groupID, userID, authorID, models.ModerationDeletePost, fmt.Sprintf("post %d", id))
 	return err
 }
 
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// GroupRoles lists the roles from most to least privileged
+var GroupRoles = []string{
+	models.GroupRoleOwner,
+	models.GroupRoleAdmin,
+	models.GroupRoleModerator,
+	models.GroupRoleMember,
+	models.GroupRoleRestricted,
+}
+
+// GroupPermissions lists every action of the matrix
+var GroupPermissions = []string{
+	models.GroupPermPost,
+	models.GroupPermComment,
+	models.GroupPermCreateEvent,
+	models.GroupPermInvite,
+	models.GroupPermApproveRequests,
+	models.GroupPermPin,
+	models.GroupPermDeleteContent,
+	models.GroupPermManageRoles,
+	models.GroupPermBanMembers,
+	models.GroupPermMuteMembers,
+	models.GroupPermAnnounce,
+	models.GroupPermApprovePosts,
+	models.GroupPermInviteLinks,
+}
+
+// defaultGroupPermissions is the matrix of every group before overrides. The owner can do everything.
+var defaultGroupPermissions = map[string]map[string]bool{
+	models.GroupRoleAdmin: {
+		models.GroupPermPost: true, models.GroupPermComment: true, models.GroupPermCreateEvent: true,
+		models.GroupPermInvite: true, models.GroupPermApproveRequests: true, models.GroupPermPin: true,
+		models.GroupPermDeleteContent: true, models.GroupPermManageRoles: true, models.GroupPermBanMembers: true,
+		models.GroupPermMuteMembers: true, models.GroupPermAnnounce: true, models.GroupPermApprovePosts: true,
+		models.GroupPermInviteLinks: true,
+	},
+	models.GroupRoleModerator: {
+		models.GroupPermPost: true, models.GroupPermComment: true, models.GroupPermCreateEvent: true,
+		models.GroupPermInvite: true, models.GroupPermApproveRequests: true, models.GroupPermPin: true,
+		models.GroupPermDeleteContent: true, models.GroupPermMuteMembers: true, models.GroupPermApprovePosts: true,
+	},
+	models.GroupRoleMember: {
+		models.GroupPermPost: true, models.GroupPermComment: true, models.GroupPermCreateEvent: true,
+		models.GroupPermInvite: true,
+	},
+	// Restricted members can only read
+	models.GroupRoleRestricted: {},
+}
+
+// IsValidGroupRole reports whether role is one of the group roles
+func IsValidGroupRole(role string) bool {
+	return GroupRoleRank(role) > 0
+}
+
+// GroupRoleRank orders roles, higher is more privileged and 0 means unknown
+func GroupRoleRank(role string) int {
+	for i, r := range GroupRoles {
+		if r == role {
+			return len(GroupRoles) - i
+		}
+	}
+	return 0
+}
+
+// IsValidGroupPermission reports whether permission is part of the matrix
+func IsValidGroupPermission(permission string) bool {
+	for _, p := range GroupPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOverridableGroupPermission reports whether a group may change the cell.
+// The owner row and role management stay fixed so a group cannot lock itself out or escalate.
+func IsOverridableGroupPermission(role, permission string) bool {
+	return role != models.GroupRoleOwner && IsValidGroupRole(role) &&
+		permission != models.GroupPermManageRoles && IsValidGroupPermission(permission)
+}
+
+// GetGroupRole returns the role of the user in the group, empty when not a member
+func (s *Service) GetGroupRole(userID, groupID int64) (string, error) {
+	var role string
+	err := s.DB.QueryRow(`SELECT role FROM group_members WHERE group_id = ? AND user_id = ?`, groupID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// GetGroupPermissionOverrides returns the cells a group changed from the default matrix
+func (s *Service) GetGroupPermissionOverrides(groupID int64) ([]models.GroupPermissionOverride, error) {
+	rows, err := s.DB.Query(`
+		SELECT role, permission, allowed FROM group_role_permissions
+		WHERE group_id = ? ORDER BY role, permission`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make([]models.GroupPermissionOverride, 0)
+	for rows.Next() {
+		var o models.GroupPermissionOverride
+		var allowed bool
+		if err := rows.Scan(&o.Role, &o.Permission, &allowed); err != nil {
+			return nil, err
+		}
+		o.Allowed = &allowed
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// GetGroupPermissionMatrix returns the effective matrix of the group: defaults with its overrides applied
+func (s *Service) GetGroupPermissionMatrix(groupID int64) (map[string]map[string]bool, error) {
+	overrides, err := s.GetGroupPermissionOverrides(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := make(map[string]map[string]bool, len(GroupRoles))
+	for _, role := range GroupRoles {
+		matrix[role] = make(map[string]bool, len(GroupPermissions))
+		for _, permission := range GroupPermissions {
+			matrix[role][permission] = role == models.GroupRoleOwner || defaultGroupPermissions[role][permission]
+		}
+	}
+	for _, o := range overrides {
+		if IsOverridableGroupPermission(o.Role, o.Permission) {
+			matrix[o.Role][o.Permission] = *o.Allowed
+		}
+	}
+	return matrix, nil
+}
+
+// HasGroupPermission is the single authorization check for group actions.
+// It returns false for users who are not members.
+func (s *Service) HasGroupPermission(userID, groupID int64, permission string) (bool, error) {
+	role, err := s.GetGroupRole(userID, groupID)
+	if err != nil || role == "" {
+		return false, err
+	}
+	return s.RoleHasGroupPermission(groupID, role, permission)
+}
+
+// RoleHasGroupPermission checks one cell of the effective matrix of a group
+func (s *Service) RoleHasGroupPermission(groupID int64, role, permission string) (bool, error) {
+	if role == models.GroupRoleOwner {
+		return true, nil
+	}
+	if !IsOverridableGroupPermission(role, permission) {
+		return defaultGroupPermissions[role][permission], nil
+	}
+
+	var allowed bool
+	err := s.DB.QueryRow(`
+		SELECT allowed FROM group_role_permissions
+		WHERE group_id = ? AND role = ? AND permission = ?`, groupID, role, permission).Scan(&allowed)
+	if err == sql.ErrNoRows {
+		return defaultGroupPermissions[role][permission], nil
+	}
+	return allowed, err
+}
+
+// SetGroupPermissionOverrides saves the overrides of a group, a nil Allowed removes the override
+func (s *Service) SetGroupPermissionOverrides(groupID int64, overrides []models.GroupPermissionOverride) error {
+	for _, o := range overrides {
+		if !IsOverridableGroupPermission(o.Role, o.Permission) {
+			return errors.New("permission cannot be overridden")
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	for _, o := range overrides {
+		if o.Allowed == nil {
+			_, err = tx.Exec(`DELETE FROM group_role_permissions WHERE group_id = ? AND role = ? AND permission = ?`,
+				groupID, o.Role, o.Permission)
+		} else {
+			_, err = tx.Exec(`
+				INSERT INTO group_role_permissions (group_id, role, permission, allowed) VALUES (?, ?, ?, ?)
+				ON CONFLICT(group_id, role, permission) DO UPDATE SET allowed = excluded.allowed`,
+				groupID, o.Role, o.Permission, *o.Allowed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"database/sql"
+
 	"github.com/Golden76z/social-network/models"
 )
 
@@ -31,23 +33,27 @@ func (s *Service) CreateGroup(request models.CreateGroupRequest, creatorID int64
 		return err
 	}
 
-	// Add the creator as an admin member
+	// Add the creator as the owner
 	_, err = tx.Exec(`
         INSERT INTO group_members (group_id, user_id, role, invited_by)
-        VALUES (?, ?, ?, ?)`, groupID, creatorID, "admin", nil)
+        VALUES (?, ?, ?, ?)`, groupID, creatorID, models.GroupRoleOwner, nil)
 
 	return err
 }
 
 func (s *Service) GetGroupByID(groupID int64) (*models.GroupResponse, error) {
+	// Deleted groups are hidden until restored
 	row := s.DB.QueryRow(`
-        SELECT id, title, avatar, bio, creator_id, created_at, updated_at
-        FROM groups WHERE id = ?`, groupID)
+        SELECT id, title, avatar, bio, creator_id, created_at, updated_at, status, archived_at, require_post_approval
+        FROM groups WHERE id = ? AND status != 'deleted'`, groupID)
 	var g models.GroupResponse
-	err := row.Scan(&g.ID, &g.Title, &g.Avatar, &g.Bio, &g.CreatorID, &g.CreatedAt, &g.UpdatedAt)
+	var archivedAt sql.NullString
+	err := row.Scan(&g.ID, &g.Title, &g.Avatar, &g.Bio, &g.CreatorID, &g.CreatedAt, &g.UpdatedAt, &g.Status, &archivedAt,
+		&g.RequirePostApproval)
 	if err != nil {
 		return nil, err
 	}
+	g.ArchivedAt = archivedAt.String
 	return &g, nil
 }
 
@@ -96,25 +102,21 @@ func (s *Service) UpdateGroup(groupID int64, request models.UpdateGroupRequest)
 		args = append(args, *request.Bio)
 	}
 
+	if request.RequirePostApproval != nil {
+		query += ", require_post_approval = ?"
+		args = append(args, *request.RequirePostApproval)
+	}
+
 	query += " WHERE id = ?"
 	args = append(args, groupID)
 
-	_, err = tx.Exec(query, args...)
-	return err
-}
-
-func (s *Service) DeleteGroup(groupID int64) error {
-	tx, err := s.DB.Begin()
-	if err != nil {
+	if _, err = tx.Exec(query, args...); err != nil {
 		return err
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-		} else {
-			_ = tx.Commit()
-		}
-	}()
-	_, err = tx.Exec(`DELETE FROM groups WHERE id = ?`, groupID)
+
+	// Refused while an admin lacks 2FA, which leaves the other fields untouched as well
+	if request.RequireAdmin2FA != nil {
+		err = setGroupRequireAdmin2FA(tx, groupID, *request.RequireAdmin2FA)
+	}
 	return err
 }
@@ -8,6 +8,9 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// InvalidCredentialsMessage answers every refused password login alike, so it doesn't tell which accounts exist
+const InvalidCredentialsMessage = "Invalid email/nickname or password. Please check them and try again later"
+
 func (s *Service) LoginDB(username, password string, w http.ResponseWriter) error {
 	tx, err := s.DB.Begin()
 	if err != nil {
@@ -25,16 +28,30 @@ func (s *Service) LoginDB(username, password string, w http.ResponseWriter) erro
 	query := `SELECT password FROM users WHERE nickname = ? OR email = ?`
 	err = tx.QueryRow(query, username, username).Scan(&hashedPassword)
 	if err == sql.ErrNoRows {
-		http.Error(w, "User not found. Please check your email/nickname and try again", http.StatusUnauthorized)
+		http.Error(w, InvalidCredentialsMessage, http.StatusUnauthorized)
 		return errors.New("invalid credentials")
 	} else if err != nil {
 		return err
 	}
 
 	if err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
-		http.Error(w, "Invalid password. Please check your password and try again", http.StatusUnauthorized)
+		http.Error(w, InvalidCredentialsMessage, http.StatusUnauthorized)
 		return errors.New("invalid credentials")
 	}
 
 	return nil
 }
+
+// VerifyUserPassword checks a password against the stored hash of an already authenticated user
+func (s *Service) VerifyUserPassword(userID int64, password string) error {
+	var hashedPassword string
+	err := s.DB.QueryRow(`SELECT password FROM users WHERE id = ?`, userID).Scan(&hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+		return errors.New("invalid credentials")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// LoginGuardPolicy controls how failed logins slow down and lock an account
+type LoginGuardPolicy struct {
+	// Failures allowed before every new failure adds a delay
+	BackoffThreshold int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	// Failures after which the account is locked for LockoutDuration
+	MaxAttempts     int
+	LockoutDuration time.Duration
+	// A failure older than this starts a new count
+	ResetAfter time.Duration
+}
+
+// LoginGuardResult describes the account state after a failed attempt
+type LoginGuardResult struct {
+	FailedCount int
+	LockedUntil time.Time
+	// Locked is true only for the failure that triggered the lockout
+	Locked bool
+	// Notify is true when the owner has not been warned about this lockout yet
+	Notify bool
+}
+
+// GetLoginRetryAfter returns how long the account must wait before the next attempt, zero if it may try now
+func (s *Service) GetLoginRetryAfter(userID int64) (time.Duration, error) {
+	var lockedUntil sql.NullTime
+	err := s.DB.QueryRow(`SELECT locked_until FROM login_guards WHERE user_id = ?`, userID).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	if !lockedUntil.Valid {
+		return 0, nil
+	}
+	if wait := time.Until(lockedUntil.Time); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// RecordFailedLogin increments the failure count and applies exponential backoff or lockout
+func (s *Service) RecordFailedLogin(userID int64, ip string, policy LoginGuardPolicy) (*LoginGuardResult, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	now := time.Now()
+
+	var failedCount int
+	var lastFailedAt, notifiedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT failed_count, last_failed_at, notified_at FROM login_guards WHERE user_id = ?
+	`, userID).Scan(&failedCount, &lastFailedAt, &notifiedAt)
+	if err == sql.ErrNoRows {
+		err = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Old failures no longer count
+	if lastFailedAt.Valid && now.Sub(lastFailedAt.Time) > policy.ResetAfter {
+		failedCount = 0
+		notifiedAt = sql.NullTime{}
+	}
+	failedCount++
+
+	result := &LoginGuardResult{FailedCount: failedCount}
+	switch {
+	case failedCount >= policy.MaxAttempts:
+		result.LockedUntil = now.Add(policy.LockoutDuration)
+		result.Locked = failedCount == policy.MaxAttempts
+		result.Notify = !notifiedAt.Valid
+	case failedCount > policy.BackoffThreshold:
+		delay := policy.BaseDelay << (failedCount - policy.BackoffThreshold - 1)
+		if delay > policy.MaxDelay || delay <= 0 {
+			delay = policy.MaxDelay
+		}
+		result.LockedUntil = now.Add(delay)
+	}
+
+	var lockedUntil interface{}
+	if !result.LockedUntil.IsZero() {
+		lockedUntil = result.LockedUntil
+	}
+	if result.Notify {
+		notifiedAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO login_guards (user_id, failed_count, last_failed_at, last_failed_ip, locked_until, notified_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			failed_count = excluded.failed_count,
+			last_failed_at = excluded.last_failed_at,
+			last_failed_ip = excluded.last_failed_ip,
+			locked_until = excluded.locked_until,
+			notified_at = excluded.notified_at
+	`, userID, failedCount, now, ip, lockedUntil, notifiedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ResetFailedLogins clears the failure count after a successful login
+func (s *Service) ResetFailedLogins(userID int64) error {
+	_, err := s.DB.Exec(`DELETE FROM login_guards WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,4 @@
+ALTER TABLE groups DROP COLUMN require_admin_2fa;
+DROP TABLE IF EXISTS login_challenges;
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+DROP TABLE IF EXISTS user_two_factor;
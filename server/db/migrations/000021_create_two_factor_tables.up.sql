@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS user_two_factor (
+  user_id INTEGER PRIMARY KEY,
+  secret VARCHAR(64) NOT NULL,
+  enabled BOOLEAN DEFAULT FALSE NOT NULL,
+  last_used_step INTEGER DEFAULT 0 NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  enabled_at TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  code_hash VARCHAR(255) NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS login_challenges (
+  token TEXT PRIMARY KEY,
+  user_id INTEGER NOT NULL,
+  attempts INTEGER DEFAULT 0 NOT NULL,
+  expires_at DATETIME NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE groups ADD COLUMN require_admin_2fa BOOLEAN DEFAULT FALSE NOT NULL;
+
+CREATE INDEX idx_recovery_codes_user ON two_factor_recovery_codes(user_id);
+CREATE INDEX idx_login_challenges_expires ON login_challenges(expires_at);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS login_guards (
+  user_id INTEGER PRIMARY KEY,
+  failed_count INTEGER DEFAULT 0 NOT NULL,
+  last_failed_at DATETIME,
+  last_failed_ip VARCHAR(64),
+  locked_until DATETIME,
+  notified_at DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
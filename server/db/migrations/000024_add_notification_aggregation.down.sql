@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_aggregation;
+ALTER TABLE notifications DROP COLUMN updated_at;
+ALTER TABLE notifications DROP COLUMN aggregation_key;
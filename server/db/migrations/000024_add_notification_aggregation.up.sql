@@ -0,0 +1,4 @@
+ALTER TABLE notifications ADD COLUMN aggregation_key VARCHAR(128);
+ALTER TABLE notifications ADD COLUMN updated_at TIMESTAMP;
+
+CREATE INDEX idx_notifications_aggregation ON notifications(user_id, aggregation_key, is_read);
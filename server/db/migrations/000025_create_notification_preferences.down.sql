@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS group_notification_mutes;
+DROP TABLE IF EXISTS notification_quiet_hours;
+DROP TABLE IF EXISTS notification_preferences;
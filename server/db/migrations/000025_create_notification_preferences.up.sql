@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+  user_id INTEGER NOT NULL,
+  type VARCHAR(32) NOT NULL,
+  channel VARCHAR(16) NOT NULL CHECK (channel IN ('in_app', 'push', 'email', 'off')),
+  PRIMARY KEY (user_id, type),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS notification_quiet_hours (
+  user_id INTEGER PRIMARY KEY,
+  start_time VARCHAR(5) NOT NULL,
+  end_time VARCHAR(5) NOT NULL,
+  timezone VARCHAR(64) DEFAULT 'UTC' NOT NULL,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS group_notification_mutes (
+  user_id INTEGER NOT NULL,
+  group_id INTEGER NOT NULL,
+  muted_until DATETIME,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, group_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS notification_digests (
+  user_id INTEGER PRIMARY KEY,
+  frequency VARCHAR(8) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'off')),
+  unsubscribe_token VARCHAR(64) NOT NULL UNIQUE,
+  last_sent_at DATETIME,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_push_subscriptions_user;
+DROP TABLE IF EXISTS push_subscriptions;
@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS group_role_permissions;
+
+CREATE TABLE group_members_old (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  role VARCHAR(6) NOT NULL CHECK (role IN ('member', 'admin')),
+  invited_by INTEGER,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE SET NULL,
+  UNIQUE (group_id, user_id)
+);
+
+INSERT INTO group_members_old (id, group_id, user_id, role, invited_by, created_at)
+SELECT id, group_id, user_id,
+       CASE WHEN role IN ('owner', 'admin') THEN 'admin' ELSE 'member' END,
+       invited_by, created_at
+FROM group_members;
+
+DROP TABLE group_members;
+ALTER TABLE group_members_old RENAME TO group_members;
@@ -0,0 +1,34 @@
+-- SQLite cannot alter a CHECK constraint, the table is rebuilt with the new roles
+CREATE TABLE group_members_new (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'admin', 'moderator', 'member', 'restricted')),
+  invited_by INTEGER,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE SET NULL,
+  UNIQUE (group_id, user_id)
+);
+
+-- The creator of each group becomes its owner
+INSERT INTO group_members_new (id, group_id, user_id, role, invited_by, created_at)
+SELECT gm.id, gm.group_id, gm.user_id,
+       CASE WHEN gm.user_id = g.creator_id THEN 'owner' ELSE gm.role END,
+       gm.invited_by, gm.created_at
+FROM group_members gm
+JOIN groups g ON g.id = gm.group_id;
+
+DROP TABLE group_members;
+ALTER TABLE group_members_new RENAME TO group_members;
+
+-- Per-group changes to the default permission matrix
+CREATE TABLE IF NOT EXISTS group_role_permissions (
+  group_id INTEGER NOT NULL,
+  role VARCHAR(10) NOT NULL CHECK (role IN ('admin', 'moderator', 'member', 'restricted')),
+  permission VARCHAR(32) NOT NULL,
+  allowed BOOLEAN NOT NULL,
+  PRIMARY KEY (group_id, role, permission),
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS group_ownership_transfers;
+DROP INDEX IF EXISTS idx_groups_purge_after;
+
+-- Soft-deleted groups have no place in the old schema
+DELETE FROM groups WHERE status = 'deleted';
+
+ALTER TABLE groups DROP COLUMN purge_after;
+ALTER TABLE groups DROP COLUMN deleted_at;
+ALTER TABLE groups DROP COLUMN archived_at;
+ALTER TABLE groups DROP COLUMN status;
@@ -0,0 +1,27 @@
+-- Groups are archived (read-only) or soft-deleted before being purged
+ALTER TABLE groups ADD COLUMN status VARCHAR(10) DEFAULT 'active' NOT NULL CHECK (status IN ('active', 'archived', 'deleted'));
+ALTER TABLE groups ADD COLUMN archived_at TIMESTAMP;
+ALTER TABLE groups ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE groups ADD COLUMN purge_after TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_groups_purge_after ON groups(purge_after) WHERE status = 'deleted';
+
+-- Ownership moves to another member once they accept
+CREATE TABLE IF NOT EXISTS group_ownership_transfers (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  from_user_id INTEGER NOT NULL,
+  to_user_id INTEGER NOT NULL,
+  status VARCHAR(10) DEFAULT 'pending' NOT NULL CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled')),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP NOT NULL,
+  responded_at TIMESTAMP,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (from_user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (to_user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- A group has at most one pending transfer
+CREATE UNIQUE INDEX IF NOT EXISTS idx_group_ownership_transfers_pending
+  ON group_ownership_transfers(group_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_group_ownership_transfers_to_user ON group_ownership_transfers(to_user_id);
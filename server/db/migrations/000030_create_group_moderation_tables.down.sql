@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS group_moderation_log_no_delete;
+DROP TRIGGER IF EXISTS group_moderation_log_no_update;
+DROP TABLE IF EXISTS group_moderation_log;
+DROP TABLE IF EXISTS group_mutes;
+DROP TABLE IF EXISTS group_bans;
@@ -0,0 +1,56 @@
+-- Banned users cannot request to join or be invited until the ban expires, a NULL expiry is permanent
+CREATE TABLE IF NOT EXISTS group_bans (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  banned_by INTEGER,
+  reason TEXT,
+  expires_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (banned_by) REFERENCES users(id) ON DELETE SET NULL,
+  UNIQUE (group_id, user_id)
+);
+
+-- Muted members keep reading but cannot post, comment or chat until expires_at
+CREATE TABLE IF NOT EXISTS group_mutes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  muted_by INTEGER,
+  reason TEXT,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (muted_by) REFERENCES users(id) ON DELETE SET NULL,
+  UNIQUE (group_id, user_id)
+);
+
+-- Who did what to whom. Users are not foreign keys so entries survive account deletion.
+CREATE TABLE IF NOT EXISTS group_moderation_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  actor_id INTEGER NOT NULL,
+  target_user_id INTEGER,
+  action VARCHAR(32) NOT NULL,
+  details TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_moderation_log_group ON group_moderation_log(group_id, created_at);
+
+-- The log is append-only, entries only go away with their purged group
+CREATE TRIGGER IF NOT EXISTS group_moderation_log_no_update
+BEFORE UPDATE ON group_moderation_log
+BEGIN
+  SELECT RAISE(ABORT, 'group moderation log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS group_moderation_log_no_delete
+BEFORE DELETE ON group_moderation_log
+WHEN EXISTS (SELECT 1 FROM groups WHERE id = OLD.group_id)
+BEGIN
+  SELECT RAISE(ABORT, 'group moderation log is append-only');
+END;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_group_posts_pinned;
+
+ALTER TABLE group_posts DROP COLUMN is_announcement;
+ALTER TABLE group_posts DROP COLUMN pin_expires_at;
+ALTER TABLE group_posts DROP COLUMN pinned_by;
+ALTER TABLE group_posts DROP COLUMN pinned_at;
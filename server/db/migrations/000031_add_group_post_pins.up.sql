@@ -0,0 +1,9 @@
+-- Pinned posts are listed before the others until unpinned or until pin_expires_at, NULL pins for good
+ALTER TABLE group_posts ADD COLUMN pinned_at TIMESTAMP;
+ALTER TABLE group_posts ADD COLUMN pinned_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE group_posts ADD COLUMN pin_expires_at TIMESTAMP;
+
+-- Announcements notify every member and cannot be commented on
+ALTER TABLE group_posts ADD COLUMN is_announcement BOOLEAN DEFAULT FALSE NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_group_posts_pinned ON group_posts(group_id, pinned_at) WHERE pinned_at IS NOT NULL;
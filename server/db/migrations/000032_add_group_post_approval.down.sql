@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_group_posts_pending;
+
+-- Posts that never made it through review have no place in the old schema
+DELETE FROM group_posts WHERE status != 'published';
+
+ALTER TABLE group_posts DROP COLUMN rejection_reason;
+ALTER TABLE group_posts DROP COLUMN reviewed_at;
+ALTER TABLE group_posts DROP COLUMN reviewed_by;
+ALTER TABLE group_posts DROP COLUMN status;
+
+ALTER TABLE groups DROP COLUMN require_post_approval;
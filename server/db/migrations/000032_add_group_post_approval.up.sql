@@ -0,0 +1,9 @@
+-- Groups can hold the posts of members without approve_posts until a moderator reviews them
+ALTER TABLE groups ADD COLUMN require_post_approval BOOLEAN DEFAULT FALSE NOT NULL;
+
+ALTER TABLE group_posts ADD COLUMN status VARCHAR(10) DEFAULT 'published' NOT NULL CHECK (status IN ('pending', 'published', 'rejected'));
+ALTER TABLE group_posts ADD COLUMN reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE group_posts ADD COLUMN reviewed_at TIMESTAMP;
+ALTER TABLE group_posts ADD COLUMN rejection_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_group_posts_pending ON group_posts(group_id, created_at) WHERE status = 'pending';
@@ -0,0 +1,18 @@
+-- Shareable invite links. Redeeming one adds the user to the group (auto) or files a join request (request).
+-- NULL max_uses and expires_at mean unlimited.
+CREATE TABLE IF NOT EXISTS group_invite_links (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  group_id INTEGER NOT NULL,
+  code VARCHAR(32) NOT NULL UNIQUE,
+  created_by INTEGER,
+  mode VARCHAR(10) DEFAULT 'request' NOT NULL CHECK (mode IN ('auto', 'request')),
+  max_uses INTEGER CHECK (max_uses IS NULL OR max_uses > 0),
+  uses INTEGER DEFAULT 0 NOT NULL,
+  expires_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE,
+  FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_invite_links_group ON group_invite_links(group_id);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS calendar_feed_tokens;
+DROP TABLE IF EXISTS group_event_exceptions;
+
+ALTER TABLE group_events DROP COLUMN rrule;
+ALTER TABLE group_events DROP COLUMN location;
+ALTER TABLE group_events DROP COLUMN timezone;
+ALTER TABLE group_events DROP COLUMN end_datetime;
@@ -0,0 +1,30 @@
+-- event_datetime and end_datetime are wall clock times in the IANA timezone of the event,
+-- rrule is an RFC 5545 recurrence rule expanded from event_datetime
+ALTER TABLE group_events ADD COLUMN end_datetime TIMESTAMP;
+ALTER TABLE group_events ADD COLUMN timezone VARCHAR(64) DEFAULT 'UTC' NOT NULL;
+ALTER TABLE group_events ADD COLUMN location VARCHAR(255);
+ALTER TABLE group_events ADD COLUMN rrule TEXT;
+
+-- Cancels or reschedules one occurrence of a recurring event, keyed by its original start.
+-- NULL overrides keep the value of the event.
+CREATE TABLE IF NOT EXISTS group_event_exceptions (
+  event_id INTEGER NOT NULL,
+  occurrence TIMESTAMP NOT NULL,
+  cancelled BOOLEAN DEFAULT FALSE NOT NULL,
+  event_datetime TIMESTAMP,
+  end_datetime TIMESTAMP,
+  title VARCHAR(255),
+  description TEXT,
+  location VARCHAR(255),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (event_id, occurrence),
+  FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE
+);
+
+-- Secret feed URLs, calendar apps subscribe without logging in
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+  user_id INTEGER PRIMARY KEY,
+  token VARCHAR(64) NOT NULL UNIQUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_event_rsvps_waitlist;
+
+ALTER TABLE event_rsvps DROP COLUMN waitlisted_at;
+ALTER TABLE event_rsvps DROP COLUMN guests;
+
+ALTER TABLE group_events DROP COLUMN rsvp_deadline;
+ALTER TABLE group_events DROP COLUMN max_guests;
+ALTER TABLE group_events DROP COLUMN capacity;
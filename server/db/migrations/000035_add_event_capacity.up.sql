@@ -0,0 +1,11 @@
+-- capacity counts attendees and their guests, NULL means unlimited.
+-- rsvp_deadline is a wall clock time in the timezone of the event, RSVPs are locked after it.
+ALTER TABLE group_events ADD COLUMN capacity INTEGER CHECK (capacity > 0);
+ALTER TABLE group_events ADD COLUMN max_guests INTEGER DEFAULT 0 NOT NULL CHECK (max_guests >= 0);
+ALTER TABLE group_events ADD COLUMN rsvp_deadline TIMESTAMP;
+
+-- waitlisted_at is set while a "come" RSVP waits for a seat, the waitlist is served oldest first
+ALTER TABLE event_rsvps ADD COLUMN guests INTEGER DEFAULT 0 NOT NULL CHECK (guests >= 0);
+ALTER TABLE event_rsvps ADD COLUMN waitlisted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_event_rsvps_waitlist ON event_rsvps(event_id, waitlisted_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_event_reminders_starts_at;
+DROP TABLE IF EXISTS event_reminders;
@@ -0,0 +1,14 @@
+-- Reminders already sent, keyed by the UTC start of the occurrence: a rescheduled event is reminded again
+-- while a restart of the server does not send the same reminder twice
+CREATE TABLE IF NOT EXISTS event_reminders (
+  event_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  starts_at TIMESTAMP NOT NULL,
+  lead_seconds INTEGER NOT NULL,
+  sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (event_id, user_id, starts_at, lead_seconds),
+  FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_reminders_starts_at ON event_reminders(starts_at);
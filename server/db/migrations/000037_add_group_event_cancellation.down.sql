@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_group_event_changes_event;
+DROP TABLE IF EXISTS group_event_changes;
+
+ALTER TABLE event_rsvps DROP COLUMN needs_confirmation;
+
+ALTER TABLE group_events DROP COLUMN cancel_reason;
+ALTER TABLE group_events DROP COLUMN cancelled_at;
+ALTER TABLE group_events DROP COLUMN status;
@@ -0,0 +1,22 @@
+-- Cancelled events stay listed with their RSVPs locked instead of being deleted
+ALTER TABLE group_events ADD COLUMN status VARCHAR(10) DEFAULT 'scheduled' NOT NULL CHECK (status IN ('scheduled', 'cancelled'));
+ALTER TABLE group_events ADD COLUMN cancelled_at TIMESTAMP;
+ALTER TABLE group_events ADD COLUMN cancel_reason TEXT;
+
+-- Set when the event moved enough for the answer to be asked again
+ALTER TABLE event_rsvps ADD COLUMN needs_confirmation BOOLEAN DEFAULT FALSE NOT NULL;
+
+-- One row per field changed by an update or the cancellation, values as stored on the event
+CREATE TABLE IF NOT EXISTS group_event_changes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  event_id INTEGER NOT NULL,
+  changed_by INTEGER,
+  field VARCHAR(32) NOT NULL,
+  old_value TEXT,
+  new_value TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (event_id) REFERENCES group_events(id) ON DELETE CASCADE,
+  FOREIGN KEY (changed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_event_changes_event ON group_event_changes(event_id, id);
@@ -0,0 +1,44 @@
+-- A poll is attached to exactly one post, group post or group chat message.
+-- Closing it copies the tally to final_voters and poll_options.final_votes, the results no longer change afterwards.
+CREATE TABLE IF NOT EXISTS polls (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  post_id INTEGER UNIQUE,
+  group_post_id INTEGER UNIQUE,
+  group_message_id INTEGER UNIQUE,
+  creator_id INTEGER NOT NULL,
+  question TEXT NOT NULL,
+  multiple_choice BOOLEAN NOT NULL DEFAULT 0,
+  anonymous BOOLEAN NOT NULL DEFAULT 0,
+  closes_at TIMESTAMP,
+  closed_at TIMESTAMP,
+  final_voters INTEGER,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CHECK ((post_id IS NOT NULL) + (group_post_id IS NOT NULL) + (group_message_id IS NOT NULL) = 1),
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_post_id) REFERENCES group_posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_message_id) REFERENCES group_messages(id) ON DELETE CASCADE,
+  FOREIGN KEY (creator_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  poll_id INTEGER NOT NULL,
+  position INTEGER NOT NULL,
+  label TEXT NOT NULL,
+  final_votes INTEGER,
+  UNIQUE (poll_id, position),
+  FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS poll_votes (
+  poll_id INTEGER NOT NULL,
+  option_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (option_id, user_id),
+  FOREIGN KEY (poll_id) REFERENCES polls(id) ON DELETE CASCADE,
+  FOREIGN KEY (option_id) REFERENCES poll_options(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_poll_votes_poll_user ON poll_votes(poll_id, user_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_post_drafts_due;
+DROP INDEX IF EXISTS idx_post_drafts_user;
+DROP TABLE IF EXISTS post_draft_visibility;
+DROP TABLE IF EXISTS post_drafts;
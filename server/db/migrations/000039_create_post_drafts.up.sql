@@ -0,0 +1,34 @@
+-- Drafts of posts and group posts, group_id is set for group drafts.
+-- A scheduled draft is published by the background publisher once publish_at has passed,
+-- revision is bumped by every edit so the publisher never publishes a version the author changed meanwhile.
+CREATE TABLE IF NOT EXISTS post_drafts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  group_id INTEGER,
+  title TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL DEFAULT '',
+  images TEXT NOT NULL DEFAULT '[]',
+  visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'private')),
+  is_announcement BOOLEAN NOT NULL DEFAULT 0,
+  poll TEXT,
+  status TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'scheduled', 'failed')),
+  publish_at TIMESTAMP,
+  failure_reason TEXT,
+  revision INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE
+);
+
+-- Followers picked at draft time for a private post, copied to post_visibility when it is published
+CREATE TABLE IF NOT EXISTS post_draft_visibility (
+  draft_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  PRIMARY KEY (draft_id, user_id),
+  FOREIGN KEY (draft_id) REFERENCES post_drafts(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_drafts_user ON post_drafts(user_id);
+CREATE INDEX IF NOT EXISTS idx_post_drafts_due ON post_drafts(status, publish_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_content_revisions_group_comment;
+DROP INDEX IF EXISTS idx_content_revisions_group_post;
+DROP INDEX IF EXISTS idx_content_revisions_comment;
+DROP INDEX IF EXISTS idx_content_revisions_post;
+DROP TABLE IF EXISTS content_revisions;
@@ -0,0 +1,27 @@
+-- Previous versions of posts, comments, group posts and group comments, one row per edit.
+-- A row keeps the content as it was before an edit replaced it, revision 1 is the original.
+CREATE TABLE IF NOT EXISTS content_revisions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  post_id INTEGER,
+  comment_id INTEGER,
+  group_post_id INTEGER,
+  group_comment_id INTEGER,
+  revision INTEGER NOT NULL,
+  title TEXT,
+  body TEXT NOT NULL DEFAULT '',
+  images TEXT,
+  written_at TIMESTAMP,
+  edited_by INTEGER,
+  edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CHECK ((post_id IS NOT NULL) + (comment_id IS NOT NULL) + (group_post_id IS NOT NULL) + (group_comment_id IS NOT NULL) = 1),
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_post_id) REFERENCES group_posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_comment_id) REFERENCES group_comments(id) ON DELETE CASCADE,
+  FOREIGN KEY (edited_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_revisions_post ON content_revisions(post_id, revision) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_revisions_comment ON content_revisions(comment_id, revision) WHERE comment_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_revisions_group_post ON content_revisions(group_post_id, revision) WHERE group_post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_revisions_group_comment ON content_revisions(group_comment_id, revision) WHERE group_comment_id IS NOT NULL;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_posts_repost_unique;
+DROP INDEX IF EXISTS idx_posts_original;
+
+-- Reposts have no content of their own to fall back to
+DELETE FROM posts WHERE kind = 'repost';
+
+ALTER TABLE posts DROP COLUMN original_post_id;
+ALTER TABLE posts DROP COLUMN kind;
@@ -0,0 +1,8 @@
+-- A repost shares another post as it is, a quote shares it under a body of its own
+ALTER TABLE posts ADD COLUMN kind VARCHAR(10) DEFAULT 'post' NOT NULL CHECK (kind IN ('post', 'repost', 'quote'));
+-- Quotes outlive the post they quote, reposts are deleted with it by the trigger of migration 000044
+ALTER TABLE posts ADD COLUMN original_post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_original ON posts(original_post_id);
+-- A post is reposted once by the same user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_repost_unique ON posts(user_id, original_post_id) WHERE kind = 'repost';
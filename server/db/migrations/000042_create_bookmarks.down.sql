@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_bookmarks_collection;
+DROP INDEX IF EXISTS idx_bookmarks_group_post;
+DROP INDEX IF EXISTS idx_bookmarks_post;
+DROP TABLE IF EXISTS bookmarks;
+DROP TABLE IF EXISTS bookmark_collections;
@@ -0,0 +1,28 @@
+-- Collections are named folders of bookmarks, private to their owner
+CREATE TABLE IF NOT EXISTS bookmark_collections (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (user_id, name),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- A bookmark saves one post or group post, outside of any collection when collection_id is NULL
+CREATE TABLE IF NOT EXISTS bookmarks (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  post_id INTEGER,
+  group_post_id INTEGER,
+  collection_id INTEGER,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  CHECK ((post_id IS NOT NULL) + (group_post_id IS NOT NULL) = 1),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_post_id) REFERENCES group_posts(id) ON DELETE CASCADE,
+  FOREIGN KEY (collection_id) REFERENCES bookmark_collections(id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookmarks_post ON bookmarks(user_id, post_id) WHERE post_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bookmarks_group_post ON bookmarks(user_id, group_post_id) WHERE group_post_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_bookmarks_collection ON bookmarks(collection_id);
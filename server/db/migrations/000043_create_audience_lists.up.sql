@@ -0,0 +1,24 @@
+-- Audience lists are named groups of followers, like close friends, reused to pick who sees a private post
+CREATE TABLE IF NOT EXISTS audience_lists (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (user_id, name),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS audience_list_members (
+  list_id INTEGER NOT NULL,
+  user_id INTEGER NOT NULL,
+  added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (list_id, user_id),
+  FOREIGN KEY (list_id) REFERENCES audience_lists(id) ON DELETE CASCADE,
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- The list a private post took its audience from, membership changes can be applied to these posts
+ALTER TABLE posts ADD COLUMN audience_list_id INTEGER REFERENCES audience_lists(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_audience_list ON posts(audience_list_id);
@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS posts_delete_reposts;
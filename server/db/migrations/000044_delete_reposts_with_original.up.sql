@@ -0,0 +1,7 @@
+-- A repost shares nothing once its original is gone, whatever path deletes the original.
+-- Quotes keep their own body and only lose the link (ON DELETE SET NULL).
+CREATE TRIGGER IF NOT EXISTS posts_delete_reposts
+BEFORE DELETE ON posts
+BEGIN
+  DELETE FROM posts WHERE original_post_id = OLD.id AND kind = 'repost';
+END;
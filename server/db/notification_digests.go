@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// sqliteTimestampFormat matches CURRENT_TIMESTAMP so stored times compare as text
+const sqliteTimestampFormat = "2006-01-02 15:04:05"
+
+// DigestRecipient is a user with unread notifications not covered by a digest yet
+type DigestRecipient struct {
+	UserID     int64
+	Email      string
+	Nickname   string
+	Frequency  string
+	LastSentAt sql.NullTime
+}
+
+// GetDigestRecipients lists users with new unread notifications whose digest is not turned off.
+// Users without settings use defaultFrequency.
+func (s *Service) GetDigestRecipients(defaultFrequency string) ([]DigestRecipient, error) {
+	rows, err := s.DB.Query(`
+		SELECT u.id, u.email, u.nickname, COALESCE(d.frequency, ?), d.last_sent_at
+		FROM users u
+		LEFT JOIN notification_digests d ON d.user_id = u.id
+		WHERE COALESCE(d.frequency, ?) != 'off'
+		AND EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = u.id AND n.is_read = FALSE
+			AND (d.last_sent_at IS NULL OR COALESCE(n.updated_at, n.created_at) > d.last_sent_at)
+		)`, defaultFrequency, defaultFrequency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []DigestRecipient
+	for rows.Next() {
+		var r DigestRecipient
+		if err := rows.Scan(&r.UserID, &r.Email, &r.Nickname, &r.Frequency, &r.LastSentAt); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, rows.Err()
+}
+
+// GetDigestNotifications returns the unread notifications created or updated after since (all when nil), newest first
+func (s *Service) GetDigestNotifications(userID int64, since *time.Time, limit int) ([]*models.NotificationResponse, error) {
+	query := `SELECT id, user_id, type, data, is_read, created_at FROM notifications WHERE user_id = ? AND is_read = FALSE`
+	args := []interface{}{userID}
+	if since != nil {
+		query += ` AND COALESCE(updated_at, created_at) > ?`
+		args = append(args, since.UTC().Format(sqliteTimestampFormat))
+	}
+	query += ` ORDER BY COALESCE(updated_at, created_at) DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*models.NotificationResponse
+	for rows.Next() {
+		var n models.NotificationResponse
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Data, &n.IsRead, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, &n)
+	}
+	return notifications, rows.Err()
+}
+
+// EnsureDigestSettings creates the digest row of a user if missing and returns its unsubscribe token
+func (s *Service) EnsureDigestSettings(userID int64, frequency, token string) (string, error) {
+	_, err := s.DB.Exec(`
+		INSERT INTO notification_digests (user_id, frequency, unsubscribe_token) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO NOTHING`, userID, frequency, token)
+	if err != nil {
+		return "", err
+	}
+
+	var stored string
+	err = s.DB.QueryRow(`SELECT unsubscribe_token FROM notification_digests WHERE user_id = ?`, userID).Scan(&stored)
+	return stored, err
+}
+
+// GetDigestFrequency returns the user's digest frequency, defaultFrequency when never set
+func (s *Service) GetDigestFrequency(userID int64, defaultFrequency string) (string, error) {
+	var frequency string
+	err := s.DB.QueryRow(`SELECT frequency FROM notification_digests WHERE user_id = ?`, userID).Scan(&frequency)
+	if err == sql.ErrNoRows {
+		return defaultFrequency, nil
+	}
+	return frequency, err
+}
+
+// SetDigestFrequency saves the frequency, token is only used when the row is created
+func (s *Service) SetDigestFrequency(userID int64, frequency, token string) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO notification_digests (user_id, frequency, unsubscribe_token) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET frequency = excluded.frequency`,
+		userID, frequency, token)
+	return err
+}
+
+// MarkDigestSent records when the last digest went out
+func (s *Service) MarkDigestSent(userID int64, sentAt time.Time) error {
+	_, err := s.DB.Exec(`UPDATE notification_digests SET last_sent_at = ? WHERE user_id = ?`,
+		sentAt.UTC().Format(sqliteTimestampFormat), userID)
+	return err
+}
+
+// DigestTokenExists reports whether an unsubscribe token belongs to a digest
+func (s *Service) DigestTokenExists(token string) (bool, error) {
+	var exists bool
+	err := s.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM notification_digests WHERE unsubscribe_token = ?)`, token).Scan(&exists)
+	return exists, err
+}
+
+// UnsubscribeDigest turns off the digest owning the token, it returns false for an unknown token
+func (s *Service) UnsubscribeDigest(token string) (bool, error) {
+	result, err := s.DB.Exec(`UPDATE notification_digests SET frequency = 'off' WHERE unsubscribe_token = ?`, token)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
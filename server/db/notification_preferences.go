@@ -0,0 +1,154 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// GetNotificationChannels returns the channels the user chose, types left at the default are absent
+func (s *Service) GetNotificationChannels(userID int64) (map[string]string, error) {
+	rows, err := s.DB.Query(`SELECT type, channel FROM notification_preferences WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := make(map[string]string)
+	for rows.Next() {
+		var notificationType, channel string
+		if err := rows.Scan(&notificationType, &channel); err != nil {
+			return nil, err
+		}
+		channels[notificationType] = channel
+	}
+	return channels, rows.Err()
+}
+
+// GetNotificationChannel returns the channel of one type, push when the user never changed it
+func (s *Service) GetNotificationChannel(userID int64, notificationType string) (string, error) {
+	var channel string
+	err := s.DB.QueryRow(`
+		SELECT channel FROM notification_preferences WHERE user_id = ? AND type = ?`,
+		userID, notificationType).Scan(&channel)
+	if err == sql.ErrNoRows {
+		return models.NotificationChannelPush, nil
+	}
+	return channel, err
+}
+
+// SetNotificationChannels saves the channel of each given type
+func (s *Service) SetNotificationChannels(userID int64, channels map[string]string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	for notificationType, channel := range channels {
+		_, err = tx.Exec(`
+			INSERT INTO notification_preferences (user_id, type, channel) VALUES (?, ?, ?)
+			ON CONFLICT(user_id, type) DO UPDATE SET channel = excluded.channel`,
+			userID, notificationType, channel)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetQuietHours returns the user's quiet hours, nil when none are set
+func (s *Service) GetQuietHours(userID int64) (*models.QuietHours, error) {
+	var qh models.QuietHours
+	err := s.DB.QueryRow(`
+		SELECT start_time, end_time, timezone FROM notification_quiet_hours WHERE user_id = ?`,
+		userID).Scan(&qh.Start, &qh.End, &qh.Timezone)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &qh, nil
+}
+
+// SetQuietHours saves the user's quiet hours, nil removes them
+func (s *Service) SetQuietHours(userID int64, quietHours *models.QuietHours) error {
+	if quietHours == nil {
+		_, err := s.DB.Exec(`DELETE FROM notification_quiet_hours WHERE user_id = ?`, userID)
+		return err
+	}
+
+	_, err := s.DB.Exec(`
+		INSERT INTO notification_quiet_hours (user_id, start_time, end_time, timezone) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET start_time = excluded.start_time, end_time = excluded.end_time, timezone = excluded.timezone`,
+		userID, quietHours.Start, quietHours.End, quietHours.Timezone)
+	return err
+}
+
+// MuteGroupNotifications mutes a group for the user, until nil mutes it until unmuted
+func (s *Service) MuteGroupNotifications(userID, groupID int64, until *time.Time) error {
+	var mutedUntil sql.NullTime
+	if until != nil {
+		mutedUntil = sql.NullTime{Time: until.UTC(), Valid: true}
+	}
+
+	_, err := s.DB.Exec(`
+		INSERT INTO group_notification_mutes (user_id, group_id, muted_until) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, group_id) DO UPDATE SET muted_until = excluded.muted_until`,
+		userID, groupID, mutedUntil)
+	return err
+}
+
+// UnmuteGroupNotifications removes the mute of a group
+func (s *Service) UnmuteGroupNotifications(userID, groupID int64) error {
+	_, err := s.DB.Exec(`DELETE FROM group_notification_mutes WHERE user_id = ? AND group_id = ?`, userID, groupID)
+	return err
+}
+
+// IsGroupMuted reports whether the user muted the group and the mute has not expired
+func (s *Service) IsGroupMuted(userID, groupID int64) (bool, error) {
+	var mutedUntil sql.NullTime
+	err := s.DB.QueryRow(`
+		SELECT muted_until FROM group_notification_mutes WHERE user_id = ? AND group_id = ?`,
+		userID, groupID).Scan(&mutedUntil)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !mutedUntil.Valid || mutedUntil.Time.After(time.Now()), nil
+}
+
+// GetMutedGroups lists the groups the user currently mutes
+func (s *Service) GetMutedGroups(userID int64) ([]models.GroupNotificationMute, error) {
+	rows, err := s.DB.Query(`
+		SELECT group_id, muted_until FROM group_notification_mutes
+		WHERE user_id = ? AND (muted_until IS NULL OR muted_until > ?)
+		ORDER BY group_id`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mutes := []models.GroupNotificationMute{}
+	for rows.Next() {
+		var mute models.GroupNotificationMute
+		var mutedUntil sql.NullTime
+		if err := rows.Scan(&mute.GroupID, &mutedUntil); err != nil {
+			return nil, err
+		}
+		if mutedUntil.Valid {
+			until := mutedUntil.Time
+			mute.MutedUntil = &until
+		}
+		mutes = append(mutes, mute)
+	}
+	return mutes, rows.Err()
+}
@@ -1,6 +1,9 @@
 package db
 
 import (
+	"database/sql"
+	"errors"
+
 	"github.com/Golden76z/social-network/models"
 )
 
@@ -91,7 +94,8 @@ func (s *Service) GetUserNotifications(userID int64, limit, offset int, unreadOn
 		query += " AND is_read = FALSE"
 	}
 
-	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+	// Aggregated notifications move up when a new actor joins them
+	query += " ORDER BY COALESCE(updated_at, created_at) DESC LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
 	rows, err := s.DB.Query(query, args...)
@@ -112,8 +116,8 @@ func (s *Service) GetUserNotifications(userID int64, limit, offset int, unreadOn
 	return notifications, nil
 }
 
-// DeleteNotificationsByTypeAndData deletes notifications matching the given type and data pattern
-func (s *Service) DeleteNotificationsByTypeAndData(notificationType, dataPattern string) error {
+// DeleteNotificationsByDataField deletes a user's notifications of a type whose payload field equals value
+func (s *Service) DeleteNotificationsByDataField(userID int64, notificationType, field string, value interface{}) error {
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return err
@@ -127,13 +131,74 @@ func (s *Service) DeleteNotificationsByTypeAndData(notificationType, dataPattern
 	}()
 
 	_, err = tx.Exec(`
-		DELETE FROM notifications 
-		WHERE type = ? AND data LIKE ?
-	`, notificationType, "%"+dataPattern+"%")
+		DELETE FROM notifications
+		WHERE user_id = ? AND type = ? AND json_extract(data, '$.' || ?) = ?
+	`, userID, notificationType, field, value)
 
 	return err
 }
 
+// InsertNotification creates a notification and returns its ID
+func (s *Service) InsertNotification(request models.CreateNotificationRequest) (int64, error) {
+	result, err := s.DB.Exec(`
+        INSERT INTO notifications (user_id, type, data, aggregation_key)
+        VALUES (?, ?, ?, NULLIF(?, ''))`, request.UserID, request.Type, request.Data, request.AggregationKey)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// AggregateNotification folds the request into the user's unread notification sharing its aggregation key,
+// or inserts a new one. merge receives the stored data ("" when there is none) and returns the data to save.
+func (s *Service) AggregateNotification(request models.CreateNotificationRequest, merge func(existing string) (string, error)) (id int64, aggregated bool, err error) {
+	if request.AggregationKey == "" {
+		return 0, false, errors.New("missing aggregation key")
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var existing sql.NullString
+	err = tx.QueryRow(`
+		SELECT id, data FROM notifications
+		WHERE user_id = ? AND type = ? AND aggregation_key = ? AND is_read = FALSE
+		ORDER BY id DESC LIMIT 1`,
+		request.UserID, request.Type, request.AggregationKey).Scan(&id, &existing)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, err
+	}
+	aggregated = err == nil
+
+	data, err := merge(existing.String)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if aggregated {
+		_, err = tx.Exec(`UPDATE notifications SET data = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, data, id)
+		return id, true, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO notifications (user_id, type, data, aggregation_key)
+		VALUES (?, ?, ?, ?)`, request.UserID, request.Type, data, request.AggregationKey)
+	if err != nil {
+		return 0, false, err
+	}
+	id, err = result.LastInsertId()
+	return id, false, err
+}
+
 // MarkNotificationAsExpired marks a notification as expired by updating its data
 func (s *Service) MarkNotificationAsExpired(notificationID int64) error {
 	tx, err := s.DB.Begin()
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SaveOIDCAuthRequest stores the state, nonce and PKCE verifier of an outgoing authorization request
+func (s *Service) SaveOIDCAuthRequest(state, nonce, codeVerifier string, ttl time.Duration) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO oidc_auth_requests (state, nonce, code_verifier, expires_at)
+		VALUES (?, ?, ?, ?)`, state, nonce, codeVerifier, time.Now().Add(ttl))
+	return err
+}
+
+// ConsumeOIDCAuthRequest returns the nonce and verifier for a state and deletes it, so a state is single-use
+func (s *Service) ConsumeOIDCAuthRequest(state string) (string, string, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var nonce, codeVerifier string
+	var expiresAt time.Time
+	err = tx.QueryRow(`
+		SELECT nonce, code_verifier, expires_at FROM oidc_auth_requests WHERE state = ?
+	`, state).Scan(&nonce, &codeVerifier, &expiresAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	if _, err = tx.Exec(`DELETE FROM oidc_auth_requests WHERE state = ?`, state); err != nil {
+		return "", "", err
+	}
+
+	if time.Now().After(expiresAt) {
+		return "", "", errors.New("authorization request expired")
+	}
+	return nonce, codeVerifier, nil
+}
+
+// FindOrLinkOIDCIdentity resolves the local user of an external identity.
+// Unknown identities are linked to the existing account with the same email, only if the provider verified it.
+func (s *Service) FindOrLinkOIDCIdentity(provider, subject, email string, emailVerified bool) (int64, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var userID int64
+	err = tx.QueryRow(`
+		SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?
+	`, provider, subject).Scan(&userID)
+	if err == nil {
+		_, err = tx.Exec(`
+			UPDATE user_identities SET last_login_at = CURRENT_TIMESTAMP, email = ?
+			WHERE provider = ? AND subject = ?`, email, provider, subject)
+		return userID, err
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if email == "" || !emailVerified {
+		err = errors.New("email not verified by identity provider")
+		return 0, err
+	}
+
+	err = tx.QueryRow(`SELECT id FROM users WHERE email = ? COLLATE NOCASE`, email).Scan(&userID)
+	if err == sql.ErrNoRows {
+		err = errors.New("no account matches this email")
+		return 0, err
+	} else if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`, userID, provider, subject, email)
+	return userID, err
+}
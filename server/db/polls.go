@@ -0,0 +1,326 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+var (
+	// ErrPollClosed is returned when voting on or closing a poll that is already closed
+	ErrPollClosed = errors.New("poll is closed")
+	// ErrInvalidPollVote is returned for options of another poll or several options on a single choice poll
+	ErrInvalidPollVote = errors.New("invalid poll vote")
+)
+
+// pollTargets are the columns a poll can be attached through
+var pollTargets = map[string]bool{
+	models.PollTargetPost:         true,
+	models.PollTargetGroupPost:    true,
+	models.PollTargetGroupMessage: true,
+}
+
+// insertPoll creates the poll of a new post, group post or group message in the transaction creating it
+func insertPoll(tx *sql.Tx, target string, targetID, creatorID int64, req *models.CreatePollRequest) error {
+	if !pollTargets[target] {
+		return errors.New("invalid poll target")
+	}
+	var closesAt any
+	if req.DurationMinutes > 0 {
+		closesAt = time.Now().UTC().Add(time.Duration(req.DurationMinutes) * time.Minute)
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO polls (`+target+`, creator_id, question, multiple_choice, anonymous, closes_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, targetID, creatorID, req.Question, req.MultipleChoice, req.Anonymous, closesAt)
+	if err != nil {
+		return err
+	}
+	pollID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for i, label := range req.Options {
+		if _, err := tx.Exec(`INSERT INTO poll_options (poll_id, position, label) VALUES (?, ?, ?)`, pollID, i, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishPoll closes a poll and copies its tally, false when it was already closed
+func finishPoll(tx *sql.Tx, pollID int64, closedAt time.Time) (bool, error) {
+	res, err := tx.Exec(`
+		UPDATE polls SET closed_at = ?,
+			final_voters = (SELECT COUNT(DISTINCT user_id) FROM poll_votes WHERE poll_id = polls.id)
+		WHERE id = ? AND closed_at IS NULL`, closedAt.UTC(), pollID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	_, err = tx.Exec(`
+		UPDATE poll_options SET final_votes = (SELECT COUNT(*) FROM poll_votes WHERE option_id = poll_options.id)
+		WHERE poll_id = ?`, pollID)
+	return err == nil, err
+}
+
+// pollState reads what decides whether a poll still takes votes
+func pollState(tx *sql.Tx, pollID int64) (creatorID int64, multipleChoice bool, closesAt, closedAt sql.NullTime, err error) {
+	err = tx.QueryRow(`SELECT creator_id, multiple_choice, closes_at, closed_at FROM polls WHERE id = ?`, pollID).
+		Scan(&creatorID, &multipleChoice, &closesAt, &closedAt)
+	return
+}
+
+// closeDuePoll locks the results of a poll whose closing time passed, true when it is closed
+func closeDuePoll(tx *sql.Tx, pollID int64, now time.Time) (bool, error) {
+	_, _, closesAt, closedAt, err := pollState(tx, pollID)
+	if err != nil {
+		return false, err
+	}
+	if closedAt.Valid {
+		return true, nil
+	}
+	if !closesAt.Valid || closesAt.Time.After(now) {
+		return false, nil
+	}
+	if _, err := finishPoll(tx, pollID, closesAt.Time); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetPoll returns a poll with its tally, the voters are listed unless the poll is anonymous
+func (s *Service) GetPoll(pollID, userID int64) (*models.Poll, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	if _, err = closeDuePoll(tx, pollID, time.Now()); err != nil {
+		return nil, err
+	}
+	poll, err := scanPoll(tx, pollID, userID)
+	return poll, err
+}
+
+// GetPollByTarget returns the poll attached to a post, group post or group message
+func (s *Service) GetPollByTarget(target string, targetID, userID int64) (*models.Poll, error) {
+	if !pollTargets[target] {
+		return nil, errors.New("invalid poll target")
+	}
+	var pollID int64
+	if err := s.DB.QueryRow(`SELECT id FROM polls WHERE `+target+` = ?`, targetID).Scan(&pollID); err != nil {
+		return nil, err
+	}
+	return s.GetPoll(pollID, userID)
+}
+
+// scanPoll reads a poll and its tally, the locked one once the poll is closed
+func scanPoll(tx *sql.Tx, pollID, userID int64) (*models.Poll, error) {
+	var poll models.Poll
+	var postID, groupPostID, groupMessageID, groupID sql.NullInt64
+	var closesAt, closedAt sql.NullTime
+	err := tx.QueryRow(`
+		SELECT p.id, p.post_id, p.group_post_id, p.group_message_id, COALESCE(gp.group_id, gm.group_id),
+			p.creator_id, p.question, p.multiple_choice, p.anonymous, p.closes_at, p.closed_at, p.created_at,
+			COALESCE(p.final_voters, (SELECT COUNT(DISTINCT user_id) FROM poll_votes WHERE poll_id = p.id))
+		FROM polls p
+		LEFT JOIN group_posts gp ON gp.id = p.group_post_id
+		LEFT JOIN group_messages gm ON gm.id = p.group_message_id
+		WHERE p.id = ?`, pollID).Scan(&poll.ID, &postID, &groupPostID, &groupMessageID, &groupID,
+		&poll.CreatorID, &poll.Question, &poll.MultipleChoice, &poll.Anonymous, &closesAt, &closedAt, &poll.CreatedAt,
+		&poll.Voters)
+	if err != nil {
+		return nil, err
+	}
+	poll.PostID = nullableID(postID)
+	poll.GroupPostID = nullableID(groupPostID)
+	poll.GroupMessageID = nullableID(groupMessageID)
+	poll.GroupID = nullableID(groupID)
+	if closesAt.Valid {
+		poll.ClosesAt = closesAt.Time.UTC().Format(time.RFC3339)
+	}
+	if closedAt.Valid {
+		poll.Closed = true
+		poll.ClosedAt = closedAt.Time.UTC().Format(time.RFC3339)
+	}
+
+	rows, err := tx.Query(`
+		SELECT o.id, o.label, COALESCE(o.final_votes, (SELECT COUNT(*) FROM poll_votes WHERE option_id = o.id))
+		FROM poll_options o WHERE o.poll_id = ? ORDER BY o.position`, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	index := map[int64]int{}
+	for rows.Next() {
+		var option models.PollOption
+		if err := rows.Scan(&option.ID, &option.Label, &option.Votes); err != nil {
+			return nil, err
+		}
+		index[option.ID] = len(poll.Options)
+		poll.Options = append(poll.Options, option)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	votes, err := tx.Query(`
+		SELECT v.option_id, v.user_id, u.nickname FROM poll_votes v
+		JOIN users u ON u.id = v.user_id
+		WHERE v.poll_id = ? ORDER BY v.created_at, v.rowid`, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer votes.Close()
+	poll.UserVotes = []int64{}
+	for votes.Next() {
+		var optionID int64
+		var voter models.PollVoter
+		if err := votes.Scan(&optionID, &voter.UserID, &voter.Nickname); err != nil {
+			return nil, err
+		}
+		if voter.UserID == userID {
+			poll.UserVotes = append(poll.UserVotes, optionID)
+		}
+		if i, ok := index[optionID]; ok && !poll.Anonymous {
+			poll.Options[i].Voters = append(poll.Options[i].Voters, voter)
+		}
+	}
+	return &poll, votes.Err()
+}
+
+// nullableID turns a NULL column into a nil pointer
+func nullableID(id sql.NullInt64) *int64 {
+	if !id.Valid {
+		return nil
+	}
+	return &id.Int64
+}
+
+// VotePoll replaces the votes of the user on an open poll, no option withdraws them
+func (s *Service) VotePoll(pollID, userID int64, optionIDs []int64) error {
+	var groupID sql.NullInt64
+	err := s.DB.QueryRow(`
+		SELECT COALESCE(gp.group_id, gm.group_id) FROM polls p
+		LEFT JOIN group_posts gp ON gp.id = p.group_post_id
+		LEFT JOIN group_messages gm ON gm.id = p.group_message_id
+		WHERE p.id = ?`, pollID).Scan(&groupID)
+	if err != nil {
+		return err
+	}
+	if groupID.Valid {
+		if err := s.ensureGroupWritable(groupID.Int64); err != nil {
+			return err
+		}
+	}
+
+	seen := map[int64]bool{}
+	var options []int64
+	for _, id := range optionIDs {
+		if !seen[id] {
+			seen[id] = true
+			options = append(options, id)
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	closed, err := closeDuePoll(tx, pollID, time.Now())
+	if err != nil {
+		return err
+	}
+	if closed {
+		// The closing of a due poll is committed all the same
+		return ErrPollClosed
+	}
+	_, multipleChoice, _, _, err := pollState(tx, pollID)
+	if err != nil {
+		return err
+	}
+	if len(options) > 1 && !multipleChoice {
+		err = ErrInvalidPollVote
+		return err
+	}
+	if len(options) > 0 {
+		args := []any{pollID}
+		for _, id := range options {
+			args = append(args, id)
+		}
+		var found int
+		err = tx.QueryRow(`SELECT COUNT(*) FROM poll_options WHERE poll_id = ? AND id IN (?`+
+			strings.Repeat(", ?", len(options)-1)+`)`, args...).Scan(&found)
+		if err != nil {
+			return err
+		}
+		if found != len(options) {
+			err = ErrInvalidPollVote
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(`DELETE FROM poll_votes WHERE poll_id = ? AND user_id = ?`, pollID, userID); err != nil {
+		return err
+	}
+	for _, id := range options {
+		if _, err = tx.Exec(`INSERT INTO poll_votes (poll_id, option_id, user_id) VALUES (?, ?, ?)`, pollID, id, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClosePoll closes a poll before its closing time and locks its results, only its creator can
+func (s *Service) ClosePoll(pollID, userID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	creatorID, _, _, _, err := pollState(tx, pollID)
+	if err != nil {
+		return err
+	}
+	if creatorID != userID {
+		err = errors.New("not authorized")
+		return err
+	}
+	closed, err := closeDuePoll(tx, pollID, time.Now())
+	if err != nil {
+		return err
+	}
+	if closed {
+		return ErrPollClosed
+	}
+	_, err = finishPoll(tx, pollID, time.Now())
+	return err
+}
@@ -0,0 +1,389 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+var (
+	// ErrDraftNotFound is returned for a draft that does not exist or belongs to someone else
+	ErrDraftNotFound = errors.New("draft not found")
+	// ErrDraftChanged is returned when a draft was edited, published or deleted since it was read
+	ErrDraftChanged = errors.New("draft was changed")
+	// ErrDraftAudienceGone is returned when none of the followers picked for a private draft follow its author anymore
+	ErrDraftAudienceGone = errors.New("none of the selected followers follow the author anymore")
+)
+
+// draftColumns are the columns scanned by scanPostDraft
+const draftColumns = `id, user_id, group_id, title, body, images, visibility, is_announcement, poll,
+	status, publish_at, failure_reason, revision, created_at, updated_at`
+
+// CreatePostDraft saves a new draft with the followers picked for it
+func (s *Service) CreatePostDraft(draft *models.PostDraft) (int64, error) {
+	images, poll, publishAt, err := draftValues(draft)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	res, err := tx.Exec(`
+		INSERT INTO post_drafts (user_id, group_id, title, body, images, visibility, is_announcement, poll, status, publish_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, draft.UserID, draft.GroupID, draft.Title, draft.Body, images,
+		draft.Visibility, draft.IsAnnouncement, poll, draft.Status, publishAt)
+	if err != nil {
+		return 0, err
+	}
+	draftID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	err = insertDraftVisibility(tx, draftID, draft.SelectedFollowers)
+	return draftID, err
+}
+
+// UpdatePostDraft saves the changes made to a draft read at draft.Revision, ErrDraftChanged when it moved on since.
+// A failed draft stays failed only if the caller keeps that status.
+func (s *Service) UpdatePostDraft(draft *models.PostDraft) error {
+	images, poll, publishAt, err := draftValues(draft)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	var failureReason any
+	if draft.Status == models.PostDraftFailed {
+		failureReason = draft.FailureReason
+	}
+	res, err := tx.Exec(`
+		UPDATE post_drafts SET title = ?, body = ?, images = ?, visibility = ?, is_announcement = ?, poll = ?,
+			status = ?, publish_at = ?, failure_reason = ?, revision = revision + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND revision = ?`, draft.Title, draft.Body, images, draft.Visibility,
+		draft.IsAnnouncement, poll, draft.Status, publishAt, failureReason, draft.ID, draft.UserID, draft.Revision)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		err = ErrDraftChanged
+		return err
+	}
+
+	if _, err = tx.Exec(`DELETE FROM post_draft_visibility WHERE draft_id = ?`, draft.ID); err != nil {
+		return err
+	}
+	err = insertDraftVisibility(tx, draft.ID, draft.SelectedFollowers)
+	return err
+}
+
+// draftValues encodes the columns of a draft that are not stored as they are
+func draftValues(draft *models.PostDraft) (images string, poll, publishAt any, err error) {
+	encoded, err := json.Marshal(append([]string{}, draft.Images...))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if draft.Poll != nil {
+		encodedPoll, err := json.Marshal(draft.Poll)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		poll = string(encodedPoll)
+	}
+	if draft.PublishAt != "" {
+		at, err := time.Parse(time.RFC3339, draft.PublishAt)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		// Whole seconds keep the stored times comparable with the ones the publisher passes
+		publishAt = at.UTC().Truncate(time.Second)
+	}
+	return string(encoded), poll, publishAt, nil
+}
+
+func insertDraftVisibility(tx *sql.Tx, draftID int64, userIDs []int64) error {
+	for _, userID := range userIDs {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO post_draft_visibility (draft_id, user_id) VALUES (?, ?)`, draftID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPostDraft returns a draft of the user
+func (s *Service) GetPostDraft(draftID, userID int64) (*models.PostDraft, error) {
+	drafts, err := s.queryPostDrafts(`WHERE id = ? AND user_id = ?`, draftID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(drafts) == 0 {
+		return nil, ErrDraftNotFound
+	}
+	return &drafts[0], nil
+}
+
+// GetPostDrafts returns the drafts of the user, the scheduled ones first by publish time
+func (s *Service) GetPostDrafts(userID int64) ([]models.PostDraft, error) {
+	return s.queryPostDrafts(`WHERE user_id = ? ORDER BY publish_at IS NULL, publish_at, updated_at DESC, id DESC`, userID)
+}
+
+// GetDuePostDrafts returns the scheduled drafts whose publish time has passed
+func (s *Service) GetDuePostDrafts(now time.Time) ([]models.PostDraft, error) {
+	return s.queryPostDrafts(`WHERE status = ? AND publish_at <= ? ORDER BY publish_at, id`,
+		models.PostDraftScheduled, now.UTC())
+}
+
+func (s *Service) queryPostDrafts(where string, args ...any) ([]models.PostDraft, error) {
+	rows, err := s.DB.Query(`SELECT `+draftColumns+` FROM post_drafts `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	drafts := []models.PostDraft{}
+	for rows.Next() {
+		draft, err := scanPostDraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, *draft)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// The followers are read once the rows are closed, tests run on a single connection
+	for i := range drafts {
+		if drafts[i].SelectedFollowers, err = s.draftVisibility(drafts[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return drafts, nil
+}
+
+func scanPostDraft(rows *sql.Rows) (*models.PostDraft, error) {
+	var draft models.PostDraft
+	var groupID sql.NullInt64
+	var images string
+	var poll, failureReason sql.NullString
+	var publishAt sql.NullTime
+	err := rows.Scan(&draft.ID, &draft.UserID, &groupID, &draft.Title, &draft.Body, &images, &draft.Visibility,
+		&draft.IsAnnouncement, &poll, &draft.Status, &publishAt, &failureReason, &draft.Revision,
+		&draft.CreatedAt, &draft.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	draft.GroupID = nullableID(groupID)
+	if err := json.Unmarshal([]byte(images), &draft.Images); err != nil {
+		return nil, err
+	}
+	if poll.Valid {
+		draft.Poll = &models.CreatePollRequest{}
+		if err := json.Unmarshal([]byte(poll.String), draft.Poll); err != nil {
+			return nil, err
+		}
+	}
+	if publishAt.Valid {
+		draft.PublishAt = publishAt.Time.UTC().Format(time.RFC3339)
+	}
+	draft.FailureReason = failureReason.String
+	return &draft, nil
+}
+
+func (s *Service) draftVisibility(draftID int64) ([]int64, error) {
+	rows, err := s.DB.Query(`SELECT user_id FROM post_draft_visibility WHERE draft_id = ? ORDER BY user_id`, draftID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int64{}
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// DeletePostDraft deletes a draft of the user
+func (s *Service) DeletePostDraft(draftID, userID int64) error {
+	res, err := s.DB.Exec(`DELETE FROM post_drafts WHERE id = ? AND user_id = ?`, draftID, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrDraftNotFound
+	}
+	return nil
+}
+
+// FailPostDraft stops a scheduled draft from being retried and keeps the reason for its author,
+// false when the draft changed since it was read
+func (s *Service) FailPostDraft(draftID, revision int64, reason string) (bool, error) {
+	res, err := s.DB.Exec(`
+		UPDATE post_drafts SET status = ?, failure_reason = ?, revision = revision + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND revision = ?`, models.PostDraftFailed, reason, draftID, revision)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FilterFollowers keeps the users who currently follow userID, in their order
+func (s *Service) FilterFollowers(userID int64, candidates []int64) ([]int64, error) {
+	if len(candidates) == 0 {
+		return []int64{}, nil
+	}
+	args := []any{userID}
+	for _, id := range candidates {
+		args = append(args, id)
+	}
+	rows, err := s.DB.Query(`
+		SELECT requester_id FROM follow_requests
+		WHERE target_id = ? AND status = 'accepted' AND requester_id IN (?`+strings.Repeat(", ?", len(candidates)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	following := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		following[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	followers := []int64{}
+	for _, id := range candidates {
+		if following[id] {
+			followers = append(followers, id)
+			delete(following, id)
+		}
+	}
+	return followers, nil
+}
+
+// PublishPostDraft turns the draft, as read at draft.Revision, into a post or a group post and deletes it.
+// The checks of a new post run again at publish time: the author must still be allowed to post in the
+// group, and a private post only goes to the picked users who still follow its author.
+// It returns the new post and its status, pending when the group reviews posts first.
+func (s *Service) PublishPostDraft(draft *models.PostDraft) (int64, string, error) {
+	status := models.GroupPostPublished
+	var groupPost models.CreateGroupPostRequest
+	var post models.CreatePostRequest
+
+	if draft.GroupID != nil {
+		groupPost = models.CreateGroupPostRequest{
+			GroupID:        *draft.GroupID,
+			Title:          draft.Title,
+			Body:           draft.Body,
+			Images:         draft.Images,
+			IsAnnouncement: draft.IsAnnouncement,
+			Poll:           draft.Poll,
+		}
+		if err := s.ensureGroupWritable(*draft.GroupID); err != nil {
+			return 0, "", err
+		}
+		if err := s.ensureNotMuted(draft.UserID, *draft.GroupID); err != nil {
+			return 0, "", err
+		}
+		var err error
+		if status, err = s.groupPostStatusFor(groupPost, draft.UserID); err != nil {
+			return 0, "", err
+		}
+	} else {
+		post = models.CreatePostRequest{
+			Title:      draft.Title,
+			Body:       draft.Body,
+			Images:     draft.Images,
+			Visibility: draft.Visibility,
+			Poll:       draft.Poll,
+		}
+		if draft.Visibility == "private" && len(draft.SelectedFollowers) > 0 {
+			followers, err := s.FilterFollowers(draft.UserID, draft.SelectedFollowers)
+			if err != nil {
+				return 0, "", err
+			}
+			// An empty list would open the post to every follower
+			if len(followers) == 0 {
+				return 0, "", ErrDraftAudienceGone
+			}
+			post.SelectedFollowers = followers
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	// Deleting the version that was read claims it, an edit or a second publisher finds nothing to delete
+	res, err := tx.Exec(`DELETE FROM post_drafts WHERE id = ? AND user_id = ? AND revision = ?`,
+		draft.ID, draft.UserID, draft.Revision)
+	if err != nil {
+		return 0, "", err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, "", err
+	}
+	if n == 0 {
+		err = ErrDraftChanged
+		return 0, "", err
+	}
+
+	var postID int64
+	if draft.GroupID != nil {
+		postID, err = insertGroupPost(tx, groupPost, draft.UserID, status)
+	} else {
+		postID, err = insertPost(tx, draft.UserID, post)
+	}
+	return postID, status, err
+}
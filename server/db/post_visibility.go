@@ -130,15 +130,12 @@ func (s *Service) GetPostVisibilityUsers(postID int64) ([]int64, error) {
 	return userIDs, nil
 }
 
-// HasPostVisibilityAccess checks if a user has access to view a specific private post
+// HasPostVisibilityAccess checks if a user has access to view a specific private post:
+// they were selected, or the post has no selection and they follow its author
 func (s *Service) HasPostVisibilityAccess(postID, userID int64) (bool, error) {
-	var count int
+	var allowed bool
 	err := s.DB.QueryRow(`
-        SELECT COUNT(*)
-        FROM post_visibility
-        WHERE post_id = ? AND user_id = ?`, postID, userID).Scan(&count)
-	if err != nil {
-		return false, err
-	}
-	return count > 0, nil
+        SELECT EXISTS(SELECT 1 FROM posts p WHERE p.id = ? AND `+visiblePostSQL("p")+`)`,
+		postID, userID, userID, userID).Scan(&allowed)
+	return allowed, err
 }
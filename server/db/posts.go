@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -14,18 +15,27 @@ func (s *Service) CreatePost(userID int64, req models.CreatePostRequest) (int64,
 		return 0, err
 	}
 
-	result, err := tx.Exec(`
-        INSERT INTO posts (user_id, title, body, visibility)
-        VALUES (?, ?, ?, ?)`,
-		userID, req.Title, req.Body, req.Visibility)
+	postID, err := insertPost(tx, userID, req)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
 
+	return postID, tx.Commit()
+}
+
+// insertPost creates a post with its images, visibility list and poll in the transaction
+func insertPost(tx *sql.Tx, userID int64, req models.CreatePostRequest) (int64, error) {
+	result, err := tx.Exec(`
+        INSERT INTO posts (user_id, title, body, visibility, audience_list_id)
+        VALUES (?, ?, ?, ?, ?)`,
+		userID, req.Title, req.Body, req.Visibility, req.AudienceListID)
+	if err != nil {
+		return 0, err
+	}
+
 	postID, err := result.LastInsertId()
 	if err != nil {
-		tx.Rollback()
 		return 0, err
 	}
 
@@ -36,7 +46,6 @@ func (s *Service) CreatePost(userID int64, req models.CreatePostRequest) (int64,
             VALUES (?, ?)`,
 			postID, imageURL)
 		if err != nil {
-			tx.Rollback()
 			return 0, err
 		}
 	}
@@ -49,13 +58,18 @@ func (s *Service) CreatePost(userID int64, req models.CreatePostRequest) (int64,
                 VALUES (?, ?)`,
 				postID, followerID)
 			if err != nil {
-				tx.Rollback()
 				return 0, err
 			}
 		}
 	}
 
-	return postID, tx.Commit()
+	if req.Poll != nil {
+		if err := insertPoll(tx, models.PollTargetPost, postID, userID, req.Poll); err != nil {
+			return 0, err
+		}
+	}
+
+	return postID, nil
 }
 
 func (s *Service) InsertPostImage(postID int, isGroupPost bool, imageURL string) error {
@@ -66,7 +80,101 @@ func (s *Service) InsertPostImage(postID int, isGroupPost bool, imageURL string)
 	return err
 }
 
+// GetPostByID returns a post the user can see, along with the post it shares when it's a repost or a quote.
+// A repost is hidden from those who can't see the post it shares, a quote only loses it.
 func (s *Service) GetPostByID(postID int64, currentUserID int64) (*models.PostResponse, error) {
+	post, err := s.getPost(postID, currentUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachOriginalPost(post, currentUserID); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// attachOriginalPost sets the post shared by a repost or a quote when the user can see it
+func (s *Service) attachOriginalPost(post *models.PostResponse, currentUserID int64) error {
+	original, err := s.sharedOriginal(post.Kind, post.OriginalPostID, currentUserID)
+	if err != nil {
+		return err
+	}
+	post.OriginalPost = original
+	return nil
+}
+
+// completePosts adds the images and shared posts of a list once its rows are read,
+// leaving out the reposts of posts the user can't see
+func (s *Service) completePosts(posts []*models.Post, currentUserID int64) ([]*models.Post, error) {
+	complete := posts[:0]
+	for _, post := range posts {
+		images, err := s.postImages(post.ID)
+		if err != nil {
+			return nil, err
+		}
+		post.Images = images
+
+		original, err := s.sharedOriginal(post.Kind, post.OriginalPostID, currentUserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) || err.Error() == "unauthorized" {
+				continue
+			}
+			return nil, err
+		}
+		post.OriginalPost = original
+		complete = append(complete, post)
+	}
+	return complete, nil
+}
+
+func (s *Service) postImages(postID int64) ([]string, error) {
+	rows, err := s.DB.Query(`
+		SELECT image_url FROM post_images
+		WHERE post_id = ? AND is_group_post = 0`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		images = append(images, url)
+	}
+	return images, rows.Err()
+}
+
+// sharedOriginal returns the post shared by a repost or a quote, nil for a plain post or a quote of a
+// post the user can't see. A repost of a post the user can't see is hidden as well.
+func (s *Service) sharedOriginal(kind string, originalPostID *int64, currentUserID int64) (*models.PostResponse, error) {
+	if kind == models.PostKindPost || kind == "" {
+		return nil, nil
+	}
+	if originalPostID == nil {
+		if kind == models.PostKindRepost {
+			return nil, sql.ErrNoRows
+		}
+		return nil, nil
+	}
+
+	original, err := s.getPost(*originalPostID, currentUserID)
+	if err != nil {
+		hidden := errors.Is(err, sql.ErrNoRows) || err.Error() == "unauthorized"
+		if !hidden {
+			return nil, err
+		}
+		if kind == models.PostKindRepost {
+			return nil, fmt.Errorf("unauthorized")
+		}
+		return nil, nil
+	}
+	return original, nil
+}
+
+func (s *Service) getPost(postID int64, currentUserID int64) (*models.PostResponse, error) {
 	query := `
         SELECT
             p.id,
@@ -83,7 +191,13 @@ func (s *Service) GetPostByID(postID int64, currentUserID int64) (*models.PostRe
             (SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
             (SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
             EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'like') AS user_liked,
-            EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked
+            EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+            (SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+            p.kind,
+            p.original_post_id,
+            (SELECT COUNT(*) FROM posts r WHERE r.original_post_id = p.id AND r.kind = 'repost') AS repost_count,
+            (SELECT COUNT(*) FROM posts r WHERE r.original_post_id = p.id AND r.kind = 'quote') AS quote_count,
+            EXISTS(SELECT 1 FROM posts r WHERE r.original_post_id = p.id AND r.kind = 'repost' AND r.user_id = ?) AS user_reposted
         FROM
             posts p
         JOIN
@@ -95,11 +209,12 @@ func (s *Service) GetPostByID(postID int64, currentUserID int64) (*models.PostRe
         GROUP BY
             p.id`
 
-	row := s.DB.QueryRow(query, currentUserID, currentUserID, postID)
+	row := s.DB.QueryRow(query, currentUserID, currentUserID, currentUserID, postID)
 
 	var post models.PostResponse
 	var images sql.NullString
 	var visibility string
+	var originalPostID sql.NullInt64
 
 	err := row.Scan(
 		&post.ID,
@@ -117,11 +232,20 @@ func (s *Service) GetPostByID(postID int64, currentUserID int64) (*models.PostRe
 		&post.Dislikes,
 		&post.UserLiked,
 		&post.UserDisliked,
+		&post.RevisionCount,
+		&post.Kind,
+		&originalPostID,
+		&post.RepostCount,
+		&post.QuoteCount,
+		&post.UserReposted,
 	)
 
 	if err != nil {
 		return nil, err
 	}
+	if originalPostID.Valid {
+		post.OriginalPostID = &originalPostID.Int64
+	}
 
 	if images.Valid && images.String != "" {
 		post.Images = strings.Split(images.String, ",")
@@ -129,6 +253,9 @@ func (s *Service) GetPostByID(postID int64, currentUserID int64) (*models.PostRe
 		post.Images = []string{}
 	}
 
+	post.Visibility = visibility
+	post.Edited = post.RevisionCount > 0
+
 	if visibility == "private" && post.AuthorID != currentUserID {
 		// Check if user is specifically allowed to see this private post
 		hasAccess, err := s.HasPostVisibilityAccess(postID, currentUserID)
@@ -143,6 +270,29 @@ func (s *Service) GetPostByID(postID int64, currentUserID int64) (*models.PostRe
 	return &post, nil
 }
 
+// visiblePostSQL is the condition for the post aliased p to be seen by a user, it takes the user id three times:
+// public posts, their own posts, and private posts shared with them or, without a selection, with their followers
+func visiblePostSQL(p string) string {
+	return `(` + p + `.visibility = 'public'
+            OR ` + p + `.user_id = ?
+            OR (` + p + `.visibility = 'private' AND (
+                EXISTS (
+                    SELECT 1 FROM post_visibility
+                    WHERE post_id = ` + p + `.id AND user_id = ?
+                )
+                OR (
+                    NOT EXISTS (
+                        SELECT 1 FROM post_visibility
+                        WHERE post_id = ` + p + `.id
+                    )
+                    AND EXISTS (
+                        SELECT 1 FROM follow_requests
+                        WHERE requester_id = ? AND target_id = ` + p + `.user_id AND status = 'accepted'
+                    )
+                )
+            )))`
+}
+
 func (s *Service) GetUserFeed(currentUserID, limit, offset int) ([]models.PostResponse, error) {
 	// Simplified approach: get user posts first, then group posts separately
 	var posts []models.PostResponse
@@ -165,6 +315,12 @@ func (s *Service) GetUserFeed(currentUserID, limit, offset int) ([]models.PostRe
             COALESCE((SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike'), 0) AS dislikes,
             EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'like') AS user_liked,
             EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+            (SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+            p.kind,
+            p.original_post_id,
+            (SELECT COUNT(*) FROM posts r WHERE r.original_post_id = p.id AND r.kind = 'repost') AS repost_count,
+            (SELECT COUNT(*) FROM posts r WHERE r.original_post_id = p.id AND r.kind = 'quote') AS quote_count,
+            EXISTS(SELECT 1 FROM posts r WHERE r.original_post_id = p.id AND r.kind = 'repost' AND r.user_id = ?) AS user_reposted,
             NULL AS group_id,
             NULL AS group_name
         FROM
@@ -174,28 +330,11 @@ func (s *Service) GetUserFeed(currentUserID, limit, offset int) ([]models.PostRe
         LEFT JOIN
             post_images pi ON p.id = pi.post_id AND pi.is_group_post = 0
         WHERE
-            p.visibility = 'public'
-            OR p.user_id = ?
-            OR (p.visibility = 'private' AND (
-                EXISTS (
-                    SELECT 1 FROM post_visibility
-                    WHERE post_id = p.id AND user_id = ?
-                )
-                OR (
-                    NOT EXISTS (
-                        SELECT 1 FROM post_visibility
-                        WHERE post_id = p.id
-                    )
-                    AND EXISTS (
-                        SELECT 1 FROM follow_requests
-                        WHERE requester_id = ? AND target_id = p.user_id AND status = 'accepted'
-                    )
-                )
-            ))
+            ` + visiblePostSQL("p") + `
         GROUP BY p.id, p.user_id, u.nickname, u.avatar, p.title, p.body, p.visibility, p.created_at, p.updated_at
         ORDER BY p.created_at DESC`
 
-	rows, err := s.DB.Query(userPostsQuery, currentUserID, currentUserID, currentUserID, currentUserID, currentUserID)
+	rows, err := s.DB.Query(userPostsQuery, currentUserID, currentUserID, currentUserID, currentUserID, currentUserID, currentUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -206,16 +345,23 @@ func (s *Service) GetUserFeed(currentUserID, limit, offset int) ([]models.PostRe
 		var images sql.NullString
 		var groupID sql.NullInt64
 		var groupName sql.NullString
+		var originalPostID sql.NullInt64
 
 		err := rows.Scan(
 			&post.ID, &post.PostType, &post.AuthorID, &post.AuthorNickname, &post.AuthorAvatar,
 			&post.Title, &post.Body, &post.Visibility, &post.CreatedAt, &post.UpdatedAt,
-			&images, &post.Likes, &post.Dislikes, &post.UserLiked, &post.UserDisliked,
+			&images, &post.Likes, &post.Dislikes, &post.UserLiked, &post.UserDisliked, &post.RevisionCount,
+			&post.Kind, &originalPostID, &post.RepostCount, &post.QuoteCount, &post.UserReposted,
 			&groupID, &groupName,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if originalPostID.Valid {
+			post.OriginalPostID = &originalPostID.Int64
+		}
+
+		post.Edited = post.RevisionCount > 0
 
 		// Parse images
 		if images.Valid && images.String != "" {
@@ -227,6 +373,19 @@ func (s *Service) GetUserFeed(currentUserID, limit, offset int) ([]models.PostRe
 		posts = append(posts, post)
 	}
 
+	// Reposts of posts the user can't see are left out, quotes of them show without the original
+	shared := posts[:0]
+	for i := range posts {
+		if err := s.attachOriginalPost(&posts[i], int64(currentUserID)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) || err.Error() == "unauthorized" {
+				continue
+			}
+			return nil, err
+		}
+		shared = append(shared, posts[i])
+	}
+	posts = shared
+
 	// Get group posts
 	groupPostsQuery := `
         SELECT
@@ -245,6 +404,12 @@ func (s *Service) GetUserFeed(currentUserID, limit, offset int) ([]models.PostRe
             COALESCE((SELECT COUNT(*) FROM likes_dislikes WHERE post_id = gp.id AND type = 'dislike'), 0) AS dislikes,
             EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = gp.id AND user_id = ? AND type = 'like') AS user_liked,
             EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = gp.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+            (SELECT COUNT(*) FROM content_revisions WHERE group_post_id = gp.id) AS revision_count,
+            'post' AS kind,
+            NULL AS original_post_id,
+            0 AS repost_count,
+            0 AS quote_count,
+            0 AS user_reposted,
             gp.group_id,
             g.title AS group_name
         FROM
@@ -273,17 +438,21 @@ func (s *Service) GetUserFeed(currentUserID, limit, offset int) ([]models.PostRe
 		var images sql.NullString
 		var groupID sql.NullInt64
 		var groupName sql.NullString
+		var originalPostID sql.NullInt64
 
 		err := groupRows.Scan(
 			&post.ID, &post.PostType, &post.AuthorID, &post.AuthorNickname, &post.AuthorAvatar,
 			&post.Title, &post.Body, &post.Visibility, &post.CreatedAt, &post.UpdatedAt,
-			&images, &post.Likes, &post.Dislikes, &post.UserLiked, &post.UserDisliked,
+			&images, &post.Likes, &post.Dislikes, &post.UserLiked, &post.UserDisliked, &post.RevisionCount,
+			&post.Kind, &originalPostID, &post.RepostCount, &post.QuoteCount, &post.UserReposted,
 			&groupID, &groupName,
 		)
 		if err != nil {
 			return nil, err
 		}
 
+		post.Edited = post.RevisionCount > 0
+
 		// Parse images
 		if images.Valid && images.String != "" {
 			post.Images = strings.Split(images.String, ",")
@@ -339,7 +508,9 @@ func (s *Service) GetPostsByUser(userID int64, currentUserID int64) ([]*models.P
 				(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
 				(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
 				EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'like') AS user_liked,
-				EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked
+				EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+				(SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+				p.kind, p.original_post_id
 			FROM posts p
 			JOIN users u ON p.user_id = u.id
 			WHERE p.user_id = ?
@@ -355,7 +526,9 @@ func (s *Service) GetPostsByUser(userID int64, currentUserID int64) ([]*models.P
 				(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
 				(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
 				EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'like') AS user_liked,
-				EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked
+				EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+				(SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+				p.kind, p.original_post_id
 			FROM posts p
 			JOIN users u ON p.user_id = u.id
 			WHERE p.user_id = ?
@@ -383,12 +556,14 @@ func (s *Service) GetPostsByUser(userID int64, currentUserID int64) ([]*models.P
 		var post models.Post
 		var userLikedInt, userDislikedInt int
 		var authorNickname, authorFirstName, authorLastName string
+		var originalPostID sql.NullInt64
 
 		err := rows.Scan(
 			&post.ID, &post.UserID, &post.Title, &post.Body, &post.Visibility,
 			&post.CreatedAt, &post.UpdatedAt,
 			&authorNickname, &authorFirstName, &authorLastName,
-			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt,
+			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt, &post.RevisionCount,
+			&post.Kind, &originalPostID,
 		)
 		if err != nil {
 			return nil, err
@@ -396,34 +571,18 @@ func (s *Service) GetPostsByUser(userID int64, currentUserID int64) ([]*models.P
 
 		post.UserLiked = userLikedInt == 1
 		post.UserDisliked = userDislikedInt == 1
+		post.Edited = post.RevisionCount > 0
+		if originalPostID.Valid {
+			post.OriginalPostID = &originalPostID.Int64
+		}
 		post.AuthorNickname = authorNickname
 		post.AuthorFirstName = authorFirstName
 		post.AuthorLastName = authorLastName
 
-		// Get images for this post
-		imageRows, err := s.DB.Query(`
-			SELECT image_url FROM post_images
-			WHERE post_id = ? AND is_group_post = 0`, post.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var images []string
-		for imageRows.Next() {
-			var url string
-			if err := imageRows.Scan(&url); err != nil {
-				imageRows.Close()
-				return nil, err
-			}
-			images = append(images, url)
-		}
-		imageRows.Close()
-		post.Images = images
-
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return s.completePosts(posts, currentUserID)
 }
 
 // GetLikedPosts retrieves posts liked by a specific user with proper visibility filtering
@@ -435,7 +594,9 @@ func (s *Service) GetLikedPosts(userID int64, currentUserID int64) ([]*models.Po
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
 			1 AS user_liked,
-			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked
+			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+			(SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+			p.kind, p.original_post_id
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN likes_dislikes ld ON p.id = ld.post_id
@@ -463,13 +624,15 @@ func (s *Service) GetLikedPosts(userID int64, currentUserID int64) ([]*models.Po
 		var post models.Post
 		var userLikedInt, userDislikedInt int
 		var authorNickname, authorFirstName, authorLastName string
+		var originalPostID sql.NullInt64
 		var authorAvatar sql.NullString
 
 		err := rows.Scan(
 			&post.ID, &post.UserID, &post.Title, &post.Body, &post.Visibility,
 			&post.CreatedAt, &post.UpdatedAt,
 			&authorNickname, &authorFirstName, &authorLastName, &authorAvatar,
-			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt,
+			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt, &post.RevisionCount,
+			&post.Kind, &originalPostID,
 		)
 		if err != nil {
 			return nil, err
@@ -477,6 +640,10 @@ func (s *Service) GetLikedPosts(userID int64, currentUserID int64) ([]*models.Po
 
 		post.UserLiked = userLikedInt == 1
 		post.UserDisliked = userDislikedInt == 1
+		post.Edited = post.RevisionCount > 0
+		if originalPostID.Valid {
+			post.OriginalPostID = &originalPostID.Int64
+		}
 		post.AuthorNickname = authorNickname
 		post.AuthorFirstName = authorFirstName
 		post.AuthorLastName = authorLastName
@@ -486,30 +653,10 @@ func (s *Service) GetLikedPosts(userID int64, currentUserID int64) ([]*models.Po
 			post.AuthorAvatar = ""
 		}
 
-		// Get images for this post
-		imageRows, err := s.DB.Query(`
-			SELECT image_url FROM post_images
-			WHERE post_id = ? AND is_group_post = 0`, post.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var images []string
-		for imageRows.Next() {
-			var url string
-			if err := imageRows.Scan(&url); err != nil {
-				imageRows.Close()
-				return nil, err
-			}
-			images = append(images, url)
-		}
-		imageRows.Close()
-		post.Images = images
-
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return s.completePosts(posts, currentUserID)
 }
 
 // GetCommentedPosts retrieves posts commented by a specific user with proper visibility filtering
@@ -521,7 +668,9 @@ func (s *Service) GetCommentedPosts(userID int64, currentUserID int64) ([]*model
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
 			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'like') AS user_liked,
-			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked
+			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+			(SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+			p.kind, p.original_post_id
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN comments c ON p.id = c.post_id
@@ -549,13 +698,15 @@ func (s *Service) GetCommentedPosts(userID int64, currentUserID int64) ([]*model
 		var post models.Post
 		var userLikedInt, userDislikedInt int
 		var authorNickname, authorFirstName, authorLastName string
+		var originalPostID sql.NullInt64
 		var authorAvatar sql.NullString
 
 		err := rows.Scan(
 			&post.ID, &post.UserID, &post.Title, &post.Body, &post.Visibility,
 			&post.CreatedAt, &post.UpdatedAt,
 			&authorNickname, &authorFirstName, &authorLastName, &authorAvatar,
-			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt,
+			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt, &post.RevisionCount,
+			&post.Kind, &originalPostID,
 		)
 		if err != nil {
 			return nil, err
@@ -563,6 +714,10 @@ func (s *Service) GetCommentedPosts(userID int64, currentUserID int64) ([]*model
 
 		post.UserLiked = userLikedInt == 1
 		post.UserDisliked = userDislikedInt == 1
+		post.Edited = post.RevisionCount > 0
+		if originalPostID.Valid {
+			post.OriginalPostID = &originalPostID.Int64
+		}
 		post.AuthorNickname = authorNickname
 		post.AuthorFirstName = authorFirstName
 		post.AuthorLastName = authorLastName
@@ -572,30 +727,10 @@ func (s *Service) GetCommentedPosts(userID int64, currentUserID int64) ([]*model
 			post.AuthorAvatar = ""
 		}
 
-		// Get images for this post
-		imageRows, err := s.DB.Query(`
-			SELECT image_url FROM post_images
-			WHERE post_id = ? AND is_group_post = 0`, post.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var images []string
-		for imageRows.Next() {
-			var url string
-			if err := imageRows.Scan(&url); err != nil {
-				imageRows.Close()
-				return nil, err
-			}
-			images = append(images, url)
-		}
-		imageRows.Close()
-		post.Images = images
-
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return s.completePosts(posts, currentUserID)
 }
 
 // GetLikedPostsByUser retrieves posts liked by a specific user with proper visibility filtering
@@ -607,7 +742,9 @@ func (s *Service) GetLikedPostsByUser(userID int64, currentUserID int64) ([]*mod
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
 			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'like') AS user_liked,
-			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked
+			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+			(SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+			p.kind, p.original_post_id
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN likes_dislikes ld ON p.id = ld.post_id
@@ -635,13 +772,15 @@ func (s *Service) GetLikedPostsByUser(userID int64, currentUserID int64) ([]*mod
 		var post models.Post
 		var userLikedInt, userDislikedInt int
 		var authorNickname, authorFirstName, authorLastName string
+		var originalPostID sql.NullInt64
 		var authorAvatar sql.NullString
 
 		err := rows.Scan(
 			&post.ID, &post.UserID, &post.Title, &post.Body, &post.Visibility,
 			&post.CreatedAt, &post.UpdatedAt,
 			&authorNickname, &authorFirstName, &authorLastName, &authorAvatar,
-			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt,
+			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt, &post.RevisionCount,
+			&post.Kind, &originalPostID,
 		)
 		if err != nil {
 			return nil, err
@@ -649,6 +788,10 @@ func (s *Service) GetLikedPostsByUser(userID int64, currentUserID int64) ([]*mod
 
 		post.UserLiked = userLikedInt == 1
 		post.UserDisliked = userDislikedInt == 1
+		post.Edited = post.RevisionCount > 0
+		if originalPostID.Valid {
+			post.OriginalPostID = &originalPostID.Int64
+		}
 		post.AuthorNickname = authorNickname
 		post.AuthorFirstName = authorFirstName
 		post.AuthorLastName = authorLastName
@@ -658,30 +801,10 @@ func (s *Service) GetLikedPostsByUser(userID int64, currentUserID int64) ([]*mod
 			post.AuthorAvatar = ""
 		}
 
-		// Get images for this post
-		imageRows, err := s.DB.Query(`
-			SELECT image_url FROM post_images
-			WHERE post_id = ? AND is_group_post = 0`, post.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var images []string
-		for imageRows.Next() {
-			var url string
-			if err := imageRows.Scan(&url); err != nil {
-				imageRows.Close()
-				return nil, err
-			}
-			images = append(images, url)
-		}
-		imageRows.Close()
-		post.Images = images
-
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return s.completePosts(posts, currentUserID)
 }
 
 // GetCommentedPostsByUser retrieves posts commented by a specific user with proper visibility filtering
@@ -693,7 +816,9 @@ func (s *Service) GetCommentedPostsByUser(userID int64, currentUserID int64) ([]
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
 			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'like') AS user_liked,
-			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked
+			EXISTS(SELECT 1 FROM likes_dislikes WHERE post_id = p.id AND user_id = ? AND type = 'dislike') AS user_disliked,
+			(SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+			p.kind, p.original_post_id
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		JOIN comments c ON p.id = c.post_id
@@ -721,13 +846,15 @@ func (s *Service) GetCommentedPostsByUser(userID int64, currentUserID int64) ([]
 		var post models.Post
 		var userLikedInt, userDislikedInt int
 		var authorNickname, authorFirstName, authorLastName string
+		var originalPostID sql.NullInt64
 		var authorAvatar sql.NullString
 
 		err := rows.Scan(
 			&post.ID, &post.UserID, &post.Title, &post.Body, &post.Visibility,
 			&post.CreatedAt, &post.UpdatedAt,
 			&authorNickname, &authorFirstName, &authorLastName, &authorAvatar,
-			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt,
+			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt, &post.RevisionCount,
+			&post.Kind, &originalPostID,
 		)
 		if err != nil {
 			return nil, err
@@ -735,6 +862,10 @@ func (s *Service) GetCommentedPostsByUser(userID int64, currentUserID int64) ([]
 
 		post.UserLiked = userLikedInt == 1
 		post.UserDisliked = userDislikedInt == 1
+		post.Edited = post.RevisionCount > 0
+		if originalPostID.Valid {
+			post.OriginalPostID = &originalPostID.Int64
+		}
 		post.AuthorNickname = authorNickname
 		post.AuthorFirstName = authorFirstName
 		post.AuthorLastName = authorLastName
@@ -744,30 +875,10 @@ func (s *Service) GetCommentedPostsByUser(userID int64, currentUserID int64) ([]
 			post.AuthorAvatar = ""
 		}
 
-		// Get images for this post
-		imageRows, err := s.DB.Query(`
-			SELECT image_url FROM post_images
-			WHERE post_id = ? AND is_group_post = 0`, post.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var images []string
-		for imageRows.Next() {
-			var url string
-			if err := imageRows.Scan(&url); err != nil {
-				imageRows.Close()
-				return nil, err
-			}
-			images = append(images, url)
-		}
-		imageRows.Close()
-		post.Images = images
-
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return s.completePosts(posts, currentUserID)
 }
 
 func (s *Service) IsFollowing(currentUserID, targetUserID int64) (bool, error) {
@@ -817,6 +928,10 @@ func (s *Service) UpdatePost(postID int64, req models.UpdatePostRequest) error {
 	}
 	defer tx.Rollback() // Rollback on any error
 
+	if err = recordRevision(tx, models.RevisionTargetPost, postID, 0, req.Title, req.Body, req.Images); err != nil {
+		return err
+	}
+
 	var setParts []string
 	var args []interface{}
 
@@ -878,7 +993,7 @@ func (s *Service) DeletePost(postID int64) error {
 	}
 
 	// Delete the post. Associated data in tables with ON DELETE CASCADE will be removed automatically.
-	// This includes: comments, likes_dislikes, post_images.
+	// This includes: comments, likes_dislikes, post_images. Its reposts are deleted by a trigger.
 	result, err := tx.Exec(`DELETE FROM posts WHERE id = ?`, postID)
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
@@ -910,7 +1025,9 @@ func (s *Service) GetPublicPosts() ([]*models.Post, error) {
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'like') AS likes,
 			(SELECT COUNT(*) FROM likes_dislikes WHERE post_id = p.id AND type = 'dislike') AS dislikes,
 			0 AS user_liked,
-			0 AS user_disliked
+			0 AS user_disliked,
+			(SELECT COUNT(*) FROM content_revisions WHERE post_id = p.id) AS revision_count,
+			p.kind, p.original_post_id
 		FROM posts p
 		JOIN users u ON p.user_id = u.id
 		WHERE p.visibility = 'public'
@@ -929,13 +1046,15 @@ func (s *Service) GetPublicPosts() ([]*models.Post, error) {
 		var post models.Post
 		var userLikedInt, userDislikedInt int
 		var authorNickname, authorFirstName, authorLastName string
+		var originalPostID sql.NullInt64
 		var authorAvatar sql.NullString
 
 		err := rows.Scan(
 			&post.ID, &post.UserID, &post.Title, &post.Body, &post.Visibility,
 			&post.CreatedAt, &post.UpdatedAt,
 			&authorNickname, &authorFirstName, &authorLastName, &authorAvatar,
-			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt,
+			&post.Likes, &post.Dislikes, &userLikedInt, &userDislikedInt, &post.RevisionCount,
+			&post.Kind, &originalPostID,
 		)
 		if err != nil {
 			return nil, err
@@ -943,6 +1062,10 @@ func (s *Service) GetPublicPosts() ([]*models.Post, error) {
 
 		post.UserLiked = userLikedInt == 1
 		post.UserDisliked = userDislikedInt == 1
+		post.Edited = post.RevisionCount > 0
+		if originalPostID.Valid {
+			post.OriginalPostID = &originalPostID.Int64
+		}
 		post.AuthorNickname = authorNickname
 		post.AuthorFirstName = authorFirstName
 		post.AuthorLastName = authorLastName
@@ -952,28 +1075,8 @@ func (s *Service) GetPublicPosts() ([]*models.Post, error) {
 			post.AuthorAvatar = ""
 		}
 
-		// Get images for this post
-		imageRows, err := s.DB.Query(`
-			SELECT image_url FROM post_images
-			WHERE post_id = ? AND is_group_post = 0`, post.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		var images []string
-		for imageRows.Next() {
-			var url string
-			if err := imageRows.Scan(&url); err != nil {
-				imageRows.Close()
-				return nil, err
-			}
-			images = append(images, url)
-		}
-		imageRows.Close()
-		post.Images = images
-
 		posts = append(posts, &post)
 	}
 
-	return posts, nil
+	return s.completePosts(posts, 0)
 }
@@ -0,0 +1,62 @@
+package db
+
+// PushSubscription is a browser registered for Web Push
+type PushSubscription struct {
+	ID        int64
+	UserID    int64
+	Endpoint  string
+	P256dh    string
+	Auth      string
+	UserAgent string
+}
+
+// SavePushSubscription registers a push endpoint for the user.
+// Endpoints are unique per browser, saving one again moves it to the current user and refreshes the keys.
+func (s *Service) SavePushSubscription(sub PushSubscription) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(endpoint) DO UPDATE SET
+			user_id = excluded.user_id,
+			p256dh = excluded.p256dh,
+			auth = excluded.auth,
+			user_agent = excluded.user_agent`,
+		sub.UserID, sub.Endpoint, sub.P256dh, sub.Auth, sub.UserAgent)
+	return err
+}
+
+// GetPushSubscriptions returns every push endpoint of the user
+func (s *Service) GetPushSubscriptions(userID int64) ([]PushSubscription, error) {
+	rows, err := s.DB.Query(`
+		SELECT id, user_id, endpoint, p256dh, auth, COALESCE(user_agent, '')
+		FROM push_subscriptions WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []PushSubscription
+	for rows.Next() {
+		var sub PushSubscription
+		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.Endpoint, &sub.P256dh, &sub.Auth, &sub.UserAgent); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	return subscriptions, rows.Err()
+}
+
+// DeletePushSubscription removes an endpoint of the user, it returns false when the user has no such endpoint
+func (s *Service) DeletePushSubscription(userID int64, endpoint string) (bool, error) {
+	result, err := s.DB.Exec(`DELETE FROM push_subscriptions WHERE user_id = ? AND endpoint = ?`, userID, endpoint)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// DeletePushSubscriptionByEndpoint removes an endpoint the push service reported as gone
+func (s *Service) DeletePushSubscriptionByEndpoint(endpoint string) error {
+	_, err := s.DB.Exec(`DELETE FROM push_subscriptions WHERE endpoint = ?`, endpoint)
+	return err
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+var (
+	// ErrAlreadyReposted is returned when the user reposts a post a second time
+	ErrAlreadyReposted = errors.New("post already reposted")
+	// ErrRepostNotFound is returned when undoing a repost the user never made
+	ErrRepostNotFound = errors.New("repost not found")
+	// ErrRepostAudience is returned when a private post would be reshared to someone who can't see it
+	ErrRepostAudience = errors.New("a private post can only be reshared privately to those who can see it")
+)
+
+// CreateRepost shares the post originalID as a repost, or as a quote when the request has a body.
+// The caller checks the user can see the post, which must not be a repost itself.
+func (s *Service) CreateRepost(userID, originalID int64, req models.RepostRequest) (int64, error) {
+	// A private repost is shown to followers of the user only, like any private post
+	if req.Visibility == "private" {
+		followers, err := s.FilterFollowers(userID, req.SelectedFollowers)
+		if err != nil {
+			return 0, err
+		}
+		if len(followers) != len(req.SelectedFollowers) {
+			return 0, ErrRepostAudience
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	var authorID int64
+	var visibility string
+	err = tx.QueryRow(`SELECT user_id, visibility FROM posts WHERE id = ?`, originalID).Scan(&authorID, &visibility)
+	if err != nil {
+		return 0, err
+	}
+
+	if visibility == "private" {
+		if req.Visibility != "private" || len(req.SelectedFollowers) == 0 {
+			err = ErrRepostAudience
+			return 0, err
+		}
+		// The audience of the original is its selected users, or all the followers of its author without a selection
+		for _, followerID := range req.SelectedFollowers {
+			if followerID == authorID {
+				continue
+			}
+			var allowed bool
+			err = tx.QueryRow(`
+				SELECT CASE
+					WHEN EXISTS(SELECT 1 FROM post_visibility WHERE post_id = ?)
+					THEN EXISTS(SELECT 1 FROM post_visibility WHERE post_id = ? AND user_id = ?)
+					ELSE EXISTS(SELECT 1 FROM follow_requests WHERE requester_id = ? AND target_id = ? AND status = 'accepted')
+				END`, originalID, originalID, followerID, followerID, authorID).Scan(&allowed)
+			if err != nil {
+				return 0, err
+			}
+			if !allowed {
+				err = ErrRepostAudience
+				return 0, err
+			}
+		}
+	}
+
+	kind := models.PostKindRepost
+	body := strings.TrimSpace(req.Body)
+	if body != "" {
+		kind = models.PostKindQuote
+	} else {
+		var reposted bool
+		err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM posts WHERE user_id = ? AND original_post_id = ? AND kind = 'repost')`,
+			userID, originalID).Scan(&reposted)
+		if err != nil {
+			return 0, err
+		}
+		if reposted {
+			err = ErrAlreadyReposted
+			return 0, err
+		}
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO posts (user_id, title, body, visibility, kind, original_post_id)
+		VALUES (?, '', ?, ?, ?, ?)`,
+		userID, body, req.Visibility, kind, originalID)
+	if err != nil {
+		return 0, err
+	}
+	postID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if req.Visibility == "private" {
+		for _, followerID := range req.SelectedFollowers {
+			if _, err = tx.Exec(`INSERT INTO post_visibility (post_id, user_id) VALUES (?, ?)`, postID, followerID); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return postID, nil
+}
+
+// DeleteRepost undoes the repost of originalID by the user, quotes are deleted like any other post
+func (s *Service) DeleteRepost(userID, originalID int64) error {
+	result, err := s.DB.Exec(`DELETE FROM posts WHERE user_id = ? AND original_post_id = ? AND kind = 'repost'`,
+		userID, originalID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRepostNotFound
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Golden76z/social-network/models"
+)
+
+// GetTwoFactor returns the 2FA record of a user, sql.ErrNoRows if the user never started enrollment
+func (s *Service) GetTwoFactor(userID int64) (*models.TwoFactor, error) {
+	var tf models.TwoFactor
+	err := s.DB.QueryRow(`
+		SELECT user_id, secret, enabled, last_used_step
+		FROM user_two_factor WHERE user_id = ?`, userID,
+	).Scan(&tf.UserID, &tf.Secret, &tf.Enabled, &tf.LastUsedStep)
+	if err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// IsTwoFactorEnabled reports whether the user has completed 2FA enrollment
+func (s *Service) IsTwoFactorEnabled(userID int64) (bool, error) {
+	var enabled bool
+	err := s.DB.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM user_two_factor WHERE user_id = ? AND enabled = TRUE)
+	`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// SaveTwoFactorSecret stores a new pending secret, replacing any unfinished enrollment
+func (s *Service) SaveTwoFactorSecret(userID int64, secret string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var enabled bool
+	err = tx.QueryRow(`SELECT enabled FROM user_two_factor WHERE user_id = ?`, userID).Scan(&enabled)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil && enabled {
+		err = errors.New("two-factor authentication already enabled")
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_two_factor (user_id, secret, enabled, last_used_step)
+		VALUES (?, ?, FALSE, 0)
+		ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, enabled = FALSE, last_used_step = 0, created_at = CURRENT_TIMESTAMP
+	`, userID, secret)
+	return err
+}
+
+// EnableTwoFactor activates the pending secret and stores the hashed recovery codes
+func (s *Service) EnableTwoFactor(userID int64, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	res, err := tx.Exec(`
+		UPDATE user_two_factor
+		SET enabled = TRUE, last_used_step = ?, enabled_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND enabled = FALSE
+	`, step, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		err = errors.New("no pending two-factor enrollment")
+		return err
+	}
+
+	err = replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	return err
+}
+
+// DisableTwoFactor removes the secret and every recovery code of the user
+func (s *Service) DisableTwoFactor(userID int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	if _, err = tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM user_two_factor WHERE user_id = ?`, userID)
+	return err
+}
+
+// MarkTOTPStepUsed records the time step of an accepted code.
+// It returns false when the step was already used, so the same code cannot be replayed.
+func (s *Service) MarkTOTPStepUsed(userID int64, step int64) (bool, error) {
+	res, err := s.DB.Exec(`
+		UPDATE user_two_factor SET last_used_step = ?
+		WHERE user_id = ? AND last_used_step < ?
+	`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user
+func (s *Service) RegenerateRecoveryCodes(userID int64, recoveryCodeHashes []string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	err = replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	return err
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int64, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`
+			INSERT INTO two_factor_recovery_codes (user_id, code_hash)
+			VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode marks a matching unused recovery code as used, returning false if none matched
+func (s *Service) ConsumeRecoveryCode(userID int64, codeHash string) (bool, error) {
+	res, err := s.DB.Exec(`
+		UPDATE two_factor_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// CountRemainingRecoveryCodes returns how many recovery codes are still unused
+func (s *Service) CountRemainingRecoveryCodes(userID int64) (int, error) {
+	var count int
+	err := s.DB.QueryRow(`
+		SELECT COUNT(*) FROM two_factor_recovery_codes
+		WHERE user_id = ? AND used_at IS NULL
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// ===== LOGIN CHALLENGES =====
+
+// CreateLoginChallenge stores the pending second step of a password login
+func (s *Service) CreateLoginChallenge(userID int64, token string, ttl time.Duration) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	// Only one pending challenge per user
+	if _, err = tx.Exec(`DELETE FROM login_challenges WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO login_challenges (token, user_id, expires_at)
+		VALUES (?, ?, ?)`, token, userID, time.Now().Add(ttl))
+	return err
+}
+
+// GetLoginChallenge returns the user and attempt count of a non-expired challenge
+func (s *Service) GetLoginChallenge(token string) (int64, int, error) {
+	var userID int64
+	var attempts int
+	var expiresAt time.Time
+	err := s.DB.QueryRow(`
+		SELECT user_id, attempts, expires_at FROM login_challenges WHERE token = ?
+	`, token).Scan(&userID, &attempts, &expiresAt)
+	if err != nil {
+		return 0, 0, err
+	}
+	if time.Now().After(expiresAt) {
+		_ = s.DeleteLoginChallenge(token)
+		return 0, 0, errors.New("login challenge expired")
+	}
+	return userID, attempts, nil
+}
+
+// ReserveLoginChallengeAttempt counts a code submission before it is checked, in a single
+// statement so concurrent submissions can't go past maxAttempts. It returns false once the
+// challenge has no attempt left or has expired.
+func (s *Service) ReserveLoginChallengeAttempt(token string, maxAttempts int) (bool, error) {
+	result, err := s.DB.Exec(`
+		UPDATE login_challenges SET attempts = attempts + 1
+		WHERE token = ? AND attempts < ? AND expires_at > ?
+	`, token, maxAttempts, time.Now())
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// DeleteLoginChallenge removes a challenge once used or abandoned
+func (s *Service) DeleteLoginChallenge(token string) error {
+	_, err := s.DB.Exec(`DELETE FROM login_challenges WHERE token = ?`, token)
+	return err
+}
+
+// ===== GROUP ADMIN REQUIREMENT =====
+
+// GetGroupRequireAdmin2FA reports whether admins of the group must have 2FA enabled
+func (s *Service) GetGroupRequireAdmin2FA(groupID int64) (bool, error) {
+	var required bool
+	err := s.DB.QueryRow(`SELECT require_admin_2fa FROM groups WHERE id = ?`, groupID).Scan(&required)
+	return required, err
+}
+
+// SetGroupRequireAdmin2FA toggles the requirement, refusing to enable it while an admin lacks 2FA
+func (s *Service) SetGroupRequireAdmin2FA(groupID int64, required bool) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	err = setGroupRequireAdmin2FA(tx, groupID, required)
+	return err
+}
+
+func setGroupRequireAdmin2FA(tx *sql.Tx, groupID int64, required bool) error {
+	if required {
+		var missing int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM group_members gm
+			LEFT JOIN user_two_factor tf ON tf.user_id = gm.user_id AND tf.enabled = TRUE
+			WHERE gm.group_id = ? AND gm.role IN ('owner', 'admin') AND tf.user_id IS NULL
+		`, groupID).Scan(&missing)
+		if err != nil {
+			return err
+		}
+		if missing > 0 {
+			return errors.New("every group admin must enable two-factor authentication first")
+		}
+	}
+
+	_, err := tx.Exec(`UPDATE groups SET require_admin_2fa = ? WHERE id = ?`, required, groupID)
+	return err
+}
+
+// checkNewOwner2FA refuses a new owner without 2FA when the group requires it of its admins
+func checkNewOwner2FA(tx *sql.Tx, groupID, userID int64) error {
+	var missing bool
+	err := tx.QueryRow(`
+		SELECT g.require_admin_2fa = TRUE AND NOT EXISTS(
+			SELECT 1 FROM user_two_factor WHERE user_id = ? AND enabled = TRUE
+		)
+		FROM groups g WHERE g.id = ?
+	`, userID, groupID).Scan(&missing)
+	if err != nil {
+		return err
+	}
+	if missing {
+		return errors.New("new owner must enable two-factor authentication first")
+	}
+	return nil
+}
+
+// IsAdminOfGroupRequiring2FA reports whether the user administers a group that requires 2FA
+func (s *Service) IsAdminOfGroupRequiring2FA(userID int64) (bool, error) {
+	var exists bool
+	err := s.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM group_members gm
+			JOIN groups g ON g.id = gm.group_id
+			WHERE gm.user_id = ? AND gm.role IN ('owner', 'admin') AND g.require_admin_2fa = TRUE
+		)
+	`, userID).Scan(&exists)
+	return exists, err
+}
@@ -311,7 +311,7 @@ func seedGroupPosts(s *db.Service) error {
 			Title:   row[3],
 			Body:    row[4],
 		}
-		if err := s.CreateGroupPost(req, userID); err != nil {
+		if _, err := s.CreateGroupPost(req, userID); err != nil {
 			log.Printf("Error creating group post (line %d): %v", i+1, err)
 		}
 	}
@@ -624,7 +624,7 @@ func SeedGroupEvents(s *db.Service, rows [][]string) error {
 			EventDateTime: row[4],
 		}
 
-		if err := s.CreateGroupEvent(event, creatorID); err != nil {
+		if _, err := s.CreateGroupEvent(event, creatorID); err != nil {
 			log.Printf("failed to create group event at row %d: %v", i, err)
 		}
 	}
@@ -659,7 +659,7 @@ func SeedEventRSVPs(s *db.Service, rows [][]string) error {
 			Status:  row[2],
 		}
 
-		if err := s.CreateEventRSVP(rsvp); err != nil {
+		if _, err := s.CreateEventRSVP(rsvp); err != nil {
 			log.Printf("failed to create event RSVP at row %d: %v", i, err)
 		}
 	}
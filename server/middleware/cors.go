@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // CORSConfig holds CORS configuration
@@ -35,7 +38,7 @@ func SetupCORS() func(http.Handler) http.Handler {
 			AllowedOrigins:   origins,
 			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With"},
-			ExposedHeaders:   []string{"Link"},
+			ExposedHeaders:   []string{"Link", "X-CSRF-Token", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 			AllowCredentials: true,
 			MaxAge:           300,
 		})
@@ -49,7 +52,7 @@ func SetupCORS() func(http.Handler) http.Handler {
 		},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "X-CSRF-Token", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
@@ -102,23 +105,91 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 	}
 }
 
-// GetClientIP extracts the client IP address from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		return strings.TrimSpace(ips[0])
+// trustedProxies are the reverse proxies whose forwarding headers are believed,
+// any other client could put an arbitrary address in them
+var trustedProxies = struct {
+	sync.RWMutex
+	nets []*net.IPNet
+}{}
+
+// SetTrustedProxies configures the addresses or CIDR ranges of the reverse proxies in front of the server
+func SetTrustedProxies(proxies []string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		nets = append(nets, ipNet)
 	}
 
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	trustedProxies.Lock()
+	trustedProxies.nets = nets
+	trustedProxies.Unlock()
+	return nil
+}
+
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	trustedProxies.RLock()
+	defer trustedProxies.RUnlock()
+	for _, ipNet := range trustedProxies.nets {
+		if ipNet.Contains(parsed) {
+			return true
+		}
 	}
+	return false
+}
 
-	// Fallback to RemoteAddr
+// GetClientIP extracts the client IP address from the request. Forwarding headers are only read
+// when the request comes from a trusted proxy, the client is the last hop no trusted proxy added.
+func GetClientIP(r *http.Request) string {
 	ip := r.RemoteAddr
-	if colon := strings.LastIndex(ip, ":"); colon != -1 {
-		ip = ip[:colon]
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	// Check X-Forwarded-For header, from the closest hop back
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			ip = hop
+			if !isTrustedProxy(hop) {
+				break
+			}
+		}
+		return ip
+	}
+
+	// Check X-Real-IP header
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xri) != nil {
+		return xri
 	}
 	return ip
 }
@@ -1,66 +1,183 @@
 package middleware
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+)
 
-	"github.com/Golden76z/social-network/utils"
+// CSRF protection: stateless double-submit tokens.
+// A token is "<nonce>.<expiry>.<signature>" where the signature is an HMAC over the
+// nonce, the expiry and the session (JWT cookie) it was issued for. The same token is
+// sent as a cookie and echoed by the client in the X-CSRF-Token header.
+const (
+	csrfHeaderName = "X-CSRF-Token"
+	csrfCookieName = "csrf_token"
 )
 
-// CSRF protection
-var csrfTokens = struct {
+var csrfSettings = struct {
 	sync.RWMutex
-	tokens map[string]time.Time
-}{tokens: make(map[string]time.Time)}
+	key []byte
+	ttl time.Duration
+}{ttl: 4 * time.Hour}
+
+// SetCSRFSecret configures the signing key and token lifetime. An empty secret keeps a random per-process key.
+func SetCSRFSecret(secret string, ttl time.Duration) {
+	csrfSettings.Lock()
+	defer csrfSettings.Unlock()
+	if secret != "" {
+		csrfSettings.key = []byte(secret)
+	}
+	if ttl > 0 {
+		csrfSettings.ttl = ttl
+	}
+}
+
+func csrfKeyAndTTL() ([]byte, time.Duration) {
+	csrfSettings.RLock()
+	key, ttl := csrfSettings.key, csrfSettings.ttl
+	csrfSettings.RUnlock()
+	if key != nil {
+		return key, ttl
+	}
+
+	csrfSettings.Lock()
+	defer csrfSettings.Unlock()
+	if csrfSettings.key == nil {
+		csrfSettings.key = make([]byte, 32)
+		if _, err := rand.Read(csrfSettings.key); err != nil {
+			panic("csrf: cannot generate signing key: " + err.Error())
+		}
+	}
+	return csrfSettings.key, csrfSettings.ttl
+}
+
+func csrfSignature(key []byte, session, payload string) string {
+	sessionHash := sha256.Sum256([]byte(session))
+	mac := hmac.New(sha256.New, key)
+	mac.Write(sessionHash[:])
+	mac.Write([]byte("|" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateCSRFToken creates a token bound to the given session
+func GenerateCSRFToken(session string) (string, error) {
+	key, ttl := csrfKeyAndTTL()
 
-func generateCSRFToken() (string, error) {
-	bytes := make([]byte, 32)
-	if _, err := rand.Read(bytes); err != nil {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
 		return "", err
 	}
-	return base64.URLEncoding.EncodeToString(bytes), nil
+
+	payload := base64.RawURLEncoding.EncodeToString(nonce) + "." + strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return payload + "." + csrfSignature(key, session, payload), nil
+}
+
+// ValidateCSRFToken checks the signature, the session binding and the expiry of a token
+func ValidateCSRFToken(token, session string) error {
+	key, _ := csrfKeyAndTTL()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("malformed CSRF token")
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(csrfSignature(key, session, payload))) {
+		return errors.New("invalid CSRF token signature")
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return errors.New("malformed CSRF token")
+	}
+	if time.Now().Unix() > expiry {
+		return errors.New("expired CSRF token")
+	}
+	return nil
+}
+
+// hasBearerToken reports whether the client authenticates with the Authorization header.
+// Browsers never attach it on their own, so these requests cannot be forged cross-site.
+func hasBearerToken(r *http.Request) bool {
+	authHeader := r.Header.Get("Authorization")
+	return len(authHeader) > 7 && authHeader[:7] == "Bearer "
+}
+
+// cookieSession returns the session cookie the CSRF token is bound to
+func cookieSession(r *http.Request) string {
+	cookie, err := r.Cookie("jwt_token")
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func setCSRFCookie(w http.ResponseWriter, token string) {
+	_, ttl := csrfKeyAndTTL()
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		SameSite: http.SameSiteLaxMode,
+		Domain:   "localhost", // Same domain as the session cookie
+	})
 }
 
 func CSRFMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
-			// Generate and set CSRF token for safe methods
-			token, err := generateCSRFToken()
-			if err != nil {
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
-			}
+		session := cookieSession(r)
 
-			csrfTokens.Lock()
-			csrfTokens.tokens[token] = time.Now().Add(1 * time.Hour)
-			csrfTokens.Unlock()
-
-			w.Header().Set("X-CSRF-Token", token)
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			// Hand out a token to cookie sessions, reusing the current one while it is still valid
+			if session != "" {
+				token := ""
+				if cookie, err := r.Cookie(csrfCookieName); err == nil && ValidateCSRFToken(cookie.Value, session) == nil {
+					token = cookie.Value
+				} else {
+					var err error
+					token, err = GenerateCSRFToken(session)
+					if err != nil {
+						http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+						return
+					}
+					setCSRFCookie(w, token)
+				}
+				w.Header().Set(csrfHeaderName, token)
+			}
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// JWT Bypass: Check for valid JWT token first
-		if hasValidJWT(r) {
+		// Bearer clients are not exposed to CSRF
+		if hasBearerToken(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Verify CSRF token for unsafe methods (fallback)
-		token := r.Header.Get("X-CSRF-Token")
+		// Cookie-authenticated unsafe request: header and cookie must carry the same valid token
+		token := r.Header.Get(csrfHeaderName)
 		if token == "" {
 			http.Error(w, "CSRF token required", http.StatusForbidden)
 			return
 		}
 
-		csrfTokens.RLock()
-		expiry, exists := csrfTokens.tokens[token]
-		csrfTokens.RUnlock()
+		cookie, err := r.Cookie(csrfCookieName)
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(token)) != 1 {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
 
-		if !exists || time.Now().After(expiry) {
+		if session == "" || ValidateCSRFToken(token, session) != nil {
 			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
 			return
 		}
@@ -68,14 +185,3 @@ func CSRFMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
-
-// hasValidJWT checks if the request has a valid JWT token
-func hasValidJWT(r *http.Request) bool {
-	cookie, err := r.Cookie("jwt_token")
-	if err != nil {
-		return false
-	}
-
-	_, err = utils.ValidateToken(cookie.Value)
-	return err == nil
-}
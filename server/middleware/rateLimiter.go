@@ -1,63 +1,243 @@
 package middleware
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Rate limiting
-type rateLimiter struct {
-	requests map[string][]time.Time
-	mutex    sync.RWMutex
-	limit    int
-	window   time.Duration
+// RateLimitPolicy describes a token bucket: Burst tokens, refilled at Limit tokens per Window
+type RateLimitPolicy struct {
+	// Name namespaces the keys of the policy inside a shared store
+	Name   string
+	Limit  int
+	Window time.Duration
+	// Burst is the bucket capacity, defaults to Limit
+	Burst int
+	// KeyFunc identifies the client, defaults to KeyByUserOrIP
+	KeyFunc func(r *http.Request) string
 }
 
-func newRateLimiter(limit int, window time.Duration) *rateLimiter {
-	return &rateLimiter{
-		requests: make(map[string][]time.Time),
-		limit:    limit,
-		window:   window,
+// RateLimitResult is the decision of a store for one request
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request is refused
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the buckets. The in-memory store fits a single instance;
+// multi-instance deployments can plug a shared implementation (Redis, database...) with SetRateLimitStore.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error)
+}
+
+func (p RateLimitPolicy) capacity() float64 {
+	if p.Burst > 0 {
+		return float64(p.Burst)
 	}
+	return float64(p.Limit)
 }
 
-func (rl *rateLimiter) allow(key string) bool {
-	rl.mutex.Lock()
-	defer rl.mutex.Unlock()
+// ratePerSecond is the refill speed of the bucket
+func (p RateLimitPolicy) ratePerSecond() float64 {
+	return float64(p.Limit) / p.Window.Seconds()
+}
 
-	now := time.Now()
+// ===== IN-MEMORY STORE =====
+
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	capacity float64
+	rate     float64
+}
 
-	// Clean old requests
-	if requests, exists := rl.requests[key]; exists {
-		var validRequests []time.Time
-		for _, reqTime := range requests {
-			if now.Sub(reqTime) < rl.window {
-				validRequests = append(validRequests, reqTime)
+// refill adds the tokens earned since the last request
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// MemoryRateLimitStore is a process-local store that evicts idle buckets periodically
+type MemoryRateLimitStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryRateLimitStore creates the store and starts evicting idle buckets every evictInterval
+func NewMemoryRateLimitStore(evictInterval time.Duration) *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		stop:    make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(evictInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.EvictIdle(now)
+			case <-s.stop:
+				return
 			}
 		}
-		rl.requests[key] = validRequests
+	}()
+
+	return s
+}
+
+// Take consumes one token of the bucket identified by key
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, policy RateLimitPolicy) (RateLimitResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{
+			tokens:   policy.capacity(),
+			last:     now,
+			capacity: policy.capacity(),
+			rate:     policy.ratePerSecond(),
+		}
+		s.buckets[key] = bucket
 	}
+	bucket.refill(now)
 
-	// Check if limit exceeded
-	if len(rl.requests[key]) >= rl.limit {
-		return false
+	result := RateLimitResult{Limit: int(bucket.capacity)}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) / bucket.rate * float64(time.Second))
 	}
+	result.Remaining = int(math.Floor(bucket.tokens))
+	result.Reset = time.Duration((bucket.capacity - bucket.tokens) / bucket.rate * float64(time.Second))
 
-	// Add current request
-	rl.requests[key] = append(rl.requests[key], now)
-	return true
+	return result, nil
 }
 
-// RateLimit middleware limits requests per IP
-func RateLimit(limit int, window time.Duration) func(http.Handler) http.Handler {
-	limiter := newRateLimiter(limit, window)
+// EvictIdle drops buckets that have refilled completely, forgetting them changes nothing for the client.
+// It returns the number of evicted keys.
+func (s *MemoryRateLimitStore) EvictIdle(now time.Time) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	evicted := 0
+	for key, bucket := range s.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.capacity {
+			delete(s.buckets, key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Len returns the number of tracked keys
+func (s *MemoryRateLimitStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.buckets)
+}
+
+// Stop ends the eviction goroutine
+func (s *MemoryRateLimitStore) Stop() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// ===== MIDDLEWARE =====
+
+var (
+	rateLimitStore    RateLimitStore
+	rateLimitStoreMu  sync.RWMutex
+	anonymousPolicies atomic.Int64
+)
+
+// SetRateLimitStore replaces the store used by every rate limit middleware
+func SetRateLimitStore(store RateLimitStore) {
+	rateLimitStoreMu.Lock()
+	defer rateLimitStoreMu.Unlock()
+	rateLimitStore = store
+}
+
+func getRateLimitStore() RateLimitStore {
+	rateLimitStoreMu.RLock()
+	store := rateLimitStore
+	rateLimitStoreMu.RUnlock()
+	if store != nil {
+		return store
+	}
+
+	// Default to a process-local store on first use
+	rateLimitStoreMu.Lock()
+	defer rateLimitStoreMu.Unlock()
+	if rateLimitStore == nil {
+		rateLimitStore = NewMemoryRateLimitStore(time.Minute)
+	}
+	return rateLimitStore
+}
+
+// KeyByIP identifies the client by its IP address
+func KeyByIP(r *http.Request) string {
+	return "ip:" + GetClientIP(r)
+}
+
+// KeyByUserOrIP uses the authenticated user ID when AuthMiddleware ran before, the IP otherwise
+func KeyByUserOrIP(r *http.Request) string {
+	if userID, ok := r.Context().Value(UserIDKey).(int); ok && userID > 0 {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return KeyByIP(r)
+}
+
+// RateLimitWith limits requests with a token bucket policy and sets the RateLimit-* headers.
+// It panics on a policy without a positive Limit and Window, which would never refill the bucket.
+func RateLimitWith(policy RateLimitPolicy) func(http.Handler) http.Handler {
+	if policy.Limit <= 0 || policy.Window <= 0 || policy.Burst < 0 {
+		panic(fmt.Sprintf("rate limit policy %q: Limit and Window must be positive, Burst not negative (got %d, %v, %d)",
+			policy.Name, policy.Limit, policy.Window, policy.Burst))
+	}
+	if policy.KeyFunc == nil {
+		policy.KeyFunc = KeyByUserOrIP
+	}
+	if policy.Name == "" {
+		policy.Name = fmt.Sprintf("policy-%d", anonymousPolicies.Add(1))
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			key := getClientIP(r)
+			key := policy.Name + ":" + policy.KeyFunc(r)
+
+			result, err := getRateLimitStore().Take(r.Context(), key, policy)
+			if err != nil {
+				// A store outage must not take the API down with it
+				next.ServeHTTP(w, r)
+				return
+			}
 
-			if !limiter.allow(key) {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
 				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 				return
 			}
@@ -66,3 +246,16 @@ func RateLimit(limit int, window time.Duration) func(http.Handler) http.Handler
 		})
 	}
 }
+
+// RateLimit middleware limits requests per IP
+func RateLimit(limit int, window time.Duration) func(http.Handler) http.Handler {
+	return RateLimitWith(RateLimitPolicy{
+		Limit:   limit,
+		Window:  window,
+		KeyFunc: KeyByIP,
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
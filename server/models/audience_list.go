@@ -0,0 +1,24 @@
+package models
+
+// AudienceList is a named list of followers, like close friends, picked as the audience of private posts
+type AudienceList struct {
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	Members   []int64 `json:"members"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+type CreateAudienceListRequest struct {
+	Name    string  `json:"name"`
+	Members []int64 `json:"members"`
+}
+
+// UpdateAudienceListRequest renames a list or replaces its members.
+// With ApplyToPosts, members added or removed are also added to or removed from the posts shared with the list.
+type UpdateAudienceListRequest struct {
+	ID           int64    `json:"id"`
+	Name         *string  `json:"name,omitempty"`
+	Members      *[]int64 `json:"members,omitempty"`
+	ApplyToPosts bool     `json:"apply_to_posts"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// Bookmark targets, the column a bookmark is attached through
+const (
+	BookmarkTargetPost      = "post_id"
+	BookmarkTargetGroupPost = "group_post_id"
+)
+
+// BookmarkCollection is a named folder of bookmarks, only seen by its owner
+type BookmarkCollection struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+}
+
+// Bookmark is a saved post or group post, CollectionID is nil when it's in no collection
+type Bookmark struct {
+	ID           int64         `json:"id"`
+	CollectionID *int64        `json:"collection_id"`
+	CreatedAt    string        `json:"created_at"`
+	Post         *PostResponse `json:"post,omitempty"`
+	GroupPost    *GroupPost    `json:"group_post,omitempty"`
+}
+
+// BookmarkRequest saves a post or a group post, or moves its bookmark to another collection
+type BookmarkRequest struct {
+	PostID       int64  `json:"post_id,omitempty"`
+	GroupPostID  int64  `json:"group_post_id,omitempty"`
+	CollectionID *int64 `json:"collection_id"`
+}
+
+// BookmarkCollectionRequest creates a collection, or renames the collection ID
+type BookmarkCollectionRequest struct {
+	ID   int64  `json:"id,omitempty"`
+	Name string `json:"name"`
+}
@@ -17,6 +17,10 @@ type Comment struct {
 
 	// Images support
 	Images []string `json:"images,omitempty"`
+
+	// Edited is set once the comment was changed, RevisionCount previous versions are kept
+	Edited        bool `json:"edited"`
+	RevisionCount int  `json:"revision_count"`
 }
 
 // Regular post comments
@@ -13,6 +13,10 @@ type UpdateGroupRequest struct {
 	Title  *string `json:"title,omitempty"`
 	Avatar *string `json:"avatar,omitempty"`
 	Bio    *string `json:"bio,omitempty"`
+	// Require every admin of the group to have two-factor authentication enabled
+	RequireAdmin2FA *bool `json:"require_admin_2fa,omitempty"`
+	// Hold the posts of members who cannot approve posts until a moderator reviews them
+	RequirePostApproval *bool `json:"require_post_approval,omitempty"`
 }
 
 type DeleteGroupRequest struct {
@@ -27,15 +31,182 @@ type GroupResponse struct {
 	CreatorID int64  `json:"creator_id"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at,omitempty"`
+	// Status is active, or archived when the group is read-only
+	Status              string `json:"status"`
+	ArchivedAt          string `json:"archived_at,omitempty"`
+	RequirePostApproval bool   `json:"require_post_approval"`
+}
+
+// ===== GROUP LIFECYCLE =====
+
+// Lifecycle states of a group. Archived groups are read-only, deleted ones are hidden until purged.
+const (
+	GroupStatusActive   = "active"
+	GroupStatusArchived = "archived"
+	GroupStatusDeleted  = "deleted"
+)
+
+// DeletedGroupResponse is a soft-deleted group its owner can still restore
+type DeletedGroupResponse struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	DeletedAt  string `json:"deleted_at"`
+	PurgeAfter string `json:"purge_after"`
+}
+
+type GroupIDRequest struct {
+	GroupID int64 `json:"group_id"`
+}
+
+// Status of an ownership transfer
+const (
+	OwnershipTransferPending   = "pending"
+	OwnershipTransferAccepted  = "accepted"
+	OwnershipTransferDeclined  = "declined"
+	OwnershipTransferCancelled = "cancelled"
+)
+
+type GroupOwnershipTransfer struct {
+	ID          int64  `json:"id"`
+	GroupID     int64  `json:"group_id"`
+	GroupTitle  string `json:"group_title"`
+	FromUserID  int64  `json:"from_user_id"`
+	ToUserID    int64  `json:"to_user_id"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"created_at"`
+	ExpiresAt   string `json:"expires_at"`
+	RespondedAt string `json:"responded_at,omitempty"`
+}
+
+type CreateOwnershipTransferRequest struct {
+	GroupID int64 `json:"group_id"`
+	UserID  int64 `json:"user_id"`
+}
+
+// RespondOwnershipTransferRequest accepts or declines a transfer addressed to the user
+type RespondOwnershipTransferRequest struct {
+	ID     int64 `json:"id"`
+	Accept bool  `json:"accept"`
+}
+
+// ===== GROUP ROLES =====
+
+// Roles of a group member, from most to least privileged
+const (
+	GroupRoleOwner      = "owner"
+	GroupRoleAdmin      = "admin"
+	GroupRoleModerator  = "moderator"
+	GroupRoleMember     = "member"
+	GroupRoleRestricted = "restricted"
+)
+
+// Actions checked against the permission matrix of a group
+const (
+	GroupPermPost            = "post"
+	GroupPermComment         = "comment"
+	GroupPermCreateEvent     = "create_event"
+	GroupPermInvite          = "invite"
+	GroupPermApproveRequests = "approve_requests"
+	GroupPermPin             = "pin"
+	GroupPermDeleteContent   = "delete_content"
+	GroupPermManageRoles     = "manage_roles"
+	GroupPermBanMembers      = "ban_members"
+	GroupPermMuteMembers     = "mute_members"
+	GroupPermAnnounce        = "announce"
+	GroupPermApprovePosts    = "approve_posts"
+	GroupPermInviteLinks     = "manage_invite_links"
+)
+
+// GroupPermissionOverride changes one cell of the matrix for a group, a nil Allowed restores the default
+type GroupPermissionOverride struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+	Allowed    *bool  `json:"allowed"`
+}
+
+type UpdateGroupPermissionsRequest struct {
+	GroupID   int64                     `json:"group_id"`
+	Overrides []GroupPermissionOverride `json:"overrides"`
+}
+
+// GroupPermissionsResponse is the effective matrix of a group and the role of the requester
+type GroupPermissionsResponse struct {
+	GroupID     int64                      `json:"group_id"`
+	Role        string                     `json:"role"`
+	Permissions map[string]map[string]bool `json:"permissions"`
+	Overrides   []GroupPermissionOverride  `json:"overrides"`
+}
+
+// ===== GROUP MODERATION =====
+
+// Actions recorded in the moderation log of a group
+const (
+	ModerationBan               = "ban"
+	ModerationUnban             = "unban"
+	ModerationMute              = "mute"
+	ModerationUnmute            = "unmute"
+	ModerationRemoveMember      = "remove_member"
+	ModerationChangeRole        = "change_role"
+	ModerationUpdatePermissions = "update_permissions"
+	ModerationDeletePost        = "delete_post"
+	ModerationEditPost          = "edit_post"
+	ModerationDeleteComment     = "delete_comment"
+	ModerationDeleteEvent       = "delete_event"
+	ModerationCancelEvent       = "cancel_event"
+	ModerationArchiveGroup      = "archive_group"
+	ModerationUnarchiveGroup    = "unarchive_group"
+	ModerationDeleteGroup       = "delete_group"
+	ModerationRestoreGroup      = "restore_group"
+	ModerationTransferOwnership = "transfer_ownership"
+	ModerationPinPost           = "pin_post"
+	ModerationUnpinPost         = "unpin_post"
+	ModerationAnnouncePost      = "announce_post"
+	ModerationApprovePost       = "approve_post"
+	ModerationRejectPost        = "reject_post"
+	ModerationCreateInviteLink  = "create_invite_link"
+	ModerationRevokeInviteLink  = "revoke_invite_link"
+)
+
+// GroupSanctionRequest bans or mutes a user. A ban without duration is permanent, mutes need one.
+type GroupSanctionRequest struct {
+	GroupID         int64  `json:"group_id"`
+	UserID          int64  `json:"user_id"`
+	Reason          string `json:"reason,omitempty"`
+	DurationMinutes int    `json:"duration_minutes,omitempty"`
+}
+
+// GroupSanction is an active ban or mute, ExpiresAt is empty for permanent bans
+type GroupSanction struct {
+	GroupID      int64  `json:"group_id"`
+	UserID       int64  `json:"user_id"`
+	UserNickname string `json:"user_nickname"`
+	ByID         int64  `json:"by_id,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+}
+
+type ModerationLogEntry struct {
+	ID             int64  `json:"id"`
+	GroupID        int64  `json:"group_id"`
+	ActorID        int64  `json:"actor_id"`
+	ActorNickname  string `json:"actor_nickname"`
+	TargetUserID   int64  `json:"target_user_id,omitempty"`
+	TargetNickname string `json:"target_nickname,omitempty"`
+	Action         string `json:"action"`
+	Details        string `json:"details,omitempty"`
+	CreatedAt      string `json:"created_at"`
 }
 
 // ===== GROUP POST =====
 
 type CreateGroupPostRequest struct {
-	GroupID int64    `json:"group_id"`
-	Title   string   `json:"title"`
-	Body    string   `json:"body"`
-	Images  []string `json:"images,omitempty"`
+	GroupID        int64              `json:"group_id"`
+	Title          string             `json:"title"`
+	Body           string             `json:"body"`
+	Images         []string           `json:"images,omitempty"`
+	IsAnnouncement bool               `json:"is_announcement,omitempty"`
+	Poll           *CreatePollRequest `json:"poll,omitempty"`
 }
 
 type GroupPost struct {
@@ -55,6 +226,41 @@ type GroupPost struct {
 	AuthorFirstName string   `json:"author_first_name,omitempty"`
 	AuthorLastName  string   `json:"author_last_name,omitempty"`
 	AuthorAvatar    string   `json:"author_avatar,omitempty"`
+	IsAnnouncement  bool     `json:"is_announcement"`
+	Pinned          bool     `json:"pinned"`
+	PinnedAt        string   `json:"pinned_at,omitempty"`
+	PinExpiresAt    string   `json:"pin_expires_at,omitempty"`
+	// Status is pending or rejected while the post waits for or failed review, only its author
+	// and the moderators see it then
+	Status          string `json:"status"`
+	RejectionReason string `json:"rejection_reason,omitempty"`
+	// Edited is set once the post was changed, RevisionCount previous versions are kept
+	Edited        bool `json:"edited"`
+	RevisionCount int  `json:"revision_count"`
+}
+
+// Review states of a group post
+const (
+	GroupPostPending   = "pending"
+	GroupPostPublished = "published"
+	GroupPostRejected  = "rejected"
+)
+
+// ReviewGroupPostRequest approves or rejects a pending post, rejections need a reason
+type ReviewGroupPostRequest struct {
+	PostID  int64  `json:"post_id"`
+	Approve bool   `json:"approve"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PinGroupPostRequest pins a post for DurationMinutes, zero pins it until it is unpinned
+type PinGroupPostRequest struct {
+	PostID          int64 `json:"post_id"`
+	DurationMinutes int   `json:"duration_minutes,omitempty"`
+}
+
+type GroupPostIDRequest struct {
+	PostID int64 `json:"post_id"`
 }
 
 type UpdateGroupPostRequest struct {
@@ -86,24 +292,62 @@ type DeleteGroupCommentRequest struct {
 
 // ===== GROUP EVENT =====
 
+// CreateGroupEventRequest dates are wall clock times in Timezone (UTC when empty),
+// RRule is an RFC 5545 recurrence rule such as "FREQ=WEEKLY;BYDAY=MO;COUNT=10"
 type CreateGroupEventRequest struct {
 	GroupID       int64  `json:"group_id"`
 	Title         string `json:"title"`
 	Description   string `json:"description"`
 	EventDateTime string `json:"event_date_time"`
+	EndDateTime   string `json:"end_date_time,omitempty"`
+	Timezone      string `json:"timezone,omitempty"`
+	Location      string `json:"location,omitempty"`
+	RRule         string `json:"rrule,omitempty"`
+	// Capacity counts attendees with their guests, 0 is unlimited
+	Capacity     int    `json:"capacity,omitempty"`
+	MaxGuests    int    `json:"max_guests,omitempty"`
+	RSVPDeadline string `json:"rsvp_deadline,omitempty"`
 }
 
+// UpdateGroupEventRequest clears EndDateTime, Location or RRule when they are set to ""
 type UpdateGroupEventRequest struct {
 	ID            int64   `json:"id"`
 	Title         *string `json:"title,omitempty"`
 	Description   *string `json:"description,omitempty"`
 	EventDateTime *string `json:"event_date_time,omitempty"`
+	EndDateTime   *string `json:"end_date_time,omitempty"`
+	Timezone      *string `json:"timezone,omitempty"`
+	Location      *string `json:"location,omitempty"`
+	RRule         *string `json:"rrule,omitempty"`
+	// A capacity of 0 or an empty deadline removes the limit
+	Capacity     *int    `json:"capacity,omitempty"`
+	MaxGuests    *int    `json:"max_guests,omitempty"`
+	RSVPDeadline *string `json:"rsvp_deadline,omitempty"`
+}
+
+// GroupEventExceptionRequest cancels or reschedules the occurrence of a recurring event
+// starting at Occurrence, the wall clock start the rule gave it
+type GroupEventExceptionRequest struct {
+	EventID       int64   `json:"event_id"`
+	Occurrence    string  `json:"occurrence"`
+	Cancelled     bool    `json:"cancelled"`
+	EventDateTime *string `json:"event_date_time,omitempty"`
+	EndDateTime   *string `json:"end_date_time,omitempty"`
+	Title         *string `json:"title,omitempty"`
+	Description   *string `json:"description,omitempty"`
+	Location      *string `json:"location,omitempty"`
 }
 
 type DeleteGroupEventRequest struct {
 	ID int64 `json:"id"`
 }
 
+// CancelGroupEventRequest cancels an event, the reason is shown to the users who answered
+type CancelGroupEventRequest struct {
+	ID     int64  `json:"id"`
+	Reason string `json:"reason,omitempty"`
+}
+
 // ===== GROUP MEMBER / INVITATION =====
 
 // GroupInvitation represents a group invitation in the database
@@ -175,7 +419,8 @@ type GroupRequestWithUser struct {
 type RSVPToEventRequest struct {
 	EventID int64  `json:"event_id"`
 	UserID  int64  `json:"user_id"`
-	Status  string `json:"status"` // "come", "interested", "not_come"
+	Status  string `json:"status"`           // "come", "interested", "not_come"
+	Guests  *int   `json:"guests,omitempty"` // Plus ones, only when coming
 }
 
 type CancelRSVPRequest struct {
@@ -193,3 +438,44 @@ type GroupSearchResult struct {
 	CreatorID int64  `json:"creator_id"`
 	IsMember  *bool  `json:"isMember,omitempty"`
 }
+
+// ===== GROUP INVITE LINKS =====
+
+// Modes of an invite link: auto adds the user to the group, request files a join request
+const (
+	GroupInviteLinkAuto    = "auto"
+	GroupInviteLinkRequest = "request"
+)
+
+// CreateGroupInviteLinkRequest creates a link, zero MaxUses or ExpiresInMinutes means unlimited
+type CreateGroupInviteLinkRequest struct {
+	GroupID          int64  `json:"group_id"`
+	Mode             string `json:"mode"`
+	MaxUses          int    `json:"max_uses,omitempty"`
+	ExpiresInMinutes int    `json:"expires_in_minutes,omitempty"`
+}
+
+type GroupInviteLink struct {
+	ID        int64  `json:"id"`
+	GroupID   int64  `json:"group_id"`
+	Code      string `json:"code"`
+	CreatedBy int64  `json:"created_by"`
+	Mode      string `json:"mode"`
+	MaxUses   int    `json:"max_uses,omitempty"`
+	Uses      int    `json:"uses"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	RevokedAt string `json:"revoked_at,omitempty"`
+	CreatedAt string `json:"created_at"`
+	// Active is false once the link is revoked, expired or used up
+	Active bool `json:"active"`
+}
+
+// RevokeGroupInviteLinkRequest revokes one link by ID, or every outstanding link of GroupID
+type RevokeGroupInviteLinkRequest struct {
+	ID      int64 `json:"id,omitempty"`
+	GroupID int64 `json:"group_id,omitempty"`
+}
+
+type RedeemGroupInviteLinkRequest struct {
+	Code string `json:"code"`
+}
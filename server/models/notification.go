@@ -8,6 +8,8 @@ type CreateNotificationRequest struct {
 	NotifID    int64  `json:"notif_id"`
 	ExternalID string `json:"external_id,omitempty"`
 	Data       string `json:"data"`
+	// AggregationKey groups notifications about the same subject (e.g. likes of one post)
+	AggregationKey string `json:"-"`
 }
 
 type UpdateNotificationRequest struct {
@@ -31,3 +33,48 @@ type NotificationResponse struct {
 type DeleteNotificationRequest struct {
 	ID int64 `json:"id"`
 }
+
+// Notification delivery channels, chosen per user and per type
+const (
+	// NotificationChannelPush stores the notification and pushes it over WebSocket (default)
+	NotificationChannelPush = "push"
+	// NotificationChannelInApp only stores it in the notification list
+	NotificationChannelInApp = "in_app"
+	// NotificationChannelEmail stores it and delivers it with the email digest instead of a push
+	NotificationChannelEmail = "email"
+	// NotificationChannelOff drops it
+	NotificationChannelOff = "off"
+)
+
+// QuietHours suppress pushes between Start and End ("HH:MM", may wrap past midnight) in Timezone
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone"`
+}
+
+type GroupNotificationMute struct {
+	GroupID int64 `json:"group_id"`
+	// MutedUntil is nil when the group is muted until unmuted
+	MutedUntil *time.Time `json:"muted_until"`
+}
+
+type NotificationPreferencesResponse struct {
+	Channels    map[string]string       `json:"channels"`
+	QuietHours  *QuietHours             `json:"quiet_hours"`
+	MutedGroups []GroupNotificationMute `json:"muted_groups"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	// Channels maps notification types to a channel, missing types are left unchanged
+	Channels   map[string]string `json:"channels,omitempty"`
+	QuietHours *QuietHours       `json:"quiet_hours,omitempty"`
+	// ClearQuietHours removes the quiet hours
+	ClearQuietHours bool `json:"clear_quiet_hours,omitempty"`
+}
+
+type MuteGroupNotificationsRequest struct {
+	GroupID int64 `json:"group_id"`
+	// DurationMinutes limits the mute, 0 mutes until unmuted
+	DurationMinutes int `json:"duration_minutes,omitempty"`
+}
@@ -0,0 +1,61 @@
+package models
+
+// Poll targets, the column a poll is attached through
+const (
+	PollTargetPost         = "post_id"
+	PollTargetGroupPost    = "group_post_id"
+	PollTargetGroupMessage = "group_message_id"
+)
+
+// CreatePollRequest attaches a poll to a new post, group post or group message.
+// Zero DurationMinutes keeps the poll open until its creator closes it.
+type CreatePollRequest struct {
+	Question        string   `json:"question"`
+	Options         []string `json:"options"`
+	MultipleChoice  bool     `json:"multiple_choice,omitempty"`
+	Anonymous       bool     `json:"anonymous,omitempty"`
+	DurationMinutes int      `json:"duration_minutes,omitempty"`
+}
+
+// VotePollRequest replaces the votes of the user, no option withdraws them
+type VotePollRequest struct {
+	PollID    int64   `json:"poll_id"`
+	OptionIDs []int64 `json:"option_ids"`
+}
+
+type ClosePollRequest struct {
+	PollID int64 `json:"poll_id"`
+}
+
+// PollVoter is listed under its options when the poll is not anonymous
+type PollVoter struct {
+	UserID   int64  `json:"user_id"`
+	Nickname string `json:"nickname"`
+}
+
+type PollOption struct {
+	ID     int64       `json:"id"`
+	Label  string      `json:"label"`
+	Votes  int         `json:"votes"`
+	Voters []PollVoter `json:"voters,omitempty"`
+}
+
+// Poll is a poll with its tally, UserVotes are the options chosen by the requesting user
+type Poll struct {
+	ID             int64        `json:"id"`
+	PostID         *int64       `json:"post_id,omitempty"`
+	GroupPostID    *int64       `json:"group_post_id,omitempty"`
+	GroupMessageID *int64       `json:"group_message_id,omitempty"`
+	GroupID        *int64       `json:"group_id,omitempty"`
+	CreatorID      int64        `json:"creator_id"`
+	Question       string       `json:"question"`
+	MultipleChoice bool         `json:"multiple_choice"`
+	Anonymous      bool         `json:"anonymous"`
+	ClosesAt       string       `json:"closes_at,omitempty"`
+	Closed         bool         `json:"closed"`
+	ClosedAt       string       `json:"closed_at,omitempty"`
+	CreatedAt      string       `json:"created_at"`
+	Options        []PollOption `json:"options"`
+	Voters         int          `json:"voters"`
+	UserVotes      []int64      `json:"user_votes"`
+}
@@ -23,8 +23,23 @@ type Post struct {
 	AuthorFirstName string   `json:"author_first_name"`
 	AuthorLastName  string   `json:"author_last_name"`
 	AuthorAvatar    string   `json:"author_avatar"`
+	// Edited is set once the post was changed, RevisionCount previous versions are kept
+	Edited        bool `json:"edited"`
+	RevisionCount int  `json:"revision_count"`
+	// Kind tells a post from a repost or a quote of OriginalPostID
+	Kind           string `json:"kind"`
+	OriginalPostID *int64 `json:"original_post_id,omitempty"`
+	// OriginalPost is the shared post, left out of a quote when the viewer can't see it
+	OriginalPost *PostResponse `json:"original_post,omitempty"`
 }
 
+// Post kinds, reposts and quotes share the post OriginalPostID points to
+const (
+	PostKindPost   = "post"
+	PostKindRepost = "repost"
+	PostKindQuote  = "quote"
+)
+
 // Create request (client → server)
 type CreatePostRequest struct {
 	Title      string   `json:"title"`
@@ -32,7 +47,10 @@ type CreatePostRequest struct {
 	Images     []string `json:"images,omitempty"`
 	Visibility string   `json:"visibility"`
 	// Selected followers for private posts (only used when visibility is "private")
-	SelectedFollowers []int64 `json:"selected_followers,omitempty"`
+	SelectedFollowers []int64            `json:"selected_followers,omitempty"`
+	Poll              *CreatePollRequest `json:"poll,omitempty"`
+	// AudienceListID adds the members of an audience list of the author to the selected followers
+	AudienceListID *int64 `json:"audience_list_id,omitempty"`
 }
 
 func (c *CreatePostRequest) UnmarshalJSON(data []byte) error {
@@ -97,4 +115,22 @@ type PostResponse struct {
 	UserDisliked   bool           `json:"user_disliked"`
 	GroupID        *int64         `json:"group_id,omitempty"`
 	GroupName      *string        `json:"group_name,omitempty"`
+	Edited         bool           `json:"edited"`
+	RevisionCount  int            `json:"revision_count"`
+	Kind           string         `json:"kind"`
+	OriginalPostID *int64         `json:"original_post_id,omitempty"`
+	// OriginalPost is the shared post, left out of a quote when the viewer can't see it
+	OriginalPost *PostResponse `json:"original_post,omitempty"`
+	RepostCount  int           `json:"repost_count"`
+	QuoteCount   int           `json:"quote_count"`
+	UserReposted bool          `json:"user_reposted"`
+}
+
+// RepostRequest shares a post, a non-empty body makes it a quote
+type RepostRequest struct {
+	PostID     int64  `json:"post_id"`
+	Body       string `json:"body,omitempty"`
+	Visibility string `json:"visibility"`
+	// Selected followers for private reposts (only used when visibility is "private")
+	SelectedFollowers []int64 `json:"selected_followers,omitempty"`
 }
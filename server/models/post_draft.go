@@ -0,0 +1,61 @@
+package models
+
+// Draft states, a failed draft keeps the reason the publisher gave up on it
+const (
+	PostDraftDraft     = "draft"
+	PostDraftScheduled = "scheduled"
+	PostDraftFailed    = "failed"
+)
+
+// CreatePostDraftRequest saves a post, or a group post when GroupID is set, without publishing it.
+// PublishAt is an RFC3339 time, the draft is published by the background publisher once it has passed.
+type CreatePostDraftRequest struct {
+	GroupID           *int64             `json:"group_id,omitempty"`
+	Title             string             `json:"title"`
+	Body              string             `json:"body"`
+	Images            []string           `json:"images,omitempty"`
+	Visibility        string             `json:"visibility,omitempty"`
+	SelectedFollowers []int64            `json:"selected_followers,omitempty"`
+	IsAnnouncement    bool               `json:"is_announcement,omitempty"`
+	Poll              *CreatePollRequest `json:"poll,omitempty"`
+	PublishAt         string             `json:"publish_at,omitempty"`
+}
+
+// UpdatePostDraftRequest changes the given fields of a draft.
+// An empty PublishAt unschedules the draft, RemovePoll drops its poll.
+type UpdatePostDraftRequest struct {
+	ID                int64              `json:"id"`
+	Title             *string            `json:"title,omitempty"`
+	Body              *string            `json:"body,omitempty"`
+	Images            *[]string          `json:"images,omitempty"`
+	Visibility        *string            `json:"visibility,omitempty"`
+	SelectedFollowers *[]int64           `json:"selected_followers,omitempty"`
+	IsAnnouncement    *bool              `json:"is_announcement,omitempty"`
+	Poll              *CreatePollRequest `json:"poll,omitempty"`
+	RemovePoll        bool               `json:"remove_poll,omitempty"`
+	PublishAt         *string            `json:"publish_at,omitempty"`
+}
+
+type PostDraftIDRequest struct {
+	ID int64 `json:"id"`
+}
+
+// PostDraft is a saved post waiting to be published, only its author sees it
+type PostDraft struct {
+	ID                int64              `json:"id"`
+	UserID            int64              `json:"user_id"`
+	GroupID           *int64             `json:"group_id,omitempty"`
+	Title             string             `json:"title"`
+	Body              string             `json:"body"`
+	Images            []string           `json:"images"`
+	Visibility        string             `json:"visibility"`
+	SelectedFollowers []int64            `json:"selected_followers"`
+	IsAnnouncement    bool               `json:"is_announcement"`
+	Poll              *CreatePollRequest `json:"poll,omitempty"`
+	Status            string             `json:"status"`
+	PublishAt         string             `json:"publish_at,omitempty"`
+	FailureReason     string             `json:"failure_reason,omitempty"`
+	Revision          int64              `json:"revision"`
+	CreatedAt         string             `json:"created_at"`
+	UpdatedAt         string             `json:"updated_at"`
+}
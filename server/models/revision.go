@@ -0,0 +1,28 @@
+package models
+
+// Revision targets, the column a revision is attached through
+const (
+	RevisionTargetPost         = "post_id"
+	RevisionTargetComment      = "comment_id"
+	RevisionTargetGroupPost    = "group_post_id"
+	RevisionTargetGroupComment = "group_comment_id"
+)
+
+// ContentRevision is a previous version of a post, comment, group post or group comment.
+// Revision 1 is the original, WrittenAt is when the version was written and EditedAt when an edit replaced it.
+type ContentRevision struct {
+	Revision       int      `json:"revision"`
+	Title          *string  `json:"title,omitempty"`
+	Body           string   `json:"body"`
+	Images         []string `json:"images,omitempty"`
+	WrittenAt      string   `json:"written_at,omitempty"`
+	EditedAt       string   `json:"edited_at"`
+	EditedBy       int64    `json:"edited_by,omitempty"`
+	EditorNickname string   `json:"editor_nickname,omitempty"`
+}
+
+// ContentHistory lists the previous versions of a content, the current one is CurrentRevision
+type ContentHistory struct {
+	CurrentRevision int               `json:"current_revision"`
+	Revisions       []ContentRevision `json:"revisions"`
+}
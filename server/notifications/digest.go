@@ -0,0 +1,202 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// Digest frequencies
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+	DigestOff    = "off"
+)
+
+//go:embed templates/digest.html templates/digest.txt
+var digestTemplateFiles embed.FS
+
+var (
+	digestHTMLTemplate = htmltemplate.Must(htmltemplate.ParseFS(digestTemplateFiles, "templates/digest.html"))
+	digestTextTemplate = texttemplate.Must(texttemplate.ParseFS(digestTemplateFiles, "templates/digest.txt"))
+)
+
+// DigestOptions configures the digest job
+type DigestOptions struct {
+	Mailer utils.Mailer
+	// UnsubscribeURL is the public unsubscribe endpoint, the token is added as ?token=
+	UnsubscribeURL string
+	// NotificationsURL is the page of the client listing notifications
+	NotificationsURL string
+	// DefaultFrequency applies to users who never chose one
+	DefaultFrequency string
+	// MaxItems is the number of notifications listed in one email
+	MaxItems int
+}
+
+type digestItem struct {
+	Message string
+	When    string
+}
+
+type digestData struct {
+	Subject          string
+	Nickname         string
+	Period           string
+	Items            []digestItem
+	More             bool
+	NotificationsURL string
+	UnsubscribeURL   string
+}
+
+// IsValidDigestFrequency reports whether frequency is daily, weekly or off
+func IsValidDigestFrequency(frequency string) bool {
+	return frequency == DigestDaily || frequency == DigestWeekly || frequency == DigestOff
+}
+
+// NewUnsubscribeToken creates the secret used in unsubscribe links
+func NewUnsubscribeToken() (string, error) {
+	return utils.RandomURLToken(24)
+}
+
+// StartDigestJob sends the due digests every interval
+func StartDigestJob(opts DigestOptions, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if sent, err := SendDigests(context.Background(), opts, now); err != nil {
+			log.Printf("Notification digest failed: %v", err)
+		} else if sent > 0 {
+			log.Printf("Sent %d notification digests", sent)
+		}
+	}
+}
+
+// SendDigests emails every user whose digest is due at now. A digest that fails to send is retried on the next run.
+func SendDigests(ctx context.Context, opts DigestOptions, now time.Time) (int, error) {
+	recipients, err := db.DBService.GetDigestRecipients(opts.DefaultFrequency)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, recipient := range recipients {
+		if !digestDue(recipient, now) {
+			continue
+		}
+		if err := sendDigest(ctx, opts, recipient, now); err != nil {
+			log.Printf("Notification digest for user %d failed: %v", recipient.UserID, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func digestDue(recipient db.DigestRecipient, now time.Time) bool {
+	if !recipient.LastSentAt.Valid {
+		return true
+	}
+	period := 24 * time.Hour
+	if recipient.Frequency == DigestWeekly {
+		period = 7 * 24 * time.Hour
+	}
+	return !now.Before(recipient.LastSentAt.Time.Add(period))
+}
+
+func sendDigest(ctx context.Context, opts DigestOptions, recipient db.DigestRecipient, now time.Time) error {
+	var since *time.Time
+	if recipient.LastSentAt.Valid {
+		since = &recipient.LastSentAt.Time
+	}
+
+	maxItems := opts.MaxItems
+	if maxItems <= 0 {
+		maxItems = 20
+	}
+	rows, err := db.DBService.GetDigestNotifications(recipient.UserID, since, maxItems+1)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	data := digestData{
+		Nickname:         recipient.Nickname,
+		Period:           recipient.Frequency,
+		More:             len(rows) > maxItems,
+		NotificationsURL: opts.NotificationsURL,
+	}
+	if data.More {
+		rows = rows[:maxItems]
+	}
+	for _, n := range rows {
+		data.Items = append(data.Items, digestItem{
+			Message: digestMessage(n.Type, n.Data),
+			When:    n.CreatedAt.UTC().Format("Jan 2, 15:04 UTC"),
+		})
+	}
+	data.Subject = fmt.Sprintf("You have %d unread notification", len(rows))
+	if len(rows) > 1 || data.More {
+		data.Subject += "s"
+	}
+
+	token, err := NewUnsubscribeToken()
+	if err != nil {
+		return err
+	}
+	token, err = db.DBService.EnsureDigestSettings(recipient.UserID, recipient.Frequency, token)
+	if err != nil {
+		return err
+	}
+	data.UnsubscribeURL = opts.UnsubscribeURL + "?token=" + url.QueryEscape(token)
+
+	var htmlBody, textBody bytes.Buffer
+	if err := digestHTMLTemplate.Execute(&htmlBody, data); err != nil {
+		return err
+	}
+	if err := digestTextTemplate.Execute(&textBody, data); err != nil {
+		return err
+	}
+
+	err = opts.Mailer.Send(ctx, utils.MailMessage{
+		To:       recipient.Email,
+		Subject:  data.Subject,
+		HTMLBody: htmlBody.String(),
+		TextBody: textBody.String(),
+		Headers: map[string]string{
+			// One-click unsubscribe (RFC 8058)
+			"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.DBService.MarkDigestSent(recipient.UserID, now)
+}
+
+// digestMessage uses the rendered message of versioned payloads, older rows fall back to their type
+func digestMessage(notificationType, data string) string {
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(data), &payload); err == nil && payload.Message != "" {
+		return payload.Message
+	}
+	return "New " + strings.ReplaceAll(notificationType, "_", " ") + " notification"
+}
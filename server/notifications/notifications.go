@@ -0,0 +1,138 @@
+// Package notifications stores typed notifications and pushes them to the recipient in real time.
+// Handlers build a payload struct and call Send instead of writing the row and the WebSocket message themselves.
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/websockets"
+)
+
+// Send stores a notification for userID, aggregating it when the payload supports it,
+// then pushes it to the user's open connections. The user's preferences decide whether it is
+// stored and pushed; it returns 0 when the user turned the type off.
+func Send(userID int64, payload Payload) (int64, error) {
+	d := resolveDelivery(userID, payload)
+	if d.Channel == models.NotificationChannelOff {
+		return 0, nil
+	}
+
+	request := models.CreateNotificationRequest{
+		UserID: userID,
+		Type:   payload.Kind(),
+	}
+
+	var (
+		id         int64
+		aggregated bool
+		err        error
+	)
+	if agg, ok := payload.(Aggregatable); ok {
+		request.AggregationKey = agg.AggregationKey()
+		id, aggregated, err = db.DBService.AggregateNotification(request, func(existing string) (string, error) {
+			if existing != "" {
+				if previous, decodeErr := Decode(payload.Kind(), existing); decodeErr == nil {
+					agg.Merge(previous)
+				}
+			}
+			return encode(payload)
+		})
+	} else {
+		request.Data, err = encode(payload)
+		if err == nil {
+			id, err = db.DBService.InsertNotification(request)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if d.Push {
+		push(userID, id, aggregated, payload)
+	}
+	return id, nil
+}
+
+// SendToMany sends the same notification to several users, logging failures instead of stopping
+func SendToMany(userIDs []int64, payload Payload) {
+	data, err := encode(payload)
+	if err != nil {
+		fmt.Printf("[NOTIFICATIONS] Error encoding %s notification: %v\n", payload.Kind(), err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		// Each recipient gets its own copy, aggregation mutates the payload
+		recipientPayload, err := Decode(payload.Kind(), data)
+		if err != nil {
+			fmt.Printf("[NOTIFICATIONS] Error decoding %s notification: %v\n", payload.Kind(), err)
+			return
+		}
+		if _, err := Send(userID, recipientPayload); err != nil {
+			fmt.Printf("[NOTIFICATIONS] Error sending %s notification to user %d: %v\n", payload.Kind(), userID, err)
+		}
+	}
+}
+
+// Withdraw deletes the notifications of a type whose payload field equals value,
+// e.g. a follow request notification once the request is cancelled
+func Withdraw(userID int64, notificationType, field string, value interface{}) error {
+	return db.DBService.DeleteNotificationsByDataField(userID, notificationType, field, value)
+}
+
+// Decode parses stored notification data into its typed payload
+func Decode(notificationType, data string) (Payload, error) {
+	newPayload, ok := registry[notificationType]
+	if !ok {
+		return nil, fmt.Errorf("unknown notification type %q", notificationType)
+	}
+	payload := newPayload()
+	if err := json.Unmarshal([]byte(data), payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// ActorFromUser describes a user as the author of a notification
+func ActorFromUser(user *models.User) Actor {
+	actor := Actor{ID: user.ID, Nickname: user.Nickname}
+	if user.Avatar.Valid {
+		actor.Avatar = user.Avatar.String
+	}
+	return actor
+}
+
+func encode(payload Payload) (string, error) {
+	if err := render(payload); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// push delivers the payload to the recipient when connected, the stored row is the source of truth
+func push(userID, notificationID int64, aggregated bool, payload Payload) {
+	hub := websockets.GetHub()
+	if hub == nil {
+		return
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	data := map[string]any{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return
+	}
+	data["notification_id"] = notificationID
+	data["aggregated"] = aggregated
+
+	hub.BroadcastNotification(int(userID), payload.Kind(), payload.header().Message, data)
+}
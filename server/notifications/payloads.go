@@ -0,0 +1,434 @@
+package notifications
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// SchemaVersion is stored as "v" in every payload. Adding fields keeps the version,
+// renaming, removing or changing the meaning of one bumps it.
+const SchemaVersion = 1
+
+// Notification types
+const (
+	TypeFollowRequest  = "follow_request"
+	TypeFollowAccepted = "follow_accepted"
+	TypeGroupInvite    = "group_invite"
+	TypeGroupRequest   = "group_request"
+	TypeGroupEvent     = "group_event"
+	TypePostLike       = "post_like"
+	TypeSecurityAlert  = "security_alert"
+
+	TypeGroupOwnershipTransfer = "group_ownership_transfer"
+	TypeGroupOwnershipResponse = "group_ownership_response"
+	TypeGroupStatus            = "group_status"
+	TypeGroupAnnouncement      = "group_announcement"
+	TypeGroupPostReview        = "group_post_review"
+	TypeEventWaitlist          = "event_waitlist"
+	TypeEventReminder          = "event_reminder"
+	TypeEventChanged           = "event_changed"
+	TypeEventCancelled         = "event_cancelled"
+	TypeScheduledPost          = "scheduled_post"
+)
+
+// maxAggregatedActors is the number of actors kept by name in an aggregated notification
+const maxAggregatedActors = 3
+
+// Header holds the fields shared by every payload, filled in by Send
+type Header struct {
+	Version int    `json:"v"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (h *Header) header() *Header { return h }
+
+// Payload is the typed content of one notification type
+type Payload interface {
+	Kind() string
+	header() *Header
+}
+
+// Aggregatable payloads are merged into the recipient's unread notification with the same key
+type Aggregatable interface {
+	Payload
+	AggregationKey() string
+	// Merge folds the previous unread payload into this one
+	Merge(previous Payload)
+}
+
+// Actor is a user who triggered a notification
+type Actor struct {
+	ID       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar,omitempty"`
+}
+
+type FollowRequest struct {
+	Header
+	RequesterID       int64  `json:"requester_id"`
+	RequesterNickname string `json:"requester_nickname"`
+	RequesterAvatar   string `json:"requester_avatar"`
+}
+
+func (*FollowRequest) Kind() string { return TypeFollowRequest }
+
+type FollowAccepted struct {
+	Header
+	TargetID       int64  `json:"target_id"`
+	TargetNickname string `json:"target_nickname"`
+	TargetAvatar   string `json:"target_avatar"`
+}
+
+func (*FollowAccepted) Kind() string { return TypeFollowAccepted }
+
+type GroupInvite struct {
+	Header
+	GroupID         int64  `json:"group_id"`
+	GroupName       string `json:"group_name"`
+	InviterID       int64  `json:"inviter_id"`
+	InviterNickname string `json:"inviter_nickname"`
+	InviterAvatar   string `json:"inviter_avatar"`
+}
+
+func (*GroupInvite) Kind() string { return TypeGroupInvite }
+
+type GroupRequest struct {
+	Header
+	GroupID           int64  `json:"group_id"`
+	GroupName         string `json:"group_name"`
+	RequesterID       int64  `json:"requester_id"`
+	RequesterNickname string `json:"requester_nickname"`
+	RequesterAvatar   string `json:"requester_avatar"`
+}
+
+func (*GroupRequest) Kind() string { return TypeGroupRequest }
+
+type GroupEvent struct {
+	Header
+	GroupID         int64  `json:"group_id"`
+	GroupName       string `json:"group_name"`
+	EventID         int64  `json:"event_id"`
+	EventTitle      string `json:"event_title"`
+	CreatorID       int64  `json:"creator_id"`
+	CreatorNickname string `json:"creator_nickname"`
+	CreatorAvatar   string `json:"creator_avatar"`
+}
+
+func (*GroupEvent) Kind() string { return TypeGroupEvent }
+
+// PostLike is aggregated per post: the liker fields describe the latest like,
+// Actors the most recent likers and ActorCount all of them
+type PostLike struct {
+	Header
+	PostID        int64   `json:"post_id"`
+	LikerID       int64   `json:"liker_id"`
+	LikerNickname string  `json:"liker_nickname"`
+	LikerAvatar   string  `json:"liker_avatar"`
+	Actors        []Actor `json:"actors"`
+	ActorCount    int     `json:"actor_count"`
+	// LikerIDs holds every distinct liker, Actors only the latest few
+	LikerIDs []int64 `json:"liker_ids"`
+}
+
+// NewPostLike creates the payload for a single like
+func NewPostLike(postID int64, liker Actor) *PostLike {
+	return &PostLike{
+		PostID:        postID,
+		LikerID:       liker.ID,
+		LikerNickname: liker.Nickname,
+		LikerAvatar:   liker.Avatar,
+		Actors:        []Actor{liker},
+		ActorCount:    1,
+		LikerIDs:      []int64{liker.ID},
+	}
+}
+
+func (*PostLike) Kind() string { return TypePostLike }
+
+func (p *PostLike) AggregationKey() string { return fmt.Sprintf("post:%d", p.PostID) }
+
+func (p *PostLike) Merge(previous Payload) {
+	prev, ok := previous.(*PostLike)
+	if !ok {
+		return
+	}
+
+	// Liked again after an unlike, or by someone no longer shown by name: don't count them twice
+	likerIDs := prev.LikerIDs
+	if likerIDs == nil {
+		for _, actor := range prev.Actors {
+			likerIDs = append(likerIDs, actor.ID)
+		}
+	}
+	count := prev.ActorCount
+	if !slices.Contains(likerIDs, p.LikerID) {
+		likerIDs = append(likerIDs, p.LikerID)
+		count++
+	}
+
+	actors := []Actor{{ID: p.LikerID, Nickname: p.LikerNickname, Avatar: p.LikerAvatar}}
+	for _, actor := range prev.Actors {
+		if actor.ID != p.LikerID && len(actors) < maxAggregatedActors {
+			actors = append(actors, actor)
+		}
+	}
+	p.Actors = actors
+	p.ActorCount = count
+	p.LikerIDs = likerIDs
+}
+
+type SecurityAlert struct {
+	Header
+	Reason         string `json:"reason"`
+	FailedAttempts int    `json:"failed_attempts"`
+	LockedUntil    string `json:"locked_until"`
+	IP             string `json:"ip"`
+}
+
+func (*SecurityAlert) Kind() string { return TypeSecurityAlert }
+
+// GroupOwnershipTransfer asks a member to become the owner of a group
+type GroupOwnershipTransfer struct {
+	Header
+	TransferID    int64  `json:"transfer_id"`
+	GroupID       int64  `json:"group_id"`
+	GroupName     string `json:"group_name"`
+	OwnerID       int64  `json:"owner_id"`
+	OwnerNickname string `json:"owner_nickname"`
+	OwnerAvatar   string `json:"owner_avatar"`
+}
+
+func (*GroupOwnershipTransfer) Kind() string { return TypeGroupOwnershipTransfer }
+
+// GroupOwnershipResponse tells the owner whether the member took over the group
+type GroupOwnershipResponse struct {
+	Header
+	TransferID        int64  `json:"transfer_id"`
+	GroupID           int64  `json:"group_id"`
+	GroupName         string `json:"group_name"`
+	RecipientID       int64  `json:"recipient_id"`
+	RecipientNickname string `json:"recipient_nickname"`
+	RecipientAvatar   string `json:"recipient_avatar"`
+	Accepted          bool   `json:"accepted"`
+}
+
+func (*GroupOwnershipResponse) Kind() string { return TypeGroupOwnershipResponse }
+
+// GroupStatus tells the members a group was archived, deleted or brought back.
+// RestoreUntil is set for deleted groups, the content is purged after it.
+type GroupStatus struct {
+	Header
+	GroupID       int64  `json:"group_id"`
+	GroupName     string `json:"group_name"`
+	Status        string `json:"status"`
+	ActorID       int64  `json:"actor_id"`
+	ActorNickname string `json:"actor_nickname"`
+	RestoreUntil  string `json:"restore_until,omitempty"`
+}
+
+func (*GroupStatus) Kind() string { return TypeGroupStatus }
+
+// GroupAnnouncement tells every member about a post marked as an announcement
+type GroupAnnouncement struct {
+	Header
+	GroupID        int64  `json:"group_id"`
+	GroupName      string `json:"group_name"`
+	PostID         int64  `json:"post_id"`
+	PostTitle      string `json:"post_title"`
+	AuthorID       int64  `json:"author_id"`
+	AuthorNickname string `json:"author_nickname"`
+	AuthorAvatar   string `json:"author_avatar"`
+}
+
+func (*GroupAnnouncement) Kind() string { return TypeGroupAnnouncement }
+
+// GroupPostReview tells the author whether their pending post was published or rejected
+type GroupPostReview struct {
+	Header
+	GroupID          int64  `json:"group_id"`
+	GroupName        string `json:"group_name"`
+	PostID           int64  `json:"post_id"`
+	PostTitle        string `json:"post_title"`
+	Approved         bool   `json:"approved"`
+	Reason           string `json:"reason,omitempty"`
+	ReviewerID       int64  `json:"reviewer_id"`
+	ReviewerNickname string `json:"reviewer_nickname"`
+}
+
+func (*GroupPostReview) Kind() string { return TypeGroupPostReview }
+
+// EventWaitlist tells a waitlisted user a seat freed up and they are now attending
+type EventWaitlist struct {
+	Header
+	GroupID    int64  `json:"group_id"`
+	GroupName  string `json:"group_name"`
+	EventID    int64  `json:"event_id"`
+	EventTitle string `json:"event_title"`
+	Guests     int    `json:"guests,omitempty"`
+}
+
+func (*EventWaitlist) Kind() string { return TypeEventWaitlist }
+
+// EventReminder tells a user who answered an event it starts soon, StartsAt carries the offset of the event timezone
+type EventReminder struct {
+	Header
+	GroupID    int64  `json:"group_id"`
+	GroupName  string `json:"group_name"`
+	EventID    int64  `json:"event_id"`
+	EventTitle string `json:"event_title"`
+	StartsAt   string `json:"starts_at"`
+	StartsIn   string `json:"starts_in"`
+	Location   string `json:"location,omitempty"`
+}
+
+func (*EventReminder) Kind() string { return TypeEventReminder }
+
+// EventFieldChange is one field of an event before and after an update, Label names it in messages
+type EventFieldChange struct {
+	Field string `json:"field"`
+	Label string `json:"label"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// EventChanged tells the users who answered an event what an update changed.
+// ConfirmRSVP is set when the event moved enough for their answer to be asked again.
+type EventChanged struct {
+	Header
+	GroupID       int64              `json:"group_id"`
+	GroupName     string             `json:"group_name"`
+	EventID       int64              `json:"event_id"`
+	EventTitle    string             `json:"event_title"`
+	Changes       []EventFieldChange `json:"changes"`
+	ConfirmRSVP   bool               `json:"confirm_rsvp,omitempty"`
+	ActorID       int64              `json:"actor_id"`
+	ActorNickname string             `json:"actor_nickname"`
+}
+
+func (*EventChanged) Kind() string { return TypeEventChanged }
+
+// EventCancelled tells the users who answered an event it was cancelled
+type EventCancelled struct {
+	Header
+	GroupID       int64  `json:"group_id"`
+	GroupName     string `json:"group_name"`
+	EventID       int64  `json:"event_id"`
+	EventTitle    string `json:"event_title"`
+	Reason        string `json:"reason,omitempty"`
+	ActorID       int64  `json:"actor_id"`
+	ActorNickname string `json:"actor_nickname"`
+}
+
+func (*EventCancelled) Kind() string { return TypeEventCancelled }
+
+// ScheduledPost tells the author what became of a draft the publisher picked up:
+// Status is published, pending when the group reviews it first, or failed with a Reason
+type ScheduledPost struct {
+	Header
+	DraftID   int64  `json:"draft_id"`
+	PostID    int64  `json:"post_id,omitempty"`
+	PostTitle string `json:"post_title"`
+	GroupID   int64  `json:"group_id,omitempty"`
+	GroupName string `json:"group_name,omitempty"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func (*ScheduledPost) Kind() string { return TypeScheduledPost }
+
+// registry creates an empty payload for each type, used to decode stored data
+var registry = map[string]func() Payload{
+	TypeFollowRequest:  func() Payload { return &FollowRequest{} },
+	TypeFollowAccepted: func() Payload { return &FollowAccepted{} },
+	TypeGroupInvite:    func() Payload { return &GroupInvite{} },
+	TypeGroupRequest:   func() Payload { return &GroupRequest{} },
+	TypeGroupEvent:     func() Payload { return &GroupEvent{} },
+	TypePostLike:       func() Payload { return &PostLike{} },
+	TypeSecurityAlert:  func() Payload { return &SecurityAlert{} },
+
+	TypeGroupOwnershipTransfer: func() Payload { return &GroupOwnershipTransfer{} },
+	TypeGroupOwnershipResponse: func() Payload { return &GroupOwnershipResponse{} },
+	TypeGroupStatus:            func() Payload { return &GroupStatus{} },
+	TypeGroupAnnouncement:      func() Payload { return &GroupAnnouncement{} },
+	TypeGroupPostReview:        func() Payload { return &GroupPostReview{} },
+	TypeEventWaitlist:          func() Payload { return &EventWaitlist{} },
+	TypeEventReminder:          func() Payload { return &EventReminder{} },
+	TypeEventChanged:           func() Payload { return &EventChanged{} },
+	TypeEventCancelled:         func() Payload { return &EventCancelled{} },
+	TypeScheduledPost:          func() Payload { return &ScheduledPost{} },
+}
+
+// templates render the human readable message of each type
+var templates = map[string]*template.Template{
+	TypeFollowRequest:  parse(TypeFollowRequest, `{{.RequesterNickname}} wants to follow you`),
+	TypeFollowAccepted: parse(TypeFollowAccepted, `{{.TargetNickname}} accepted your follow request`),
+	TypeGroupInvite:    parse(TypeGroupInvite, `{{.InviterNickname}} invited you to join {{.GroupName}}`),
+	TypeGroupRequest:   parse(TypeGroupRequest, `{{.RequesterNickname}} wants to join {{.GroupName}}`),
+	TypeGroupEvent:     parse(TypeGroupEvent, `{{.CreatorNickname}} created the event "{{.EventTitle}}" in {{.GroupName}}`),
+	TypePostLike:       parse(TypePostLike, `{{actors .Actors .ActorCount}} liked your post`),
+	TypeSecurityAlert:  parse(TypeSecurityAlert, `Your account was temporarily locked after several failed login attempts`),
+
+	TypeGroupOwnershipTransfer: parse(TypeGroupOwnershipTransfer, `{{.OwnerNickname}} wants to make you the owner of {{.GroupName}}`),
+	TypeGroupOwnershipResponse: parse(TypeGroupOwnershipResponse, `{{.RecipientNickname}} {{if .Accepted}}is now the owner of{{else}}declined to take over{{end}} {{.GroupName}}`),
+	TypeGroupStatus: parse(TypeGroupStatus, `{{if eq .Status "archived"}}{{.GroupName}} was archived and is now read-only`+
+		`{{else if eq .Status "deleted"}}{{.GroupName}} was deleted by {{.ActorNickname}}`+
+		`{{else}}{{.GroupName}} is active again{{end}}`),
+	TypeGroupAnnouncement: parse(TypeGroupAnnouncement, `{{.AuthorNickname}} posted an announcement in {{.GroupName}}: "{{.PostTitle}}"`),
+	TypeGroupPostReview: parse(TypeGroupPostReview, `Your post "{{.PostTitle}}" in {{.GroupName}} was `+
+		`{{if .Approved}}approved{{else}}rejected: {{.Reason}}{{end}}`),
+	TypeEventWaitlist: parse(TypeEventWaitlist, `A seat opened up, you are now attending "{{.EventTitle}}" in {{.GroupName}}`+
+		`{{if .Guests}} with {{.Guests}} guest{{if gt .Guests 1}}s{{end}}{{end}}`),
+	TypeEventReminder: parse(TypeEventReminder, `"{{.EventTitle}}" in {{.GroupName}} starts in {{.StartsIn}}`+
+		`{{if .Location}} at {{.Location}}{{end}}`),
+	TypeEventChanged: parse(TypeEventChanged, `{{.ActorNickname}} changed the `+
+		`{{range $i, $c := .Changes}}{{if $i}}, {{end}}{{$c.Label}}{{end}} of "{{.EventTitle}}" in {{.GroupName}}`+
+		`{{if .ConfirmRSVP}}, please confirm your RSVP{{end}}`),
+	TypeEventCancelled: parse(TypeEventCancelled, `{{.ActorNickname}} cancelled "{{.EventTitle}}" in {{.GroupName}}`+
+		`{{if .Reason}}: {{.Reason}}{{end}}`),
+	TypeScheduledPost: parse(TypeScheduledPost, `Your scheduled post "{{.PostTitle}}" `+
+		`{{if eq .Status "failed"}}could not be published: {{.Reason}}`+
+		`{{else if eq .Status "pending"}}was submitted for review in {{.GroupName}}`+
+		`{{else}}is now live{{if .GroupName}} in {{.GroupName}}{{end}}{{end}}`),
+}
+
+func parse(name, text string) *template.Template {
+	return template.Must(template.New(name).Funcs(template.FuncMap{"actors": actorSummary}).Parse(text))
+}
+
+// actorSummary renders "Alice", "Alice and Bob" or "Alice and 4 others"
+func actorSummary(actors []Actor, count int) string {
+	if len(actors) == 0 {
+		return "Someone"
+	}
+	switch {
+	case count <= 1:
+		return actors[0].Nickname
+	case count == 2 && len(actors) >= 2:
+		return actors[0].Nickname + " and " + actors[1].Nickname
+	case count == 2:
+		return actors[0].Nickname + " and 1 other"
+	default:
+		return fmt.Sprintf("%s and %d others", actors[0].Nickname, count-1)
+	}
+}
+
+// render fills the header and the message of a payload
+func render(payload Payload) error {
+	h := payload.header()
+	h.Version = SchemaVersion
+	h.Type = payload.Kind()
+
+	tmpl, ok := templates[payload.Kind()]
+	if !ok {
+		return fmt.Errorf("no template for notification type %q", payload.Kind())
+	}
+	var message strings.Builder
+	if err := tmpl.Execute(&message, payload); err != nil {
+		return err
+	}
+	h.Message = message.String()
+	return nil
+}
@@ -0,0 +1,151 @@
+package notifications
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+)
+
+// mandatoryTypes ignore preferences, the user must always be told about them
+var mandatoryTypes = map[string]bool{
+	TypeSecurityAlert: true,
+	// Members must learn their group is going away, even when they muted it
+	TypeGroupStatus: true,
+}
+
+// GroupScoped payloads are about a group and follow the user's per-group mute
+type GroupScoped interface {
+	Group() int64
+}
+
+func (p *GroupInvite) Group() int64       { return p.GroupID }
+func (p *GroupRequest) Group() int64      { return p.GroupID }
+func (p *GroupEvent) Group() int64        { return p.GroupID }
+func (p *GroupAnnouncement) Group() int64 { return p.GroupID }
+func (p *GroupPostReview) Group() int64   { return p.GroupID }
+func (p *EventWaitlist) Group() int64     { return p.GroupID }
+func (p *EventReminder) Group() int64     { return p.GroupID }
+func (p *EventChanged) Group() int64      { return p.GroupID }
+func (p *EventCancelled) Group() int64    { return p.GroupID }
+
+// delivery is what Send does with a notification once preferences are applied
+type delivery struct {
+	Channel string
+	Push    bool
+}
+
+// ConfigurableTypes lists the notification types a user can set a channel for
+func ConfigurableTypes() []string {
+	types := make([]string, 0, len(registry))
+	for notificationType := range registry {
+		if !mandatoryTypes[notificationType] {
+			types = append(types, notificationType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// IsConfigurable reports whether a user can set a channel for the type
+func IsConfigurable(notificationType string) bool {
+	_, known := registry[notificationType]
+	return known && !mandatoryTypes[notificationType]
+}
+
+// IsValidChannel reports whether channel is one of the delivery channels
+func IsValidChannel(channel string) bool {
+	switch channel {
+	case models.NotificationChannelPush, models.NotificationChannelInApp, models.NotificationChannelEmail, models.NotificationChannelOff:
+		return true
+	}
+	return false
+}
+
+// ValidateQuietHours checks the times and the timezone
+func ValidateQuietHours(qh *models.QuietHours) error {
+	if _, err := time.Parse("15:04", qh.Start); err != nil {
+		return fmt.Errorf("invalid start time %q, use HH:MM", qh.Start)
+	}
+	if _, err := time.Parse("15:04", qh.End); err != nil {
+		return fmt.Errorf("invalid end time %q, use HH:MM", qh.End)
+	}
+	if qh.Start == qh.End {
+		return fmt.Errorf("start and end time must differ")
+	}
+	if _, err := time.LoadLocation(qh.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", qh.Timezone)
+	}
+	return nil
+}
+
+// InQuietHours reports whether now falls inside the quiet hours, in the user's timezone
+func InQuietHours(qh *models.QuietHours, now time.Time) bool {
+	if qh == nil {
+		return false
+	}
+	loc, err := time.LoadLocation(qh.Timezone)
+	if err != nil {
+		return false
+	}
+	start, errStart := time.Parse("15:04", qh.Start)
+	end, errEnd := time.Parse("15:04", qh.End)
+	if errStart != nil || errEnd != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+
+	if from < to {
+		return minutes >= from && minutes < to
+	}
+	// Wraps past midnight, e.g. 22:00 - 07:00
+	return minutes >= from || minutes < to
+}
+
+// Enabled reports whether the user receives notifications of this type at all
+func Enabled(userID int64, notificationType string) bool {
+	if mandatoryTypes[notificationType] {
+		return true
+	}
+	channel, err := db.DBService.GetNotificationChannel(userID, notificationType)
+	if err != nil {
+		// Preferences must not make notifications disappear when they can't be read
+		return true
+	}
+	return channel != models.NotificationChannelOff
+}
+
+// resolveDelivery applies the user's channel, group mutes and quiet hours to a payload
+func resolveDelivery(userID int64, payload Payload) delivery {
+	if mandatoryTypes[payload.Kind()] {
+		return delivery{Channel: models.NotificationChannelPush, Push: true}
+	}
+
+	channel, err := db.DBService.GetNotificationChannel(userID, payload.Kind())
+	if err != nil {
+		fmt.Printf("[NOTIFICATIONS] Error reading preferences of user %d: %v\n", userID, err)
+		channel = models.NotificationChannelPush
+	}
+	d := delivery{Channel: channel, Push: channel == models.NotificationChannelPush}
+	if !d.Push {
+		return d
+	}
+
+	if scoped, ok := payload.(GroupScoped); ok {
+		if muted, err := db.DBService.IsGroupMuted(userID, scoped.Group()); err == nil && muted {
+			d.Push = false
+			return d
+		}
+	}
+
+	if qh, err := db.DBService.GetQuietHours(userID); err == nil && InQuietHours(qh, time.Now()) {
+		d.Push = false
+	}
+	return d
+}
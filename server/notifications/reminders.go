@@ -0,0 +1,90 @@
+package notifications
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+)
+
+// StartEventReminderJob sends the due event reminders every interval
+func StartEventReminderJob(leadTimes []time.Duration, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if sent, err := SendEventReminders(leadTimes, now); err != nil {
+			log.Printf("Event reminders failed: %v", err)
+		} else if sent > 0 {
+			log.Printf("Sent %d event reminders", sent)
+		}
+	}
+}
+
+// SendEventReminders reminds the users of the events starting within one of the lead times of now.
+// Each reminder is claimed before it is sent, a restart or a second server never sends it twice.
+func SendEventReminders(leadTimes []time.Duration, now time.Time) (int, error) {
+	if err := db.DBService.PruneEventReminders(now); err != nil {
+		return 0, err
+	}
+	reminders, err := db.DBService.GetDueEventReminders(now, leadTimes)
+	if err != nil {
+		return 0, err
+	}
+
+	groupNames := map[int64]string{}
+	sent := 0
+	for _, reminder := range reminders {
+		claimed, err := db.DBService.ClaimEventReminder(reminder)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		event := reminder.Event
+		name, ok := groupNames[event.GroupID]
+		if !ok {
+			group, err := db.DBService.GetGroupByID(event.GroupID)
+			if err != nil {
+				log.Printf("Event reminder for event %d: %v", event.ID, err)
+				continue
+			}
+			name = group.Title
+			groupNames[event.GroupID] = name
+		}
+		Send(reminder.UserID, &EventReminder{
+			GroupID:    event.GroupID,
+			GroupName:  name,
+			EventID:    event.ID,
+			EventTitle: event.Title,
+			StartsAt:   event.EventDateTime,
+			StartsIn:   formatLeadTime(event.StartsAt().Sub(now)),
+			Location:   event.Location,
+		})
+		sent++
+	}
+	return sent, nil
+}
+
+// formatLeadTime writes how long until an event starts, rounded to the largest unit that fits
+func formatLeadTime(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= 48*time.Hour:
+		return plural(int64(d.Round(24*time.Hour)/(24*time.Hour)), "day")
+	case d >= time.Hour:
+		return plural(int64(d.Round(time.Hour)/time.Hour), "hour")
+	case d >= time.Minute:
+		return plural(int64(d.Round(time.Minute)/time.Minute), "minute")
+	default:
+		return "less than a minute"
+	}
+}
@@ -0,0 +1,175 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/utils"
+	"github.com/Golden76z/social-network/websockets"
+)
+
+// Sender delivers one encrypted message to a push service, utils.WebPushSender in production
+type Sender interface {
+	Send(ctx context.Context, sub utils.PushSubscription, payload []byte) error
+}
+
+// Payload is the JSON handed to the service worker of the client
+type Payload struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	// Tag lets the browser replace an older notification of the same conversation
+	Tag  string         `json:"tag,omitempty"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+type job struct {
+	userID  int
+	payload Payload
+}
+
+// Worker sends Web Push messages to users without an open socket.
+// Enqueue is the websockets.OfflineHandler, deliveries happen on background goroutines.
+type Worker struct {
+	sender  Sender
+	queue   chan job
+	timeout time.Duration
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewWorker creates a worker with the given number of goroutines and queue size
+func NewWorker(sender Sender, workers, queueSize int) *Worker {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	w := &Worker{
+		sender:  sender,
+		queue:   make(chan job, queueSize),
+		timeout: 15 * time.Second,
+	}
+	for i := 0; i < workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	return w
+}
+
+// Enqueue converts a hub message to a push payload and queues it.
+// Messages without a push equivalent are ignored, and a full queue drops the message rather than block the hub.
+func (w *Worker) Enqueue(userID int, message websockets.Message) {
+	payload, ok := PayloadFromMessage(message)
+	if !ok {
+		return
+	}
+
+	select {
+	case w.queue <- job{userID: userID, payload: payload}:
+	default:
+		log.Printf("Push queue full, dropping %s for user %d", payload.Type, userID)
+	}
+}
+
+// Stop waits for the queued messages to be sent, Enqueue must not be called afterwards
+func (w *Worker) Stop() {
+	w.once.Do(func() { close(w.queue) })
+	w.wg.Wait()
+}
+
+func (w *Worker) run() {
+	defer w.wg.Done()
+	for j := range w.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+		if err := w.Deliver(ctx, j.userID, j.payload); err != nil {
+			log.Printf("Push to user %d failed: %v", j.userID, err)
+		}
+		cancel()
+	}
+}
+
+// Deliver sends the payload to every subscription of the user.
+// Subscriptions the push service reports as gone are deleted.
+func (w *Worker) Deliver(ctx context.Context, userID int, payload Payload) error {
+	subscriptions, err := db.DBService.GetPushSubscriptions(int64(userID))
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if len(body) > utils.MaxPushPayload {
+		// Keep the title so the user still knows something happened
+		payload.Body, payload.Data = "", nil
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	var failed []error
+	for _, sub := range subscriptions {
+		err := w.sender.Send(ctx, utils.PushSubscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth}, body)
+		switch {
+		case errors.Is(err, utils.ErrPushSubscriptionGone):
+			if err := db.DBService.DeletePushSubscriptionByEndpoint(sub.Endpoint); err != nil {
+				failed = append(failed, err)
+			}
+		case err != nil:
+			failed = append(failed, fmt.Errorf("subscription %d: %w", sub.ID, err))
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// PayloadFromMessage maps the hub messages worth a push: private messages and notifications
+func PayloadFromMessage(message websockets.Message) (Payload, bool) {
+	data, _ := message.Data.(map[string]any)
+
+	switch message.Type {
+	case websockets.MessageTypePrivateMessage:
+		return Payload{
+			Type:  message.Type,
+			Title: "New message from " + message.Username,
+			Body:  truncate(message.Content, 200),
+			Tag:   fmt.Sprintf("chat-%d", message.UserID),
+			Data: map[string]any{
+				"sender_id":  message.UserID,
+				"message_id": message.MessageID,
+			},
+		}, true
+
+	case "notification", websockets.MessageTypeNotify:
+		if data == nil || message.Content == "" {
+			return Payload{}, false
+		}
+		return Payload{
+			Type:  "notification",
+			Title: "New notification",
+			Body:  message.Content,
+			Data:  data,
+		}, true
+	}
+	return Payload{}, false
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/Golden76z/social-network/api"
+)
+
+func setupAudienceRoutes(r *Router) {
+	r.GET("/api/audience", api.GetAudienceListsHandler)
+	r.POST("/api/audience", api.CreateAudienceListHandler)
+	r.PUT("/api/audience", api.UpdateAudienceListHandler)
+	r.DELETE("/api/audience", api.DeleteAudienceListHandler)
+}
@@ -8,12 +8,33 @@ import (
 )
 
 func setupAuthRoutes(r *Router) {
+	// Credential checks get the strictest policy
 	r.Group(func(r *Router) {
-		r.Use(middleware.RateLimit(5, time.Minute))
+		r.Use(middleware.RateLimitWith(middleware.RateLimitPolicy{
+			Name:    "auth-credentials",
+			Limit:   5,
+			Window:  time.Minute,
+			KeyFunc: middleware.KeyByIP,
+		}))
 
-		r.GET("/", api.HomeHandler)
 		r.POST("/auth/login", api.LoginHandler)
+		r.POST("/auth/login/2fa", api.LoginTwoFactorHandler)
 		r.POST("/auth/register", api.RegisterHandler)
+	})
+
+	r.Group(func(r *Router) {
+		r.Use(middleware.RateLimitWith(middleware.RateLimitPolicy{
+			Name:    "auth",
+			Limit:   30,
+			Window:  time.Minute,
+			KeyFunc: middleware.KeyByIP,
+		}))
+
+		r.GET("/", api.HomeHandler)
 		r.POST("/auth/logout", api.LogoutHandler)
+
+		// OpenID Connect sign-in
+		r.GET("/auth/oidc/login", api.OIDCLoginHandler)
+		r.GET("/auth/oidc/callback", api.OIDCCallbackHandler)
 	})
 }
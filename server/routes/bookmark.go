@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/Golden76z/social-network/api"
+)
+
+func setupBookmarkRoutes(r *Router) {
+	r.GET("/api/bookmarks", api.GetBookmarksHandler)
+	r.POST("/api/bookmarks", api.CreateBookmarkHandler)
+	r.PUT("/api/bookmarks", api.MoveBookmarkHandler)
+	r.DELETE("/api/bookmarks", api.DeleteBookmarkHandler)
+
+	// Collections
+	r.GET("/api/bookmarks/collections", api.GetBookmarkCollectionsHandler)
+	r.POST("/api/bookmarks/collections", api.CreateBookmarkCollectionHandler)
+	r.PUT("/api/bookmarks/collections", api.RenameBookmarkCollectionHandler)
+	r.DELETE("/api/bookmarks/collections", api.DeleteBookmarkCollectionHandler)
+}
@@ -15,6 +15,16 @@ func setupGroupRoutes(r *Router) {
 	r.DELETE("/api/group", api.DeleteGroupHandler)
 	r.DELETE("/api/group/{id}", api.DeleteGroupHandler)
 
+	// Group lifecycle: archiving, restoring deleted groups and ownership transfers
+	r.POST("/api/group/archive", api.ArchiveGroupHandler)
+	r.POST("/api/group/unarchive", api.UnarchiveGroupHandler)
+	r.GET("/api/group/deleted", api.GetDeletedGroupsHandler) // Deleted groups the user can still restore
+	r.POST("/api/group/restore", api.RestoreGroupHandler)
+	r.GET("/api/group/transfer", api.GetOwnershipTransfersHandler)
+	r.POST("/api/group/transfer", api.CreateOwnershipTransferHandler)
+	r.PUT("/api/group/transfer", api.RespondOwnershipTransferHandler)
+	r.DELETE("/api/group/transfer", api.CancelOwnershipTransferHandler)
+
 	// Group posts - RESTful approach
 	r.POST("/api/group/post", api.CreateGroupPostHandler)
 	r.GET("/api/group/post", api.GetGroupPostHandler)            // For list: ?groupId=1&offset=0
@@ -23,6 +33,11 @@ func setupGroupRoutes(r *Router) {
 	r.PUT("/api/group/post/{id}", api.UpdateGroupPostHandler)    // For path-based updates: /api/group/post/{id}
 	r.DELETE("/api/group/post", api.DeleteGroupPostHandler)      // For body-based deletes
 	r.DELETE("/api/group/post/{id}", api.DeleteGroupPostHandler) // For path-based deletes: /api/group/post/{id}
+	r.POST("/api/group/post/pin", api.PinGroupPostHandler)
+	r.DELETE("/api/group/post/pin", api.UnpinGroupPostHandler)
+	r.POST("/api/group/post/announcement", api.AnnounceGroupPostHandler)
+	r.GET("/api/group/post/queue", api.GetGroupPostQueueHandler) // Posts waiting for review: ?group_id=1
+	r.PUT("/api/group/post/review", api.ReviewGroupPostHandler)
 
 	// Group comments
 	r.POST("/api/group/comment", api.CreateGroupCommentHandler)
@@ -41,6 +56,12 @@ func setupGroupRoutes(r *Router) {
 	r.PUT("/api/group/event/{id}", api.UpdateGroupEventHandler)    // Path-based
 	r.DELETE("/api/group/event", api.DeleteGroupEventHandler)      // Body-based
 	r.DELETE("/api/group/event/{id}", api.DeleteGroupEventHandler) // Path-based
+	r.GET("/api/group/event/ics", api.ExportGroupEventHandler)
+	r.GET("/api/group/event/exception", api.GetGroupEventExceptionsHandler)
+	r.PUT("/api/group/event/exception", api.SetGroupEventExceptionHandler)
+	r.DELETE("/api/group/event/exception", api.DeleteGroupEventExceptionHandler)
+	r.POST("/api/group/event/cancel", api.CancelGroupEventHandler)
+	r.GET("/api/group/event/changes", api.GetGroupEventChangesHandler)
 
 	// Group membership
 	r.POST("/api/group/member", api.CreateGroupMemberHandler)
@@ -48,12 +69,31 @@ func setupGroupRoutes(r *Router) {
 	r.PUT("/api/group/member", api.UpdateGroupMemberHandler)
 	r.DELETE("/api/group/member", api.DeleteGroupMemberHandler)
 
+	// Group roles and permission matrix
+	r.GET("/api/group/permissions", api.GetGroupPermissionsHandler)
+	r.PUT("/api/group/permissions", api.UpdateGroupPermissionsHandler)
+
+	// Group moderation: bans, mutes and the moderation log
+	r.GET("/api/group/ban", api.GetGroupBansHandler)
+	r.POST("/api/group/ban", api.BanGroupMemberHandler)
+	r.DELETE("/api/group/ban", api.UnbanGroupMemberHandler)
+	r.GET("/api/group/mute", api.GetGroupMutesHandler)
+	r.POST("/api/group/mute", api.MuteGroupMemberHandler)
+	r.DELETE("/api/group/mute", api.UnmuteGroupMemberHandler)
+	r.GET("/api/group/moderation-log", api.GetModerationLogHandler)
+
 	// Group invitations
 	r.POST("/api/group/invitation", api.CreateGroupInvitationHandler)
 	r.GET("/api/group/invitation", api.GetGroupInvitationHandler)
 	r.PUT("/api/group/invitation", api.UpdateGroupInvitationHandler)
 	r.DELETE("/api/group/invitation", api.DeleteGroupInvitationHandler)
 
+	// Shareable invite links
+	r.GET("/api/group/invite-link", api.GetGroupInviteLinksHandler)
+	r.POST("/api/group/invite-link", api.CreateGroupInviteLinkHandler)
+	r.DELETE("/api/group/invite-link", api.RevokeGroupInviteLinkHandler)
+	r.POST("/api/group/invite-link/redeem", api.RedeemGroupInviteLinkHandler)
+
 	// Event RSVP
 	r.POST("/api/group/event/rsvp", api.RSVPToEventHandler)
 	r.GET("/api/group/event/rsvp", api.GetEventRSVPsHandler)
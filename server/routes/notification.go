@@ -11,4 +11,12 @@ func setupNotificationsRoutes(r *Router) {
 	r.DELETE("/api/user/notifications", api.DeleteUserNotificationsHandler)
 	r.POST("/api/user/notifications/accept", api.AcceptNotificationHandler)
 	r.POST("/api/user/notifications/decline", api.DeclineNotificationHandler)
+
+	// Preferences
+	r.GET("/api/user/notifications/preferences", api.GetNotificationPreferencesHandler)
+	r.PUT("/api/user/notifications/preferences", api.UpdateNotificationPreferencesHandler)
+	r.POST("/api/user/notifications/mute", api.MuteGroupNotificationsHandler)
+	r.DELETE("/api/user/notifications/mute", api.UnmuteGroupNotificationsHandler)
+	r.GET("/api/user/notifications/digest", api.GetNotificationDigestHandler)
+	r.PUT("/api/user/notifications/digest", api.UpdateNotificationDigestHandler)
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/Golden76z/social-network/api"
+)
+
+func setupPollRoutes(r *Router) {
+	r.GET("/api/poll", api.GetPollHandler)
+	r.POST("/api/poll/vote", api.VotePollHandler)
+	r.POST("/api/poll/close", api.ClosePollHandler)
+}
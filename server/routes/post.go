@@ -14,6 +14,17 @@ func setupPostRoutes(r *Router) {
 	r.GET("/api/post/{id}/visibility", api.GetPostVisibilityHandler)
 	r.PUT("/api/post/{id}/visibility", api.UpdatePostVisibilityHandler)
 
+	// Reposts and quotes
+	r.POST("/api/post/repost", api.RepostHandler)
+	r.DELETE("/api/post/repost", api.DeleteRepostHandler)
+
+	// Drafts and scheduled posts
+	r.GET("/api/post/draft", api.GetPostDraftHandler)
+	r.POST("/api/post/draft", api.CreatePostDraftHandler)
+	r.PUT("/api/post/draft", api.UpdatePostDraftHandler)
+	r.DELETE("/api/post/draft", api.DeletePostDraftHandler)
+	r.POST("/api/post/draft/publish", api.PublishPostDraftHandler)
+
 	// Upload post image
 	r.POST("/api/upload/post-image", api.UploadPostImageHandler)
 }
@@ -0,0 +1,9 @@
+package routes
+
+import "github.com/Golden76z/social-network/api"
+
+func setupPushRoutes(r *Router) {
+	r.GET("/api/push/vapid-public-key", api.GetVAPIDPublicKeyHandler)
+	r.POST("/api/push/subscriptions", api.SubscribePushHandler)
+	r.DELETE("/api/push/subscriptions", api.UnsubscribePushHandler)
+}
@@ -0,0 +1,9 @@
+package routes
+
+import (
+	"github.com/Golden76z/social-network/api"
+)
+
+func setupRevisionRoutes(r *Router) {
+	r.GET("/api/revisions", api.GetContentRevisionsHandler)
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/Golden76z/social-network/api"
 	"github.com/Golden76z/social-network/config"
@@ -11,7 +12,7 @@ import (
 
 func SetupRoutes(r *Router, db *sql.DB, wsHub *websockets.Hub, cfg *config.Config) {
 	setupPublicRoutes(r, wsHub, cfg)
-	setupProtectedRoutes(r)
+	setupProtectedRoutes(r, cfg)
 }
 
 func setupPublicRoutes(r *Router, wsHub *websockets.Hub, cfg *config.Config) {
@@ -32,27 +33,44 @@ func setupPublicRoutes(r *Router, wsHub *websockets.Hub, cfg *config.Config) {
 		// Public profile route (no authentication required)
 		r.GET("/api/public/user/profile", api.GetPublicUserProfileHandler)
 
+		// Unsubscribe links of notification digests carry their own token
+		r.GET("/notifications/unsubscribe", api.UnsubscribeDigestHandler)
+		r.POST("/notifications/unsubscribe", api.UnsubscribeDigestHandler)
+
+		// Calendar apps subscribe to the feed with the token of its URL
+		r.GET("/calendar/feed.ics", api.CalendarFeedHandler)
+
 		// Test routes for development (public)
 		r.GET("/api/test/token", api.TestTokenHandler)
 	})
 }
 
-func setupProtectedRoutes(r *Router) {
+func setupProtectedRoutes(r *Router, cfg *config.Config) {
 	// Protected routes group
 	r.Group(func(r *Router) {
 		// Apply auth middleware
 		r.Use(middleware.AuthMiddleware())
 		r.Use(middleware.CSRFMiddleware)
-		// r.Use(middleware.RateLimit(1000000, time.Minute))
+		// Runs after auth so each user gets their own bucket
+		r.Use(middleware.RateLimitWith(middleware.RateLimitPolicy{
+			Name:   "api",
+			Limit:  cfg.RateLimitRequests,
+			Window: time.Duration(cfg.RateLimitWindowMinutes) * time.Minute,
+		}))
 
 		setupUserRoutes(r)
 		setupPostRoutes(r)
 		setupCommentRoutes(r)
 		setupReactionRoutes(r)
+		setupPollRoutes(r)
+		setupRevisionRoutes(r)
+		setupBookmarkRoutes(r)
+		setupAudienceRoutes(r)
 		setupGroupRoutes(r)
 		setupFollowRoutes(r)
 		setupChatRoutes(r)
 		setupNotificationsRoutes(r)
+		setupPushRoutes(r)
 		setupSearchRoutes(r)
 	})
 }
@@ -9,12 +9,23 @@ func setupUserRoutes(r *Router) {
 	r.GET("/api/user/profile", api.GetUserProfileHandler)
 	r.PUT("/api/user/profile", api.UpdateUserProfileHandler)
 
+	// Two-factor authentication routes
+	r.GET("/api/user/2fa", api.GetTwoFactorStatusHandler)
+	r.POST("/api/user/2fa/setup", api.SetupTwoFactorHandler)
+	r.POST("/api/user/2fa/enable", api.EnableTwoFactorHandler)
+	r.POST("/api/user/2fa/disable", api.DisableTwoFactorHandler)
+	r.POST("/api/user/2fa/recovery-codes", api.RegenerateRecoveryCodesHandler)
+
 	// Notification routes
 	r.POST("/api/user/notifications", api.SendUserNotificationsHandler)
 	r.GET("/api/user/notifications", api.GetUserNotificationsHandler)
 	r.PUT("/api/user/notifications", api.UpdateUserNotificationsHandler)
 	r.DELETE("/api/user/notifications", api.DeleteUserNotificationsHandler)
 
+	// Calendar feed covering the events of every group of the user
+	r.GET("/api/user/calendar-feed", api.GetCalendarFeedHandler)
+	r.DELETE("/api/user/calendar-feed", api.DeleteCalendarFeedHandler)
+
 	// Realtime helpers
 	r.GET("/api/ws/token", api.GetWebSocketTokenHandler)
 
@@ -15,6 +15,8 @@ import (
 	"github.com/Golden76z/social-network/db/migrations"
 	"github.com/Golden76z/social-network/demo"
 	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/notifications"
+	"github.com/Golden76z/social-network/push"
 	"github.com/Golden76z/social-network/routes"
 	"github.com/Golden76z/social-network/utils"
 	"github.com/Golden76z/social-network/websockets"
@@ -59,13 +61,71 @@ func main() {
 	// Generating the seed data
 	demo.GenerateSeed()
 
+	// Enable third-party sign-in when a provider is configured
+	if cfg.OIDCEnabled {
+		api.SetOIDCProvider(utils.NewOIDCProvider(utils.OIDCConfig{
+			Name:         cfg.OIDCProviderName,
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		}), cfg.OIDCPostLoginRedirect)
+	}
+
 	// Start session cleanup with configurable interval
 	go utils.StartSessionCleanup(dbService.DB, cfg.SessionCleanupInterval)
 
+	// Deleted groups stay restorable for a while, then are purged
+	api.SetGroupRestoreWindow(cfg.GroupRestoreWindow)
+	go db.StartGroupPurgeJob(cfg.GroupPurgeInterval)
+
+	// Calendar apps reach the feed on the public address
+	api.SetCalendarFeedURL(cfg.PublicBaseURL + "/calendar/feed.ics")
+
+	// Moving an event by at least this much asks its RSVPs to be confirmed again
+	api.SetEventRescheduleThreshold(cfg.EventRescheduleThreshold)
+
+	// Email unread notifications to users who were away
+	if cfg.DigestEnabled {
+		go notifications.StartDigestJob(notifications.DigestOptions{
+			Mailer:           newMailer(cfg),
+			UnsubscribeURL:   cfg.PublicBaseURL + "/notifications/unsubscribe",
+			NotificationsURL: cfg.ClientBaseURL + "/notifications",
+			DefaultFrequency: cfg.DigestDefaultFrequency,
+			MaxItems:         cfg.DigestMaxItems,
+		}, cfg.DigestInterval)
+	}
+
+	// Remind members who answered an event shortly before it starts
+	if cfg.EventRemindersEnabled {
+		go notifications.StartEventReminderJob(cfg.EventReminderLeadTimes, cfg.EventReminderInterval)
+	}
+
+	// Publish the scheduled drafts once their time has come
+	if cfg.ScheduledPostsEnabled {
+		go api.StartPostDraftPublisher(cfg.ScheduledPostsInterval)
+	}
+
 	// Initialize WebSocket hub
 	websockets.InitHub(dbService.DB)
 	wsHub := websockets.GetHub()
 
+	// Reach users without an open socket through Web Push
+	if cfg.WebPushEnabled {
+		sender := &utils.WebPushSender{Keys: cfg.VAPIDKeys, Subject: cfg.VAPIDSubject, AllowPrivateHosts: cfg.Environment != "production"}
+		wsHub.SetOfflineHandler(push.NewWorker(sender, cfg.WebPushWorkers, 1000).Enqueue)
+		api.SetWebPush(cfg.VAPIDKeys.PublicKey(), cfg.Environment != "production")
+	}
+
+	// Sign CSRF tokens with the configured secret
+	middleware.SetCSRFSecret(cfg.CSRFSecret, cfg.CSRFTokenTTL)
+
+	// Client addresses for rate limits and login failures come from these proxies' headers only
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Setup router and middleware
 	r := routes.New()
 	// r.Use(middleware.Logger)
@@ -80,6 +140,20 @@ func main() {
 	startServer(r, cfg)
 }
 
+// newMailer picks the mail transport, the file mailer keeps emails on disk for development
+func newMailer(cfg *config.Config) utils.Mailer {
+	if cfg.MailDriver == "smtp" {
+		return &utils.SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	}
+	return &utils.FileMailer{Dir: cfg.MailFileDir, From: cfg.MailFrom}
+}
+
 func startServer(handler http.Handler, cfg *config.Config) {
 	// Redirect HTTP to HTTPS in production
 	if cfg.Environment == "production" {
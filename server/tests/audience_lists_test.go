@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+)
+
+// setupAudienceListsTestDB makes user 5 follow the member (user 4) too
+func setupAudienceListsTestDB(t *testing.T) {
+	setupFollowersTestDB(t)
+	seedTestDB(t, `INSERT INTO follow_requests (requester_id, target_id, status) VALUES (5, 4, 'accepted')`)
+}
+
+func createListPost(t *testing.T, listID int64, selected []int64, want int) int64 {
+	t.Helper()
+	req := models.CreatePostRequest{Title: "Plans", Body: "Just for you", Visibility: "private", AudienceListID: &listID, SelectedFollowers: selected}
+	rr := callGroupHandler(api.CreatePostHandler, http.MethodPost, "/api/post", req, 4)
+	if rr.Code != want {
+		t.Fatalf("create post with list %d: expected %d, got %d: %s", listID, want, rr.Code, rr.Body.String())
+	}
+	var created struct{ PostID int64 }
+	json.NewDecoder(rr.Body).Decode(&created)
+	return created.PostID
+}
+
+func postAudience(t *testing.T, postID int64) []int64 {
+	t.Helper()
+	audience, err := db.DBService.GetPostVisibilityUsers(postID)
+	if err != nil {
+		t.Fatalf("get post visibility: %v", err)
+	}
+	sort.Slice(audience, func(i, j int) bool { return audience[i] < audience[j] })
+	return audience
+}
+
+func updatePostAudience(t *testing.T, postID int64, body map[string]any, want int) {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/post/%d/visibility", postID), bytes.NewReader(data))
+	req = asUser(req.WithContext(utils.SetPathParam(req.Context(), "id", fmt.Sprint(postID))), 4)
+	api.UpdatePostVisibilityHandler(rr, req)
+	if rr.Code != want {
+		t.Fatalf("update post visibility: expected %d, got %d: %s", want, rr.Code, rr.Body.String())
+	}
+}
+
+func TestAudienceLists(t *testing.T) {
+	t.Run("lists only hold followers of their owner", func(t *testing.T) {
+		setupAudienceListsTestDB(t)
+		list := decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Close friends", Members: []int64{2, 2}}, 4, http.StatusCreated)
+		if list.Name != "Close friends" || len(list.Members) != 1 || list.Members[0] != 2 {
+			t.Errorf("expected the list with user 2 once, got %+v", list)
+		}
+
+		decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Pending", Members: []int64{6}}, 4, http.StatusBadRequest)
+		decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Close friends"}, 4, http.StatusConflict)
+		decodeCall[models.AudienceList](t, api.GetAudienceListsHandler, http.MethodGet, fmt.Sprintf("/api/audience?id=%d", list.ID), nil, 2, http.StatusNotFound)
+	})
+
+	t.Run("a private post takes its audience from a list", func(t *testing.T) {
+		setupAudienceListsTestDB(t)
+		list := decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Close friends", Members: []int64{2}}, 4, http.StatusCreated)
+
+		postID := createListPost(t, list.ID, []int64{3}, http.StatusCreated)
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[2 3]" {
+			t.Errorf("expected the list and the picked follower, got %v", audience)
+		}
+		if _, err := db.DBService.GetPostByID(postID, 2); err != nil {
+			t.Errorf("expected the list member to see the post: %v", err)
+		}
+
+		empty := decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Nobody"}, 4, http.StatusCreated)
+		createListPost(t, empty.ID, nil, http.StatusBadRequest)
+		createListPost(t, 99, nil, http.StatusNotFound)
+
+		public := models.CreatePostRequest{Title: "Plans", Body: "For all", Visibility: "public", AudienceListID: &list.ID}
+		if rr := callGroupHandler(api.CreatePostHandler, http.MethodPost, "/api/post", public, 4); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for a public post with a list, got %d", rr.Code)
+		}
+	})
+
+	t.Run("member changes reach the posts only when asked", func(t *testing.T) {
+		setupAudienceListsTestDB(t)
+		list := decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Close friends", Members: []int64{2}}, 4, http.StatusCreated)
+		postID := createListPost(t, list.ID, nil, http.StatusCreated)
+		otherID := createListPost(t, list.ID, nil, http.StatusCreated)
+
+		members := []int64{3}
+		decodeCall[models.AudienceList](t, api.UpdateAudienceListHandler, http.MethodPut, "/api/audience",
+			models.UpdateAudienceListRequest{ID: list.ID, Members: &members}, 4, http.StatusOK)
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[2]" {
+			t.Errorf("expected the post to keep its audience, got %v", audience)
+		}
+
+		// The second post no longer follows the list once its audience is picked by hand
+		updatePostAudience(t, otherID, map[string]any{"selected_followers": []int64{3}}, http.StatusOK)
+
+		members = []int64{2, 5}
+		updated := decodeCall[models.AudienceList](t, api.UpdateAudienceListHandler, http.MethodPut, "/api/audience",
+			models.UpdateAudienceListRequest{ID: list.ID, Members: &members, ApplyToPosts: true}, 4, http.StatusOK)
+		if fmt.Sprint(updated.Members) != "[2 5]" {
+			t.Errorf("expected the new members, got %v", updated.Members)
+		}
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[2 5]" {
+			t.Errorf("expected user 5 to be added to the post, got %v", audience)
+		}
+
+		members = []int64{5}
+		decodeCall[models.AudienceList](t, api.UpdateAudienceListHandler, http.MethodPut, "/api/audience",
+			models.UpdateAudienceListRequest{ID: list.ID, Members: &members, ApplyToPosts: true}, 4, http.StatusOK)
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[5]" {
+			t.Errorf("expected user 2 to be removed from the post, got %v", audience)
+		}
+		if audience := postAudience(t, otherID); fmt.Sprint(audience) != "[3]" {
+			t.Errorf("expected the hand-picked post to be left alone, got %v", audience)
+		}
+
+		members = []int64{}
+		decodeCall[models.AudienceList](t, api.UpdateAudienceListHandler, http.MethodPut, "/api/audience",
+			models.UpdateAudienceListRequest{ID: list.ID, Members: &members, ApplyToPosts: true}, 4, http.StatusBadRequest)
+		decodeCall[models.AudienceList](t, api.UpdateAudienceListHandler, http.MethodPut, "/api/audience",
+			models.UpdateAudienceListRequest{ID: list.ID, Members: &members}, 2, http.StatusNotFound)
+	})
+
+	t.Run("deleting a list keeps the audience of its posts", func(t *testing.T) {
+		setupAudienceListsTestDB(t)
+		list := decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Close friends", Members: []int64{2, 3}}, 4, http.StatusCreated)
+		postID := createListPost(t, list.ID, nil, http.StatusCreated)
+
+		target := fmt.Sprintf("/api/audience?id=%d", list.ID)
+		if rr := callGroupHandler(api.DeleteAudienceListHandler, http.MethodDelete, target, nil, 2); rr.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for someone else's list, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.DeleteAudienceListHandler, http.MethodDelete, target, nil, 4); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[2 3]" {
+			t.Errorf("expected the post to keep its audience, got %v", audience)
+		}
+		var listID *int64
+		db.DBService.DB.QueryRow(`SELECT audience_list_id FROM posts WHERE id = ?`, postID).Scan(&listID)
+		if listID != nil {
+			t.Errorf("expected the post to no longer point to the list, got %d", *listID)
+		}
+	})
+
+	t.Run("members who stopped following are left out", func(t *testing.T) {
+		setupAudienceListsTestDB(t)
+		list := decodeCall[models.AudienceList](t, api.CreateAudienceListHandler, http.MethodPost, "/api/audience",
+			models.CreateAudienceListRequest{Name: "Close friends", Members: []int64{2, 3}}, 4, http.StatusCreated)
+		postID := createListPost(t, list.ID, nil, http.StatusCreated)
+
+		seedTestDB(t, `DELETE FROM follow_requests WHERE requester_id = 3 AND target_id = 4`)
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[2 3]" {
+			t.Fatalf("expected the post to keep its audience, got %v", audience)
+		}
+		updatePostAudience(t, postID, map[string]any{"audience_list_id": list.ID}, http.StatusOK)
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[2]" {
+			t.Errorf("expected the former follower to be left out, got %v", audience)
+		}
+
+		// Once nobody on the list follows, the post is not opened to every follower
+		seedTestDB(t, `DELETE FROM follow_requests WHERE requester_id = 2 AND target_id = 4`)
+		createListPost(t, list.ID, nil, http.StatusBadRequest)
+		updatePostAudience(t, postID, map[string]any{"audience_list_id": list.ID}, http.StatusBadRequest)
+		if audience := postAudience(t, postID); fmt.Sprint(audience) != "[2]" {
+			t.Errorf("expected the refused update to change nothing, got %v", audience)
+		}
+	})
+}
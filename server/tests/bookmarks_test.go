@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+)
+
+// setupBookmarksTestDB adds a group post of the member (user 4) to the repost posts
+func setupBookmarksTestDB(t *testing.T) {
+	setupRepostsTestDB(t)
+	seedTestDB(t, `INSERT INTO group_posts (group_id, user_id, title, body) VALUES (1, 4, 'Meetup', 'Saturday')`)
+}
+
+func listBookmarks(t *testing.T, query string, userID int) []models.Bookmark {
+	t.Helper()
+	return *decodeCall[[]models.Bookmark](t, api.GetBookmarksHandler, http.MethodGet, "/api/bookmarks?"+query, nil, userID, http.StatusOK)
+}
+
+func TestBookmarks(t *testing.T) {
+	t.Run("posts and group posts are saved into collections", func(t *testing.T) {
+		setupBookmarksTestDB(t)
+		rr := expectCall(t, api.CreateBookmarkCollectionHandler, http.MethodPost, "/api/bookmarks/collections",
+			models.BookmarkCollectionRequest{Name: " Hikes "}, 2, http.StatusCreated)
+		var collection models.BookmarkCollection
+		json.NewDecoder(rr.Body).Decode(&collection)
+		if collection.Name != "Hikes" {
+			t.Errorf("expected the name to be trimmed, got %q", collection.Name)
+		}
+		expectCall(t, api.CreateBookmarkCollectionHandler, http.MethodPost, "/api/bookmarks/collections",
+			models.BookmarkCollectionRequest{Name: "Hikes"}, 2, http.StatusConflict)
+
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 1, CollectionID: &collection.ID}, 2, http.StatusCreated)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{GroupPostID: 1}, 2, http.StatusCreated)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 1}, 2, http.StatusConflict)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 1, GroupPostID: 1}, 2, http.StatusBadRequest)
+
+		all := listBookmarks(t, "", 2)
+		if len(all) != 2 || all[0].GroupPost == nil || all[0].GroupPost.ID != 1 || all[1].Post == nil || all[1].Post.ID != 1 {
+			t.Fatalf("expected the group post then the post, got %+v", all)
+		}
+		inCollection := fmt.Sprintf("collection_id=%d", collection.ID)
+		if saved := listBookmarks(t, inCollection, 2); len(saved) != 1 || saved[0].Post == nil {
+			t.Errorf("expected only the post in the collection, got %+v", saved)
+		}
+
+		expectCall(t, api.MoveBookmarkHandler, http.MethodPut, "/api/bookmarks",
+			models.BookmarkRequest{GroupPostID: 1, CollectionID: &collection.ID}, 2, http.StatusOK)
+		if saved := listBookmarks(t, inCollection, 2); len(saved) != 2 {
+			t.Errorf("expected both bookmarks in the collection, got %d", len(saved))
+		}
+
+		// Collections are private to their owner
+		expectCall(t, api.GetBookmarksHandler, http.MethodGet, "/api/bookmarks?"+inCollection, nil, 3, http.StatusNotFound)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 1, CollectionID: &collection.ID}, 3, http.StatusNotFound)
+		if saved := listBookmarks(t, "", 3); len(saved) != 0 {
+			t.Errorf("expected no bookmark for another user, got %d", len(saved))
+		}
+	})
+
+	t.Run("only content the user can see is saved", func(t *testing.T) {
+		setupBookmarksTestDB(t)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 2}, 6, http.StatusForbidden)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{GroupPostID: 1}, 6, http.StatusForbidden)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 99}, 6, http.StatusNotFound)
+	})
+
+	t.Run("bookmarks leave the results while access is lost", func(t *testing.T) {
+		setupBookmarksTestDB(t)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 2}, 3, http.StatusCreated)
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{GroupPostID: 1}, 3, http.StatusCreated)
+
+		_, err := db.DBService.DB.Exec(`
+			DELETE FROM post_visibility WHERE post_id = 2 AND user_id = 3;
+			DELETE FROM group_members WHERE group_id = 1 AND user_id = 3;
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved := listBookmarks(t, "", 3); len(saved) != 0 {
+			t.Fatalf("expected the bookmarks to be hidden, got %+v", saved)
+		}
+
+		_, err = db.DBService.DB.Exec(`INSERT INTO group_members (group_id, user_id, role) VALUES (1, 3, 'member')`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved := listBookmarks(t, "", 3); len(saved) != 1 || saved[0].GroupPost == nil {
+			t.Errorf("expected the group post back once access is back, got %+v", saved)
+		}
+	})
+
+	t.Run("pages are counted on the bookmarks the user can see", func(t *testing.T) {
+		setupBookmarksTestDB(t)
+		// Newest first: the public post, then the private one the user loses, then the group post
+		seedTestDB(t, `
+			INSERT INTO bookmarks (user_id, group_post_id, created_at) VALUES (3, 1, '2025-01-01 10:00:00');
+			INSERT INTO bookmarks (user_id, post_id, created_at) VALUES (3, 2, '2025-01-02 10:00:00'), (3, 1, '2025-01-03 10:00:00');
+			DELETE FROM post_visibility WHERE post_id = 2 AND user_id = 3;
+		`)
+
+		first := listBookmarks(t, "limit=1", 3)
+		second := listBookmarks(t, "limit=1&offset=1", 3)
+		if len(first) != 1 || first[0].Post == nil || first[0].Post.ID != 1 {
+			t.Fatalf("expected the public post first, got %+v", first)
+		}
+		if len(second) != 1 || second[0].GroupPost == nil {
+			t.Fatalf("expected the group post on the second page, got %+v", second)
+		}
+		if third := listBookmarks(t, "limit=1&offset=2", 3); len(third) != 0 {
+			t.Errorf("expected no third page, got %+v", third)
+		}
+	})
+
+	t.Run("a bookmark that cannot be read does not fail the list", func(t *testing.T) {
+		setupBookmarksTestDB(t)
+		seedTestDB(t, `
+			INSERT INTO bookmarks (user_id, post_id) VALUES (3, 1);
+			INSERT INTO bookmarks (user_id, group_post_id) VALUES (3, 1);
+			UPDATE group_posts SET user_id = 99 WHERE id = 1;
+		`)
+		if saved := listBookmarks(t, "", 3); len(saved) != 1 || saved[0].Post == nil {
+			t.Errorf("expected only the readable bookmark, got %+v", saved)
+		}
+	})
+
+	t.Run("deleting a collection keeps its bookmarks", func(t *testing.T) {
+		setupBookmarksTestDB(t)
+		id, err := db.DBService.CreateBookmarkCollection(2, "Later")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectCall(t, api.CreateBookmarkHandler, http.MethodPost, "/api/bookmarks",
+			models.BookmarkRequest{PostID: 1, CollectionID: &id}, 2, http.StatusCreated)
+		expectCall(t, api.RenameBookmarkCollectionHandler, http.MethodPut, "/api/bookmarks/collections",
+			models.BookmarkCollectionRequest{ID: id, Name: "Someday"}, 3, http.StatusNotFound)
+
+		target := fmt.Sprintf("/api/bookmarks/collections?id=%d", id)
+		expectCall(t, api.DeleteBookmarkCollectionHandler, http.MethodDelete, target, nil, 3, http.StatusNotFound)
+		expectCall(t, api.DeleteBookmarkCollectionHandler, http.MethodDelete, target, nil, 2, http.StatusOK)
+		if saved := listBookmarks(t, "", 2); len(saved) != 1 || saved[0].CollectionID != nil {
+			t.Fatalf("expected the bookmark out of any collection, got %+v", saved)
+		}
+
+		expectCall(t, api.DeleteBookmarkHandler, http.MethodDelete, "/api/bookmarks?post_id=1", nil, 2, http.StatusOK)
+		expectCall(t, api.DeleteBookmarkHandler, http.MethodDelete, "/api/bookmarks?post_id=1", nil, 2, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+)
+
+// setupContentRevisionsTestDB adds a public and a private post of user 4, a group post of user 4 and a comment on each
+func setupContentRevisionsTestDB(t *testing.T) {
+	setupGroupRolesTestDB(t)
+	seedTestDB(t, `
+		INSERT INTO posts (user_id, title, body, visibility) VALUES (4, 'Trail', 'First draft', 'public'), (4, 'Secret', 'Only for you', 'private');
+		INSERT INTO post_visibility (post_id, user_id) VALUES (2, 2);
+		INSERT INTO post_images (post_id, is_group_post, image_url) VALUES (1, 0, '/uploads/a.jpg');
+		INSERT INTO comments (post_id, user_id, body) VALUES (1, 2, 'Nice'), (2, 2, 'Thanks');
+		INSERT INTO group_posts (group_id, user_id, title, body) VALUES (1, 4, 'Meetup', 'Saturday');
+		INSERT INTO group_comments (group_post_id, user_id, body) VALUES (1, 4, 'See you');
+	`)
+}
+
+func getRevisions(t *testing.T, query string, userID int, want int) *models.ContentHistory {
+	t.Helper()
+	return decodeCall[models.ContentHistory](t, api.GetContentRevisionsHandler, http.MethodGet, "/api/revisions?"+query, nil, userID, want)
+}
+
+func TestContentRevisions(t *testing.T) {
+	t.Run("each edit of a post keeps the version it replaces", func(t *testing.T) {
+		setupContentRevisionsTestDB(t)
+		title, body := "Trail", "Second draft"
+		if err := db.DBService.UpdatePost(1, models.UpdatePostRequest{Title: &title, Body: &body}); err != nil {
+			t.Fatalf("update post: %v", err)
+		}
+		images := []string{"/uploads/b.jpg"}
+		if err := db.DBService.UpdatePost(1, models.UpdatePostRequest{Images: &images}); err != nil {
+			t.Fatalf("update post: %v", err)
+		}
+
+		post, err := db.DBService.GetPostByID(1, 6)
+		if err != nil {
+			t.Fatalf("get post: %v", err)
+		}
+		if !post.Edited || post.RevisionCount != 2 {
+			t.Errorf("expected an edited post with 2 revisions, got %v and %d", post.Edited, post.RevisionCount)
+		}
+
+		history := getRevisions(t, "post_id=1", 6, http.StatusOK)
+		if history.CurrentRevision != 3 || len(history.Revisions) != 2 {
+			t.Fatalf("expected 2 previous versions of revision 3, got %+v", history)
+		}
+		original := history.Revisions[0]
+		if original.Revision != 1 || original.Body != "First draft" || original.Title == nil || *original.Title != "Trail" {
+			t.Errorf("expected the original version first, got %+v", original)
+		}
+		if len(original.Images) != 1 || original.Images[0] != "/uploads/a.jpg" {
+			t.Errorf("expected the original images to be kept, got %v", original.Images)
+		}
+		if original.EditedBy != 4 || original.EditorNickname == "" {
+			t.Errorf("expected the author as editor, got %d %q", original.EditedBy, original.EditorNickname)
+		}
+		if second := history.Revisions[1]; second.Body != "Second draft" || second.Images[0] != "/uploads/a.jpg" {
+			t.Errorf("expected the second version before the image change, got %+v", second)
+		}
+	})
+
+	t.Run("an edit changing nothing keeps no revision", func(t *testing.T) {
+		setupContentRevisionsTestDB(t)
+		body := "First draft"
+		visibility := "public"
+		if err := db.DBService.UpdatePost(1, models.UpdatePostRequest{Body: &body, Visibility: &visibility}); err != nil {
+			t.Fatalf("update post: %v", err)
+		}
+		comment := "Nice"
+		if err := db.DBService.UpdateComment(1, models.UpdateCommentRequest{Body: &comment}); err != nil {
+			t.Fatalf("update comment: %v", err)
+		}
+		if history := getRevisions(t, "post_id=1", 4, http.StatusOK); len(history.Revisions) != 0 || history.CurrentRevision != 1 {
+			t.Errorf("expected no revision, got %+v", history)
+		}
+		if post, _ := db.DBService.GetPostByID(1, 4); post.Edited {
+			t.Error("expected the post not to show as edited")
+		}
+	})
+
+	t.Run("comment history follows who can see the post", func(t *testing.T) {
+		setupContentRevisionsTestDB(t)
+		body := "Thanks a lot"
+		if err := db.DBService.UpdateComment(2, models.UpdateCommentRequest{Body: &body}); err != nil {
+			t.Fatalf("update comment: %v", err)
+		}
+		comment, err := db.DBService.GetCommentByID(2)
+		if err != nil {
+			t.Fatalf("get comment: %v", err)
+		}
+		if !comment.Edited || comment.RevisionCount != 1 {
+			t.Errorf("expected an edited comment with 1 revision, got %v and %d", comment.Edited, comment.RevisionCount)
+		}
+
+		history := getRevisions(t, "comment_id=2", 2, http.StatusOK)
+		if len(history.Revisions) != 1 || history.Revisions[0].Body != "Thanks" || history.Revisions[0].Title != nil {
+			t.Errorf("expected the original comment, got %+v", history.Revisions)
+		}
+		getRevisions(t, "comment_id=2", 6, http.StatusForbidden)
+		getRevisions(t, "post_id=2", 6, http.StatusForbidden)
+		getRevisions(t, "comment_id=9", 2, http.StatusNotFound)
+	})
+
+	t.Run("a moderator edit of a group post records the moderator", func(t *testing.T) {
+		setupContentRevisionsTestDB(t)
+		body := "Sunday"
+		if err := db.DBService.UpdateGroupPost(models.UpdateGroupPostRequest{ID: 1, Body: &body}, 3); err != nil {
+			t.Fatalf("update group post: %v", err)
+		}
+
+		post, err := db.DBService.GetGroupPostWithImagesByID(1, 4)
+		if err != nil {
+			t.Fatalf("get group post: %v", err)
+		}
+		if !post.Edited || post.RevisionCount != 1 {
+			t.Errorf("expected an edited group post with 1 revision, got %v and %d", post.Edited, post.RevisionCount)
+		}
+		history := getRevisions(t, "group_post_id=1", 4, http.StatusOK)
+		if len(history.Revisions) != 1 || history.Revisions[0].EditedBy != 3 || history.Revisions[0].Body != "Saturday" {
+			t.Errorf("expected the moderator to be recorded, got %+v", history.Revisions)
+		}
+		getRevisions(t, "group_post_id=1", 6, http.StatusForbidden)
+	})
+
+	t.Run("one revision of a group comment can be viewed", func(t *testing.T) {
+		setupContentRevisionsTestDB(t)
+		for _, body := range []string{"See you there", "See you at noon"} {
+			if err := db.DBService.UpdateGroupComment(1, 4, models.UpdateGroupCommentRequest{ID: 1, Body: &body}); err != nil {
+				t.Fatalf("update group comment: %v", err)
+			}
+		}
+
+		rr := callGroupHandler(api.GetContentRevisionsHandler, http.MethodGet, "/api/revisions?group_comment_id=1&revision=2", nil, 2)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var revision models.ContentRevision
+		json.NewDecoder(rr.Body).Decode(&revision)
+		if revision.Revision != 2 || revision.Body != "See you there" {
+			t.Errorf("expected the second version, got %+v", revision)
+		}
+
+		for query, want := range map[string]int{
+			"group_comment_id=1&revision=3": http.StatusNotFound,
+			"group_comment_id=1&revision=x": http.StatusBadRequest,
+		} {
+			if rr := callGroupHandler(api.GetContentRevisionsHandler, http.MethodGet, "/api/revisions?"+query, nil, 2); rr.Code != want {
+				t.Errorf("%s: expected %d, got %d", query, want, rr.Code)
+			}
+		}
+		getRevisions(t, "group_comment_id=1", 6, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/middleware"
+)
+
+func newCSRFHandler() http.Handler {
+	return middleware.CSRFMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	middleware.SetCSRFSecret("test-csrf-secret", time.Hour)
+	handler := newCSRFHandler()
+	session := &http.Cookie{Name: "jwt_token", Value: "session-a"}
+
+	// fetchToken performs a GET like the client does on startup
+	fetchToken := func(cookies ...*http.Cookie) (string, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodGet, "/api/post", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Header().Get("X-CSRF-Token"), rr
+	}
+
+	post := func(token string, cookies ...*http.Cookie) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/post", nil)
+		if token != "" {
+			req.Header.Set("X-CSRF-Token", token)
+		}
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	token, rr := fetchToken(session)
+	if token == "" {
+		t.Fatal("expected a CSRF token for a cookie session")
+	}
+	if len(rr.Result().Cookies()) != 1 || rr.Result().Cookies()[0].Value != token {
+		t.Fatal("expected the token to be set as csrf_token cookie")
+	}
+	csrfCookie := &http.Cookie{Name: "csrf_token", Value: token}
+
+	t.Run("reuses a valid token on later GETs", func(t *testing.T) {
+		again, rr := fetchToken(session, csrfCookie)
+		if again != token {
+			t.Error("expected the same token to be echoed back")
+		}
+		if len(rr.Result().Cookies()) != 0 {
+			t.Error("no new cookie should be minted while the token is valid")
+		}
+	})
+
+	t.Run("accepts matching header and cookie", func(t *testing.T) {
+		if code := post(token, session, csrfCookie); code != http.StatusOK {
+			t.Errorf("expected 200, got %d", code)
+		}
+	})
+
+	t.Run("rejects a missing or mismatched token", func(t *testing.T) {
+		if code := post("", session, csrfCookie); code != http.StatusForbidden {
+			t.Errorf("missing header: expected 403, got %d", code)
+		}
+		if code := post(token, session); code != http.StatusForbidden {
+			t.Errorf("missing cookie: expected 403, got %d", code)
+		}
+		other, _ := middleware.GenerateCSRFToken("session-a")
+		if code := post(other, session, csrfCookie); code != http.StatusForbidden {
+			t.Errorf("mismatched cookie: expected 403, got %d", code)
+		}
+	})
+
+	t.Run("rejects a token of another session", func(t *testing.T) {
+		otherSession := &http.Cookie{Name: "jwt_token", Value: "session-b"}
+		if code := post(token, otherSession, csrfCookie); code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", code)
+		}
+	})
+
+	t.Run("rejects a tampered token", func(t *testing.T) {
+		tampered := token[:len(token)-2] + "xx"
+		if code := post(tampered, session, &http.Cookie{Name: "csrf_token", Value: tampered}); code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", code)
+		}
+	})
+
+	t.Run("rejects an expired token", func(t *testing.T) {
+		middleware.SetCSRFSecret("", time.Nanosecond)
+		expired, _ := middleware.GenerateCSRFToken("session-a")
+		middleware.SetCSRFSecret("", time.Hour)
+		time.Sleep(1100 * time.Millisecond)
+
+		if err := middleware.ValidateCSRFToken(expired, "session-a"); err == nil {
+			t.Error("expected the token to be expired")
+		}
+	})
+
+	t.Run("exempts bearer clients only", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/post", nil)
+		req.Header.Set("Authorization", "Bearer some.jwt.token")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected bearer request to pass, got %d", rr.Code)
+		}
+
+		// A valid session cookie alone is not enough anymore
+		if code := post("", session); code != http.StatusForbidden {
+			t.Errorf("expected cookie-only request to be refused, got %d", code)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package tests
 import (
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/Golden76z/social-network/db"
 	"github.com/Golden76z/social-network/models"
@@ -10,148 +11,7 @@ import (
 )
 
 func setupDBTest(t *testing.T) *sql.DB {
-	db, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("failed to open db: %v", err)
-	}
-
-	_, err = db.Exec(`
-        CREATE TABLE users (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            nickname VARCHAR(30) UNIQUE NOT NULL,
-            first_name VARCHAR(50) NOT NULL,
-            last_name VARCHAR(50) NOT NULL,
-            email VARCHAR(70) UNIQUE NOT NULL,
-            password VARCHAR(255) NOT NULL,
-            date_of_birth DATE NOT NULL,
-            avatar VARCHAR(255),
-            bio TEXT,
-            is_private BOOLEAN DEFAULT FALSE NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            followers INT DEFAULT 0,
-            followed INT DEFAULT 0
-        );
-		CREATE TABLE sessions (
-			token TEXT PRIMARY KEY,
-			user_id INTEGER NOT NULL,
-			expires_at DATETIME NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		);
-
-		CREATE INDEX idx_sessions_token ON sessions(token);
-		CREATE INDEX idx_sessions_expires ON sessions(expires_at);
-		
-        CREATE TABLE posts (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            user_id INTEGER NOT NULL,
-            title VARCHAR(255),
-            body TEXT,
-            image VARCHAR(255),
-            visibility VARCHAR(20),
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE post_visibility (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            post_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL
-        );
-        CREATE TABLE notifications (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            user_id INTEGER NOT NULL,
-            type VARCHAR(32) NOT NULL,
-            data TEXT,
-            is_read BOOLEAN DEFAULT FALSE,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE likes_dislikes (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            user_id INTEGER NOT NULL,
-            post_id INTEGER,
-            comment_id INTEGER,
-            group_post_id INTEGER,
-            group_comment_id INTEGER,
-            type VARCHAR(7) NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE groups (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            title VARCHAR(30) NOT NULL,
-            avatar VARCHAR(255),
-            bio TEXT,
-            creator_id INTEGER NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE group_members (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            group_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL,
-            role VARCHAR(6) NOT NULL,
-            status VARCHAR(8) NOT NULL,
-            invited_by INTEGER,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE group_posts (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            group_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL,
-            title VARCHAR(255),
-            body TEXT,
-            image VARCHAR(255),
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE group_requests (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            group_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL,
-            status VARCHAR(8) NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE group_messages (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            group_id INTEGER NOT NULL,
-            sender_id INTEGER NOT NULL,
-            body TEXT,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE private_messages (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            sender_id INTEGER NOT NULL,
-            receiver_id INTEGER NOT NULL,
-            body TEXT,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE follow_requests (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            requester_id INTEGER NOT NULL,
-            target_id INTEGER NOT NULL,
-            status VARCHAR(8) NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE event_rsvps (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            event_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL,
-            status VARCHAR(14) NOT NULL,
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-        CREATE TABLE group_comments (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            group_post_id INTEGER NOT NULL,
-            user_id INTEGER NOT NULL,
-            body TEXT,
-            image VARCHAR(255),
-            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-        );
-    `)
-	if err != nil {
-		t.Fatalf("failed to create tables: %v", err)
-	}
-	return db
+	return newTestDB(t)
 }
 
 // Helper function to get a service instance
@@ -408,7 +268,7 @@ func TestGroupCRUD(t *testing.T) {
 		t.Fatalf("UpdateGroup failed: %v", err)
 	}
 
-	err = getService(dbConn).DeleteGroup(group.ID)
+	_, err = getService(dbConn).DeleteGroup(group.ID, time.Hour)
 	if err != nil {
 		t.Fatalf("DeleteGroup failed: %v", err)
 	}
@@ -425,10 +285,11 @@ func TestGroupMemberCRUD(t *testing.T) {
 		Bio:    "bio",
 	}
 	_ = getService(dbConn).CreateGroup(groupReq, user.ID)
+	member := createDBTestUser(t, dbConn, "member", "Jane", "Doe", "jane@doe.com")
 
 	req := models.GroupMember{
 		GroupID:   1,
-		UserID:    user.ID,
+		UserID:    member.ID,
 		Role:      "member",
 		InvitedBy: nil,
 	}
@@ -438,8 +299,8 @@ func TestGroupMemberCRUD(t *testing.T) {
 		t.Fatalf("CreateGroupMember failed: %v", err)
 	}
 
-	gm, err := getService(dbConn).GetGroupMemberByID(1)
-	if err != nil || gm.UserID != user.ID {
+	gm, err := getService(dbConn).GetGroupMemberByID(2)
+	if err != nil || gm.UserID != member.ID {
 		t.Fatalf("GetGroupMemberByID failed: %v", err)
 	}
 
@@ -448,12 +309,18 @@ func TestGroupMemberCRUD(t *testing.T) {
 	// 	t.Fatalf("UpdateGroupMemberStatus failed: %v", err)
 	// }
 
+	// The owner hands the group over before leaving
+	err = getService(dbConn).DeleteGroupMember(models.LeaveGroupRequest{GroupID: 1, UserID: user.ID}, int(user.ID))
+	if err == nil {
+		t.Fatal("expected the owner not to leave the group")
+	}
+
 	leaveReq := models.LeaveGroupRequest{
 		GroupID: 1,
-		UserID:  gm.ID,
+		UserID:  gm.UserID,
 	}
 
-	err = getService(dbConn).DeleteGroupMember(leaveReq, int(gm.ID))
+	err = getService(dbConn).DeleteGroupMember(leaveReq, int(gm.UserID))
 	if err != nil {
 		t.Fatalf("DeleteGroupMember failed: %v", err)
 	}
@@ -477,7 +344,7 @@ func TestGroupPostCRUD(t *testing.T) {
 		Body:    "Body",
 	}
 
-	err := getService(dbConn).CreateGroupPost(postReq, user.ID)
+	_, err := getService(dbConn).CreateGroupPost(postReq, user.ID)
 	if err != nil {
 		t.Fatalf("CreateGroupPost failed: %v", err)
 	}
@@ -622,19 +489,7 @@ func TestEventRSVPCRUD(t *testing.T) {
 	defer dbConn.Close()
 
 	// Minimal event_id for test
-	_, err := dbConn.Exec(`CREATE TABLE group_events (
-        id INTEGER PRIMARY KEY AUTOINCREMENT,
-        group_id INTEGER NOT NULL,
-        creator_id INTEGER NOT NULL,
-        title VARCHAR(255) NOT NULL,
-        description TEXT,
-        event_datetime TIMESTAMP NOT NULL,
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-    );`)
-	if err != nil {
-		t.Fatalf("failed to create group_events table: %v", err)
-	}
-	_, err = dbConn.Exec(`INSERT INTO group_events (group_id, creator_id, title, description, event_datetime) VALUES (1, 1, 'Event', 'Desc', '2025-01-01 10:00:00')`)
+	_, err := dbConn.Exec(`INSERT INTO group_events (group_id, creator_id, title, description, event_datetime) VALUES (1, 1, 'Event', 'Desc', '2025-01-01 10:00:00')`)
 	if err != nil {
 		t.Fatalf("failed to insert group_event: %v", err)
 	}
@@ -647,7 +502,7 @@ func TestEventRSVPCRUD(t *testing.T) {
 		Status:  "come",
 	}
 
-	err = getService(dbConn).CreateEventRSVP(rsvpReq)
+	_, err = getService(dbConn).CreateEventRSVP(rsvpReq)
 	if err != nil {
 		t.Fatalf("CreateEventRSVP failed: %v", err)
 	}
@@ -657,12 +512,12 @@ func TestEventRSVPCRUD(t *testing.T) {
 		t.Fatalf("GetEventRSVPByID failed: %v", err)
 	}
 
-	err = getService(dbConn).UpdateEventRSVPStatus(rsvp.ID, "not_come")
+	_, _, err = getService(dbConn).UpdateEventRSVPStatus(rsvp.ID, "not_come", 0)
 	if err != nil {
 		t.Fatalf("UpdateEventRSVPStatus failed: %v", err)
 	}
 
-	err = getService(dbConn).DeleteEventRSVP(rsvp.ID)
+	_, err = getService(dbConn).DeleteEventRSVP(rsvp.ID)
 	if err != nil {
 		t.Fatalf("DeleteEventRSVP failed: %v", err)
 	}
@@ -685,7 +540,7 @@ func TestGroupCommentCRUD(t *testing.T) {
 		Title:   "Title",
 		Body:    "Body",
 	}
-	_ = getService(dbConn).CreateGroupPost(postReq, user.ID)
+	_, _ = getService(dbConn).CreateGroupPost(postReq, user.ID)
 
 	commentReq := models.CreateGroupCommentRequest{
 		GroupPostID: 1,
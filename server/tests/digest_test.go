@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/notifications"
+	"github.com/Golden76z/social-network/utils"
+)
+
+func setupDigestTestDB(t *testing.T) {
+	useTestDB(t, testUsers("alice", "bob"))
+}
+
+// readOutbox returns the emails written by the file mailer
+func readOutbox(t *testing.T, dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	var mails []string
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("Failed to read mail: %v", err)
+		}
+		// Undo quoted-printable soft line breaks to search the body
+		mails = append(mails, strings.ReplaceAll(string(content), "=\r\n", ""))
+	}
+	return mails
+}
+
+func TestNotificationDigest(t *testing.T) {
+	setupDigestTestDB(t)
+	outbox := t.TempDir()
+	opts := notifications.DigestOptions{
+		Mailer:           &utils.FileMailer{Dir: outbox, From: "no-reply@example.com"},
+		UnsubscribeURL:   "http://localhost:8080/notifications/unsubscribe",
+		NotificationsURL: "http://localhost:3000/notifications",
+		DefaultFrequency: notifications.DigestDaily,
+		MaxItems:         10,
+	}
+
+	notifications.Send(1, &notifications.FollowRequest{RequesterID: 2, RequesterNickname: "<b>bob</b>"})
+	notifications.Send(1, notifications.NewPostLike(5, notifications.Actor{ID: 2, Nickname: "bob"}))
+
+	now := time.Now()
+	sent, err := notifications.SendDigests(context.Background(), opts, now)
+	if err != nil {
+		t.Fatalf("SendDigests failed: %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("expected one digest (bob has no notifications), got %d", sent)
+	}
+
+	mails := readOutbox(t, outbox)
+	if len(mails) != 1 {
+		t.Fatalf("expected one email in the outbox, got %d", len(mails))
+	}
+	mail := mails[0]
+	if !strings.Contains(mail, "To: alice@example.com") || !strings.Contains(mail, "Subject: You have 2 unread notifications") {
+		t.Errorf("unexpected headers:\n%s", mail)
+	}
+	if !strings.Contains(mail, "bob liked your post") {
+		t.Error("expected the digest to list the notifications")
+	}
+	htmlPart := mail[strings.Index(mail, "Content-Type: text/html"):]
+	if strings.Contains(htmlPart, "<b>bob</b> wants") || !strings.Contains(htmlPart, "&lt;b&gt;bob&lt;/b&gt; wants") {
+		t.Error("expected user content to be escaped in the HTML part")
+	}
+	if !strings.Contains(mail, "List-Unsubscribe-Post: List-Unsubscribe=One-Click") {
+		t.Error("expected one-click unsubscribe headers")
+	}
+
+	t.Run("does not send again before the next period", func(t *testing.T) {
+		notifications.Send(1, &notifications.FollowAccepted{TargetID: 2, TargetNickname: "bob"})
+		if sent, _ := notifications.SendDigests(context.Background(), opts, now.Add(time.Hour)); sent != 0 {
+			t.Errorf("expected no digest within the day, sent %d", sent)
+		}
+	})
+
+	t.Run("unsubscribe link turns the digest off", func(t *testing.T) {
+		token := regexp.MustCompile(`unsubscribe\?token=([A-Za-z0-9_-]+)`).FindStringSubmatch(mail)
+		if token == nil {
+			t.Fatal("no unsubscribe link in the email")
+		}
+
+		// Opening the link only asks to confirm, so a link scanner cannot unsubscribe
+		rr := httptest.NewRecorder()
+		api.UnsubscribeDigestHandler(rr, httptest.NewRequest(http.MethodGet, "/notifications/unsubscribe?token="+token[1], nil))
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `<form method="POST" action="/notifications/unsubscribe?token=`+token[1]+`"`) {
+			t.Fatalf("expected a confirmation form, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var frequency string
+		db.DBService.DB.QueryRow(`SELECT frequency FROM notification_digests WHERE unsubscribe_token = ?`, token[1]).Scan(&frequency)
+		if frequency == notifications.DigestOff {
+			t.Fatal("expected the digest to stay on until confirmed")
+		}
+
+		rr = httptest.NewRecorder()
+		api.UnsubscribeDigestHandler(rr, httptest.NewRequest(http.MethodPost, "/notifications/unsubscribe?token="+token[1], nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		if sent, _ := notifications.SendDigests(context.Background(), opts, now.Add(48*time.Hour)); sent != 0 {
+			t.Errorf("expected no digest after unsubscribing, sent %d", sent)
+		}
+
+		rr = httptest.NewRecorder()
+		api.UnsubscribeDigestHandler(rr, httptest.NewRequest(http.MethodGet, "/notifications/unsubscribe?token=unknown", nil))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for an unknown token, got %d", rr.Code)
+		}
+	})
+
+	t.Run("weekly digests wait seven days", func(t *testing.T) {
+		db.DBService.SetDigestFrequency(2, notifications.DigestWeekly, "bob-token")
+		// A minute earlier so the new notification is strictly after it
+		db.DBService.MarkDigestSent(2, now.Add(-time.Minute))
+		notifications.Send(2, &notifications.FollowRequest{RequesterID: 1, RequesterNickname: "alice"})
+
+		if sent, _ := notifications.SendDigests(context.Background(), opts, now.Add(3*24*time.Hour)); sent != 0 {
+			t.Errorf("expected no weekly digest after three days, sent %d", sent)
+		}
+		if sent, _ := notifications.SendDigests(context.Background(), opts, now.Add(7*24*time.Hour)); sent != 1 {
+			t.Errorf("expected the weekly digest after seven days, sent %d", sent)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+func insertRemindedEvent(t *testing.T, start, rrule string) int64 {
+	t.Helper()
+	res, err := db.DBService.DB.Exec(`
+		INSERT INTO group_events (group_id, creator_id, title, description, event_datetime, location, rrule)
+		VALUES (1, 1, 'Meetup', 'Desc', ?, 'Town hall', ?)`, start, rrule)
+	if err != nil {
+		t.Fatalf("Failed to insert event: %v", err)
+	}
+	eventID, _ := res.LastInsertId()
+	// Member 2 declined and member 5 waits for a seat, neither is reminded
+	_, err = db.DBService.DB.Exec(`
+		INSERT INTO event_rsvps (event_id, user_id, status, waitlisted_at) VALUES
+			(?, 2, 'not_come', NULL), (?, 3, 'interested', NULL), (?, 4, 'come', NULL), (?, 5, 'come', CURRENT_TIMESTAMP)`,
+		eventID, eventID, eventID, eventID)
+	if err != nil {
+		t.Fatalf("Failed to insert RSVPs: %v", err)
+	}
+	return eventID
+}
+
+func sendReminders(t *testing.T, now time.Time, want int) {
+	t.Helper()
+	sent, err := notifications.SendEventReminders([]time.Duration{24 * time.Hour, time.Hour}, now)
+	if err != nil {
+		t.Fatalf("SendEventReminders failed: %v", err)
+	}
+	if sent != want {
+		t.Fatalf("at %s: expected %d reminders, got %d", now.Format(time.RFC3339), want, sent)
+	}
+}
+
+func lastReminderMessage(t *testing.T, userID int64) string {
+	t.Helper()
+	var data string
+	err := db.DBService.DB.QueryRow(`SELECT data FROM notifications WHERE user_id = ? AND type = ? ORDER BY id DESC LIMIT 1`,
+		userID, notifications.TypeEventReminder).Scan(&data)
+	if err != nil {
+		t.Fatalf("Failed to read the reminder: %v", err)
+	}
+	return data
+}
+
+func TestEventReminders(t *testing.T) {
+	setupGroupRolesTestDB(t)
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	eventID := insertRemindedEvent(t, "2030-01-02 11:30:00", "")
+	sendReminders(t, now, 2)
+	if countNotifications(t, 4, notifications.TypeEventReminder) != 1 || countNotifications(t, 3, notifications.TypeEventReminder) != 1 {
+		t.Fatal("expected the attendee and the interested member to be reminded")
+	}
+	if countNotifications(t, 2, notifications.TypeEventReminder) != 0 || countNotifications(t, 5, notifications.TypeEventReminder) != 0 {
+		t.Fatal("expected no reminder for a declined or waitlisted RSVP")
+	}
+	if msg := lastReminderMessage(t, 4); !strings.Contains(msg, "starts in 24 hours at Town hall") {
+		t.Fatalf("unexpected reminder: %s", msg)
+	}
+
+	// Sent reminders are stored, running again or after a restart sends nothing
+	sendReminders(t, now.Add(time.Minute), 0)
+	sendReminders(t, now.Add(22*time.Hour), 0)
+
+	sendReminders(t, now.Add(23*time.Hour), 2)
+	if msg := lastReminderMessage(t, 4); !strings.Contains(msg, "starts in 30 minutes") {
+		t.Fatalf("unexpected reminder: %s", msg)
+	}
+	sendReminders(t, now.Add(23*time.Hour+10*time.Minute), 0)
+
+	// A rescheduled event is reminded of its new time
+	moved := "2030-01-02 14:00:00"
+	if _, err := db.DBService.UpdateGroupEvent(eventID, models.UpdateGroupEventRequest{EventDateTime: &moved}, 1, time.Hour); err != nil {
+		t.Fatalf("UpdateGroupEvent failed: %v", err)
+	}
+	sendReminders(t, now.Add(23*time.Hour+10*time.Minute), 2)
+	sendReminders(t, now.Add(25*time.Hour), 2)
+	if countNotifications(t, 4, notifications.TypeEventReminder) != 4 {
+		t.Fatalf("expected 4 reminders for the attendee, got %d", countNotifications(t, 4, notifications.TypeEventReminder))
+	}
+
+	// Deleted events are not reminded
+	if err := db.DBService.DeleteGroupEvent(eventID); err != nil {
+		t.Fatalf("DeleteGroupEvent failed: %v", err)
+	}
+	sendReminders(t, now.Add(25*time.Hour+30*time.Minute), 0)
+}
+
+func TestEventRemindersCatchUp(t *testing.T) {
+	setupGroupRolesTestDB(t)
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// The server was down for both reminders, only the closest one is sent late
+	insertRemindedEvent(t, "2030-01-01 12:20:00", "")
+	sendReminders(t, now, 2)
+	if msg := lastReminderMessage(t, 3); !strings.Contains(msg, "starts in 20 minutes") {
+		t.Fatalf("unexpected reminder: %s", msg)
+	}
+	sendReminders(t, now.Add(10*time.Minute), 0)
+
+	// Each occurrence of a recurring event is reminded, cancelled ones are not
+	seriesID := insertRemindedEvent(t, "2029-12-01 18:00:00", "FREQ=DAILY")
+	err := db.DBService.SetGroupEventException(models.GroupEventExceptionRequest{
+		EventID: seriesID, Occurrence: "2030-01-02 18:00:00", Cancelled: true,
+	})
+	if err != nil {
+		t.Fatalf("SetGroupEventException failed: %v", err)
+	}
+	sendReminders(t, now.Add(time.Hour), 2)
+	sendReminders(t, now.Add(5*time.Hour+30*time.Minute), 2)
+	sendReminders(t, now.Add(18*time.Hour+30*time.Minute), 0)
+	sendReminders(t, now.Add(30*time.Hour+30*time.Minute), 2)
+	sendReminders(t, now.Add(53*time.Hour+30*time.Minute), 2)
+	if countNotifications(t, 4, notifications.TypeEventReminder) != 5 {
+		t.Fatalf("expected 5 reminders for the attendee, got %d", countNotifications(t, 4, notifications.TypeEventReminder))
+	}
+}
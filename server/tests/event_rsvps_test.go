@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+func rsvpCall(t *testing.T, handler http.HandlerFunc, method string, body map[string]any, userID int, want int) map[string]any {
+	t.Helper()
+	return *decodeCall[map[string]any](t, handler, method, "/api/group/event/rsvp", body, userID, want)
+}
+
+func waitlistOf(t *testing.T, eventID int64) []int64 {
+	t.Helper()
+	rsvps, err := db.DBService.GetEventRSVPs(eventID, "waitlisted", 50, 0)
+	if err != nil {
+		t.Fatalf("GetEventRSVPs failed: %v", err)
+	}
+	ids := []int64{}
+	for _, r := range rsvps {
+		ids = append(ids, r.UserID)
+	}
+	return ids
+}
+
+func TestEventCapacityAndWaitlist(t *testing.T) {
+	setupGroupRolesTestDB(t)
+
+	res, err := db.DBService.DB.Exec(`
+		INSERT INTO group_events (group_id, creator_id, title, description, event_datetime, capacity, max_guests)
+		VALUES (1, 1, 'Dinner', 'Desc', '2099-06-01 19:00:00', 3, 1)`)
+	if err != nil {
+		t.Fatalf("Failed to insert event: %v", err)
+	}
+	eventID, _ := res.LastInsertId()
+
+	// 2 of 3 seats taken
+	resp := rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": eventID, "status": "come", "guests": 1}, 1, http.StatusCreated)
+	if resp["waitlisted"] != false {
+		t.Fatalf("expected the first RSVP to be confirmed, got %v", resp)
+	}
+	rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": eventID, "status": "come", "guests": 2}, 4, http.StatusBadRequest)
+	rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": eventID, "status": "interested", "guests": 1}, 4, http.StatusBadRequest)
+
+	// Needs 2 seats, 1 is left
+	resp = rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": eventID, "status": "come", "guests": 1}, 2, http.StatusCreated)
+	if resp["waitlisted"] != true {
+		t.Fatalf("expected user 2 to be waitlisted, got %v", resp)
+	}
+	// Would fit but someone is already waiting
+	resp = rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": eventID, "status": "come"}, 3, http.StatusCreated)
+	if resp["waitlisted"] != true {
+		t.Fatalf("expected user 3 to queue behind user 2, got %v", resp)
+	}
+	if got := waitlistOf(t, eventID); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Fatalf("expected waitlist [2 3], got %v", got)
+	}
+
+	event, err := db.DBService.GetGroupEventByID(eventID)
+	if err != nil {
+		t.Fatalf("GetGroupEventByID failed: %v", err)
+	}
+	if event.Capacity != 3 || event.MaxGuests != 1 || event.Attending != 2 || event.Waitlisted != 2 {
+		t.Fatalf("unexpected counts: capacity %d, max guests %d, attending %d, waitlisted %d",
+			event.Capacity, event.MaxGuests, event.Attending, event.Waitlisted)
+	}
+
+	// Dropping the guest frees a seat, enough for user 2 and the guest
+	rsvpCall(t, api.UpdateEventRSVPHandler, http.MethodPut, map[string]any{"event_id": eventID, "status": "come", "guests": 0}, 1, http.StatusOK)
+	if got := waitlistOf(t, eventID); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("expected user 2 promoted, waitlist %v", got)
+	}
+	if countNotifications(t, 2, notifications.TypeEventWaitlist) != 1 {
+		t.Fatal("expected user 2 to be notified of the promotion")
+	}
+	if countNotifications(t, 3, notifications.TypeEventWaitlist) != 0 {
+		t.Fatal("expected user 3 to still be waiting")
+	}
+
+	// A confirmed attendee cannot take a seat away from the waitlist
+	rsvpCall(t, api.UpdateEventRSVPHandler, http.MethodPut, map[string]any{"event_id": eventID, "status": "come", "guests": 1}, 1, http.StatusConflict)
+
+	// Leaving promotes the next in line
+	rsvpCall(t, api.DeleteEventRSVPHandler, http.MethodDelete, map[string]any{"event_id": eventID}, 2, http.StatusOK)
+	if got := waitlistOf(t, eventID); len(got) != 0 {
+		t.Fatalf("expected user 3 promoted, waitlist %v", got)
+	}
+	if countNotifications(t, 3, notifications.TypeEventWaitlist) != 1 {
+		t.Fatal("expected user 3 to be notified of the promotion")
+	}
+	rsvpCall(t, api.UpdateEventRSVPHandler, http.MethodPut, map[string]any{"event_id": eventID, "status": "come", "guests": 1}, 1, http.StatusOK)
+	resp = rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": eventID, "status": "come"}, 4, http.StatusCreated)
+	if resp["waitlisted"] != true {
+		t.Fatalf("expected user 4 to be waitlisted on a full event, got %v", resp)
+	}
+
+	// Raising the capacity promotes the waitlist
+	rr := callGroupHandler(api.UpdateGroupEventHandler, http.MethodPut, "/api/group/event", map[string]any{"id": eventID, "capacity": 4}, 1)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 raising the capacity, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := waitlistOf(t, eventID); len(got) != 0 {
+		t.Fatalf("expected user 4 promoted, waitlist %v", got)
+	}
+	if countNotifications(t, 4, notifications.TypeEventWaitlist) != 1 {
+		t.Fatal("expected user 4 to be notified of the promotion")
+	}
+	rsvp, err := db.DBService.GetEventRSVPByUserAndEvent(eventID, 1)
+	if err != nil || rsvp.Guests != 1 || rsvp.Waitlisted {
+		t.Fatalf("expected user 1 confirmed with a guest, got %+v (%v)", rsvp, err)
+	}
+}
+
+func TestEventRSVPDeadline(t *testing.T) {
+	setupGroupRolesTestDB(t)
+
+	rr := callGroupHandler(api.CreateGroupEventHandler, http.MethodPost, "/api/group/event", map[string]any{
+		"group_id": 1, "title": "Trip", "description": "Desc",
+		"event_date_time": "2099-06-01 09:00:00", "rsvp_deadline": "2099-06-02 09:00:00",
+	}, 1)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a deadline after the start, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = callGroupHandler(api.CreateGroupEventHandler, http.MethodPost, "/api/group/event", map[string]any{
+		"group_id": 1, "title": "Trip", "description": "Desc", "timezone": "Europe/Paris",
+		"event_date_time": "2099-06-01 09:00:00", "rsvp_deadline": "2099-05-25T12:00:00Z",
+	}, 1)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating the event, got %d: %s", rr.Code, rr.Body.String())
+	}
+	event, err := db.DBService.GetGroupEventByID(1)
+	if err != nil {
+		t.Fatalf("GetGroupEventByID failed: %v", err)
+	}
+	if event.RSVPDeadline != "2099-05-25T14:00:00+02:00" {
+		t.Fatalf("expected the deadline in the event timezone, got %q", event.RSVPDeadline)
+	}
+
+	rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": 1, "status": "interested"}, 4, http.StatusCreated)
+
+	// Once the deadline passed every change is locked
+	if _, err := db.DBService.DB.Exec(`UPDATE group_events SET rsvp_deadline = '2000-01-01 00:00:00' WHERE id = 1`); err != nil {
+		t.Fatalf("Failed to move the deadline: %v", err)
+	}
+	rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": 1, "status": "come"}, 3, http.StatusConflict)
+	rsvpCall(t, api.UpdateEventRSVPHandler, http.MethodPut, map[string]any{"event_id": 1, "status": "come"}, 4, http.StatusConflict)
+	rsvpCall(t, api.DeleteEventRSVPHandler, http.MethodDelete, map[string]any{"event_id": 1}, 4, http.StatusConflict)
+
+	rsvp, err := db.DBService.GetEventRSVPByUserAndEvent(1, 4)
+	if err != nil || rsvp.Status != "interested" {
+		t.Fatalf("expected the RSVP to be unchanged, got %+v (%v)", rsvp, err)
+	}
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+func updateEvent(t *testing.T, body map[string]any, userID int, want int) map[string]any {
+	t.Helper()
+	return *decodeCall[map[string]any](t, api.UpdateGroupEventHandler, http.MethodPut, "/api/group/event", body, userID, want)
+}
+
+func rsvpNeedsConfirmation(t *testing.T, eventID, userID int64) bool {
+	t.Helper()
+	rsvp, err := db.DBService.GetEventRSVPByUserAndEvent(eventID, userID)
+	if err != nil {
+		t.Fatalf("GetEventRSVPByUserAndEvent failed: %v", err)
+	}
+	return rsvp.NeedsConfirmation
+}
+
+func TestGroupEventChanges(t *testing.T) {
+	setupGroupRolesTestDB(t)
+
+	// Member 4 created the event, the owner comes, the moderator is interested and the restricted member declined
+	_, err := db.DBService.DB.Exec(`
+		INSERT INTO group_events (id, group_id, creator_id, title, description, event_datetime)
+		VALUES (1, 1, 4, 'Picnic', 'In the park', '2099-06-01 12:00:00');
+		INSERT INTO event_rsvps (event_id, user_id, status) VALUES (1, 1, 'come'), (1, 3, 'interested'), (1, 5, 'not_come');`)
+	if err != nil {
+		t.Fatalf("Failed to insert event: %v", err)
+	}
+
+	// Setting a field to its current value changes nothing
+	resp := updateEvent(t, map[string]any{"id": 1, "title": "Picnic"}, 4, http.StatusOK)
+	if changes := resp["changes"].([]any); len(changes) != 0 {
+		t.Fatalf("expected no change, got %v", changes)
+	}
+	if countNotifications(t, 1, notifications.TypeEventChanged) != 0 {
+		t.Fatal("expected no notification without a change")
+	}
+
+	resp = updateEvent(t, map[string]any{"id": 1, "description": "By the lake", "event_date_time": "2099-06-01 12:30:00"}, 4, http.StatusOK)
+	if changes := resp["changes"].([]any); len(changes) != 2 || resp["rsvps_reset"] != false {
+		t.Fatalf("expected 2 changes without reset, got %v", resp)
+	}
+	for _, userID := range []int64{1, 3, 5} {
+		if countNotifications(t, userID, notifications.TypeEventChanged) != 1 {
+			t.Fatalf("expected user %d to be told about the change", userID)
+		}
+	}
+	if countNotifications(t, 4, notifications.TypeEventChanged) != 0 {
+		t.Fatal("expected the creator not to be notified of their own change")
+	}
+	if rsvpNeedsConfirmation(t, 1, 1) {
+		t.Fatal("expected a small move to keep the RSVPs")
+	}
+
+	// Moving by more than the threshold asks the attendees to confirm again
+	resp = updateEvent(t, map[string]any{"id": 1, "event_date_time": "2099-06-02 12:30:00"}, 4, http.StatusOK)
+	if resp["rsvps_reset"] != true {
+		t.Fatalf("expected the RSVPs to be reset, got %v", resp)
+	}
+	if !rsvpNeedsConfirmation(t, 1, 1) || !rsvpNeedsConfirmation(t, 1, 3) || rsvpNeedsConfirmation(t, 1, 5) {
+		t.Fatal("expected the come and interested RSVPs to need confirmation")
+	}
+	if msg := lastNotificationData(t, 1, notifications.TypeEventChanged); !strings.Contains(msg, "please confirm your RSVP") ||
+		!strings.Contains(msg, `"old":"2099-06-01 12:30:00"`) {
+		t.Fatalf("unexpected notification: %s", msg)
+	}
+	rsvpCall(t, api.UpdateEventRSVPHandler, http.MethodPut, map[string]any{"event_id": 1, "status": "come"}, 1, http.StatusOK)
+	if rsvpNeedsConfirmation(t, 1, 1) {
+		t.Fatal("expected the same answer to confirm the RSVP")
+	}
+	rsvpCall(t, api.UpdateEventRSVPHandler, http.MethodPut, map[string]any{"event_id": 1, "status": "come"}, 1, http.StatusBadRequest)
+
+	rr := callGroupHandler(api.GetGroupEventChangesHandler, http.MethodGet, "/api/group/event/changes?event_id=1", nil, 5)
+	var changes []db.GroupEventChange
+	json.NewDecoder(rr.Body).Decode(&changes)
+	if rr.Code != http.StatusOK || len(changes) != 3 {
+		t.Fatalf("expected 3 logged changes, got %d: %v", rr.Code, changes)
+	}
+	if changes[0].Field != "event_datetime" || changes[0].NewValue != "2099-06-02 12:30:00" || changes[0].ChangedBy != 4 {
+		t.Fatalf("expected the latest change first, got %+v", changes[0])
+	}
+
+	// The move and the reset are saved together or not at all
+	if _, err := db.DBService.DB.Exec(`
+		CREATE TEMP TRIGGER failing_reset BEFORE UPDATE OF needs_confirmation ON event_rsvps
+		BEGIN SELECT RAISE(ABORT, 'reset failed'); END;`); err != nil {
+		t.Fatal(err)
+	}
+	updateEvent(t, map[string]any{"id": 1, "event_date_time": "2099-06-05 12:30:00"}, 4, http.StatusInternalServerError)
+	if event, _ := db.DBService.GetGroupEventByID(1); event == nil || !strings.HasPrefix(event.EventDateTime, "2099-06-02") {
+		t.Fatalf("expected the event to stay on its previous date, got %+v", event)
+	}
+}
+
+func TestCancelGroupEvent(t *testing.T) {
+	setupGroupRolesTestDB(t)
+
+	_, err := db.DBService.DB.Exec(`
+		INSERT INTO group_events (id, group_id, creator_id, title, description, event_datetime)
+		VALUES (1, 1, 4, 'Picnic', 'In the park', '2099-06-01 12:00:00');
+		INSERT INTO event_rsvps (event_id, user_id, status) VALUES (1, 1, 'come'), (1, 3, 'interested');`)
+	if err != nil {
+		t.Fatalf("Failed to insert event: %v", err)
+	}
+
+	// Only the creator or a member allowed to delete content cancels
+	rr := callGroupHandler(api.CancelGroupEventHandler, http.MethodPost, "/api/group/event/cancel", map[string]any{"id": 1}, 5)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a restricted member, got %d", rr.Code)
+	}
+	rr = callGroupHandler(api.CancelGroupEventHandler, http.MethodPost, "/api/group/event/cancel", map[string]any{"id": 1, "reason": "Rain"}, 2)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 cancelling, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr = callGroupHandler(api.CancelGroupEventHandler, http.MethodPost, "/api/group/event/cancel", map[string]any{"id": 1}, 4)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 cancelling twice, got %d", rr.Code)
+	}
+
+	for _, userID := range []int64{1, 3} {
+		if countNotifications(t, userID, notifications.TypeEventCancelled) != 1 {
+			t.Fatalf("expected user %d to be told about the cancellation", userID)
+		}
+	}
+	if msg := lastNotificationData(t, 1, notifications.TypeEventCancelled); !strings.Contains(msg, `cancelled \"Picnic\" in`) ||
+		!strings.Contains(msg, ": Rain") {
+		t.Fatalf("unexpected notification: %s", msg)
+	}
+	var logged int
+	db.DBService.DB.QueryRow(`SELECT COUNT(*) FROM group_moderation_log WHERE action = ?`, models.ModerationCancelEvent).Scan(&logged)
+	if logged != 1 {
+		t.Fatalf("expected the cancellation by an admin to be logged, got %d", logged)
+	}
+
+	// The event stays listed, locked
+	event, err := db.DBService.GetGroupEventByID(1)
+	if err != nil || event.Status != "cancelled" || event.CancelReason != "Rain" {
+		t.Fatalf("expected a cancelled event, got %+v (%v)", event, err)
+	}
+	rsvpCall(t, api.RSVPToEventHandler, http.MethodPost, map[string]any{"event_id": 1, "status": "come"}, 4, http.StatusConflict)
+	rsvpCall(t, api.UpdateEventRSVPHandler, http.MethodPut, map[string]any{"event_id": 1, "status": "not_come"}, 1, http.StatusConflict)
+	updateEvent(t, map[string]any{"id": 1, "title": "Indoor picnic"}, 4, http.StatusConflict)
+
+	rr = callGroupHandler(api.ExportGroupEventHandler, http.MethodGet, "/api/group/event/ics?id=1", nil, 1)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "STATUS:CANCELLED\r\n") {
+		t.Fatalf("expected a cancelled VEVENT, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func lastNotificationData(t *testing.T, userID int64, notificationType string) string {
+	t.Helper()
+	var data string
+	err := db.DBService.DB.QueryRow(`SELECT data FROM notifications WHERE user_id = ? AND type = ? ORDER BY id DESC LIMIT 1`,
+		userID, notificationType).Scan(&data)
+	if err != nil {
+		t.Fatalf("Failed to read the notification: %v", err)
+	}
+	return data
+}
@@ -0,0 +1,272 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/calendar"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+)
+
+func listGroupEvents(t *testing.T, query string) []db.GroupEvent {
+	rr := callGroupHandler(api.GetGroupEventHandler, http.MethodGet, "/api/group/event?group_id=1&"+query, nil, 4)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 listing events, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var events []db.GroupEvent
+	json.NewDecoder(rr.Body).Decode(&events)
+	return events
+}
+
+func occurrenceStarts(events []db.GroupEvent) []string {
+	starts := make([]string, len(events))
+	for i, e := range events {
+		starts[i] = e.EventDateTime
+	}
+	return starts
+}
+
+func TestRecurrenceRules(t *testing.T) {
+	paris, err := calendar.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []string
+	}{
+		{
+			name:    "weekly on two days",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5",
+			dtstart: time.Date(2026, 3, 2, 19, 0, 0, 0, time.UTC),
+			want:    []string{"2026-03-02", "2026-03-04", "2026-03-09", "2026-03-11", "2026-03-16"},
+		},
+		{
+			name:    "last friday of every other month",
+			rule:    "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR;COUNT=3",
+			dtstart: time.Date(2026, 1, 30, 18, 0, 0, 0, time.UTC),
+			want:    []string{"2026-01-30", "2026-03-27", "2026-05-29"},
+		},
+		{
+			name:    "months without the day are skipped",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC),
+			want:    []string{"2026-01-31", "2026-03-31", "2026-05-31"},
+		},
+		{
+			name:    "yearly until a date",
+			rule:    "FREQ=YEARLY;BYMONTH=6;BYMONTHDAY=21;UNTIL=20280621",
+			dtstart: time.Date(2026, 6, 21, 12, 0, 0, 0, time.UTC),
+			want:    []string{"2026-06-21", "2027-06-21", "2028-06-21"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rule, err := calendar.ParseRule(c.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("ParseRule failed: %v", err)
+			}
+			var got []string
+			for _, occ := range rule.Occurrences(c.dtstart, c.dtstart, time.Time{}, 0) {
+				got = append(got, occ.Format("2006-01-02"))
+			}
+			if strings.Join(got, " ") != strings.Join(c.want, " ") {
+				t.Errorf("expected %v, got %v", c.want, got)
+			}
+		})
+	}
+
+	t.Run("wall clock time survives daylight saving", func(t *testing.T) {
+		rule, _ := calendar.ParseRule("FREQ=WEEKLY;COUNT=2", paris)
+		dtstart := time.Date(2026, 3, 23, 19, 0, 0, 0, paris)
+		occ := rule.Occurrences(dtstart, dtstart, time.Time{}, 0)
+		if len(occ) != 2 || occ[1].Hour() != 19 || occ[1].Sub(occ[0]) != 7*24*time.Hour-time.Hour {
+			t.Errorf("expected 19:00 on both sides of the change, got %v", occ)
+		}
+	})
+
+	t.Run("unsupported rules are refused", func(t *testing.T) {
+		for _, rule := range []string{"", "FREQ=HOURLY", "FREQ=DAILY;BYSETPOS=1", "FREQ=WEEKLY;COUNT=2;UNTIL=20270101", "FREQ=WEEKLY;BYDAY=2MO"} {
+			if _, err := calendar.ParseRule(rule, time.UTC); err == nil {
+				t.Errorf("expected %q to be refused", rule)
+			}
+		}
+	})
+}
+
+func TestRecurringGroupEvents(t *testing.T) {
+	setupGroupRolesTestDB(t)
+
+	create := models.CreateGroupEventRequest{
+		GroupID:       1,
+		Title:         "Evening hike",
+		Description:   "Meet at the car park",
+		EventDateTime: "2026-03-16 19:00:00",
+		EndDateTime:   "2026-03-16 21:00:00",
+		Timezone:      "Europe/Paris",
+		Location:      "Fontainebleau",
+		RRule:         "RRULE:FREQ=WEEKLY;COUNT=4",
+	}
+	invalid := create
+	invalid.Timezone = "Mars/Olympus"
+	if rr := callGroupHandler(api.CreateGroupEventHandler, http.MethodPost, "/api/group/event", invalid, 2); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown timezone, got %d", rr.Code)
+	}
+	invalid = create
+	invalid.RRule = "FREQ=SECONDLY"
+	if rr := callGroupHandler(api.CreateGroupEventHandler, http.MethodPost, "/api/group/event", invalid, 2); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported rule, got %d", rr.Code)
+	}
+	if rr := callGroupHandler(api.CreateGroupEventHandler, http.MethodPost, "/api/group/event", create, 2); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	single := models.CreateGroupEventRequest{GroupID: 1, Title: "Picnic", Description: "Bring food", EventDateTime: "2026-03-25T12:00:00Z"}
+	if rr := callGroupHandler(api.CreateGroupEventHandler, http.MethodPost, "/api/group/event", single, 2); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("occurrences are listed within the range", func(t *testing.T) {
+		events := listGroupEvents(t, "from_date=2026-03-20&to_date=2026-04-30")
+		want := []string{"2026-03-23T19:00:00+01:00", "2026-03-25T12:00:00Z", "2026-03-30T19:00:00+02:00", "2026-04-06T19:00:00+02:00"}
+		if got := occurrenceStarts(events); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		if events[2].EndDateTime != "2026-03-30T21:00:00+02:00" || events[2].Occurrence != events[2].EventDateTime {
+			t.Errorf("expected the occurrence to keep its length, got %+v", events[2])
+		}
+		if events[2].Location != "Fontainebleau" || events[2].RRule != "FREQ=WEEKLY;COUNT=4" {
+			t.Errorf("expected the details of the series, got %+v", events[2])
+		}
+
+		if paged := listGroupEvents(t, "from_date=2026-03-20&to_date=2026-04-30&limit=2&offset=1"); len(paged) != 2 || paged[0].EventDateTime != want[1] {
+			t.Errorf("expected the second page to start at the picnic, got %v", occurrenceStarts(paged))
+		}
+		if rr := callGroupHandler(api.GetGroupEventHandler, http.MethodGet, "/api/group/event?group_id=1&from_date=soon", nil, 4); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an invalid date, got %d", rr.Code)
+		}
+	})
+
+	t.Run("occurrences can be cancelled and moved", func(t *testing.T) {
+		cancel := models.GroupEventExceptionRequest{EventID: 1, Occurrence: "2026-03-23T19:00:00+01:00", Cancelled: true}
+		if rr := callGroupHandler(api.SetGroupEventExceptionHandler, http.MethodPut, "/api/group/event/exception", cancel, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for someone else than the creator, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.SetGroupEventExceptionHandler, http.MethodPut, "/api/group/event/exception", cancel, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 cancelling, got %d: %s", rr.Code, rr.Body.String())
+		}
+		moved := "2026-04-01 20:00:00"
+		place := "Barbizon"
+		move := models.GroupEventExceptionRequest{EventID: 1, Occurrence: "2026-03-30 19:00:00", EventDateTime: &moved, Location: &place}
+		if rr := callGroupHandler(api.SetGroupEventExceptionHandler, http.MethodPut, "/api/group/event/exception", move, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 moving, got %d: %s", rr.Code, rr.Body.String())
+		}
+		missing := models.GroupEventExceptionRequest{EventID: 1, Occurrence: "2026-03-31 19:00:00", Cancelled: true}
+		if rr := callGroupHandler(api.SetGroupEventExceptionHandler, http.MethodPut, "/api/group/event/exception", missing, 2); rr.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for a time the rule never gives, got %d", rr.Code)
+		}
+
+		events := listGroupEvents(t, "from_date=2026-03-20&to_date=2026-04-30")
+		want := []string{"2026-03-25T12:00:00Z", "2026-04-01T20:00:00+02:00", "2026-04-06T19:00:00+02:00"}
+		if got := occurrenceStarts(events); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		if !events[1].Modified || events[1].Location != "Barbizon" || events[1].EndDateTime != "2026-04-01T22:00:00+02:00" {
+			t.Errorf("expected the moved occurrence, got %+v", events[1])
+		}
+
+		restore := models.GroupEventExceptionRequest{EventID: 1, Occurrence: "2026-03-23 19:00:00"}
+		if rr := callGroupHandler(api.DeleteGroupEventExceptionHandler, http.MethodDelete, "/api/group/event/exception", restore, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 restoring, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if events := listGroupEvents(t, "from_date=2026-03-20&to_date=2026-03-24"); len(events) != 1 {
+			t.Errorf("expected the restored occurrence listed, got %v", occurrenceStarts(events))
+		}
+	})
+
+	t.Run("events export as iCalendar", func(t *testing.T) {
+		cancel := models.GroupEventExceptionRequest{EventID: 1, Occurrence: "2026-04-06 19:00:00", Cancelled: true}
+		callGroupHandler(api.SetGroupEventExceptionHandler, http.MethodPut, "/api/group/event/exception", cancel, 2)
+
+		rr := callGroupHandler(api.ExportGroupEventHandler, http.MethodGet, "/api/group/event/ics?id=1", nil, 4)
+		if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/calendar") {
+			t.Fatalf("expected an .ics file, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		ics := rr.Body.String()
+		for _, line := range []string{
+			"BEGIN:VCALENDAR\r\n",
+			"TZID:Europe/Paris\r\n",
+			"DTSTART;TZID=Europe/Paris:20260316T190000\r\n",
+			"DTEND;TZID=Europe/Paris:20260316T210000\r\n",
+			"RRULE:FREQ=WEEKLY;COUNT=4\r\n",
+			"EXDATE;TZID=Europe/Paris:20260406T190000\r\n",
+			"RECURRENCE-ID;TZID=Europe/Paris:20260330T190000\r\n",
+			"DTSTART;TZID=Europe/Paris:20260401T200000\r\n",
+			"LOCATION:Barbizon\r\n",
+			"TZOFFSETTO:+0200\r\n",
+		} {
+			if !strings.Contains(ics, line) {
+				t.Errorf("expected %q in the export:\n%s", line, ics)
+			}
+		}
+
+		if rr := callGroupHandler(api.ExportGroupEventHandler, http.MethodGet, "/api/group/event/ics?id=1", nil, 6); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for a non member, got %d", rr.Code)
+		}
+	})
+
+	t.Run("the calendar feed covers the groups of the user", func(t *testing.T) {
+		rr := callGroupHandler(api.GetCalendarFeedHandler, http.MethodGet, "/api/user/calendar-feed", nil, 4)
+		var feed struct{ URL string }
+		json.NewDecoder(rr.Body).Decode(&feed)
+		parsed, err := url.Parse(feed.URL)
+		if rr.Code != http.StatusOK || err != nil || parsed.Query().Get("token") == "" {
+			t.Fatalf("expected a feed URL with a token, got %d %q", rr.Code, feed.URL)
+		}
+		again := callGroupHandler(api.GetCalendarFeedHandler, http.MethodGet, "/api/user/calendar-feed", nil, 4)
+		if !strings.Contains(again.Body.String(), parsed.Query().Get("token")) {
+			t.Error("expected the feed URL to stay the same")
+		}
+
+		fetch := func() *httptest.ResponseRecorder {
+			rr := httptest.NewRecorder()
+			api.CalendarFeedHandler(rr, httptest.NewRequest(http.MethodGet, "/calendar/feed.ics?"+parsed.RawQuery, bytes.NewReader(nil)))
+			return rr
+		}
+		rr = fetch()
+		if rr.Code != http.StatusOK || strings.Count(rr.Body.String(), "BEGIN:VEVENT") != 3 {
+			t.Errorf("expected the series, its moved occurrence and the picnic, got %d:\n%s", rr.Code, rr.Body.String())
+		}
+
+		callGroupHandler(api.DeleteCalendarFeedHandler, http.MethodDelete, "/api/user/calendar-feed", nil, 4)
+		if rr := fetch(); rr.Code != http.StatusNotFound {
+			t.Errorf("expected 404 once the feed is revoked, got %d", rr.Code)
+		}
+	})
+
+	t.Run("rescheduling the series drops its exceptions", func(t *testing.T) {
+		rule := "FREQ=WEEKLY;BYDAY=TU;COUNT=2"
+		update := models.UpdateGroupEventRequest{ID: 1, RRule: &rule}
+		if rr := callGroupHandler(api.UpdateGroupEventHandler, http.MethodPut, "/api/group/event", update, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if exceptions, _ := db.DBService.GetGroupEventExceptions(1); len(exceptions) != 0 {
+			t.Errorf("expected the exceptions dropped, got %+v", exceptions)
+		}
+		end := "2026-03-16 18:00:00"
+		update = models.UpdateGroupEventRequest{ID: 1, EndDateTime: &end}
+		if rr := callGroupHandler(api.UpdateGroupEventHandler, http.MethodPut, "/api/group/event", update, 2); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 ending before the start, got %d", rr.Code)
+		}
+	})
+}
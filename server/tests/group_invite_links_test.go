@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// setupGroupInviteLinksTestDB adds users 7 and 8, who are not in the group either
+func setupGroupInviteLinksTestDB(t *testing.T) {
+	setupGroupRolesTestDB(t)
+	seedTestDB(t, testUsers("guest", "stranger"))
+}
+
+func createInviteLink(t *testing.T, req models.CreateGroupInviteLinkRequest) models.GroupInviteLink {
+	rr := callGroupHandler(api.CreateGroupInviteLinkHandler, http.MethodPost, "/api/group/invite-link", req, 2)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var link models.GroupInviteLink
+	json.NewDecoder(rr.Body).Decode(&link)
+	if link.Code == "" || !link.Active {
+		t.Fatalf("expected an active link with a code, got %+v", link)
+	}
+	return link
+}
+
+func redeemInviteLink(code string, userID int) int {
+	return callGroupHandler(api.RedeemGroupInviteLinkHandler, http.MethodPost, "/api/group/invite-link/redeem",
+		models.RedeemGroupInviteLinkRequest{Code: code}, userID).Code
+}
+
+func TestGroupInviteLinks(t *testing.T) {
+	t.Run("only roles managing invite links can create and list them", func(t *testing.T) {
+		setupGroupInviteLinksTestDB(t)
+		req := models.CreateGroupInviteLinkRequest{GroupID: 1}
+
+		if rr := callGroupHandler(api.CreateGroupInviteLinkHandler, http.MethodPost, "/api/group/invite-link", req, 3); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for a moderator, got %d", rr.Code)
+		}
+		invalid := models.CreateGroupInviteLinkRequest{GroupID: 1, Mode: "open"}
+		if rr := callGroupHandler(api.CreateGroupInviteLinkHandler, http.MethodPost, "/api/group/invite-link", invalid, 2); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for an unknown mode, got %d", rr.Code)
+		}
+		link := createInviteLink(t, req)
+		if link.Mode != models.GroupInviteLinkRequest {
+			t.Errorf("expected links to require a request by default, got %q", link.Mode)
+		}
+
+		if rr := callGroupHandler(api.GetGroupInviteLinksHandler, http.MethodGet, "/api/group/invite-link?group_id=1", nil, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 listing links as a member, got %d", rr.Code)
+		}
+		rr := callGroupHandler(api.GetGroupInviteLinksHandler, http.MethodGet, "/api/group/invite-link?group_id=1", nil, 1)
+		var links []models.GroupInviteLink
+		json.NewDecoder(rr.Body).Decode(&links)
+		if rr.Code != http.StatusOK || len(links) != 1 || links[0].Code != link.Code {
+			t.Errorf("expected the link listed, got %d %+v", rr.Code, links)
+		}
+	})
+
+	t.Run("auto links add the member until they are used up", func(t *testing.T) {
+		setupGroupInviteLinksTestDB(t)
+		link := createInviteLink(t, models.CreateGroupInviteLinkRequest{GroupID: 1, Mode: models.GroupInviteLinkAuto, MaxUses: 1})
+
+		if code := redeemInviteLink(link.Code, 6); code != http.StatusOK {
+			t.Fatalf("expected 200 redeeming, got %d", code)
+		}
+		if role, _ := db.DBService.GetGroupRole(6, 1); role != models.GroupRoleMember {
+			t.Errorf("expected user 6 to be a member, got %q", role)
+		}
+		if code := redeemInviteLink(link.Code, 6); code != http.StatusGone {
+			t.Errorf("expected 410 once the link is used up, got %d", code)
+		}
+		if code := redeemInviteLink(link.Code, 7); code != http.StatusGone {
+			t.Errorf("expected 410 for another user, got %d", code)
+		}
+		if code := redeemInviteLink("unknown", 7); code != http.StatusNotFound {
+			t.Errorf("expected 404 for an unknown code, got %d", code)
+		}
+	})
+
+	t.Run("request links file a join request for the owner", func(t *testing.T) {
+		setupGroupInviteLinksTestDB(t)
+		link := createInviteLink(t, models.CreateGroupInviteLinkRequest{GroupID: 1})
+
+		if code := redeemInviteLink(link.Code, 6); code != http.StatusOK {
+			t.Fatalf("expected 200 redeeming, got %d", code)
+		}
+		if role, _ := db.DBService.GetGroupRole(6, 1); role != "" {
+			t.Errorf("expected user 6 to wait for approval, got role %q", role)
+		}
+		if pending, _ := db.DBService.HasPendingGroupRequest(6, 1); !pending {
+			t.Error("expected a pending join request")
+		}
+		if countNotifications(t, 1, notifications.TypeGroupRequest) != 1 {
+			t.Error("expected the owner to be notified of the request")
+		}
+		if code := redeemInviteLink(link.Code, 6); code != http.StatusConflict {
+			t.Errorf("expected 409 with a pending request, got %d", code)
+		}
+		if code := redeemInviteLink(link.Code, 4); code != http.StatusConflict {
+			t.Errorf("expected 409 for a member, got %d", code)
+		}
+	})
+
+	t.Run("expired, revoked and banned redemptions are refused", func(t *testing.T) {
+		setupGroupInviteLinksTestDB(t)
+		auto := models.CreateGroupInviteLinkRequest{GroupID: 1, Mode: models.GroupInviteLinkAuto}
+		expired := createInviteLink(t, auto)
+		first := createInviteLink(t, auto)
+		second := createInviteLink(t, auto)
+
+		db.DBService.DB.Exec(`UPDATE group_invite_links SET expires_at = datetime('now', '-1 minute') WHERE id = ?`, expired.ID)
+		if code := redeemInviteLink(expired.Code, 6); code != http.StatusGone {
+			t.Errorf("expected 410 for an expired link, got %d", code)
+		}
+
+		if _, err := db.DBService.DB.Exec(`INSERT INTO group_bans (group_id, user_id, banned_by) VALUES (1, 8, 2)`); err != nil {
+			t.Fatalf("Failed to ban: %v", err)
+		}
+		if code := redeemInviteLink(first.Code, 8); code != http.StatusForbidden {
+			t.Errorf("expected 403 for a banned user, got %d", code)
+		}
+
+		revokeOne := models.RevokeGroupInviteLinkRequest{ID: first.ID, GroupID: 1}
+		if rr := callGroupHandler(api.RevokeGroupInviteLinkHandler, http.MethodDelete, "/api/group/invite-link", revokeOne, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 revoking as a member, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.RevokeGroupInviteLinkHandler, http.MethodDelete, "/api/group/invite-link", revokeOne, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 revoking a link, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if code := redeemInviteLink(first.Code, 6); code != http.StatusGone {
+			t.Errorf("expected 410 for a revoked link, got %d", code)
+		}
+		if code := redeemInviteLink(second.Code, 6); code != http.StatusOK {
+			t.Errorf("expected the other link to still work, got %d", code)
+		}
+
+		revokeAll := models.RevokeGroupInviteLinkRequest{GroupID: 1}
+		rr := callGroupHandler(api.RevokeGroupInviteLinkHandler, http.MethodDelete, "/api/group/invite-link", revokeAll, 1)
+		var body struct{ Revoked int64 }
+		json.NewDecoder(rr.Body).Decode(&body)
+		if rr.Code != http.StatusOK || body.Revoked != 2 {
+			t.Errorf("expected the 2 outstanding links revoked, got %d %d", rr.Code, body.Revoked)
+		}
+		if code := redeemInviteLink(second.Code, 7); code != http.StatusGone {
+			t.Errorf("expected 410 after revoking every link, got %d", code)
+		}
+		if rr := callGroupHandler(api.RevokeGroupInviteLinkHandler, http.MethodDelete, "/api/group/invite-link", revokeAll, 1); rr.Code != http.StatusNotFound {
+			t.Errorf("expected 404 with nothing left to revoke, got %d", rr.Code)
+		}
+
+		entries, _ := db.DBService.GetModerationLog(1, 20, 0)
+		created, revoked := 0, 0
+		for _, e := range entries {
+			switch e.Action {
+			case models.ModerationCreateInviteLink:
+				created++
+			case models.ModerationRevokeInviteLink:
+				revoked++
+			}
+		}
+		if created != 3 || revoked != 2 {
+			t.Errorf("expected 3 creations and 2 revocations logged, got %d and %d", created, revoked)
+		}
+	})
+}
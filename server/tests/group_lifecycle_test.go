@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+func countNotifications(t *testing.T, userID int64, notificationType string) int {
+	var count int
+	err := db.DBService.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND type = ?`,
+		userID, notificationType).Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to count notifications: %v", err)
+	}
+	return count
+}
+
+func TestGroupLifecycle(t *testing.T) {
+	t.Run("ownership moves only once the new owner accepts", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		transfer := models.CreateOwnershipTransferRequest{GroupID: 1, UserID: 4}
+
+		if rr := callGroupHandler(api.CreateOwnershipTransferHandler, http.MethodPost, "/api/group/transfer", transfer, 2); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when an admin transfers ownership, got %d", rr.Code)
+		}
+		rr := callGroupHandler(api.CreateOwnershipTransferHandler, http.MethodPost, "/api/group/transfer", transfer, 1)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var created struct{ ID int64 }
+		json.NewDecoder(rr.Body).Decode(&created)
+
+		if countNotifications(t, 4, notifications.TypeGroupOwnershipTransfer) != 1 {
+			t.Error("expected the new owner to be asked")
+		}
+		if role, _ := db.DBService.GetGroupRole(1, 1); role != models.GroupRoleOwner {
+			t.Errorf("expected ownership to stay until accepted, user 1 is %s", role)
+		}
+
+		answer := models.RespondOwnershipTransferRequest{ID: created.ID, Accept: true}
+		if rr := callGroupHandler(api.RespondOwnershipTransferHandler, http.MethodPut, "/api/group/transfer", answer, 3); rr.Code != http.StatusNotFound {
+			t.Errorf("expected 404 when someone else answers, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.RespondOwnershipTransferHandler, http.MethodPut, "/api/group/transfer", answer, 4); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		if role, _ := db.DBService.GetGroupRole(4, 1); role != models.GroupRoleOwner {
+			t.Errorf("expected user 4 to own the group, got %s", role)
+		}
+		if role, _ := db.DBService.GetGroupRole(1, 1); role != models.GroupRoleAdmin {
+			t.Errorf("expected the previous owner to become admin, got %s", role)
+		}
+		if group, _ := db.DBService.GetGroupByID(1); group == nil || group.CreatorID != 4 {
+			t.Errorf("expected creator_id to follow the owner, got %+v", group)
+		}
+		if countNotifications(t, 4, notifications.TypeGroupOwnershipTransfer) != 0 {
+			t.Error("expected the answered request to be withdrawn")
+		}
+		if countNotifications(t, 1, notifications.TypeGroupOwnershipResponse) != 1 {
+			t.Error("expected the previous owner to be told")
+		}
+		if rr := callGroupHandler(api.RespondOwnershipTransferHandler, http.MethodPut, "/api/group/transfer", answer, 4); rr.Code != http.StatusConflict {
+			t.Errorf("expected 409 when answering twice, got %d", rr.Code)
+		}
+	})
+
+	t.Run("a group requiring 2FA of its admins only passes to an owner with 2FA", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		seedTestDB(t, `
+			INSERT INTO user_two_factor (user_id, secret, enabled) VALUES (1, 'JBSWY3DPEHPK3PXP', TRUE), (2, 'JBSWY3DPEHPK3PXP', TRUE);
+			UPDATE groups SET require_admin_2fa = TRUE WHERE id = 1;
+		`)
+
+		transfer := models.CreateOwnershipTransferRequest{GroupID: 1, UserID: 4}
+		expectCall(t, api.CreateOwnershipTransferHandler, http.MethodPost, "/api/group/transfer", transfer, 1, http.StatusConflict)
+
+		// Sent before the requirement, the transfer still cannot be accepted without 2FA
+		seedTestDB(t, `UPDATE groups SET require_admin_2fa = FALSE WHERE id = 1`)
+		created := decodeCall[struct{ ID int64 }](t, api.CreateOwnershipTransferHandler, http.MethodPost, "/api/group/transfer", transfer, 1, http.StatusCreated)
+		seedTestDB(t, `UPDATE groups SET require_admin_2fa = TRUE WHERE id = 1`)
+
+		answer := models.RespondOwnershipTransferRequest{ID: created.ID, Accept: true}
+		expectCall(t, api.RespondOwnershipTransferHandler, http.MethodPut, "/api/group/transfer", answer, 4, http.StatusConflict)
+		if role, _ := db.DBService.GetGroupRole(4, 1); role != models.GroupRoleMember {
+			t.Errorf("expected user 4 to stay a member, got %s", role)
+		}
+		if transfer, _ := db.DBService.GetOwnershipTransferByID(created.ID); transfer.Status != models.OwnershipTransferPending {
+			t.Errorf("expected the transfer to stay pending, got %s", transfer.Status)
+		}
+
+		seedTestDB(t, `INSERT INTO user_two_factor (user_id, secret, enabled) VALUES (4, 'JBSWY3DPEHPK3PXP', TRUE)`)
+		expectCall(t, api.RespondOwnershipTransferHandler, http.MethodPut, "/api/group/transfer", answer, 4, http.StatusOK)
+		if role, _ := db.DBService.GetGroupRole(4, 1); role != models.GroupRoleOwner {
+			t.Errorf("expected user 4 to own the group, got %s", role)
+		}
+	})
+
+	t.Run("a transfer cancelled while it is being accepted changes nothing", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		transferID, err := db.DBService.CreateOwnershipTransfer(1, 1, 4)
+		if err != nil {
+			t.Fatalf("create transfer: %v", err)
+		}
+		// Stands in for a cancel landing between the checks and the update: the row is left untouched
+		seedTestDB(t, `
+			CREATE TEMP TRIGGER cancelled_meanwhile BEFORE UPDATE OF status ON group_ownership_transfers
+			BEGIN SELECT RAISE(IGNORE); END;
+		`)
+
+		if _, err := db.DBService.RespondOwnershipTransfer(transferID, 4, true); err == nil || err.Error() != "transfer is no longer pending" {
+			t.Fatalf("expected the transfer to be refused, got %v", err)
+		}
+		if role, _ := db.DBService.GetGroupRole(1, 1); role != models.GroupRoleOwner {
+			t.Errorf("expected user 1 to keep the group, got %s", role)
+		}
+		if role, _ := db.DBService.GetGroupRole(4, 1); role != models.GroupRoleMember {
+			t.Errorf("expected user 4 to stay a member, got %s", role)
+		}
+	})
+
+	t.Run("archived groups are read-only", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		if _, err := db.DBService.DB.Exec(`INSERT INTO group_posts (group_id, user_id, title, body) VALUES (1, 4, 'Old', 'Post')`); err != nil {
+			t.Fatalf("Failed to insert post: %v", err)
+		}
+		archive := models.GroupIDRequest{GroupID: 1}
+
+		if rr := callGroupHandler(api.ArchiveGroupHandler, http.MethodPost, "/api/group/archive", archive, 2); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when an admin archives, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.ArchiveGroupHandler, http.MethodPost, "/api/group/archive", archive, 1); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if countNotifications(t, 4, notifications.TypeGroupStatus) != 1 || countNotifications(t, 1, notifications.TypeGroupStatus) != 0 {
+			t.Error("expected every member but the owner to be told")
+		}
+
+		post := models.CreateGroupPostRequest{GroupID: 1, Title: "New", Body: "Post"}
+		if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4); rr.Code != http.StatusConflict {
+			t.Errorf("expected 409 when posting in an archived group, got %d", rr.Code)
+		}
+		title := "Edited"
+		if err := db.DBService.UpdateGroupPost(models.UpdateGroupPostRequest{ID: 1, Title: &title}, 4); err != db.ErrGroupArchived {
+			t.Errorf("expected authors not to edit, got %v", err)
+		}
+		if _, err := db.DBService.CreateGroupMessage(1, 4, "hello"); err != db.ErrGroupArchived {
+			t.Errorf("expected the chat to be closed, got %v", err)
+		}
+		if group, err := db.DBService.GetGroupByID(1); err != nil || group.Status != models.GroupStatusArchived {
+			t.Errorf("expected the group to stay readable as archived, got %+v %v", group, err)
+		}
+
+		if rr := callGroupHandler(api.UnarchiveGroupHandler, http.MethodPost, "/api/group/unarchive", archive, 1); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4); rr.Code != http.StatusCreated {
+			t.Errorf("expected posting again once unarchived, got %d", rr.Code)
+		}
+	})
+
+	t.Run("deleted groups can be restored until purged", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		api.SetGroupRestoreWindow(24 * time.Hour)
+
+		if rr := callGroupHandler(api.DeleteGroupHandler, http.MethodDelete, "/api/group?id=1", nil, 2); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when an admin deletes, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.DeleteGroupHandler, http.MethodDelete, "/api/group?id=1", nil, 1); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if _, err := db.DBService.GetGroupByID(1); err == nil {
+			t.Error("expected the deleted group to be hidden")
+		}
+		if ok, _ := db.DBService.IsUserInGroup(4, 1); ok {
+			t.Error("expected members to lose access to the deleted group")
+		}
+		if countNotifications(t, 3, notifications.TypeGroupStatus) != 1 {
+			t.Error("expected members to be told about the deletion")
+		}
+		if deleted, _ := db.DBService.GetDeletedGroups(1); len(deleted) != 1 {
+			t.Errorf("expected the owner to see the deleted group, got %+v", deleted)
+		}
+
+		restore := models.GroupIDRequest{GroupID: 1}
+		if rr := callGroupHandler(api.RestoreGroupHandler, http.MethodPost, "/api/group/restore", restore, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a member restores, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.RestoreGroupHandler, http.MethodPost, "/api/group/restore", restore, 1); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if group, err := db.DBService.GetGroupByID(1); err != nil || group.Status != models.GroupStatusActive {
+			t.Errorf("expected the group back, got %+v %v", group, err)
+		}
+
+		if _, err := db.DBService.DeleteGroup(1, time.Hour); err != nil {
+			t.Fatalf("DeleteGroup failed: %v", err)
+		}
+		if purged, err := db.DBService.PurgeDeletedGroups(time.Now()); err != nil || purged != 0 {
+			t.Errorf("expected nothing purged within the window, got %d %v", purged, err)
+		}
+		if purged, err := db.DBService.PurgeDeletedGroups(time.Now().Add(2 * time.Hour)); err != nil || purged != 1 {
+			t.Fatalf("expected the group purged, got %d %v", purged, err)
+		}
+		if status, _ := db.DBService.GetGroupStatus(1); status != "" {
+			t.Errorf("expected the group row gone, got %q", status)
+		}
+		if err := db.DBService.RestoreGroup(1); err == nil {
+			t.Error("expected a purged group not to be restored")
+		}
+	})
+
+	t.Run("the owner cannot leave without a successor", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+
+		err := db.DBService.DeleteGroupMember(models.LeaveGroupRequest{GroupID: 1, UserID: 1}, 1)
+		if err == nil || err.Error() != "owner must transfer ownership before leaving" {
+			t.Errorf("expected the owner to be kept, got %v", err)
+		}
+		if status, _ := db.DBService.GetGroupStatus(1); status != models.GroupStatusActive {
+			t.Errorf("expected the group untouched, got %q", status)
+		}
+	})
+}
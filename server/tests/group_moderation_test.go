@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+)
+
+func TestGroupModeration(t *testing.T) {
+	t.Run("bans remove the member and block requests and invitations", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		ban := models.GroupSanctionRequest{GroupID: 1, UserID: 4, Reason: "spam"}
+
+		if rr := callGroupHandler(api.BanGroupMemberHandler, http.MethodPost, "/api/group/ban", ban, 3); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a moderator bans, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.BanGroupMemberHandler, http.MethodPost, "/api/group/ban",
+			models.GroupSanctionRequest{GroupID: 1, UserID: 1}, 2); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when an admin bans the owner, got %d", rr.Code)
+		}
+		db.DBService.DB.Exec(`INSERT INTO group_invitations (group_id, invited_user_id, status) VALUES (1, 4, 'pending')`)
+		if rr := callGroupHandler(api.BanGroupMemberHandler, http.MethodPost, "/api/group/ban", ban, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		if role, _ := db.DBService.GetGroupRole(4, 1); role != "" {
+			t.Errorf("expected the banned member to be removed, still %s", role)
+		}
+		var invitations int
+		db.DBService.DB.QueryRow(`SELECT COUNT(*) FROM group_invitations WHERE invited_user_id = 4`).Scan(&invitations)
+		if invitations != 0 {
+			t.Error("expected the pending invitation to be dropped")
+		}
+		if rr := callGroupHandler(api.CreateGroupRequestHandler, http.MethodPost, "/api/group/request",
+			map[string]int64{"group_id": 1}, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 on a join request, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.CreateGroupInvitationHandler, http.MethodPost, "/api/group/invitation",
+			models.InviteToGroupRequest{GroupID: 1, UserID: 4}, 1); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 on an invitation, got %d", rr.Code)
+		}
+		if err := db.DBService.CreateGroupMember(models.GroupMember{GroupID: 1, UserID: 4, Role: models.GroupRoleMember}); err != db.ErrGroupBanned {
+			t.Errorf("expected ErrGroupBanned, got %v", err)
+		}
+
+		if rr := callGroupHandler(api.UnbanGroupMemberHandler, http.MethodDelete, "/api/group/ban", ban, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 on unban, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if banned, _ := db.DBService.IsBannedFromGroup(4, 1); banned {
+			t.Error("expected the ban to be lifted")
+		}
+	})
+
+	t.Run("bans expire", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		past := time.Now().Add(-time.Minute)
+		if err := db.DBService.BanFromGroup(1, 2, 4, "", &past); err != nil {
+			t.Fatalf("BanFromGroup failed: %v", err)
+		}
+		if banned, _ := db.DBService.IsBannedFromGroup(4, 1); banned {
+			t.Error("expected an expired ban to be ignored")
+		}
+		if bans, _ := db.DBService.GetGroupBans(1); len(bans) != 0 {
+			t.Errorf("expected no active ban, got %d", len(bans))
+		}
+	})
+
+	t.Run("mutes block posting and chatting until lifted", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		mute := models.GroupSanctionRequest{GroupID: 1, UserID: 4, DurationMinutes: 10}
+
+		if rr := callGroupHandler(api.MuteGroupMemberHandler, http.MethodPost, "/api/group/mute",
+			models.GroupSanctionRequest{GroupID: 1, UserID: 4}, 3); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for a mute without duration, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.MuteGroupMemberHandler, http.MethodPost, "/api/group/mute",
+			models.GroupSanctionRequest{GroupID: 1, UserID: 2, DurationMinutes: 10}, 3); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a moderator mutes an admin, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.MuteGroupMemberHandler, http.MethodPost, "/api/group/mute", mute, 3); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		post := models.CreateGroupPostRequest{GroupID: 1, Title: "Hello there", Body: "Anyone around?"}
+		if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a muted member posts, got %d", rr.Code)
+		}
+		if _, err := db.DBService.CreateGroupMessage(1, 4, "hello"); err != db.ErrGroupMuted {
+			t.Errorf("expected ErrGroupMuted, got %v", err)
+		}
+		if mutes, _ := db.DBService.GetGroupMutes(1); len(mutes) != 1 || mutes[0].UserID != 4 {
+			t.Errorf("expected one active mute, got %+v", mutes)
+		}
+
+		if rr := callGroupHandler(api.UnmuteGroupMemberHandler, http.MethodDelete, "/api/group/mute", mute, 3); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 on unmute, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if _, err := db.DBService.CreateGroupMessage(1, 4, "hello"); err != nil {
+			t.Errorf("expected the unmuted member to chat, got %v", err)
+		}
+	})
+
+	t.Run("moderation log is append-only and readable by admins", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		if err := db.DBService.MuteInGroup(1, 3, 4, "off topic", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("MuteInGroup failed: %v", err)
+		}
+		if err := db.DBService.DeleteGroupMember(models.LeaveGroupRequest{GroupID: 1, UserID: 5}, 2); err != nil {
+			t.Fatalf("DeleteGroupMember failed: %v", err)
+		}
+		if rr := callGroupHandler(api.ArchiveGroupHandler, http.MethodPost, "/api/group/archive", models.GroupIDRequest{GroupID: 1}, 1); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 on archive, got %d", rr.Code)
+		}
+
+		if rr := callGroupHandler(api.GetModerationLogHandler, http.MethodGet, "/api/group/moderation-log?group_id=1", nil, 3); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 for a moderator, got %d", rr.Code)
+		}
+		rr := callGroupHandler(api.GetModerationLogHandler, http.MethodGet, "/api/group/moderation-log?group_id=1", nil, 2)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var entries []models.ModerationLogEntry
+		json.NewDecoder(rr.Body).Decode(&entries)
+		actions := map[string]models.ModerationLogEntry{}
+		for _, e := range entries {
+			actions[e.Action] = e
+		}
+		if e, ok := actions[models.ModerationMute]; !ok || e.ActorNickname != "moderator" || e.TargetNickname != "member" {
+			t.Errorf("expected the mute to name who did it to whom, got %+v", e)
+		}
+		if _, ok := actions[models.ModerationRemoveMember]; !ok {
+			t.Error("expected the removal to be logged")
+		}
+		if _, ok := actions[models.ModerationArchiveGroup]; !ok {
+			t.Error("expected the archiving to be logged")
+		}
+
+		if _, err := db.DBService.DB.Exec(`UPDATE group_moderation_log SET details = ''`); err == nil {
+			t.Error("expected log entries to be immutable")
+		}
+		if _, err := db.DBService.DB.Exec(`DELETE FROM group_moderation_log`); err == nil {
+			t.Error("expected log entries to be undeletable")
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// setupGroupPostPinsTestDB adds three group posts by the member, post 1 being the oldest
+func setupGroupPostPinsTestDB(t *testing.T) {
+	setupGroupRolesTestDB(t)
+	seedTestDB(t, `
+		INSERT INTO group_posts (group_id, user_id, title, body, created_at) VALUES
+			(1, 4, 'First', 'Body', '2025-01-01 10:00:00'),
+			(1, 4, 'Second', 'Body', '2025-01-02 10:00:00'),
+			(1, 4, 'Third', 'Body', '2025-01-03 10:00:00');
+	`)
+}
+
+func listGroupPostTitles(t *testing.T, offset int) []string {
+	posts, err := db.DBService.GetGroupPostsWithImagesByGroupID(1, offset, 4)
+	if err != nil {
+		t.Fatalf("GetGroupPostsWithImagesByGroupID failed: %v", err)
+	}
+	titles := make([]string, 0, len(posts))
+	for _, p := range posts {
+		titles = append(titles, p.Title)
+	}
+	return titles
+}
+
+func TestGroupPostPins(t *testing.T) {
+	t.Run("pinned posts come first until unpinned or expired", func(t *testing.T) {
+		setupGroupPostPinsTestDB(t)
+		pin := models.PinGroupPostRequest{PostID: 1}
+
+		if rr := callGroupHandler(api.PinGroupPostHandler, http.MethodPost, "/api/group/post/pin", pin, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a member pins, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.PinGroupPostHandler, http.MethodPost, "/api/group/post/pin", pin, 3); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		if got := listGroupPostTitles(t, 0); len(got) != 3 || got[0] != "First" || got[1] != "Third" || got[2] != "Second" {
+			t.Errorf("expected the pinned post first without duplicates, got %v", got)
+		}
+		if got := listGroupPostTitles(t, 1); len(got) != 1 || got[0] != "Second" {
+			t.Errorf("expected later pages to leave the pinned post out, got %v", got)
+		}
+		if post, _ := db.DBService.GetGroupPostWithImagesByID(1, 4); post == nil || !post.Pinned {
+			t.Errorf("expected the post to be flagged as pinned, got %+v", post)
+		}
+
+		if rr := callGroupHandler(api.UnpinGroupPostHandler, http.MethodDelete, "/api/group/post/pin", pin, 3); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200 on unpin, got %d", rr.Code)
+		}
+		if got := listGroupPostTitles(t, 0); got[0] != "Third" {
+			t.Errorf("expected chronological order once unpinned, got %v", got)
+		}
+
+		expired := time.Now().Add(-time.Minute)
+		if err := db.DBService.PinGroupPost(1, 3, &expired); err != nil {
+			t.Fatalf("PinGroupPost failed: %v", err)
+		}
+		if got := listGroupPostTitles(t, 0); len(got) != 3 || got[0] != "Third" {
+			t.Errorf("expected an expired pin to be ignored, got %v", got)
+		}
+	})
+
+	t.Run("announcements notify members and close comments", func(t *testing.T) {
+		setupGroupPostPinsTestDB(t)
+		post := models.CreateGroupPostRequest{GroupID: 1, Title: "House rules", Body: "Please read", IsAnnouncement: true}
+
+		if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a member announces, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 2); rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		for _, member := range []int64{1, 3, 4, 5} {
+			if countNotifications(t, member, notifications.TypeGroupAnnouncement) != 1 {
+				t.Errorf("expected user %d to be notified", member)
+			}
+		}
+		if countNotifications(t, 2, notifications.TypeGroupAnnouncement) != 0 {
+			t.Error("expected the author not to be notified")
+		}
+
+		comment := models.CreateGroupCommentRequest{GroupPostID: 4, Body: "Got it"}
+		if rr := callGroupHandler(api.CreateGroupCommentHandler, http.MethodPost, "/api/group/comment", comment, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when commenting an announcement, got %d", rr.Code)
+		}
+
+		existing := models.GroupPostIDRequest{PostID: 1}
+		if rr := callGroupHandler(api.AnnounceGroupPostHandler, http.MethodPost, "/api/group/post/announcement", existing, 3); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a moderator announces, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.AnnounceGroupPostHandler, http.MethodPost, "/api/group/post/announcement", existing, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if countNotifications(t, 1, notifications.TypeGroupAnnouncement) != 2 {
+			t.Error("expected members to be told about the marked post")
+		}
+		if rr := callGroupHandler(api.AnnounceGroupPostHandler, http.MethodPost, "/api/group/post/announcement", existing, 2); rr.Code != http.StatusConflict {
+			t.Errorf("expected 409 for an existing announcement, got %d", rr.Code)
+		}
+
+		entries, _ := db.DBService.GetModerationLog(1, 10, 0)
+		if len(entries) == 0 || entries[0].Action != models.ModerationAnnouncePost {
+			t.Errorf("expected the announcement to be logged, got %+v", entries)
+		}
+	})
+
+	t.Run("a post waiting for review cannot be pinned", func(t *testing.T) {
+		setupGroupPostPinsTestDB(t)
+		seedTestDB(t, `UPDATE group_posts SET status = 'pending' WHERE id = 1`)
+		pin := models.PinGroupPostRequest{PostID: 1}
+
+		expectCall(t, api.PinGroupPostHandler, http.MethodPost, "/api/group/post/pin", pin, 3, http.StatusConflict)
+		// Members who cannot see the post are not told it exists
+		expectCall(t, api.PinGroupPostHandler, http.MethodPost, "/api/group/post/pin", pin, 5, http.StatusNotFound)
+		expectCall(t, api.AnnounceGroupPostHandler, http.MethodPost, "/api/group/post/announcement", models.GroupPostIDRequest{PostID: 1}, 2, http.StatusConflict)
+	})
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+func getPostQueue(t *testing.T, userID int) []models.GroupPost {
+	rr := callGroupHandler(api.GetGroupPostQueueHandler, http.MethodGet, "/api/group/post/queue?group_id=1", nil, userID)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 on the queue, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var posts []models.GroupPost
+	json.NewDecoder(rr.Body).Decode(&posts)
+	return posts
+}
+
+func TestGroupPostReview(t *testing.T) {
+	setupGroupPostPinsTestDB(t)
+	required := true
+	if err := db.DBService.UpdateGroup(1, models.UpdateGroupRequest{RequirePostApproval: &required}); err != nil {
+		t.Fatalf("UpdateGroup failed: %v", err)
+	}
+
+	post := models.CreateGroupPostRequest{GroupID: 1, Title: "Trip report", Body: "Great hike"}
+	rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4)
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for a member post, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created struct{ ID int64 }
+	json.NewDecoder(rr.Body).Decode(&created)
+
+	if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 3); rr.Code != http.StatusCreated {
+		t.Errorf("expected moderators to publish directly, got %d", rr.Code)
+	}
+	if titles := listGroupPostTitles(t, 0); len(titles) != 4 {
+		t.Errorf("expected the pending post to stay out of the feed, got %v", titles)
+	}
+
+	t.Run("pending posts are visible to the author and moderators only", func(t *testing.T) {
+		if _, err := db.DBService.GetGroupPostWithImagesByID(created.ID, 5); err == nil {
+			t.Error("expected another member not to see the pending post")
+		}
+		for _, userID := range []int64{3, 4} {
+			if p, err := db.DBService.GetGroupPostWithImagesByID(created.ID, userID); err != nil || p.Status != models.GroupPostPending {
+				t.Errorf("expected user %d to see the pending post, got %v", userID, err)
+			}
+		}
+		if queue := getPostQueue(t, 3); len(queue) != 1 || queue[0].ID != created.ID {
+			t.Errorf("expected the moderator queue to hold the post, got %+v", queue)
+		}
+		if queue := getPostQueue(t, 5); len(queue) != 0 {
+			t.Errorf("expected an empty queue for other members, got %+v", queue)
+		}
+	})
+
+	t.Run("rejections need a reason and reach the author", func(t *testing.T) {
+		review := models.ReviewGroupPostRequest{PostID: created.ID}
+		if rr := callGroupHandler(api.ReviewGroupPostHandler, http.MethodPut, "/api/group/post/review", review, 4); rr.Code != http.StatusForbidden {
+			t.Errorf("expected 403 when a member reviews, got %d", rr.Code)
+		}
+		if rr := callGroupHandler(api.ReviewGroupPostHandler, http.MethodPut, "/api/group/post/review", review, 3); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 without a reason, got %d", rr.Code)
+		}
+		review.Reason = "Off topic"
+		if rr := callGroupHandler(api.ReviewGroupPostHandler, http.MethodPut, "/api/group/post/review", review, 3); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		var data string
+		db.DBService.DB.QueryRow(`SELECT data FROM notifications WHERE user_id = 4 AND type = ?`, notifications.TypeGroupPostReview).Scan(&data)
+		if !strings.Contains(data, "Off topic") {
+			t.Errorf("expected the author to get the reason, got %q", data)
+		}
+		if queue := getPostQueue(t, 4); len(queue) != 1 || queue[0].Status != models.GroupPostRejected || queue[0].RejectionReason != "Off topic" {
+			t.Errorf("expected the author to see the rejection, got %+v", queue)
+		}
+		if rr := callGroupHandler(api.ReviewGroupPostHandler, http.MethodPut, "/api/group/post/review", review, 3); rr.Code != http.StatusConflict {
+			t.Errorf("expected 409 once reviewed, got %d", rr.Code)
+		}
+	})
+
+	t.Run("approved posts join the feed", func(t *testing.T) {
+		rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4)
+		var second struct{ ID int64 }
+		json.NewDecoder(rr.Body).Decode(&second)
+
+		review := models.ReviewGroupPostRequest{PostID: second.ID, Approve: true}
+		if rr := callGroupHandler(api.ReviewGroupPostHandler, http.MethodPut, "/api/group/post/review", review, 2); rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if titles := listGroupPostTitles(t, 0); len(titles) != 5 {
+			t.Errorf("expected the approved post in the feed, got %v", titles)
+		}
+		if countNotifications(t, 4, notifications.TypeGroupPostReview) != 2 {
+			t.Error("expected the author to be told about the approval")
+		}
+	})
+}
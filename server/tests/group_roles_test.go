@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/models"
+)
+
+// setupGroupRolesTestDB creates users 1 to 6 and group 1 with one member per role: user 1 owner, 2 admin,
+// 3 moderator, 4 member and 5 restricted, user 6 is an outsider
+func setupGroupRolesTestDB(t *testing.T) {
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	useTestDB(t, testUsers("owner", "admin", "moderator", "member", "restricted", "outsider")+`
+		INSERT INTO groups (title, avatar, bio, creator_id) VALUES ('Hikers', '', '', 1);
+		INSERT INTO group_members (group_id, user_id, role) VALUES
+			(1, 1, 'owner'), (1, 2, 'admin'), (1, 3, 'moderator'), (1, 4, 'member'), (1, 5, 'restricted');
+	`)
+}
+
+func asUser(req *http.Request, userID int) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+}
+
+func TestGroupRoles(t *testing.T) {
+	t.Run("default matrix", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+
+		cases := []struct {
+			userID     int64
+			permission string
+			want       bool
+		}{
+			{1, models.GroupPermManageRoles, true},
+			{2, models.GroupPermManageRoles, true},
+			{3, models.GroupPermManageRoles, false},
+			{3, models.GroupPermDeleteContent, true},
+			{4, models.GroupPermPost, true},
+			{4, models.GroupPermApproveRequests, false},
+			{5, models.GroupPermComment, false},
+			{6, models.GroupPermPost, false}, // not a member
+		}
+		for _, c := range cases {
+			got, err := db.DBService.HasGroupPermission(c.userID, 1, c.permission)
+			if err != nil {
+				t.Fatalf("HasGroupPermission failed: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("user %d %s: expected %v, got %v", c.userID, c.permission, c.want, got)
+			}
+		}
+	})
+
+	t.Run("per-group overrides", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+		allow, deny := true, false
+
+		err := db.DBService.SetGroupPermissionOverrides(1, []models.GroupPermissionOverride{
+			{Role: models.GroupRoleRestricted, Permission: models.GroupPermComment, Allowed: &allow},
+			{Role: models.GroupRoleMember, Permission: models.GroupPermCreateEvent, Allowed: &deny},
+		})
+		if err != nil {
+			t.Fatalf("SetGroupPermissionOverrides failed: %v", err)
+		}
+		if ok, _ := db.DBService.HasGroupPermission(5, 1, models.GroupPermComment); !ok {
+			t.Error("expected restricted members to comment after the override")
+		}
+		if ok, _ := db.DBService.HasGroupPermission(4, 1, models.GroupPermCreateEvent); ok {
+			t.Error("expected members to lose create_event after the override")
+		}
+
+		// Removing the override restores the default
+		db.DBService.SetGroupPermissionOverrides(1, []models.GroupPermissionOverride{
+			{Role: models.GroupRoleMember, Permission: models.GroupPermCreateEvent},
+		})
+		if ok, _ := db.DBService.HasGroupPermission(4, 1, models.GroupPermCreateEvent); !ok {
+			t.Error("expected the default back once the override is removed")
+		}
+
+		err = db.DBService.SetGroupPermissionOverrides(1, []models.GroupPermissionOverride{
+			{Role: models.GroupRoleModerator, Permission: models.GroupPermManageRoles, Allowed: &allow},
+		})
+		if err == nil {
+			t.Error("expected manage_roles to be fixed")
+		}
+	})
+
+	t.Run("role changes follow the hierarchy", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+
+		cases := []struct {
+			name      string
+			requester int64
+			member    int64
+			role      string
+			wantErr   string
+		}{
+			{"admin promotes a member to moderator", 2, 4, models.GroupRoleModerator, ""},
+			{"admin cannot create admins", 2, 4, models.GroupRoleAdmin, "cannot assign a role equal to or above your own"},
+			{"admin cannot demote the owner", 2, 1, models.GroupRoleMember, "cannot change the role of a member at or above your own role"},
+			{"moderator cannot manage roles", 3, 5, models.GroupRoleMember, "not authorized: requester cannot manage roles in this group"},
+			{"owner role is never assigned", 1, 2, models.GroupRoleOwner, "ownership must be transferred"},
+			{"owner promotes an admin", 1, 3, models.GroupRoleAdmin, ""},
+		}
+		for _, c := range cases {
+			err := db.DBService.UpdateGroupMemberRole(models.UpdateGroupMemberRequest{GroupID: 1, MemberID: c.member, Role: c.role}, c.requester)
+			switch {
+			case c.wantErr == "" && err != nil:
+				t.Errorf("%s: unexpected error %v", c.name, err)
+			case c.wantErr != "" && (err == nil || err.Error() != c.wantErr):
+				t.Errorf("%s: expected %q, got %v", c.name, c.wantErr, err)
+			}
+		}
+
+		if role, _ := db.DBService.GetGroupRole(3, 1); role != models.GroupRoleAdmin {
+			t.Errorf("expected user 3 to be admin, got %s", role)
+		}
+	})
+
+	t.Run("removing members", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+
+		if err := db.DBService.DeleteGroupMember(models.LeaveGroupRequest{GroupID: 1, UserID: 4}, 3); err == nil {
+			t.Error("expected a moderator not to remove members")
+		}
+		if err := db.DBService.DeleteGroupMember(models.LeaveGroupRequest{GroupID: 1, UserID: 1}, 2); err == nil {
+			t.Error("expected an admin not to remove the owner")
+		}
+		if err := db.DBService.DeleteGroupMember(models.LeaveGroupRequest{GroupID: 1, UserID: 4}, 2); err != nil {
+			t.Errorf("expected an admin to remove a member: %v", err)
+		}
+		if err := db.DBService.DeleteGroupMember(models.LeaveGroupRequest{GroupID: 1, UserID: 5}, 5); err != nil {
+			t.Errorf("expected members to leave by themselves: %v", err)
+		}
+	})
+
+	t.Run("handlers use the matrix", func(t *testing.T) {
+		setupGroupRolesTestDB(t)
+
+		post := func(userID int) int {
+			body, _ := json.Marshal(models.CreateGroupPostRequest{GroupID: 1, Title: "Trail report", Body: "Muddy but fine"})
+			rr := httptest.NewRecorder()
+			api.CreateGroupPostHandler(rr, asUser(httptest.NewRequest(http.MethodPost, "/api/group/post", bytes.NewReader(body)), userID))
+			return rr.Code
+		}
+		if code := post(5); code != http.StatusForbidden {
+			t.Errorf("expected 403 for a restricted member, got %d", code)
+		}
+		if code := post(4); code != http.StatusCreated {
+			t.Errorf("expected 201 for a member, got %d", code)
+		}
+
+		update := func(userID int, role, permission string) int {
+			body, _ := json.Marshal(map[string]any{
+				"group_id":  1,
+				"overrides": []map[string]any{{"role": role, "permission": permission, "allowed": false}},
+			})
+			rr := httptest.NewRecorder()
+			api.UpdateGroupPermissionsHandler(rr, asUser(httptest.NewRequest(http.MethodPut, "/api/group/permissions", bytes.NewReader(body)), userID))
+			return rr.Code
+		}
+		if code := update(2, models.GroupRoleAdmin, models.GroupPermPin); code != http.StatusForbidden {
+			t.Errorf("expected 403 when an admin changes the admin row, got %d", code)
+		}
+		if code := update(3, models.GroupRoleMember, models.GroupPermPost); code != http.StatusForbidden {
+			t.Errorf("expected 403 for a moderator, got %d", code)
+		}
+		if code := update(1, models.GroupRoleOwner, models.GroupPermPost); code != http.StatusBadRequest {
+			t.Errorf("expected 400 for the owner row, got %d", code)
+		}
+		if code := update(2, models.GroupRoleMember, models.GroupPermPost); code != http.StatusOK {
+			t.Errorf("expected 200 when an admin changes the member row, got %d", code)
+		}
+		if code := post(4); code != http.StatusForbidden {
+			t.Errorf("expected members to lose posting after the override, got %d", code)
+		}
+
+		rr := httptest.NewRecorder()
+		api.GetGroupPermissionsHandler(rr, asUser(httptest.NewRequest(http.MethodGet, "/api/group/permissions?group_id=1", nil), 4))
+		var resp models.GroupPermissionsResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Role != models.GroupRoleMember || resp.Permissions[models.GroupRoleMember][models.GroupPermPost] || !resp.Permissions[models.GroupRoleOwner][models.GroupPermPost] {
+			t.Errorf("unexpected permissions response: %+v", resp)
+		}
+	})
+}
+
+func TestGroupRequireAdmin2FA(t *testing.T) {
+	setupGroupRolesTestDB(t)
+	title := "Climbers"
+	required := true
+	req := models.UpdateGroupRequest{Title: &title, RequireAdmin2FA: &required}
+
+	// Neither the owner nor the admin has 2FA, so nothing of the update is kept
+	if rr := callGroupHandler(api.UpdateGroupHandler, http.MethodPut, "/api/group?id=1", req, 1); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+	}
+	group, err := db.DBService.GetGroupByID(1)
+	if err != nil {
+		t.Fatalf("GetGroupByID failed: %v", err)
+	}
+	if group.Title != "Hikers" {
+		t.Errorf("expected the title to be left as is, got %q", group.Title)
+	}
+
+	seedTestDB(t, `INSERT INTO user_two_factor (user_id, secret, enabled) VALUES (1, 'x', TRUE), (2, 'x', TRUE)`)
+	if rr := callGroupHandler(api.UpdateGroupHandler, http.MethodPut, "/api/group?id=1", req, 1); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if on, _ := db.DBService.GetGroupRequireAdmin2FA(1); !on {
+		t.Error("expected the requirement to be on")
+	}
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+)
+
+func setupLoginGuardTestDB(t *testing.T) *db.Service {
+	return &db.Service{DB: newTestDB(t)}
+}
+
+func TestLoginGuard(t *testing.T) {
+	policy := db.LoginGuardPolicy{
+		BackoffThreshold: 2,
+		BaseDelay:        time.Second,
+		MaxDelay:         10 * time.Second,
+		MaxAttempts:      6,
+		LockoutDuration:  time.Hour,
+		ResetAfter:       24 * time.Hour,
+	}
+
+	t.Run("backs off exponentially then locks and notifies once", func(t *testing.T) {
+		s := setupLoginGuardTestDB(t)
+
+		expectedDelays := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+		for i, want := range expectedDelays {
+			res, err := s.RecordFailedLogin(1, "127.0.0.1", policy)
+			if err != nil {
+				t.Fatalf("RecordFailedLogin failed: %v", err)
+			}
+			wait, _ := s.GetLoginRetryAfter(1)
+			if want == 0 && wait != 0 {
+				t.Errorf("failure %d: expected no delay, got %v", i+1, wait)
+			}
+			if want > 0 && (wait <= want-time.Second || wait > want) {
+				t.Errorf("failure %d: expected delay close to %v, got %v", i+1, want, wait)
+			}
+			if res.Locked || res.Notify {
+				t.Errorf("failure %d: account should not be locked yet", i+1)
+			}
+		}
+
+		res, err := s.RecordFailedLogin(1, "127.0.0.1", policy)
+		if err != nil {
+			t.Fatalf("RecordFailedLogin failed: %v", err)
+		}
+		if !res.Locked || !res.Notify {
+			t.Error("expected the sixth failure to lock the account and notify the owner")
+		}
+		if wait, _ := s.GetLoginRetryAfter(1); wait < 59*time.Minute {
+			t.Errorf("expected lockout of about an hour, got %v", wait)
+		}
+
+		res, _ = s.RecordFailedLogin(1, "127.0.0.1", policy)
+		if res.Notify {
+			t.Error("owner should only be notified once per lockout")
+		}
+	})
+
+	t.Run("successful login clears the state", func(t *testing.T) {
+		s := setupLoginGuardTestDB(t)
+		for i := 0; i < 4; i++ {
+			s.RecordFailedLogin(1, "127.0.0.1", policy)
+		}
+		if err := s.ResetFailedLogins(1); err != nil {
+			t.Fatalf("ResetFailedLogins failed: %v", err)
+		}
+		if wait, _ := s.GetLoginRetryAfter(1); wait != 0 {
+			t.Errorf("expected no delay after reset, got %v", wait)
+		}
+		res, _ := s.RecordFailedLogin(1, "127.0.0.1", policy)
+		if res.FailedCount != 1 {
+			t.Errorf("expected count to restart, got %d", res.FailedCount)
+		}
+	})
+}
+
+func TestLoginLockoutAnswer(t *testing.T) {
+	setupTwoFactorLoginTestDB(t)
+	if _, err := db.DBService.DB.Exec(`INSERT INTO login_guards (user_id, failed_count, locked_until) VALUES (1, 10, ?)`, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A locked account, even with the right password, can't be told from an unknown one
+	unknown := postJSON(api.LoginHandler, "/auth/login", models.LoginRequest{Username: "nobody", Password: "password"})
+	for _, password := range []string{"password", "wrong"} {
+		rr := postJSON(api.LoginHandler, "/auth/login", models.LoginRequest{Username: "alice", Password: password})
+		if rr.Code != unknown.Code || rr.Body.String() != unknown.Body.String() || rr.Header().Get("Retry-After") != "" {
+			t.Errorf("expected %d %q, got %d %q", unknown.Code, unknown.Body.String(), rr.Code, rr.Body.String())
+		}
+	}
+	if unknown.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an unknown account, got %d", unknown.Code)
+	}
+}
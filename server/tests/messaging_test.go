@@ -264,54 +264,8 @@ func TestWebSocketMessaging(t *testing.T) {
 
 // Helper functions
 func setupTestDB(t *testing.T) *sql.DB {
-	// Create in-memory SQLite database for testing
-	testDB, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("Failed to create test database: %v", err)
-	}
-
-	// Create necessary tables for testing
-	_, err = testDB.Exec(`
-		CREATE TABLE users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			nickname VARCHAR(30) UNIQUE NOT NULL,
-			first_name VARCHAR(50) NOT NULL,
-			last_name VARCHAR(50) NOT NULL,
-			email VARCHAR(70) UNIQUE NOT NULL,
-			password VARCHAR(255) NOT NULL,
-			date_of_birth DATE NOT NULL,
-			avatar VARCHAR(255),
-			bio TEXT,
-			is_private BOOLEAN DEFAULT FALSE NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			followers INT DEFAULT 0,
-			followed INT DEFAULT 0
-		);
-		
-		CREATE TABLE sessions (
-			token TEXT PRIMARY KEY,
-			user_id INTEGER NOT NULL,
-			expires_at DATETIME NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
-		);
-		
-		CREATE TABLE private_messages (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			sender_id INTEGER NOT NULL,
-			receiver_id INTEGER NOT NULL,
-			body TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-	`)
-	if err != nil {
-		t.Fatalf("Failed to create tables: %v", err)
-	}
-
-	// Initialize database service with test database
-	db.DBService = &db.Service{DB: testDB}
-
-	return testDB
+	// In-memory database with the schema of the migrations, used by the database service
+	return useTestDB(t, "")
 }
 
 func createTestUser(t *testing.T, db *sql.DB, email, password string) int {
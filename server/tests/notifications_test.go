@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+func TestNotificationsService(t *testing.T) {
+	t.Run("encodes typed payloads with version and message", func(t *testing.T) {
+		useTestDB(t, "")
+
+		id, err := notifications.Send(2, &notifications.FollowRequest{
+			RequesterID:       1,
+			RequesterNickname: `Bob "the builder"`,
+		})
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+
+		n, err := db.DBService.GetNotificationByID(id)
+		if err != nil {
+			t.Fatalf("GetNotificationByID failed: %v", err)
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(n.Data), &data); err != nil {
+			t.Fatalf("stored data is not valid JSON: %v (%s)", err, n.Data)
+		}
+		if data["v"] != float64(notifications.SchemaVersion) || data["type"] != notifications.TypeFollowRequest {
+			t.Errorf("missing header fields: %v", data)
+		}
+		if data["message"] != `Bob "the builder" wants to follow you` {
+			t.Errorf("unexpected message %q", data["message"])
+		}
+		if data["requester_id"] != float64(1) {
+			t.Errorf("unexpected requester_id %v", data["requester_id"])
+		}
+	})
+
+	t.Run("aggregates unread likes of the same post", func(t *testing.T) {
+		useTestDB(t, "")
+
+		var firstID int64
+		for i, name := range []string{"Carol", "Dave", "Erin", "Frank", "Alice"} {
+			id, err := notifications.Send(1, notifications.NewPostLike(10, notifications.Actor{ID: int64(i + 2), Nickname: name}))
+			if err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			if i == 0 {
+				firstID = id
+			} else if id != firstID {
+				t.Fatalf("like %d created a new notification instead of aggregating", i+1)
+			}
+		}
+
+		n, _ := db.DBService.GetNotificationByID(firstID)
+		payload, err := notifications.Decode(n.Type, n.Data)
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		like := payload.(*notifications.PostLike)
+		if like.Message != "Alice and 4 others liked your post" {
+			t.Errorf("unexpected message %q", like.Message)
+		}
+		if like.ActorCount != 5 || len(like.Actors) != 3 || like.Actors[0].Nickname != "Alice" {
+			t.Errorf("unexpected actors %+v (count %d)", like.Actors, like.ActorCount)
+		}
+
+		// A like on another post or after reading starts a new notification
+		otherID, _ := notifications.Send(1, notifications.NewPostLike(11, notifications.Actor{ID: 2, Nickname: "Carol"}))
+		if otherID == firstID {
+			t.Error("likes of different posts must not be aggregated")
+		}
+		db.DBService.MarkNotificationRead(firstID)
+		nextID, _ := notifications.Send(1, notifications.NewPostLike(10, notifications.Actor{ID: 9, Nickname: "Grace"}))
+		if nextID == firstID {
+			t.Error("read notifications must not be aggregated")
+		}
+	})
+
+	t.Run("does not count the same liker twice", func(t *testing.T) {
+		useTestDB(t, "")
+
+		notifications.Send(1, notifications.NewPostLike(10, notifications.Actor{ID: 2, Nickname: "Carol"}))
+		id, _ := notifications.Send(1, notifications.NewPostLike(10, notifications.Actor{ID: 2, Nickname: "Carol"}))
+
+		n, _ := db.DBService.GetNotificationByID(id)
+		payload, _ := notifications.Decode(n.Type, n.Data)
+		if like := payload.(*notifications.PostLike); like.ActorCount != 1 || like.Message != "Carol liked your post" {
+			t.Errorf("unexpected aggregation: count %d, message %q", like.ActorCount, like.Message)
+		}
+	})
+
+	t.Run("does not count again a liker no longer shown by name", func(t *testing.T) {
+		useTestDB(t, "")
+
+		var id int64
+		for i, name := range []string{"Carol", "Dave", "Erin", "Frank", "Carol"} {
+			id, _ = notifications.Send(1, notifications.NewPostLike(10, notifications.Actor{ID: int64(2 + i%4), Nickname: name}))
+		}
+
+		n, _ := db.DBService.GetNotificationByID(id)
+		payload, _ := notifications.Decode(n.Type, n.Data)
+		if like := payload.(*notifications.PostLike); like.ActorCount != 4 || like.Message != "Carol and 3 others liked your post" {
+			t.Errorf("unexpected aggregation: count %d, message %q", like.ActorCount, like.Message)
+		}
+	})
+
+	t.Run("withdraws by payload field", func(t *testing.T) {
+		useTestDB(t, "")
+
+		keep, _ := notifications.Send(2, &notifications.FollowRequest{RequesterID: 3, RequesterNickname: "Carol"})
+		drop, _ := notifications.Send(2, &notifications.FollowRequest{RequesterID: 1, RequesterNickname: "Bob"})
+
+		if err := notifications.Withdraw(2, notifications.TypeFollowRequest, "requester_id", 1); err != nil {
+			t.Fatalf("Withdraw failed: %v", err)
+		}
+		if _, err := db.DBService.GetNotificationByID(drop); err == nil {
+			t.Error("expected the notification to be deleted")
+		}
+		if _, err := db.DBService.GetNotificationByID(keep); err != nil {
+			t.Error("other notifications must be kept")
+		}
+	})
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	t.Run("drops notifications of a type turned off", func(t *testing.T) {
+		useTestDB(t, "")
+		db.DBService.SetNotificationChannels(2, map[string]string{notifications.TypeFollowRequest: models.NotificationChannelOff})
+
+		id, err := notifications.Send(2, &notifications.FollowRequest{RequesterID: 1, RequesterNickname: "Bob"})
+		if err != nil || id != 0 {
+			t.Fatalf("expected the notification to be dropped, got id %d, err %v", id, err)
+		}
+
+		// Other users and other types are unaffected
+		if id, _ := notifications.Send(3, &notifications.FollowRequest{RequesterID: 1, RequesterNickname: "Bob"}); id == 0 {
+			t.Error("expected the notification of another user to be stored")
+		}
+		if id, _ := notifications.Send(2, &notifications.FollowAccepted{TargetID: 1, TargetNickname: "Bob"}); id == 0 {
+			t.Error("expected a notification of another type to be stored")
+		}
+	})
+
+	t.Run("security alerts ignore preferences", func(t *testing.T) {
+		useTestDB(t, "")
+		db.DBService.SetNotificationChannels(2, map[string]string{notifications.TypeSecurityAlert: models.NotificationChannelOff})
+
+		if id, _ := notifications.Send(2, &notifications.SecurityAlert{Reason: "account_locked"}); id == 0 {
+			t.Error("security alerts must always be delivered")
+		}
+		if notifications.IsConfigurable(notifications.TypeSecurityAlert) {
+			t.Error("security alerts must not be configurable")
+		}
+	})
+
+	t.Run("quiet hours use the user's timezone and wrap past midnight", func(t *testing.T) {
+		qh := &models.QuietHours{Start: "22:00", End: "07:00", Timezone: "Europe/Paris"}
+		if err := notifications.ValidateQuietHours(qh); err != nil {
+			t.Fatalf("ValidateQuietHours failed: %v", err)
+		}
+
+		cases := map[string]bool{
+			"2024-01-15T21:30:00Z": true,  // 22:30 in Paris
+			"2024-01-15T05:59:00Z": true,  // 06:59 in Paris
+			"2024-01-15T06:00:00Z": false, // 07:00 in Paris
+			"2024-01-15T12:00:00Z": false,
+		}
+		for at, want := range cases {
+			now, _ := time.Parse(time.RFC3339, at)
+			if got := notifications.InQuietHours(qh, now); got != want {
+				t.Errorf("%s: expected %v, got %v", at, want, got)
+			}
+		}
+
+		if err := notifications.ValidateQuietHours(&models.QuietHours{Start: "22:00", End: "07:00", Timezone: "Mars/Olympus"}); err == nil {
+			t.Error("expected an unknown timezone to be refused")
+		}
+	})
+
+	t.Run("group mutes expire", func(t *testing.T) {
+		useTestDB(t, "")
+		past := time.Now().Add(-time.Minute)
+		db.DBService.MuteGroupNotifications(1, 5, nil)
+		db.DBService.MuteGroupNotifications(1, 6, &past)
+
+		if muted, _ := db.DBService.IsGroupMuted(1, 5); !muted {
+			t.Error("expected group 5 to be muted")
+		}
+		if muted, _ := db.DBService.IsGroupMuted(1, 6); muted {
+			t.Error("expected the mute of group 6 to be expired")
+		}
+		if mutes, _ := db.DBService.GetMutedGroups(1); len(mutes) != 1 || mutes[0].GroupID != 5 {
+			t.Errorf("unexpected muted groups %+v", mutes)
+		}
+	})
+}
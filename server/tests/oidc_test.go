@@ -0,0 +1,495 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	fakeClientID     = "social-network"
+	fakeClientSecret = "s3cr3t"
+	fakeRedirectURL  = "http://localhost:8080/auth/oidc/callback"
+)
+
+type fakeAuthCode struct {
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// fakeIdP is a local OpenID provider implementing discovery, authorize, token and JWKS endpoints
+type fakeIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthCode
+
+	// Knobs to produce invalid tokens
+	subject       string
+	email         string
+	emailVerified bool
+	audience      string
+	nonceOverride string
+	expiresIn     time.Duration
+	signingKey    *rsa.PrivateKey
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	idp := &fakeIdP{
+		key:           key,
+		kid:           "test-key",
+		codes:         map[string]fakeAuthCode{},
+		subject:       "idp-user-1",
+		email:         "alice@example.com",
+		emailVerified: true,
+		audience:      fakeClientID,
+		expiresIn:     time.Hour,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	// Same document served under another path, so its issuer does not match that path
+	mux.HandleFunc("/tenant/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *fakeIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                           idp.server.URL,
+		"authorization_endpoint":           idp.server.URL + "/authorize",
+		"token_endpoint":                   idp.server.URL + "/token",
+		"jwks_uri":                         idp.server.URL + "/jwks",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+func (idp *fakeIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" || q.Get("client_id") != fakeClientID || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := utils.RandomURLToken(16)
+	idp.mu.Lock()
+	idp.codes[code] = fakeAuthCode{nonce: q.Get("nonce"), codeChallenge: q.Get("code_challenge"), redirectURI: q.Get("redirect_uri")}
+	idp.mu.Unlock()
+
+	http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != fakeClientID || secret != fakeClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	r.ParseForm()
+
+	idp.mu.Lock()
+	auth, found := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.codeChallenge || r.PostForm.Get("redirect_uri") != auth.redirectURI {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	nonce := auth.nonce
+	if idp.nonceOverride != "" {
+		nonce = idp.nonceOverride
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idp.idToken(nonce),
+	})
+}
+
+func (idp *fakeIdP) idToken(nonce string) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"sub":            idp.subject,
+		"aud":            idp.audience,
+		"iat":            now.Unix(),
+		"exp":            now.Add(idp.expiresIn).Unix(),
+		"nonce":          nonce,
+		"email":          idp.email,
+		"email_verified": idp.emailVerified,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+
+	key := idp.key
+	if idp.signingKey != nil {
+		key = idp.signingKey
+	}
+	signed, _ := token.SignedString(key)
+	return signed
+}
+
+func (idp *fakeIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": idp.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (idp *fakeIdP) provider() *utils.OIDCProvider {
+	return utils.NewOIDCProvider(utils.OIDCConfig{
+		Name:         "fake",
+		IssuerURL:    idp.server.URL,
+		ClientID:     fakeClientID,
+		ClientSecret: fakeClientSecret,
+		RedirectURL:  fakeRedirectURL,
+	})
+}
+
+// authorizeCode follows the authorization URL on the fake IdP and returns the code and state it redirects with
+func (idp *fakeIdP) authorizeCode(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect from authorize, got %d", resp.StatusCode)
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+// runProviderFlow performs the relying party side of the flow without HTTP handlers
+func runProviderFlow(t *testing.T, idp *fakeIdP, p *utils.OIDCProvider) (*utils.OIDCClaims, error) {
+	ctx := context.Background()
+	nonce, _ := utils.RandomURLToken(16)
+	verifier, _ := utils.RandomURLToken(32)
+
+	authURL, err := p.AuthCodeURL(ctx, "state-1", nonce, utils.PKCEChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	code, state := idp.authorizeCode(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("state not round-tripped, got %q", state)
+	}
+
+	token, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	return p.VerifyIDToken(ctx, token.IDToken, nonce)
+}
+
+func TestOIDCProvider(t *testing.T) {
+	t.Run("valid flow returns verified claims", func(t *testing.T) {
+		idp := newFakeIdP(t)
+		claims, err := runProviderFlow(t, idp, idp.provider())
+		if err != nil {
+			t.Fatalf("VerifyIDToken failed: %v", err)
+		}
+		if claims.Subject != "idp-user-1" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+			t.Errorf("unexpected claims: %+v", claims)
+		}
+	})
+
+	t.Run("rejects nonce mismatch", func(t *testing.T) {
+		idp := newFakeIdP(t)
+		idp.nonceOverride = "attacker-nonce"
+		if _, err := runProviderFlow(t, idp, idp.provider()); err == nil {
+			t.Error("expected nonce mismatch to be rejected")
+		}
+	})
+
+	t.Run("rejects wrong audience", func(t *testing.T) {
+		idp := newFakeIdP(t)
+		idp.audience = "another-client"
+		if _, err := runProviderFlow(t, idp, idp.provider()); err == nil {
+			t.Error("expected wrong audience to be rejected")
+		}
+	})
+
+	t.Run("rejects expired token", func(t *testing.T) {
+		idp := newFakeIdP(t)
+		idp.expiresIn = -time.Hour
+		if _, err := runProviderFlow(t, idp, idp.provider()); err == nil {
+			t.Error("expected expired token to be rejected")
+		}
+	})
+
+	t.Run("rejects token signed with an unknown key", func(t *testing.T) {
+		idp := newFakeIdP(t)
+		other, _ := rsa.GenerateKey(rand.Reader, 2048)
+		idp.signingKey = other
+		if _, err := runProviderFlow(t, idp, idp.provider()); err == nil {
+			t.Error("expected forged signature to be rejected")
+		}
+	})
+
+	t.Run("rejects wrong PKCE verifier", func(t *testing.T) {
+		idp := newFakeIdP(t)
+		p := idp.provider()
+		ctx := context.Background()
+		authURL, _ := p.AuthCodeURL(ctx, "s", "n", utils.PKCEChallenge("right-verifier"))
+		code, _ := idp.authorizeCode(t, authURL)
+		if _, err := p.Exchange(ctx, code, "wrong-verifier"); err == nil {
+			t.Error("expected exchange with wrong verifier to fail")
+		}
+	})
+
+	t.Run("rejects discovery with mismatched issuer", func(t *testing.T) {
+		idp := newFakeIdP(t)
+		p := utils.NewOIDCProvider(utils.OIDCConfig{Name: "fake", IssuerURL: idp.server.URL + "/tenant", ClientID: fakeClientID})
+		_, err := p.Discover(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+			t.Errorf("expected issuer mismatch, got %v", err)
+		}
+	})
+}
+
+func setupOIDCTestDB(t *testing.T) *sql.DB {
+	testDB := useTestDB(t, `
+		INSERT INTO users (nickname, first_name, last_name, email, password, date_of_birth)
+		VALUES ('alice', 'Alice', 'Liddell', 'alice@example.com', 'x', '1990-01-01');
+	`)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT key: %v", err)
+	}
+	utils.SetConfig(time.Hour, key, &key.PublicKey, "development", 280, 10)
+
+	return testDB
+}
+
+// signInThroughHandlers runs the login and callback handlers against the fake IdP and returns the final redirect
+func signInThroughHandlers(t *testing.T, idp *fakeIdP) *httptest.ResponseRecorder {
+	code, state, cookie := startSignIn(t, idp)
+	return oidcCallback(code, state, cookie)
+}
+
+// startSignIn goes through the login handler and the IdP, returning the code, the state and the state cookie
+func startSignIn(t *testing.T, idp *fakeIdP) (string, string, *http.Cookie) {
+	login := httptest.NewRecorder()
+	api.OIDCLoginHandler(login, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	if login.Code != http.StatusFound {
+		t.Fatalf("expected redirect to the IdP, got %d: %s", login.Code, login.Body.String())
+	}
+	var stateCookie *http.Cookie
+	for _, cookie := range login.Result().Cookies() {
+		if cookie.Name == "oidc_state" {
+			stateCookie = cookie
+		}
+	}
+	if stateCookie == nil || !stateCookie.HttpOnly || !stateCookie.Secure || stateCookie.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("expected an HttpOnly, Secure, SameSite=Lax state cookie, got %+v", stateCookie)
+	}
+
+	code, state := idp.authorizeCode(t, login.Header().Get("Location"))
+	return code, state, stateCookie
+}
+
+func oidcCallback(code, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet,
+		"/auth/oidc/callback?code="+url.QueryEscape(code)+"&state="+url.QueryEscape(state), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	callback := httptest.NewRecorder()
+	api.OIDCCallbackHandler(callback, req)
+	return callback
+}
+
+func hasSessionCookie(rr *httptest.ResponseRecorder) bool {
+	for _, cookie := range rr.Result().Cookies() {
+		if cookie.Name == "jwt_token" {
+			return true
+		}
+	}
+	return false
+}
+
+func redirectParam(t *testing.T, rr *httptest.ResponseRecorder, key string) string {
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected redirect to the client, got %d: %s", rr.Code, rr.Body.String())
+	}
+	loc, _ := url.Parse(rr.Header().Get("Location"))
+	return loc.Query().Get(key)
+}
+
+func TestOIDCCallbackHandler(t *testing.T) {
+	t.Run("links existing account by verified email and starts a session", func(t *testing.T) {
+		testDB := setupOIDCTestDB(t)
+		defer testDB.Close()
+		idp := newFakeIdP(t)
+		api.SetOIDCProvider(idp.provider(), "http://localhost:3000/")
+
+		rr := signInThroughHandlers(t, idp)
+		if got := redirectParam(t, rr, "login"); got != "success" {
+			t.Fatalf("expected login=success, got %q (%s)", got, rr.Header().Get("Location"))
+		}
+		if !hasSessionCookie(rr) {
+			t.Error("expected session cookie to be set")
+		}
+
+		var linked int
+		testDB.QueryRow(`SELECT COUNT(*) FROM user_identities WHERE provider = 'fake' AND subject = 'idp-user-1' AND user_id = 1`).Scan(&linked)
+		if linked != 1 {
+			t.Error("expected identity to be linked to the existing user")
+		}
+
+		// Second sign-in goes through the stored identity even if the email changed
+		idp.email = "alice@new-domain.example"
+		rr = signInThroughHandlers(t, idp)
+		if got := redirectParam(t, rr, "login"); got != "success" {
+			t.Errorf("expected linked identity to sign in, got %q", rr.Header().Get("Location"))
+		}
+	})
+
+	t.Run("refuses unverified email", func(t *testing.T) {
+		testDB := setupOIDCTestDB(t)
+		defer testDB.Close()
+		idp := newFakeIdP(t)
+		idp.emailVerified = false
+		api.SetOIDCProvider(idp.provider(), "http://localhost:3000/")
+
+		if got := redirectParam(t, signInThroughHandlers(t, idp), "oidc_error"); got != "email_not_verified" {
+			t.Errorf("expected email_not_verified, got %q", got)
+		}
+	})
+
+	t.Run("refuses unknown email", func(t *testing.T) {
+		testDB := setupOIDCTestDB(t)
+		defer testDB.Close()
+		idp := newFakeIdP(t)
+		idp.email = "bob@example.com"
+		api.SetOIDCProvider(idp.provider(), "http://localhost:3000/")
+
+		if got := redirectParam(t, signInThroughHandlers(t, idp), "oidc_error"); got != "account_not_found" {
+			t.Errorf("expected account_not_found, got %q", got)
+		}
+	})
+
+	t.Run("rejects unknown or replayed state", func(t *testing.T) {
+		testDB := setupOIDCTestDB(t)
+		defer testDB.Close()
+		idp := newFakeIdP(t)
+		api.SetOIDCProvider(idp.provider(), "http://localhost:3000/")
+
+		forged := &http.Cookie{Name: "oidc_state", Value: "forged"}
+		if got := redirectParam(t, oidcCallback("abc", "forged", forged), "oidc_error"); got != "invalid_state" {
+			t.Errorf("expected invalid_state, got %q", got)
+		}
+
+		code, state, cookie := startSignIn(t, idp)
+		if got := redirectParam(t, oidcCallback(code, state, cookie), "login"); got != "success" {
+			t.Fatalf("expected login=success, got %q", got)
+		}
+		if got := redirectParam(t, oidcCallback(code, state, cookie), "oidc_error"); got != "invalid_state" {
+			t.Errorf("expected the replayed state to be refused, got %q", got)
+		}
+	})
+
+	t.Run("rejects a callback without the state cookie of the browser", func(t *testing.T) {
+		testDB := setupOIDCTestDB(t)
+		defer testDB.Close()
+		idp := newFakeIdP(t)
+		api.SetOIDCProvider(idp.provider(), "http://localhost:3000/")
+
+		// An attacker's own code and state, sent to a victim who never started the sign-in
+		code, state, _ := startSignIn(t, idp)
+		rr := oidcCallback(code, state, nil)
+		if got := redirectParam(t, rr, "oidc_error"); got != "invalid_state" {
+			t.Errorf("expected invalid_state, got %q", got)
+		}
+
+		// Or who started another one
+		code, state, _ = startSignIn(t, idp)
+		_, _, other := startSignIn(t, idp)
+		rr = oidcCallback(code, state, other)
+		if got := redirectParam(t, rr, "oidc_error"); got != "invalid_state" {
+			t.Errorf("expected invalid_state, got %q", got)
+		}
+		if hasSessionCookie(rr) {
+			t.Error("no session must be issued")
+		}
+	})
+
+	t.Run("refuses a locked account", func(t *testing.T) {
+		testDB := setupOIDCTestDB(t)
+		defer testDB.Close()
+		testDB.Exec(`INSERT INTO login_guards (user_id, failed_count, locked_until) VALUES (1, 10, ?)`, time.Now().Add(time.Hour))
+		idp := newFakeIdP(t)
+		api.SetOIDCProvider(idp.provider(), "http://localhost:3000/")
+
+		rr := signInThroughHandlers(t, idp)
+		if got := redirectParam(t, rr, "oidc_error"); got != "account_locked" {
+			t.Errorf("expected account_locked, got %q", got)
+		}
+		if hasSessionCookie(rr) {
+			t.Error("no session must be issued to a locked account")
+		}
+	})
+
+	t.Run("requires the second factor when 2FA is enabled", func(t *testing.T) {
+		testDB := setupOIDCTestDB(t)
+		defer testDB.Close()
+		testDB.Exec(`INSERT INTO user_two_factor (user_id, secret, enabled) VALUES (1, 'JBSWY3DPEHPK3PXP', TRUE)`)
+		idp := newFakeIdP(t)
+		api.SetOIDCProvider(idp.provider(), "http://localhost:3000/")
+
+		rr := signInThroughHandlers(t, idp)
+		loc, _ := url.Parse(rr.Header().Get("Location"))
+		fragment, _ := url.ParseQuery(loc.Fragment)
+		if fragment.Get("two_factor_required") != "1" || fragment.Get("challenge_token") == "" {
+			t.Errorf("expected a 2FA challenge, got %s", rr.Header().Get("Location"))
+		}
+		if hasSessionCookie(rr) {
+			t.Error("session must not be issued before the second factor")
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/websockets"
+)
+
+func getPoll(t *testing.T, query string, userID int, want int) *models.Poll {
+	t.Helper()
+	return decodeCall[models.Poll](t, api.GetPollHandler, http.MethodGet, "/api/poll?"+query, nil, userID, want)
+}
+
+func votePoll(t *testing.T, pollID int64, optionIDs []int64, userID int, want int) *models.Poll {
+	t.Helper()
+	return decodeCall[models.Poll](t, api.VotePollHandler, http.MethodPost, "/api/poll/vote",
+		models.VotePollRequest{PollID: pollID, OptionIDs: optionIDs}, userID, want)
+}
+
+func pollVotes(poll *models.Poll) []int {
+	votes := make([]int, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		votes = append(votes, option.Votes)
+	}
+	return votes
+}
+
+// nextSocketMessage waits for a message of the given type on a client, skipping the others
+func nextSocketMessage(t *testing.T, client *websockets.Client, messageType string) websockets.Message {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case message := <-client.Send:
+			if message.Type == messageType {
+				return message
+			}
+		case <-timeout:
+			t.Fatalf("no %s message received", messageType)
+		}
+	}
+}
+
+func TestGroupPostPoll(t *testing.T) {
+	setupGroupRolesTestDB(t)
+
+	post := map[string]any{
+		"group_id": 1, "title": "Lunch", "body": "Where do we go?",
+		"poll": map[string]any{"question": "Where?", "options": []string{"Pizza", "Sushi", " pizza "}},
+	}
+	if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for duplicate options, got %d", rr.Code)
+	}
+	post["poll"] = map[string]any{"question": "Where?", "options": []string{"Pizza", "Sushi", "Tacos"}, "duration_minutes": 60}
+	if rr := callGroupHandler(api.CreateGroupPostHandler, http.MethodPost, "/api/group/post", post, 4); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Members connected to the group receive the new tallies
+	websockets.InitHub(db.DBService.DB)
+	client := &websockets.Client{ID: "poll-socket", UserID: 3, Send: make(chan websockets.Message, 50), Groups: map[string]*websockets.Group{}}
+	websockets.GetHub().RegisterClient(client)
+	t.Cleanup(func() { websockets.GetHub().UnregisterClient(client) })
+
+	poll := getPoll(t, "group_post_id=1", 5, http.StatusOK)
+	if len(poll.Options) != 3 || poll.Closed || poll.ClosesAt == "" || len(poll.UserVotes) != 0 {
+		t.Fatalf("unexpected poll: %+v", poll)
+	}
+	getPoll(t, "group_post_id=1", 6, http.StatusForbidden)
+	pizza, sushi := poll.Options[0].ID, poll.Options[1].ID
+
+	votePoll(t, poll.ID, []int64{pizza, sushi}, 5, http.StatusBadRequest)
+	votePoll(t, poll.ID, []int64{999}, 5, http.StatusBadRequest)
+	votePoll(t, poll.ID, []int64{pizza}, 5, http.StatusOK)
+	poll = votePoll(t, poll.ID, []int64{sushi}, 5, http.StatusOK)
+	if got := pollVotes(poll); got[0] != 0 || got[1] != 1 || poll.Voters != 1 || len(poll.UserVotes) != 1 || poll.UserVotes[0] != sushi {
+		t.Fatalf("expected the vote to move to sushi, got %v %+v", got, poll)
+	}
+	poll = votePoll(t, poll.ID, []int64{sushi}, 3, http.StatusOK)
+	if voters := poll.Options[1].Voters; len(voters) != 2 || voters[0].Nickname != "restricted" || voters[1].Nickname != "moderator" {
+		t.Fatalf("expected named voters in voting order, got %+v", voters)
+	}
+
+	update := nextSocketMessage(t, client, websockets.MessageTypePollUpdate)
+	for update.Data.(map[string]any)["voters"] != 2 {
+		update = nextSocketMessage(t, client, websockets.MessageTypePollUpdate)
+	}
+	if update.GroupID != "1" || update.Data.(map[string]any)["poll_id"] != poll.ID {
+		t.Fatalf("unexpected poll update: %+v", update)
+	}
+
+	// Past its closing time the tally is locked
+	if _, err := db.DBService.DB.Exec(`UPDATE polls SET closes_at = ? WHERE id = ?`, time.Now().UTC().Add(-time.Minute), poll.ID); err != nil {
+		t.Fatalf("Failed to move the closing time: %v", err)
+	}
+	votePoll(t, poll.ID, []int64{pizza}, 4, http.StatusConflict)
+	if _, err := db.DBService.DB.Exec(`DELETE FROM poll_votes WHERE user_id = 5`); err != nil {
+		t.Fatalf("Failed to delete a vote: %v", err)
+	}
+	poll = getPoll(t, "id=1", 4, http.StatusOK)
+	if got := pollVotes(poll); !poll.Closed || got[1] != 2 || poll.Voters != 2 {
+		t.Fatalf("expected the final results to stay, got %v %+v", got, poll)
+	}
+	if rr := callGroupHandler(api.ClosePollHandler, http.MethodPost, "/api/poll/close", models.ClosePollRequest{PollID: poll.ID}, 4); rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409 closing a closed poll, got %d", rr.Code)
+	}
+}
+
+func TestPollClosingAndVisibility(t *testing.T) {
+	setupGroupRolesTestDB(t)
+
+	// A private post seen by user 2 only, with an anonymous multiple choice poll
+	_, err := db.DBService.CreatePost(1, models.CreatePostRequest{
+		Title: "Weekend", Body: "Plans?", Visibility: "private", SelectedFollowers: []int64{2},
+		Poll: &models.CreatePollRequest{Question: "What do we do?", Options: []string{"Hike", "Movie", "Beach"},
+			MultipleChoice: true, Anonymous: true},
+	})
+	if err != nil {
+		t.Fatalf("CreatePost failed: %v", err)
+	}
+	poll := getPoll(t, "post_id=1", 2, http.StatusOK)
+	getPoll(t, "post_id=1", 3, http.StatusForbidden)
+	votePoll(t, poll.ID, []int64{poll.Options[0].ID}, 3, http.StatusForbidden)
+
+	poll = votePoll(t, poll.ID, []int64{poll.Options[0].ID, poll.Options[2].ID}, 2, http.StatusOK)
+	if got := pollVotes(poll); got[0] != 1 || got[1] != 0 || got[2] != 1 || poll.Voters != 1 {
+		t.Fatalf("expected two options for one voter, got %v %+v", got, poll)
+	}
+	if len(poll.Options[0].Voters) != 0 {
+		t.Fatal("expected an anonymous poll not to list voters")
+	}
+	poll = votePoll(t, poll.ID, []int64{}, 2, http.StatusOK)
+	if poll.Voters != 0 || len(poll.UserVotes) != 0 {
+		t.Fatalf("expected the votes to be withdrawn, got %+v", poll)
+	}
+	votePoll(t, poll.ID, []int64{poll.Options[1].ID}, 2, http.StatusOK)
+
+	// Only the creator closes early
+	closeReq := models.ClosePollRequest{PollID: poll.ID}
+	if rr := callGroupHandler(api.ClosePollHandler, http.MethodPost, "/api/poll/close", closeReq, 2); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 closing someone else's poll, got %d", rr.Code)
+	}
+	if rr := callGroupHandler(api.ClosePollHandler, http.MethodPost, "/api/poll/close", closeReq, 1); rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 closing, got %d: %s", rr.Code, rr.Body.String())
+	}
+	votePoll(t, poll.ID, []int64{poll.Options[0].ID}, 2, http.StatusConflict)
+	if poll = getPoll(t, "id=1", 1, http.StatusOK); !poll.Closed || poll.ClosedAt == "" || poll.Options[1].Votes != 1 {
+		t.Fatalf("expected a closed poll with its results, got %+v", poll)
+	}
+
+	// Group chat polls follow the membership and the archiving of the group
+	messageID, err := db.DBService.CreateGroupMessageWithPoll(1, 4, "Vote please", &models.CreatePollRequest{
+		Question: "Next meetup?", Options: []string{"Monday", "Friday"},
+	})
+	if err != nil {
+		t.Fatalf("CreateGroupMessageWithPoll failed: %v", err)
+	}
+	poll = getPoll(t, "group_message_id=1", 2, http.StatusOK)
+	if poll.GroupMessageID == nil || *poll.GroupMessageID != messageID || poll.GroupID == nil || *poll.GroupID != 1 {
+		t.Fatalf("expected the poll of the message, got %+v", poll)
+	}
+	getPoll(t, "group_message_id=1", 6, http.StatusForbidden)
+	if _, err := db.DBService.DB.Exec(`UPDATE groups SET status = 'archived' WHERE id = 1`); err != nil {
+		t.Fatalf("Failed to archive the group: %v", err)
+	}
+	votePoll(t, poll.ID, []int64{poll.Options[0].ID}, 2, http.StatusConflict)
+	getPoll(t, "group_message_id=2", 2, http.StatusNotFound)
+}
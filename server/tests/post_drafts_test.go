@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/notifications"
+)
+
+// setupFollowersTestDB makes users 2 and 3 follow the member (user 4), user 6 is still waiting for an answer
+func setupFollowersTestDB(t *testing.T) {
+	setupGroupRolesTestDB(t)
+	seedTestDB(t, `INSERT INTO follow_requests (requester_id, target_id, status) VALUES (2, 4, 'accepted'), (3, 4, 'accepted'), (6, 4, 'pending')`)
+}
+
+func draftCall(t *testing.T, handler http.HandlerFunc, method string, body any, userID int, want int) *models.PostDraft {
+	t.Helper()
+	return decodeCall[models.PostDraft](t, handler, method, "/api/post/draft", body, userID, want)
+}
+
+func TestPostDrafts(t *testing.T) {
+	setupFollowersTestDB(t)
+
+	draft := draftCall(t, api.CreatePostDraftHandler, http.MethodPost, map[string]any{"body": "Half an idea"}, 4, http.StatusCreated)
+	if draft.Status != models.PostDraftDraft || draft.Visibility != "public" || draft.Revision != 0 {
+		t.Fatalf("expected an unscheduled public draft, got %+v", draft)
+	}
+	if rr := callGroupHandler(api.GetPostDraftHandler, http.MethodGet, "/api/post/draft?id=1", nil, 5); rr.Code != http.StatusNotFound {
+		t.Errorf("expected someone else's draft to be hidden, got %d", rr.Code)
+	}
+
+	// Scheduling asks for a complete post and a time to come
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	draftCall(t, api.UpdatePostDraftHandler, http.MethodPut, map[string]any{"id": draft.ID, "title": "Idea", "publish_at": past}, 4, http.StatusBadRequest)
+	draftCall(t, api.UpdatePostDraftHandler, http.MethodPut, map[string]any{"id": draft.ID, "body": "", "publish_at": future}, 4, http.StatusBadRequest)
+	draft = draftCall(t, api.UpdatePostDraftHandler, http.MethodPut, map[string]any{"id": draft.ID, "title": "Idea", "publish_at": future}, 4, http.StatusOK)
+	if draft.Status != models.PostDraftScheduled || draft.Title != "Idea" || draft.Body != "Half an idea" || draft.Revision != 1 {
+		t.Fatalf("expected the draft scheduled with its body kept, got %+v", draft)
+	}
+	draft = draftCall(t, api.UpdatePostDraftHandler, http.MethodPut, map[string]any{"id": draft.ID, "publish_at": ""}, 4, http.StatusOK)
+	if draft.Status != models.PostDraftDraft || draft.PublishAt != "" {
+		t.Errorf("expected an empty publish_at to unschedule, got %+v", draft)
+	}
+
+	// Only followers can be picked for a private draft
+	private := map[string]any{"title": "Close", "body": "Friends only", "visibility": "private", "selected_followers": []int64{2, 6}}
+	draftCall(t, api.CreatePostDraftHandler, http.MethodPost, private, 4, http.StatusBadRequest)
+	private["selected_followers"] = []int64{2, 3}
+	if created := draftCall(t, api.CreatePostDraftHandler, http.MethodPost, private, 4, http.StatusCreated); len(created.SelectedFollowers) != 2 {
+		t.Errorf("expected the picked followers to be kept, got %v", created.SelectedFollowers)
+	}
+
+	// Restricted members cannot even draft into the group
+	draftCall(t, api.CreatePostDraftHandler, http.MethodPost, map[string]any{"group_id": 1, "title": "Hi"}, 5, http.StatusForbidden)
+
+	var drafts []models.PostDraft
+	rr := callGroupHandler(api.GetPostDraftHandler, http.MethodGet, "/api/post/draft", nil, 4)
+	json.NewDecoder(rr.Body).Decode(&drafts)
+	if len(drafts) != 2 {
+		t.Fatalf("expected 2 drafts, got %d", len(drafts))
+	}
+
+	rr = callGroupHandler(api.PublishPostDraftHandler, http.MethodPost, "/api/post/draft/publish", models.PostDraftIDRequest{ID: draft.ID}, 4)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected the draft to be published now, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if post, err := db.DBService.GetPostByID(1, 4); err != nil || post.Title != "Idea" {
+		t.Errorf("expected the published post, got %+v, %v", post, err)
+	}
+	draftCall(t, api.UpdatePostDraftHandler, http.MethodPut, map[string]any{"id": draft.ID, "title": "Again"}, 4, http.StatusNotFound)
+}
+
+func TestScheduledPostPublishing(t *testing.T) {
+	setupFollowersTestDB(t)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	later := time.Now().Add(2 * time.Hour)
+
+	draftCall(t, api.CreatePostDraftHandler, http.MethodPost, map[string]any{
+		"title": "Trip", "body": "Photos soon", "visibility": "private", "selected_followers": []int64{2, 3},
+		"poll": map[string]any{"question": "Next trip?", "options": []string{"Sea", "Mountains"}}, "publish_at": future,
+	}, 4, http.StatusCreated)
+	draftCall(t, api.CreatePostDraftHandler, http.MethodPost, map[string]any{
+		"group_id": 1, "title": "Meeting", "body": "Friday at noon", "is_announcement": true, "publish_at": future,
+	}, 2, http.StatusCreated)
+	unfollowed := draftCall(t, api.CreatePostDraftHandler, http.MethodPost, map[string]any{
+		"title": "Secret", "body": "Only for you", "visibility": "private", "selected_followers": []int64{3}, "publish_at": future,
+	}, 4, http.StatusCreated)
+
+	if published, err := api.PublishDueDrafts(time.Now()); err != nil || published != 0 {
+		t.Fatalf("expected nothing due yet, got %d, %v", published, err)
+	}
+
+	// The author changes their mind while the publisher holds the old version
+	due, err := db.DBService.GetDuePostDrafts(later)
+	if err != nil || len(due) != 3 {
+		t.Fatalf("expected 3 due drafts, got %d, %v", len(due), err)
+	}
+	draftCall(t, api.UpdatePostDraftHandler, http.MethodPut, map[string]any{"id": due[0].ID, "body": "Photos tonight"}, 4, http.StatusOK)
+	if _, _, err := db.DBService.PublishPostDraft(&due[0]); !errors.Is(err, db.ErrDraftChanged) {
+		t.Fatalf("expected the stale version to be refused, got %v", err)
+	}
+
+	// User 3 stops following before the secret post goes out
+	db.DBService.DB.Exec(`DELETE FROM follow_requests WHERE requester_id = 3`)
+
+	published, err := api.PublishDueDrafts(later)
+	if err != nil || published != 2 {
+		t.Fatalf("expected 2 drafts published, got %d, %v", published, err)
+	}
+
+	post, err := db.DBService.GetPostByID(1, 4)
+	if err != nil || post.Body != "Photos tonight" || post.Visibility != "private" {
+		t.Fatalf("expected the edited private post, got %+v, %v", post, err)
+	}
+	if viewers, _ := db.DBService.GetPostVisibilityUsers(1); len(viewers) != 1 || viewers[0] != 2 {
+		t.Errorf("expected only the follower still following to see the post, got %v", viewers)
+	}
+	if _, err := db.DBService.GetPostByID(1, 3); err == nil {
+		t.Error("expected the unfollowed user to be kept out")
+	}
+	if poll, err := db.DBService.GetPollByTarget(models.PollTargetPost, 1, 2); err != nil || len(poll.Options) != 2 {
+		t.Errorf("expected the poll published with the post, got %+v, %v", poll, err)
+	}
+	var live int
+	db.DBService.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = 4 AND type = ? AND data LIKE '%is now live%'`,
+		notifications.TypeScheduledPost).Scan(&live)
+	if live != 1 {
+		t.Errorf("expected the author told once the post is live, got %d", live)
+	}
+
+	// The announcement reaches the members at publish time
+	if countNotifications(t, 4, notifications.TypeGroupAnnouncement) != 1 {
+		t.Error("expected the members to be told about the announcement")
+	}
+
+	// With nobody left to see it, the private post fails instead of opening to every follower
+	failed, err := db.DBService.GetPostDraft(unfollowed.ID, 4)
+	if err != nil || failed.Status != models.PostDraftFailed || failed.FailureReason == "" {
+		t.Fatalf("expected the draft marked failed, got %+v, %v", failed, err)
+	}
+	if data := lastNotificationData(t, 4, notifications.TypeScheduledPost); !strings.Contains(data, "could not be published") {
+		t.Errorf("expected the author told of the failure, got %s", data)
+	}
+	if published, _ := api.PublishDueDrafts(later); published != 0 {
+		t.Errorf("expected a failed draft not to be retried, got %d", published)
+	}
+
+	// Editing a failed draft brings it back to the drafts
+	edited := draftCall(t, api.UpdatePostDraftHandler, http.MethodPut, map[string]any{"id": unfollowed.ID, "selected_followers": []int64{2}}, 4, http.StatusOK)
+	if edited.Status != models.PostDraftDraft || edited.FailureReason != "" {
+		t.Errorf("expected the failed draft back to draft, got %+v", edited)
+	}
+}
+
+func TestScheduledGroupPostPermissions(t *testing.T) {
+	setupFollowersTestDB(t)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	later := time.Now().Add(2 * time.Hour)
+
+	left := draftCall(t, api.CreatePostDraftHandler, http.MethodPost, map[string]any{
+		"group_id": 1, "title": "Hello", "body": "See you all", "publish_at": future,
+	}, 3, http.StatusCreated)
+	draftCall(t, api.CreatePostDraftHandler, http.MethodPost, map[string]any{
+		"group_id": 1, "title": "Review", "body": "Please check", "publish_at": future,
+	}, 4, http.StatusCreated)
+
+	// The group starts reviewing posts and the moderator leaves before publish time
+	db.DBService.DB.Exec(`UPDATE groups SET require_post_approval = 1 WHERE id = 1`)
+	db.DBService.DB.Exec(`DELETE FROM group_members WHERE user_id = 3`)
+
+	if published, err := api.PublishDueDrafts(later); err != nil || published != 1 {
+		t.Fatalf("expected 1 draft published, got %d, %v", published, err)
+	}
+	failed, err := db.DBService.GetPostDraft(left.ID, 3)
+	if err != nil || failed.Status != models.PostDraftFailed || !strings.Contains(failed.FailureReason, "not a member") {
+		t.Errorf("expected the draft of the former member to fail, got %+v, %v", failed, err)
+	}
+	if status, err := db.DBService.GetGroupPostStatus(1); err != nil || status != models.GroupPostPending {
+		t.Errorf("expected the member's post held for review, got %s, %v", status, err)
+	}
+	if data := lastNotificationData(t, 4, notifications.TypeScheduledPost); !strings.Contains(data, "submitted for review") {
+		t.Errorf("expected the author told the post waits for review, got %s", data)
+	}
+}
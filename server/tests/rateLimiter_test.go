@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/middleware"
+)
+
+func newRateLimitedHandler(policy middleware.RateLimitPolicy) http.Handler {
+	return middleware.RateLimitWith(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestRateLimitTokenBucket(t *testing.T) {
+	store := middleware.NewMemoryRateLimitStore(time.Hour)
+	defer store.Stop()
+	middleware.SetRateLimitStore(store)
+	defer middleware.SetRateLimitStore(nil)
+
+	t.Run("allows the burst then refuses with headers", func(t *testing.T) {
+		handler := newRateLimitedHandler(middleware.RateLimitPolicy{Name: "burst", Limit: 3, Window: time.Minute, KeyFunc: middleware.KeyByIP})
+
+		for i := 0; i < 3; i++ {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("request %d: expected 200, got %d", i+1, rr.Code)
+			}
+			if got := rr.Header().Get("RateLimit-Remaining"); got != strconv.Itoa(2-i) {
+				t.Errorf("request %d: expected remaining %d, got %s", i+1, 2-i, got)
+			}
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %d", rr.Code)
+		}
+		// One token every 20 seconds
+		if got := rr.Header().Get("Retry-After"); got != "20" {
+			t.Errorf("expected Retry-After 20, got %s", got)
+		}
+		if rr.Header().Get("RateLimit-Limit") != "3" || rr.Header().Get("RateLimit-Policy") != "3;w=60" {
+			t.Errorf("unexpected headers: %v", rr.Header())
+		}
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		handler := newRateLimitedHandler(middleware.RateLimitPolicy{Name: "refill", Limit: 1, Window: 50 * time.Millisecond, KeyFunc: middleware.KeyByIP})
+
+		codes := []int{}
+		for _, wait := range []time.Duration{0, 0, 60 * time.Millisecond} {
+			time.Sleep(wait)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			codes = append(codes, rr.Code)
+		}
+		if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests || codes[2] != http.StatusOK {
+			t.Errorf("unexpected status sequence %v", codes)
+		}
+	})
+
+	t.Run("keys by authenticated user before IP", func(t *testing.T) {
+		handler := newRateLimitedHandler(middleware.RateLimitPolicy{Name: "per-user", Limit: 1, Window: time.Minute})
+
+		request := func(userID int) int {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		if request(1) != http.StatusOK || request(2) != http.StatusOK {
+			t.Error("different users behind the same IP must have their own bucket")
+		}
+		if request(1) != http.StatusTooManyRequests {
+			t.Error("expected second request of user 1 to be limited")
+		}
+	})
+
+	t.Run("a forged X-Forwarded-For doesn't get a new bucket", func(t *testing.T) {
+		handler := newRateLimitedHandler(middleware.RateLimitPolicy{Name: "forged", Limit: 1, Window: time.Minute, KeyFunc: middleware.KeyByIP})
+
+		codes := []int{}
+		for _, forwardedFor := range []string{"198.51.100.1", "198.51.100.2"} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			codes = append(codes, rr.Code)
+		}
+		if codes[1] != http.StatusTooManyRequests {
+			t.Errorf("expected the client to stay limited whatever it forwards, got %v", codes)
+		}
+	})
+
+	t.Run("evicts refilled buckets", func(t *testing.T) {
+		evictStore := middleware.NewMemoryRateLimitStore(time.Hour)
+		defer evictStore.Stop()
+		policy := middleware.RateLimitPolicy{Name: "evict", Limit: 2, Window: time.Minute}
+
+		evictStore.Take(context.Background(), "a", policy)
+		evictStore.Take(context.Background(), "b", policy)
+
+		if n := evictStore.EvictIdle(time.Now()); n != 0 {
+			t.Errorf("active buckets must be kept, evicted %d", n)
+		}
+		if n := evictStore.EvictIdle(time.Now().Add(time.Minute)); n != 2 || evictStore.Len() != 0 {
+			t.Errorf("expected both idle buckets to be evicted, evicted %d", n)
+		}
+	})
+}
+
+func TestGetClientIP(t *testing.T) {
+	if err := middleware.SetTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"}); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+	defer middleware.SetTrustedProxies(nil)
+
+	cases := []struct {
+		name, remoteAddr, forwardedFor, want string
+	}{
+		{"a direct client can't pick its address", "203.0.113.9:5000", "198.51.100.1", "203.0.113.9"},
+		{"a trusted proxy forwards the client", "10.0.0.1:5000", "198.51.100.1", "198.51.100.1"},
+		{"hops the client added are ignored", "10.0.0.1:5000", "1.2.3.4, 198.51.100.1", "198.51.100.1"},
+		{"chained trusted proxies are skipped", "10.0.0.1:5000", "198.51.100.1, 192.168.1.5", "198.51.100.1"},
+		{"a garbled header leaves the proxy", "10.0.0.1:5000", "not-an-ip", "10.0.0.1"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			if got := middleware.GetClientIP(req); got != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+
+	if err := middleware.SetTrustedProxies([]string{"10.0.0.300"}); err == nil {
+		t.Error("expected an invalid proxy address to be refused")
+	}
+}
+
+func TestRateLimitPolicyValidation(t *testing.T) {
+	invalid := []middleware.RateLimitPolicy{
+		{Name: "no-window", Limit: 5},
+		{Name: "no-limit", Window: time.Minute},
+		{Name: "negative-limit", Limit: -1, Window: time.Minute},
+		{Name: "negative-burst", Limit: 5, Window: time.Minute, Burst: -1},
+	}
+	for _, policy := range invalid {
+		t.Run(policy.Name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected the policy to be refused when the middleware is built")
+				}
+			}()
+			middleware.RateLimitWith(policy)
+		})
+	}
+}
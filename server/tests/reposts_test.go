@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+)
+
+// setupRepostsTestDB adds a public post of the member (user 4) and a private one shown to users 2 and 3
+func setupRepostsTestDB(t *testing.T) {
+	setupFollowersTestDB(t)
+	seedTestDB(t, `
+		INSERT INTO posts (user_id, title, body, visibility) VALUES (4, 'Trail', 'Open to all', 'public'), (4, 'Secret', 'Only for you', 'private');
+		INSERT INTO post_visibility (post_id, user_id) VALUES (2, 2), (2, 3);
+	`)
+}
+
+func repost(t *testing.T, req models.RepostRequest, userID int, want int) int64 {
+	t.Helper()
+	return decodeCall[struct{ PostID int64 }](t, api.RepostHandler, http.MethodPost, "/api/post/repost", req, userID, want).PostID
+}
+
+func feedPost(t *testing.T, userID int, postID int64) *models.PostResponse {
+	t.Helper()
+	feed, err := db.DBService.GetUserFeed(userID, 50, 0)
+	if err != nil {
+		t.Fatalf("get feed: %v", err)
+	}
+	for i := range feed {
+		if feed[i].PostType == "user_post" && feed[i].ID == postID {
+			return &feed[i]
+		}
+	}
+	return nil
+}
+
+func profilePost(t *testing.T, userID int, authorID int64, postID int64) *models.Post {
+	t.Helper()
+	posts, err := db.DBService.GetPostsByUser(authorID, int64(userID))
+	if err != nil {
+		t.Fatalf("get posts by user: %v", err)
+	}
+	for _, post := range posts {
+		if post.ID == postID {
+			return post
+		}
+	}
+	return nil
+}
+
+func TestReposts(t *testing.T) {
+	t.Run("reposts and quotes are counted on the original and show in the feed", func(t *testing.T) {
+		setupRepostsTestDB(t)
+		repostID := repost(t, models.RepostRequest{PostID: 1, Visibility: "public"}, 2, http.StatusCreated)
+		repost(t, models.RepostRequest{PostID: 1, Visibility: "public"}, 2, http.StatusConflict)
+		quoteID := repost(t, models.RepostRequest{PostID: 1, Body: "Worth the climb", Visibility: "public"}, 3, http.StatusCreated)
+
+		original, err := db.DBService.GetPostByID(1, 2)
+		if err != nil {
+			t.Fatalf("get post: %v", err)
+		}
+		if original.RepostCount != 1 || original.QuoteCount != 1 || !original.UserReposted {
+			t.Errorf("expected 1 repost by the user and 1 quote, got %d %d %v", original.RepostCount, original.QuoteCount, original.UserReposted)
+		}
+
+		shared := feedPost(t, 6, repostID)
+		if shared == nil || shared.Kind != models.PostKindRepost || shared.OriginalPost == nil || shared.OriginalPost.ID != 1 {
+			t.Fatalf("expected the repost with its original in the feed, got %+v", shared)
+		}
+		quote := feedPost(t, 6, quoteID)
+		if quote == nil || quote.Kind != models.PostKindQuote || quote.Body != "Worth the climb" || quote.OriginalPost == nil {
+			t.Errorf("expected the quote with its original in the feed, got %+v", quote)
+		}
+
+		// Sharing a repost shares the post it reposts
+		again, err := db.DBService.GetPostByID(repost(t, models.RepostRequest{PostID: repostID, Visibility: "public"}, 6, http.StatusCreated), 6)
+		if err != nil || again.OriginalPostID == nil || *again.OriginalPostID != 1 {
+			t.Errorf("expected the repost of a repost to point to the original, got %+v (%v)", again, err)
+		}
+	})
+
+	t.Run("a private post is only reshared to those who can see it", func(t *testing.T) {
+		setupRepostsTestDB(t)
+		seedTestDB(t, `INSERT INTO follow_requests (requester_id, target_id, status) VALUES (3, 2, 'accepted'), (6, 2, 'accepted')`)
+		repost(t, models.RepostRequest{PostID: 2, Visibility: "public"}, 6, http.StatusForbidden)
+		repost(t, models.RepostRequest{PostID: 2, Visibility: "public"}, 2, http.StatusForbidden)
+		repost(t, models.RepostRequest{PostID: 2, Visibility: "private", SelectedFollowers: []int64{6}}, 2, http.StatusForbidden)
+		repostID := repost(t, models.RepostRequest{PostID: 2, Visibility: "private", SelectedFollowers: []int64{3}}, 2, http.StatusCreated)
+
+		if shared := feedPost(t, 3, repostID); shared == nil || shared.OriginalPost == nil || shared.OriginalPost.ID != 2 {
+			t.Fatalf("expected user 3 to see the repost, got %+v", shared)
+		}
+		if shared := profilePost(t, 3, 2, repostID); shared == nil || shared.OriginalPost == nil || shared.OriginalPost.ID != 2 {
+			t.Fatalf("expected the repost with its original on the reposter's profile, got %+v", shared)
+		}
+
+		// Once the original is hidden from them, so is the repost
+		if _, err := db.DBService.DB.Exec(`DELETE FROM post_visibility WHERE post_id = 2 AND user_id = 3`); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.DBService.GetPostByID(repostID, 3); err == nil || err.Error() != "unauthorized" {
+			t.Errorf("expected the repost to be hidden, got %v", err)
+		}
+		if shared := feedPost(t, 3, repostID); shared != nil {
+			t.Error("expected the repost to leave the feed")
+		}
+		if shared := profilePost(t, 3, 2, repostID); shared != nil {
+			t.Error("expected the repost to leave the reposter's profile")
+		}
+	})
+
+	t.Run("a post shared with all followers is reshared to followers of both", func(t *testing.T) {
+		setupRepostsTestDB(t)
+		seedTestDB(t, `
+			INSERT INTO posts (user_id, title, body, visibility) VALUES (4, 'Camp', 'Followers only', 'private');
+			INSERT INTO follow_requests (requester_id, target_id, status) VALUES (3, 2, 'accepted'), (6, 2, 'accepted');
+		`)
+
+		// User 6 follows the reposter but their request to the author is still pending
+		repost(t, models.RepostRequest{PostID: 3, Visibility: "private", SelectedFollowers: []int64{6}}, 2, http.StatusForbidden)
+		repostID := repost(t, models.RepostRequest{PostID: 3, Visibility: "private", SelectedFollowers: []int64{3}}, 2, http.StatusCreated)
+		if shared := feedPost(t, 3, repostID); shared == nil || shared.OriginalPost == nil || shared.OriginalPost.ID != 3 {
+			t.Fatalf("expected user 3 to see the repost, got %+v", shared)
+		}
+	})
+
+	t.Run("a private repost only goes to followers of the reposter", func(t *testing.T) {
+		setupRepostsTestDB(t)
+		// User 3 follows the author but not user 2
+		repost(t, models.RepostRequest{PostID: 1, Visibility: "private", SelectedFollowers: []int64{3}}, 2, http.StatusForbidden)
+		seedTestDB(t, `INSERT INTO follow_requests (requester_id, target_id, status) VALUES (3, 2, 'accepted')`)
+		repost(t, models.RepostRequest{PostID: 1, Visibility: "private", SelectedFollowers: []int64{3}}, 2, http.StatusCreated)
+	})
+
+	t.Run("deleting the original deletes its reposts but not its quotes", func(t *testing.T) {
+		setupRepostsTestDB(t)
+		repostID := repost(t, models.RepostRequest{PostID: 1, Visibility: "public"}, 2, http.StatusCreated)
+		quoteID := repost(t, models.RepostRequest{PostID: 1, Body: "Worth the climb", Visibility: "public"}, 3, http.StatusCreated)
+
+		if err := db.DBService.DeletePost(1); err != nil {
+			t.Fatalf("delete post: %v", err)
+		}
+		if exists, _ := db.DBService.PostExists(repostID); exists {
+			t.Error("expected the repost to be deleted")
+		}
+		quote, err := db.DBService.GetPostByID(quoteID, 6)
+		if err != nil {
+			t.Fatalf("expected the quote to remain: %v", err)
+		}
+		if quote.OriginalPost != nil {
+			t.Error("expected the quote to lose its original")
+		}
+
+		// Whatever deletes the original
+		repostID = repost(t, models.RepostRequest{PostID: quoteID, Visibility: "public"}, 2, http.StatusCreated)
+		seedTestDB(t, fmt.Sprintf(`DELETE FROM posts WHERE id = %d`, quoteID))
+		if exists, _ := db.DBService.PostExists(repostID); exists {
+			t.Error("expected the repost to be deleted with its original")
+		}
+	})
+
+	t.Run("a repost can be undone", func(t *testing.T) {
+		setupRepostsTestDB(t)
+		repost(t, models.RepostRequest{PostID: 1, Visibility: "public"}, 2, http.StatusCreated)
+
+		for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+			if rr := callGroupHandler(api.DeleteRepostHandler, http.MethodDelete, "/api/post/repost?post_id=1", nil, 2); rr.Code != want {
+				t.Errorf("expected %d, got %d: %s", want, rr.Code, rr.Body.String())
+			}
+		}
+		if post, _ := db.DBService.GetPostByID(1, 2); post.RepostCount != 0 || post.UserReposted {
+			t.Errorf("expected no repost left, got %d", post.RepostCount)
+		}
+	})
+}
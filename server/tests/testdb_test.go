@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Golden76z/social-network/db"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+)
+
+// newTestDB opens an in-memory database with the schema of db/migrations, so the tests always
+// run against the real tables
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	testDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	// Every connection to :memory: is a new database
+	testDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { testDB.Close() })
+
+	driver, err := sqlite3.WithInstance(testDB, &sqlite3.Config{})
+	if err != nil {
+		t.Fatalf("Failed to prepare migrations: %v", err)
+	}
+	// Not closed, that would close testDB too
+	m, err := migrate.NewWithDatabaseInstance("file://../db/migrations", "sqlite3", driver)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return testDB
+}
+
+// useTestDB points db.DBService to a new test database for the length of the test, seeded with the
+// given statements
+func useTestDB(t *testing.T, seed string) *sql.DB {
+	t.Helper()
+	testDB := newTestDB(t)
+	previous := db.DBService
+	db.DBService = &db.Service{DB: testDB}
+	t.Cleanup(func() { db.DBService = previous })
+
+	seedTestDB(t, seed)
+	return testDB
+}
+
+// seedTestDB runs the statements seed on the test database in use
+func seedTestDB(t *testing.T, seed string) {
+	t.Helper()
+	if seed == "" {
+		return
+	}
+	if _, err := db.DBService.DB.Exec(seed); err != nil {
+		t.Fatalf("Failed to seed test database: %v", err)
+	}
+}
+
+// testUsers inserts users with the given nicknames, they get ids 1, 2, ... in order
+func testUsers(nicknames ...string) string {
+	seed := ""
+	for _, nickname := range nicknames {
+		seed += `INSERT INTO users (nickname, first_name, last_name, email, password, date_of_birth)
+			VALUES ('` + nickname + `', 'Test', 'User', '` + nickname + `@example.com', 'x', '2000-01-01');
+		`
+	}
+	return seed
+}
+
+func callGroupHandler(handler http.HandlerFunc, method, target string, body any, userID int) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	rr := httptest.NewRecorder()
+	handler(rr, asUser(httptest.NewRequest(method, target, bytes.NewReader(data)), userID))
+	return rr
+}
+
+// expectCall calls handler as userID and fails the test unless it answers with the status want
+func expectCall(t *testing.T, handler http.HandlerFunc, method, target string, body any, userID int, want int) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := callGroupHandler(handler, method, target, body, userID)
+	if rr.Code != want {
+		t.Fatalf("%s %s as user %d: expected %d, got %d: %s", method, target, userID, want, rr.Code, rr.Body.String())
+	}
+	return rr
+}
+
+// decodeCall is expectCall decoding the JSON response into a T
+func decodeCall[T any](t *testing.T, handler http.HandlerFunc, method, target string, body any, userID int, want int) *T {
+	t.Helper()
+	rr := expectCall(t, handler, method, target, body, userID, want)
+	var out T
+	json.NewDecoder(rr.Body).Decode(&out)
+	return &out
+}
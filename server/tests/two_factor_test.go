@@ -0,0 +1,268 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/config"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/models"
+	"github.com/Golden76z/social-network/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RFC 6238 appendix B vectors (SHA1 seed), truncated to 6 digits
+func TestTOTPVectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, want := range vectors {
+		got, err := utils.GenerateTOTPCode(secret, utils.TOTPStep(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("at %d: expected %s, got %s", unix, want, got)
+		}
+	}
+}
+
+func TestTOTPValidation(t *testing.T) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret failed: %v", err)
+	}
+	now := time.Now()
+
+	t.Run("accepts codes within the skew window", func(t *testing.T) {
+		previous, _ := utils.GenerateTOTPCode(secret, utils.TOTPStep(now)-1)
+		if step, ok := utils.ValidateTOTPCode(secret, previous, now); !ok || step != utils.TOTPStep(now)-1 {
+			t.Error("expected previous step code to be accepted")
+		}
+	})
+
+	t.Run("rejects codes outside the window", func(t *testing.T) {
+		old, _ := utils.GenerateTOTPCode(secret, utils.TOTPStep(now)-5)
+		if _, ok := utils.ValidateTOTPCode(secret, old, now); ok {
+			t.Error("expected old code to be rejected")
+		}
+	})
+
+	t.Run("rejects malformed codes", func(t *testing.T) {
+		if _, ok := utils.ValidateTOTPCode(secret, "12345", now); ok {
+			t.Error("expected short code to be rejected")
+		}
+	})
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := utils.TOTPProvisioningURI("Social Network", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/Social%20Network:alice@example.com?") {
+		t.Errorf("unexpected label in %s", uri)
+	}
+	for _, part := range []string{"secret=JBSWY3DPEHPK3PXP", "issuer=Social+Network", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("missing %s in %s", part, uri)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := utils.GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes failed: %v", err)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected recovery code format: %s", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate recovery code: %s", code)
+		}
+		seen[code] = true
+	}
+
+	if utils.HashRecoveryCode(codes[0]) != utils.HashRecoveryCode("  "+strings.ToUpper(codes[0])+" ") {
+		t.Error("expected hashing to ignore case and surrounding spaces")
+	}
+}
+
+// setupTwoFactorLoginTestDB creates alice with the password "password" and 2FA enabled, returning her
+// secret and recovery codes
+func setupTwoFactorLoginTestDB(t *testing.T) (string, []string) {
+	if err := config.Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate JWT key: %v", err)
+	}
+	utils.SetConfig(time.Hour, key, &key.PublicKey, "development", 280, 10)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	useTestDB(t, "")
+	_, err = db.DBService.DB.Exec(`
+		INSERT INTO users (nickname, first_name, last_name, email, password, date_of_birth)
+		VALUES ('alice', 'Alice', 'Liddell', 'alice@example.com', ?, '1990-01-01')`, string(hash))
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret failed: %v", err)
+	}
+	codes, err := utils.GenerateRecoveryCodes(2)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes failed: %v", err)
+	}
+	hashes := []string{utils.HashRecoveryCode(codes[0]), utils.HashRecoveryCode(codes[1])}
+	if err := db.DBService.SaveTwoFactorSecret(1, secret); err != nil {
+		t.Fatalf("SaveTwoFactorSecret failed: %v", err)
+	}
+	if err := db.DBService.EnableTwoFactor(1, 0, hashes); err != nil {
+		t.Fatalf("EnableTwoFactor failed: %v", err)
+	}
+	return secret, codes
+}
+
+func postJSON(handler http.HandlerFunc, target string, body any) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest(http.MethodPost, target, bytes.NewReader(data)))
+	return rr
+}
+
+// passwordLogin logs alice in with her password and returns the challenge token
+func passwordLogin(t *testing.T) string {
+	t.Helper()
+	rr := postJSON(api.LoginHandler, "/auth/login", models.LoginRequest{Username: "alice", Password: "password"})
+	var resp struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+		Token             string `json:"token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if rr.Code != http.StatusOK || !resp.TwoFactorRequired || resp.ChallengeToken == "" {
+		t.Fatalf("expected a challenge, got %d %+v", rr.Code, resp)
+	}
+	if resp.Token != "" || len(rr.Result().Cookies()) != 0 {
+		t.Fatal("expected no session before the second step")
+	}
+	return resp.ChallengeToken
+}
+
+func countSessions(t *testing.T) int {
+	t.Helper()
+	var count int
+	if err := db.DBService.DB.QueryRow(`SELECT COUNT(*) FROM sessions`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count sessions: %v", err)
+	}
+	return count
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	t.Run("a valid code completes the login once", func(t *testing.T) {
+		secret, _ := setupTwoFactorLoginTestDB(t)
+		challenge := passwordLogin(t)
+		if countSessions(t) != 0 {
+			t.Fatal("expected no session after the password alone")
+		}
+
+		code, _ := utils.GenerateTOTPCode(secret, utils.TOTPStep(time.Now()))
+		rr := postJSON(api.LoginTwoFactorHandler, "/auth/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: challenge, Code: code})
+		if rr.Code != http.StatusOK || countSessions(t) != 1 {
+			t.Fatalf("expected a session, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		// The challenge is spent, and the same code can't open a second session
+		rr = postJSON(api.LoginTwoFactorHandler, "/auth/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: challenge, Code: code})
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401 reusing the challenge, got %d", rr.Code)
+		}
+		rr = postJSON(api.LoginTwoFactorHandler, "/auth/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: passwordLogin(t), Code: code})
+		if rr.Code != http.StatusUnauthorized || countSessions(t) != 1 {
+			t.Errorf("expected the replayed code to be refused, got %d", rr.Code)
+		}
+	})
+
+	t.Run("recovery codes work once", func(t *testing.T) {
+		_, codes := setupTwoFactorLoginTestDB(t)
+		rr := postJSON(api.LoginTwoFactorHandler, "/auth/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: passwordLogin(t), RecoveryCode: codes[0]})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if remaining, _ := db.DBService.CountRemainingRecoveryCodes(1); remaining != 1 {
+			t.Errorf("expected one recovery code left, got %d", remaining)
+		}
+		rr = postJSON(api.LoginTwoFactorHandler, "/auth/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: passwordLogin(t), RecoveryCode: codes[0]})
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected a used recovery code to be refused, got %d", rr.Code)
+		}
+	})
+
+	t.Run("the challenge is dropped after too many wrong codes", func(t *testing.T) {
+		secret, _ := setupTwoFactorLoginTestDB(t)
+		challenge := passwordLogin(t)
+		rr := postJSON(api.LoginTwoFactorHandler, "/auth/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: challenge, Code: "000000"})
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for a wrong code, got %d", rr.Code)
+		}
+		if _, attempts, err := db.DBService.GetLoginChallenge(challenge); err != nil || attempts != 1 {
+			t.Fatalf("expected the wrong code to be counted, got %d (%v)", attempts, err)
+		}
+
+		// Jump to the limit without waiting out the login backoff of each failure
+		db.DBService.DB.Exec(`UPDATE login_challenges SET attempts = 5 WHERE token = ?`, challenge)
+		db.DBService.ResetFailedLogins(1)
+		code, _ := utils.GenerateTOTPCode(secret, utils.TOTPStep(time.Now()))
+		rr = postJSON(api.LoginTwoFactorHandler, "/auth/login/2fa", models.LoginTwoFactorRequest{ChallengeToken: challenge, Code: code})
+		if rr.Code != http.StatusUnauthorized || countSessions(t) != 0 {
+			t.Errorf("expected even a valid code to be refused, got %d", rr.Code)
+		}
+		if _, _, err := db.DBService.GetLoginChallenge(challenge); err == nil {
+			t.Error("expected the challenge to be deleted")
+		}
+	})
+
+	t.Run("only the attempts left can be reserved", func(t *testing.T) {
+		setupTwoFactorLoginTestDB(t)
+		challenge := passwordLogin(t)
+		db.DBService.DB.Exec(`UPDATE login_challenges SET attempts = 4 WHERE token = ?`, challenge)
+		for _, want := range []bool{true, false} {
+			if reserved, err := db.DBService.ReserveLoginChallengeAttempt(challenge, 5); err != nil || reserved != want {
+				t.Errorf("expected reserved %v, got %v (%v)", want, reserved, err)
+			}
+		}
+
+		// An expired challenge has none left
+		challenge = passwordLogin(t)
+		db.DBService.DB.Exec(`UPDATE login_challenges SET expires_at = ? WHERE token = ?`, time.Now().Add(-time.Minute), challenge)
+		if reserved, err := db.DBService.ReserveLoginChallengeAttempt(challenge, 5); err != nil || reserved {
+			t.Errorf("expected no attempt on an expired challenge, got %v (%v)", reserved, err)
+		}
+	})
+}
@@ -0,0 +1,366 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Golden76z/social-network/api"
+	"github.com/Golden76z/social-network/db"
+	"github.com/Golden76z/social-network/middleware"
+	"github.com/Golden76z/social-network/push"
+	"github.com/Golden76z/social-network/utils"
+	"github.com/Golden76z/social-network/websockets"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+// browserKeys plays the user agent side of a subscription
+type browserKeys struct {
+	private *ecdh.PrivateKey
+	auth    []byte
+}
+
+func newBrowserKeys(t *testing.T) *browserKeys {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	return &browserKeys{private: key, auth: auth}
+}
+
+func (b *browserKeys) p256dh() string {
+	return base64.RawURLEncoding.EncodeToString(b.private.PublicKey().Bytes())
+}
+
+func (b *browserKeys) authSecret() string {
+	return base64.RawURLEncoding.EncodeToString(b.auth)
+}
+
+// decrypt reverses RFC 8291 aes128gcm the way a browser does
+func (b *browserKeys) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("body too short: %d bytes", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != 4096 {
+		t.Errorf("unexpected record size %d", rs)
+	}
+	idLen := int(body[20])
+	serverPublicBytes := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	serverPublic, err := ecdh.P256().NewPublicKey(serverPublicBytes)
+	if err != nil {
+		t.Fatalf("invalid server key in header: %v", err)
+	}
+	shared, err := b.private.ECDH(serverPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	derive := func(secret, salt, info []byte, n int) []byte {
+		out := make([]byte, n)
+		io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out)
+		return out
+	}
+	keyInfo := append([]byte("WebPush: info\x00"), b.private.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, serverPublicBytes...)
+	ikm := derive(shared, b.auth, keyInfo, 32)
+	cek := derive(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := derive(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt push message: %v", err)
+	}
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatal("missing last record delimiter")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+// pushService is a local stand-in for FCM / Mozilla autopush
+type pushService struct {
+	t       *testing.T
+	server  *httptest.Server
+	browser *browserKeys
+
+	mu       sync.Mutex
+	received [][]byte
+}
+
+func newPushService(t *testing.T, browser *browserKeys) *pushService {
+	ps := &pushService{t: t, browser: browser}
+	ps.server = httptest.NewServer(http.HandlerFunc(ps.handle))
+	t.Cleanup(ps.server.Close)
+	return ps
+}
+
+func (ps *pushService) handle(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/gone") {
+		w.WriteHeader(http.StatusGone)
+		return
+	}
+
+	if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+		ps.t.Errorf("missing push headers: %v", r.Header)
+	}
+	if err := ps.verifyVAPID(r.Header.Get("Authorization")); err != nil {
+		ps.t.Errorf("invalid VAPID authorization: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	plaintext := ps.browser.decrypt(ps.t, body)
+
+	ps.mu.Lock()
+	ps.received = append(ps.received, plaintext)
+	ps.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (ps *pushService) verifyVAPID(header string) error {
+	var token, key string
+	for _, part := range strings.Split(strings.TrimPrefix(header, "vapid "), ",") {
+		part = strings.TrimSpace(part)
+		if v, ok := strings.CutPrefix(part, "t="); ok {
+			token = v
+		} else if v, ok := strings.CutPrefix(part, "k="); ok {
+			key = v
+		}
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(key)
+	if err != nil || len(raw) != 65 {
+		return jwt.ErrInvalidKey
+	}
+	publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(raw[1:33]), Y: new(big.Int).SetBytes(raw[33:])}
+
+	_, err = jwt.Parse(token, func(*jwt.Token) (interface{}, error) { return publicKey, nil },
+		jwt.WithValidMethods([]string{"ES256"}),
+		jwt.WithAudience(ps.server.URL),
+		jwt.WithExpirationRequired())
+	return err
+}
+
+func (ps *pushService) messages(t *testing.T) []push.Payload {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var payloads []push.Payload
+	for _, raw := range ps.received {
+		var p push.Payload
+		if err := json.Unmarshal(raw, &p); err != nil {
+			t.Fatalf("push payload is not JSON: %s", raw)
+		}
+		payloads = append(payloads, p)
+	}
+	return payloads
+}
+
+func subscribe(t *testing.T, userID int, endpoint string, browser *browserKeys) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]any{
+		"endpoint": endpoint,
+		"keys":     map[string]string{"p256dh": browser.p256dh(), "auth": browser.authSecret()},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/push/subscriptions", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+	rr := httptest.NewRecorder()
+	api.SubscribePushHandler(rr, req)
+	return rr
+}
+
+func TestWebPush(t *testing.T) {
+	useTestDB(t, "")
+
+	keys, err := utils.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("GenerateVAPIDKeys failed: %v", err)
+	}
+	api.SetWebPush(keys.PublicKey(), true)
+	t.Cleanup(func() { api.SetWebPush("", false) })
+
+	browser := newBrowserKeys(t)
+	service := newPushService(t, browser)
+
+	if rr := subscribe(t, 1, service.server.URL+"/push/device-1", browser); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	t.Run("offline users receive encrypted messages and notifications", func(t *testing.T) {
+		worker := push.NewWorker(&utils.WebPushSender{Keys: keys, Subject: "mailto:admin@example.com", AllowPrivateHosts: true}, 2, 10)
+		hub := websockets.NewHub(nil)
+		hub.SetOfflineHandler(worker.Enqueue)
+
+		hub.BroadcastToUser(1, websockets.Message{
+			Type:      websockets.MessageTypePrivateMessage,
+			Content:   "Are you coming tonight?",
+			UserID:    2,
+			Username:  "bob",
+			MessageID: 7,
+			Timestamp: time.Now(),
+		})
+		hub.BroadcastNotification(1, "follow_request", "bob wants to follow you", map[string]any{"requester_id": 2})
+		// Presence updates have no push equivalent
+		hub.BroadcastToUser(1, websockets.Message{Type: websockets.MessageTypeConversationUpdate})
+		worker.Stop()
+
+		payloads := service.messages(t)
+		if len(payloads) != 2 {
+			t.Fatalf("expected 2 push messages, got %d", len(payloads))
+		}
+		byType := map[string]push.Payload{}
+		for _, p := range payloads {
+			byType[p.Type] = p
+		}
+		if p := byType["private_message"]; p.Title != "New message from bob" || p.Body != "Are you coming tonight?" {
+			t.Errorf("unexpected private message push: %+v", p)
+		}
+		if p := byType["notification"]; p.Body != "bob wants to follow you" || p.Data["notification_type"] != "follow_request" {
+			t.Errorf("unexpected notification push: %+v", p)
+		}
+	})
+
+	t.Run("connected users are not pushed", func(t *testing.T) {
+		before := len(service.messages(t))
+		worker := push.NewWorker(&utils.WebPushSender{Keys: keys, Subject: "mailto:admin@example.com", AllowPrivateHosts: true}, 1, 10)
+		hub := websockets.NewHub(nil)
+		hub.SetOfflineHandler(worker.Enqueue)
+
+		client := &websockets.Client{ID: "socket-1", UserID: 1, Send: make(chan websockets.Message, 10), Groups: map[string]*websockets.Group{}}
+		hub.RegisterClient(client)
+		hub.BroadcastNotification(1, "follow_request", "bob wants to follow you", map[string]any{"requester_id": 2})
+		worker.Stop()
+
+		if after := len(service.messages(t)); after != before {
+			t.Errorf("expected no push for a connected user, got %d new", after-before)
+		}
+		if len(client.Send) != 2 {
+			t.Errorf("expected the welcome and the notification on the socket, got %d messages", len(client.Send))
+		}
+	})
+
+	t.Run("gone subscriptions are deleted", func(t *testing.T) {
+		other := newBrowserKeys(t)
+		if rr := subscribe(t, 3, service.server.URL+"/push/gone", other); rr.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", rr.Code)
+		}
+
+		worker := push.NewWorker(&utils.WebPushSender{Keys: keys, Subject: "mailto:admin@example.com", AllowPrivateHosts: true}, 1, 10)
+		if err := worker.Deliver(context.Background(), 3, push.Payload{Type: "notification", Title: "Hi"}); err != nil {
+			t.Fatalf("Deliver failed: %v", err)
+		}
+		worker.Stop()
+
+		subs, _ := db.DBService.GetPushSubscriptions(3)
+		if len(subs) != 0 {
+			t.Errorf("expected the gone subscription to be deleted, %d left", len(subs))
+		}
+	})
+
+	t.Run("rejects invalid subscriptions", func(t *testing.T) {
+		bad := newBrowserKeys(t)
+		bad.auth = []byte("short")
+		if rr := subscribe(t, 1, service.server.URL+"/push/device-2", bad); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for a bad auth secret, got %d", rr.Code)
+		}
+
+		api.SetWebPush(keys.PublicKey(), false)
+		defer api.SetWebPush(keys.PublicKey(), true)
+		if rr := subscribe(t, 1, service.server.URL+"/push/device-3", browser); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for a plain http endpoint, got %d", rr.Code)
+		}
+		for _, endpoint := range []string{
+			"https://127.0.0.1/push", "https://[::1]/push", "https://10.0.0.5/push",
+			"https://169.254.169.254/latest", "https://localhost/push",
+		} {
+			if rr := subscribe(t, 1, endpoint, browser); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for %s, got %d", endpoint, rr.Code)
+			}
+		}
+	})
+
+	t.Run("the sender does not reach private addresses", func(t *testing.T) {
+		before := len(service.messages(t))
+		sender := &utils.WebPushSender{Keys: keys, Subject: "mailto:admin@example.com"}
+		sub := utils.PushSubscription{Endpoint: service.server.URL + "/push/device-1", P256dh: browser.p256dh(), Auth: browser.authSecret()}
+		if err := sender.Send(context.Background(), sub, []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "non-public address") {
+			t.Errorf("expected the private address to be refused, got %v", err)
+		}
+		if after := len(service.messages(t)); after != before {
+			t.Error("expected nothing to reach the local service")
+		}
+	})
+}
+
+func TestVAPIDKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vapid_private_key")
+	keys, created, err := utils.LoadOrCreateVAPIDKeys(path)
+	if err != nil || !created {
+		t.Fatalf("expected a new key, got %v (%v)", created, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the key file to be readable by its owner only, got %v (%v)", info.Mode().Perm(), err)
+	}
+
+	again, created, err := utils.LoadOrCreateVAPIDKeys(path)
+	if err != nil || created || again.PublicKey() != keys.PublicKey() {
+		t.Errorf("expected the saved key to be reused, got %v (%v)", created, err)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":        true,
+		"2606:4700::1111":      true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"169.254.169.254":      false,
+		"0.0.0.0":              false,
+		"0.1.2.3":              false,
+		"100.64.0.1":           false,
+		"100.127.255.254":      false,
+		"100.128.0.1":          true,
+		"198.18.0.1":           false,
+		"198.19.255.254":       false,
+		"198.20.0.1":           true,
+		"::1":                  false,
+		"fd00::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:100.64.0.1":    false,
+		"::ffff:198.18.0.1":    false,
+		"::ffff:93.184.216.34": true,
+	}
+	for address, want := range cases {
+		if got := utils.IsPublicIP(net.ParseIP(address)); got != want {
+			t.Errorf("%s: expected public %v, got %v", address, want, got)
+		}
+	}
+}
@@ -38,5 +38,20 @@ func cleanupSessions(db *sql.DB) error {
 	if rowsAffected > 0 {
 		log.Printf("Cleaned up %d expired sessions", rowsAffected)
 	}
-	return nil
+
+	// Abandoned two-factor login challenges and sign-in requests expire the same way
+	_, err = db.Exec(`
+		DELETE FROM login_challenges 
+		WHERE expires_at < ?`,
+		time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`
+		DELETE FROM oidc_auth_requests 
+		WHERE expires_at < ?`,
+		time.Now(),
+	)
+	return err
 }
@@ -0,0 +1,388 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Web Push (RFC 8030) with VAPID authentication (RFC 8292) and aes128gcm payload encryption (RFC 8291)
+
+const (
+	// pushRecordSize is the aes128gcm record size, payloads always fit in one record
+	pushRecordSize = 4096
+	// MaxPushPayload is the largest plaintext push services must accept
+	MaxPushPayload = 3993
+)
+
+// PushSubscription is what the browser's PushManager returns for one device
+type PushSubscription struct {
+	Endpoint string
+	// P256dh is the user agent public key, base64url
+	P256dh string
+	// Auth is the 16 bytes authentication secret, base64url
+	Auth string
+}
+
+// VAPIDKeys identify this application server to push services
+type VAPIDKeys struct {
+	PrivateKey *ecdsa.PrivateKey
+}
+
+// GenerateVAPIDKeys creates a new P-256 key pair
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &VAPIDKeys{PrivateKey: key}, nil
+}
+
+// ParseVAPIDPrivateKey reads the base64url encoded private scalar, the format of common web-push tools
+func ParseVAPIDPrivateKey(encoded string) (*VAPIDKeys, error) {
+	d, err := decodeBase64URL(encoded)
+	if err != nil || len(d) != 32 {
+		return nil, errors.New("VAPID private key must be 32 bytes encoded in base64url")
+	}
+
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d)
+	return &VAPIDKeys{PrivateKey: key}, nil
+}
+
+// LoadOrCreateVAPIDKeys reads the private key saved in path, or generates one and saves it there
+// readable by the owner only. It reports whether the key was created.
+func LoadOrCreateVAPIDKeys(path string) (*VAPIDKeys, bool, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		keys, err := ParseVAPIDPrivateKey(strings.TrimSpace(string(data)))
+		return keys, false, err
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, false, err
+	}
+
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		return nil, false, err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, false, err
+	}
+	if _, err := file.WriteString(keys.PrivateKeyString() + "\n"); err != nil {
+		file.Close()
+		return nil, false, err
+	}
+	return keys, true, file.Close()
+}
+
+// PublicKey returns the uncompressed public key in base64url, the applicationServerKey of the browser
+func (k *VAPIDKeys) PublicKey() string {
+	ecdhKey, err := k.PrivateKey.PublicKey.ECDH()
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(ecdhKey.Bytes())
+}
+
+// PrivateKeyString returns the private scalar in base64url, to be stored in the environment
+func (k *VAPIDKeys) PrivateKeyString() string {
+	d := make([]byte, 32)
+	k.PrivateKey.D.FillBytes(d)
+	return base64.RawURLEncoding.EncodeToString(d)
+}
+
+// Authorization builds the VAPID Authorization header for a push endpoint
+func (k *VAPIDKeys) Authorization(endpoint, subject string, expiresAt time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": expiresAt.Unix(),
+		"sub": subject,
+	})
+	signed, err := token.SignedString(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signed + ", k=" + k.PublicKey(), nil
+}
+
+// ValidatePushSubscription checks the endpoint and the keys sent by a browser.
+// allowInsecure accepts http and private addresses, for a push service running locally.
+func ValidatePushSubscription(sub PushSubscription, allowInsecure bool) error {
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil || u.Hostname() == "" {
+		return errors.New("invalid endpoint")
+	}
+	if u.Scheme != "https" && !(allowInsecure && u.Scheme == "http") {
+		return errors.New("endpoint must use https")
+	}
+	if !allowInsecure {
+		// The server posts to the endpoint, it must not be pointed at our own network
+		if net.ParseIP(u.Hostname()) != nil {
+			return errors.New("endpoint must use a host name")
+		}
+		ips, err := net.LookupIP(u.Hostname())
+		if err != nil || len(ips) == 0 {
+			return errors.New("endpoint host cannot be resolved")
+		}
+		for _, ip := range ips {
+			if !IsPublicIP(ip) {
+				return errors.New("endpoint must be a public host")
+			}
+		}
+	}
+
+	p256dh, err := decodeBase64URL(sub.P256dh)
+	if err != nil {
+		return errors.New("invalid p256dh key")
+	}
+	if _, err := ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return errors.New("invalid p256dh key")
+	}
+	auth, err := decodeBase64URL(sub.Auth)
+	if err != nil || len(auth) != 16 {
+		return errors.New("invalid auth secret")
+	}
+	return nil
+}
+
+// nonPublicIPv4 are the special-purpose ranges the net.IP methods don't report: "this network",
+// carrier-grade NAT, IETF protocol assignments, benchmarking and the reserved block with broadcast
+var nonPublicIPv4 = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// IsPublicIP reports whether ip can be reached on the internet, and not only on our own network
+func IsPublicIP(ip net.IP) bool {
+	// An IPv4-mapped IPv6 address reaches the IPv4 one
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, ipNet := range nonPublicIPv4 {
+		if ipNet.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicOnlyDialer refuses connections to non-public addresses. Checked once the host is resolved, so
+// a name that resolved to a public address at subscription time cannot be rebound to an internal one.
+func publicOnlyDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("push endpoint resolves to a non-public address %s", host)
+			}
+			return nil
+		},
+	}
+}
+
+// EncryptPushPayload encrypts plaintext for the subscription (RFC 8291, aes128gcm)
+func EncryptPushPayload(sub PushSubscription, plaintext []byte) ([]byte, error) {
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptPushPayload(sub, plaintext, serverKey, salt)
+}
+
+func encryptPushPayload(sub PushSubscription, plaintext []byte, serverKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(plaintext) > MaxPushPayload {
+		return nil, fmt.Errorf("push payload too large: %d bytes", len(plaintext))
+	}
+
+	uaPublicBytes, err := decodeBase64URL(sub.P256dh)
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+	authSecret, err := decodeBase64URL(sub.Auth)
+	if err != nil {
+		return nil, errors.New("invalid auth secret")
+	}
+
+	sharedSecret, err := serverKey.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, serverPublic...)
+	ikm, err := hkdfBytes(sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfBytes(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Single record: plaintext followed by the last record delimiter
+	record := append(append([]byte{}, plaintext...), 0x02)
+
+	// Header: salt (16) || record size (4) || key id length (1) || key id (the server public key)
+	var body bytes.Buffer
+	body.Write(salt)
+	binary.Write(&body, binary.BigEndian, uint32(pushRecordSize))
+	body.WriteByte(byte(len(serverPublic)))
+	body.Write(serverPublic)
+	body.Write(gcm.Seal(nil, nonce, record, nil))
+	return body.Bytes(), nil
+}
+
+func hkdfBytes(secret, salt, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	// Browsers and tools don't agree on padding
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// ErrPushSubscriptionGone means the push service dropped the subscription, it must be deleted
+var ErrPushSubscriptionGone = errors.New("push subscription expired or unsubscribed")
+
+// WebPushSender delivers encrypted messages to push services
+type WebPushSender struct {
+	Keys *VAPIDKeys
+	// Subject is a mailto: or https: contact for the push service operator
+	Subject    string
+	TTL        time.Duration
+	HTTPClient *http.Client
+	// AllowPrivateHosts lets the default client reach private addresses, for a push service running locally
+	AllowPrivateHosts bool
+}
+
+// Send encrypts and posts the payload to the subscription endpoint
+func (s *WebPushSender) Send(ctx context.Context, sub PushSubscription, payload []byte) error {
+	body, err := EncryptPushPayload(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := s.Keys.Authorization(sub.Endpoint, s.Subject, time.Now().Add(12*time.Hour))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+		if !s.AllowPrivateHosts {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.DialContext = publicOnlyDialer().DialContext
+			// A proxy would make the dialer check the proxy address instead of the endpoint
+			transport.Proxy = nil
+			client.Transport = transport
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrPushSubscriptionGone
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("push service answered %s", resp.Status)
+	}
+	return nil
+}
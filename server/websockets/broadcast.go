@@ -51,13 +51,21 @@ func (h *Hub) broadcastToGroup(groupID string, message Message) {
 	}
 }
 
-// broadcastToUser sends a message to all connections of a specific user
+// BroadcastToUser sends a message to all connections of a specific user
 func (h *Hub) BroadcastToUser(userID int, message Message) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	h.sendToUser(userID, message)
+}
+
+// sendToUser delivers to every connection of the user, or to the offline handler when there is none.
+// The caller holds the read lock.
+func (h *Hub) sendToUser(userID int, message Message) {
+	delivered := false
 	for _, client := range h.clients {
 		if client.UserID == userID {
+			delivered = true
 			select {
 			case client.Send <- message:
 			default:
@@ -67,6 +75,17 @@ func (h *Hub) BroadcastToUser(userID int, message Message) {
 			}
 		}
 	}
+
+	if !delivered && h.offline != nil {
+		h.offline(userID, message)
+	}
+}
+
+// SetOfflineHandler registers the handler for messages to users without an open socket
+func (h *Hub) SetOfflineHandler(handler OfflineHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.offline = handler
 }
 
 // BroadcastUserList sends the current user list to all clients
@@ -123,30 +142,25 @@ func (h *Hub) BroadcastGroupList(client *Client) {
 	}
 }
 
-// BroadcastNotification sends a notification to a specific user
+// BroadcastNotification sends a notification to a specific user.
+// The payload fields are sent as is, with the type under "notification_type".
 func (h *Hub) BroadcastNotification(userID int, notificationType, content string, data map[string]any) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	payload := make(map[string]any, len(data)+1)
+	for key, value := range data {
+		payload[key] = value
+	}
+	payload["notification_type"] = notificationType
+
 	message := Message{
 		Type:      "notification",
 		Content:   content,
+		UserID:    userID,
 		Timestamp: time.Now(),
-		Data: map[string]any{
-			"type": notificationType,
-			"data": data,
-		},
+		Data:      payload,
 	}
 
-	for _, client := range h.clients {
-		if client.UserID == userID {
-			select {
-			case client.Send <- message:
-			default:
-				go func(c *Client) {
-					h.unregister <- c
-				}(client)
-			}
-		}
-	}
+	h.sendToUser(userID, message)
 }
@@ -1,6 +1,7 @@
 package websockets
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
@@ -415,6 +416,14 @@ func (c *Client) handleGroupMessage(msg Message) {
 	// Save message to database
 	messageID, err := db.DBService.CreateGroupMessage(groupID, c.UserID, msg.Content)
 	if err != nil {
+		if errors.Is(err, db.ErrGroupArchived) {
+			c.sendError("This group is archived and read-only")
+			return
+		}
+		if errors.Is(err, db.ErrGroupMuted) {
+			c.sendError("You are muted in this group")
+			return
+		}
 		c.sendError("Failed to save message")
 		return
 	}
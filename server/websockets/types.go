@@ -28,6 +28,7 @@ const (
 	MessageTypeConversationUpdate = "conversation_update"
 	MessageTypePrivateMessageAck  = "private_message_ack"
 	MessageTypeGroupMessageAck    = "group_message_ack"
+	MessageTypePollUpdate         = "poll_update"
 )
 
 // Message represents a WebSocket message
@@ -78,8 +79,15 @@ type Hub struct {
 	leaveGroup chan *GroupLeaveRequest
 	db         *sql.DB // Database reference for group membership checks
 	mu         sync.RWMutex
+
+	// offline receives the direct messages of users without an open socket
+	offline OfflineHandler
 }
 
+// OfflineHandler is called with messages addressed to a user who has no open connection.
+// It runs under the hub lock and must not block.
+type OfflineHandler func(userID int, message Message)
+
 type GroupJoinRequest struct {
 	Client  *Client
 	GroupID string